@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/EngineerBetter/control-tower/bosh/internal/aws"
 	"github.com/EngineerBetter/control-tower/db"
 )
 
@@ -80,6 +81,18 @@ func (client *AWSClient) deployConcourse(creds []byte, detach bool) ([]byte, err
 	vmap["tags"] = t
 	flagFiles = append(flagFiles, "--ops-file", client.workingdir.PathInWorkingDir(extraTagsFilename))
 
+	env := client.concourseEnvironment()
+	env.ATCPublicIP = atcPublicIP
+	ops, err := env.ConfigureConcourseOps()
+	if err != nil {
+		return creds, fmt.Errorf("failed to render the concourse ops: [%v]", err)
+	}
+	opsFlags, err := saveConcourseOps(client.workingdir, ops)
+	if err != nil {
+		return creds, err
+	}
+	flagFiles = append(flagFiles, opsFlags...)
+
 	vs := vars(vmap)
 
 	directorPublicIP, err := client.outputs.Get("DirectorPublicIP")
@@ -102,6 +115,36 @@ func (client *AWSClient) deployConcourse(creds []byte, detach bool) ([]byte, err
 	return ioutil.ReadFile(client.workingdir.PathInWorkingDir(credsFilename))
 }
 
+// concourseEnvironment returns the Environment customising the concourse deployment from the config.
+// The cloud config, the stemcell upload and the deployment all start from it so that the vm_extensions,
+// disk types and stemcells the concourse ops refer to exist on the director
+func (client *AWSClient) concourseEnvironment() aws.Environment {
+	return aws.Environment{
+		ATCPort:               client.config.GetATCPort(),
+		DBDiskSizeGB:          client.config.GetDBDiskSizeGB(),
+		Domain:                concourseDomain(client.config),
+		ExternalDBHost:        client.config.GetExternalDBHost(),
+		ExternalDBName:        client.config.GetExternalDBName(),
+		ExternalDBPassword:    client.config.GetExternalDBPassword(),
+		ExternalDBPort:        client.config.GetExternalDBPort(),
+		ExternalDBUser:        client.config.GetExternalDBUser(),
+		ExtraHosts:            client.config.GetExtraHosts(),
+		LetsEncrypt:           client.config.GetLetsEncrypt(),
+		RequireIMDSv2:         client.config.GetRequireIMDSv2(),
+		TokenSigningKey:       client.config.GetTokenSigningKey(),
+		WebDiskSizeGB:         client.config.GetWebDiskSizeGB(),
+		WebInstanceCount:      client.config.GetWebInstanceCount(),
+		WorkerDiskSizeGB:      client.config.GetWorkerDiskSizeGB(),
+		WorkerDiskType:        client.config.GetWorkerDiskType(),
+		WorkerMaxTasks:        client.config.GetWorkerMaxTasks(),
+		WorkerPlacementGroup:  client.config.GetWorkerPlacementGroup(),
+		WorkerSize:            client.config.GetConcourseWorkerSize(),
+		WorkerStemcellOS:      client.config.GetWorkerStemcellOS(),
+		WorkerStemcellVersion: client.config.GetWorkerStemcellVersion(),
+		WorkerType:            client.config.GetWorkerType(),
+	}
+}
+
 func (client *AWSClient) buildTagsYaml(project interface{}, component string) (string, error) {
 	var b strings.Builder
 
@@ -176,6 +176,9 @@ func (client *AWSClient) createEnv(bosh boshcli.ICLI, state, creds []byte, custo
 		S3AWSSecretAccessKey: blobstoreSecretAccessKey,
 		Spot:                 client.config.IsSpot(),
 		WorkerType:           client.config.GetWorkerType(),
+		RequireIMDSv2:        client.config.GetRequireIMDSv2(),
+		MbusPort:             client.config.GetMbusPort(),
+		NATSPort:             client.config.GetNATSPort(),
 		CustomOperations:     customOps,
 	}, client.config.GetDirectorPassword(), client.config.GetDirectorCert(), client.config.GetDirectorKey(), client.config.GetDirectorCACert(), tags)
 	if err1 != nil {
@@ -240,30 +243,29 @@ func (client *AWSClient) updateCloudConfig(bosh boshcli.ICLI) error {
 		return err
 	}
 
-	return bosh.UpdateCloudConfig(aws.Environment{
-		AZ:                  client.config.GetAvailabilityZone(),
-		PublicSubnetID:      publicSubnetID,
-		PrivateSubnetID:     privateSubnetID,
-		ATCSecurityGroup:    aTCSecurityGroupID,
-		VMSecurityGroup:     vMsSecurityGroupID,
-		Spot:                client.config.IsSpot(),
-		ExternalIP:          directorPublicIP,
-		WorkerType:          client.config.GetWorkerType(),
-		PublicCIDR:          publicCIDR,
-		PublicCIDRGateway:   publicCIDRGateway,
-		PublicCIDRStatic:    publicCIDRStatic,
-		PublicCIDRReserved:  publicCIDRReserved,
-		PrivateCIDR:         privateCIDR,
-		PrivateCIDRGateway:  privateCIDRGateway,
-		PrivateCIDRReserved: privateCIDRReserved,
-	}, directorPublicIP, client.config.GetDirectorPassword(), client.config.GetDirectorCACert())
+	env := client.concourseEnvironment()
+	env.AZ = client.config.GetAvailabilityZone()
+	env.PublicSubnetID = publicSubnetID
+	env.PrivateSubnetID = privateSubnetID
+	env.ATCSecurityGroup = aTCSecurityGroupID
+	env.VMSecurityGroup = vMsSecurityGroupID
+	env.Spot = client.config.IsSpot()
+	env.ExternalIP = directorPublicIP
+	env.PublicCIDR = publicCIDR
+	env.PublicCIDRGateway = publicCIDRGateway
+	env.PublicCIDRStatic = publicCIDRStatic
+	env.PublicCIDRReserved = publicCIDRReserved
+	env.PrivateCIDR = privateCIDR
+	env.PrivateCIDRGateway = privateCIDRGateway
+	env.PrivateCIDRReserved = privateCIDRReserved
+	return bosh.UpdateCloudConfig(env, directorPublicIP, client.config.GetDirectorPassword(), client.config.GetDirectorCACert())
 }
 func (client *AWSClient) uploadConcourseStemcell(bosh boshcli.ICLI) error {
 	directorPublicIP, err := client.outputs.Get("DirectorPublicIP")
 	if err != nil {
 		return err
 	}
-	return bosh.UploadConcourseStemcell(aws.Environment{
-		ExternalIP: directorPublicIP,
-	}, directorPublicIP, client.config.GetDirectorPassword(), client.config.GetDirectorCACert())
+	env := client.concourseEnvironment()
+	env.ExternalIP = directorPublicIP
+	return bosh.UploadConcourseStemcell(env, directorPublicIP, client.config.GetDirectorPassword(), client.config.GetDirectorCACert())
 }
@@ -0,0 +1,111 @@
+package bosh
+
+import (
+	"io/ioutil"
+
+	"github.com/EngineerBetter/control-tower/bosh/internal/aws"
+	"github.com/EngineerBetter/control-tower/bosh/internal/boshcli/boshclifakes"
+	"github.com/EngineerBetter/control-tower/bosh/internal/workingdir"
+	"github.com/EngineerBetter/control-tower/config"
+	"github.com/EngineerBetter/control-tower/iaas/iaasfakes"
+	"github.com/EngineerBetter/control-tower/terraform/terraformfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AWSClient", func() {
+	var client *AWSClient
+	var boshCLI *boshclifakes.FakeICLI
+	var workingDir workingdir.IClient
+
+	BeforeEach(func() {
+		boshCLI = &boshclifakes.FakeICLI{}
+		outputs := &terraformfakes.FakeOutputs{}
+		outputs.GetReturns("1.2.3.4", nil)
+		var err error
+		workingDir, err = workingdir.New()
+		Expect(err).ToNot(HaveOccurred())
+		client = &AWSClient{
+			config: config.Config{
+				ATCPort:               8443,
+				ConcourseWorkerSize:   "12xlarge",
+				MbusPort:              7868,
+				NATSPort:              5222,
+				PrivateCIDR:           "10.0.1.0/24",
+				PublicCIDR:            "10.0.0.0/24",
+				RequireIMDSv2:         true,
+				WorkerMaxTasks:        8,
+				WorkerPlacementGroup:  "build-farm",
+				WorkerStemcellOS:      "ubuntu-jammy",
+				WorkerStemcellVersion: "1.200",
+				WorkerType:            "m6g",
+			},
+			outputs:    outputs,
+			workingdir: workingDir,
+			provider:   &iaasfakes.FakeProvider{},
+			boshCLI:    boshCLI,
+		}
+	})
+
+	AfterEach(func() {
+		Expect(workingDir.Cleanup()).To(Succeed())
+	})
+
+	It("uploads the stemcell for the architecture of the worker type", func() {
+		Expect(client.uploadConcourseStemcell(boshCLI)).To(Succeed())
+		env, _, _, _ := boshCLI.UploadConcourseStemcellArgsForCall(0)
+		Expect(env.(aws.Environment).WorkerType).To(Equal("m6g"))
+	})
+
+	It("renders the cloud config for the worker size", func() {
+		Expect(client.updateCloudConfig(boshCLI)).To(Succeed())
+		env, _, _, _ := boshCLI.UpdateCloudConfigArgsForCall(0)
+		Expect(env.(aws.Environment).WorkerSize).To(Equal("12xlarge"))
+		Expect(env.(aws.Environment).WorkerType).To(Equal("m6g"))
+	})
+
+	It("renders the cloud config with the worker vm extension the concourse ops refer to", func() {
+		Expect(client.updateCloudConfig(boshCLI)).To(Succeed())
+		env, _, _, _ := boshCLI.UpdateCloudConfigArgsForCall(0)
+		Expect(env.(aws.Environment).WorkerPlacementGroup).To(Equal("build-farm"))
+		Expect(env.(aws.Environment).RequireIMDSv2).To(BeTrue())
+	})
+
+	It("creates the director with the configured agent ports", func() {
+		_, _, err := client.createEnv(boshCLI, nil, nil, "")
+		Expect(err).ToNot(HaveOccurred())
+		_, env, _, _, _, _, _ := boshCLI.CreateEnvArgsForCall(0)
+		Expect(env.(aws.Environment).MbusPort).To(Equal(7868))
+		Expect(env.(aws.Environment).NATSPort).To(Equal(5222))
+	})
+
+	It("passes the concourse ops rendered from the config to bosh deploy", func() {
+		_, err := client.deployConcourse([]byte("creds"), false)
+		Expect(err).ToNot(HaveOccurred())
+		action, _, _, _, _, _, flags := boshCLI.RunAuthenticatedCommandArgsForCall(0)
+		Expect(action).To(Equal("deploy"))
+		opsPath := workingDir.PathInWorkingDir(concourseOpsFilename)
+		Expect(flags).To(ContainElement(opsPath))
+		for i, flag := range flags {
+			if flag == opsPath {
+				Expect(flags[i-1]).To(Equal("--ops-file"))
+			}
+		}
+		ops, err := ioutil.ReadFile(opsPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(ops)).To(ContainSubstring("max_active_tasks_per_worker"))
+		Expect(string(ops)).To(ContainSubstring("value: worker-placement"))
+		Expect(string(ops)).To(ContainSubstring("https://1.2.3.4:8443"))
+	})
+
+	It("does not pass an ops file when the config does not customise concourse", func() {
+		client.config = config.Config{
+			ConcourseWorkerSize: "xlarge",
+			WorkerType:          "m5",
+		}
+		_, err := client.deployConcourse([]byte("creds"), false)
+		Expect(err).ToNot(HaveOccurred())
+		_, _, _, _, _, _, flags := boshCLI.RunAuthenticatedCommandArgsForCall(0)
+		Expect(flags).ToNot(ContainElement(workingDir.PathInWorkingDir(concourseOpsFilename)))
+	})
+})
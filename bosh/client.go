@@ -13,6 +13,8 @@ import (
 	"github.com/EngineerBetter/control-tower/bosh/internal/boshcli"
 	"github.com/EngineerBetter/control-tower/bosh/internal/workingdir"
 	"github.com/EngineerBetter/control-tower/config"
+	"github.com/EngineerBetter/control-tower/util"
+	"github.com/asaskevich/govalidator"
 )
 
 // StateFilename is default name for bosh-init state file
@@ -50,7 +52,7 @@ func New(config config.ConfigView, outputs terraform.Outputs, stdout, stderr io.
 		return nil, err
 	}
 
-	boshCLI, err := boshcli.New(boshcli.DownloadBOSH())
+	boshCLI, err := boshcli.New(boshcli.DownloadBOSH(), boshcli.Context(util.Interrupted()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create boshCLI: [%v]", err)
 	}
@@ -137,3 +139,25 @@ func saveFilesToWorkingDir(workingdir workingdir.IClient, provider iaas.Provider
 	}
 	return nil
 }
+
+// saveConcourseOps saves the operations customising the concourse deployment to the working directory
+// and returns the flags passing them to bosh deploy, which are empty when ops makes no changes
+func saveConcourseOps(workingdir workingdir.IClient, ops string) ([]string, error) {
+	if ops == "" {
+		return nil, nil
+	}
+	path, err := workingdir.SaveFileToWorkingDir(concourseOpsFilename, []byte(ops))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save %s to working directory: [%v]", concourseOpsFilename, err)
+	}
+	return []string{"--ops-file", path}, nil
+}
+
+// concourseDomain returns the domain concourse is served on, which is empty when the
+// config holds the ATC public IP in its place
+func concourseDomain(config config.ConfigView) string {
+	if govalidator.IsIPv4(config.GetDomain()) {
+		return ""
+	}
+	return config.GetDomain()
+}
@@ -0,0 +1,36 @@
+package bosh
+
+import (
+	"github.com/EngineerBetter/control-tower/bosh/internal/concourseops"
+	"github.com/EngineerBetter/control-tower/util/yaml"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	goyaml "gopkg.in/yaml.v2"
+)
+
+var _ = Describe("concourse ops", func() {
+	It("give each instance group of the concourse manifest its own persistent disk", func() {
+		ops, err := concourseops.Render(concourseops.Params{
+			DiskSizes: concourseops.DiskSizes{DB: 20, Web: 10, Worker: 100},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		manifest, err := yaml.Interpolate(string(concourseManifestContents), ops, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		var deployment struct {
+			InstanceGroups []struct {
+				Name               string `yaml:"name"`
+				PersistentDiskType string `yaml:"persistent_disk_type"`
+			} `yaml:"instance_groups"`
+		}
+		Expect(goyaml.Unmarshal([]byte(manifest), &deployment)).To(Succeed())
+		diskTypes := map[string]string{}
+		for _, group := range deployment.InstanceGroups {
+			diskTypes[group.Name] = group.PersistentDiskType
+		}
+		Expect(diskTypes).To(HaveKeyWithValue("db", concourseops.DiskType("db")))
+		Expect(diskTypes).To(HaveKeyWithValue("web", concourseops.DiskType("web")))
+		Expect(diskTypes).To(HaveKeyWithValue("worker", concourseops.DiskType("worker")))
+	})
+})
@@ -10,6 +10,7 @@ const concourseCompatibilityFilename = "cup_compatibility.yml"
 const concourseGitHubAuthFilename = "github-auth.yml"
 const extraTagsFilename = "extra_tags.yml"
 const uaaCertFilename = "uaa-cert.yml"
+const concourseOpsFilename = "concourse-ops.yml"
 
 //go:generate go-bindata -pkg $GOPACKAGE -ignore \.git assets/... ../../control-tower-ops/... ../resource/assets/...
 var concourseGrafana = MustAsset("assets/grafana_dashboard.yml")
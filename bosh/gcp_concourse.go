@@ -5,6 +5,8 @@ import (
 	"io/ioutil"
 	"os"
 	"strings"
+
+	"github.com/EngineerBetter/control-tower/bosh/internal/gcp"
 )
 
 func (client *GCPClient) deployConcourse(creds []byte, detach bool) ([]byte, error) {
@@ -92,6 +94,18 @@ func (client *GCPClient) deployConcourse(creds []byte, detach bool) ([]byte, err
 	vmap["tags"] = t
 	flagFiles = append(flagFiles, "--ops-file", client.workingdir.PathInWorkingDir(extraTagsFilename))
 
+	env := client.concourseEnvironment()
+	env.ATCPublicIP = atcPublicIP
+	ops, err := env.ConfigureConcourseOps()
+	if err != nil {
+		return nil, fmt.Errorf("failed to render the concourse ops: [%v]", err)
+	}
+	opsFlags, err := saveConcourseOps(client.workingdir, ops)
+	if err != nil {
+		return nil, err
+	}
+	flagFiles = append(flagFiles, opsFlags...)
+
 	vs := vars(vmap)
 
 	directorPublicIP, err := client.outputs.Get("DirectorPublicIP")
@@ -114,6 +128,34 @@ func (client *GCPClient) deployConcourse(creds []byte, detach bool) ([]byte, err
 	return ioutil.ReadFile(client.workingdir.PathInWorkingDir(credsFilename))
 }
 
+// concourseEnvironment returns the Environment customising the concourse deployment from the config.
+// The cloud config, the stemcell upload and the deployment all start from it so that the vm_extensions,
+// AZs and stemcells the concourse ops refer to exist on the director
+func (client *GCPClient) concourseEnvironment() gcp.Environment {
+	return gcp.Environment{
+		ATCPort:               client.config.GetATCPort(),
+		DBDiskSizeGB:          client.config.GetDBDiskSizeGB(),
+		Domain:                concourseDomain(client.config),
+		ExternalDBHost:        client.config.GetExternalDBHost(),
+		ExternalDBName:        client.config.GetExternalDBName(),
+		ExternalDBPassword:    client.config.GetExternalDBPassword(),
+		ExternalDBPort:        client.config.GetExternalDBPort(),
+		ExternalDBUser:        client.config.GetExternalDBUser(),
+		ExtraHosts:            client.config.GetExtraHosts(),
+		LetsEncrypt:           client.config.GetLetsEncrypt(),
+		TokenSigningKey:       client.config.GetTokenSigningKey(),
+		WebDiskSizeGB:         client.config.GetWebDiskSizeGB(),
+		WebInstanceCount:      client.config.GetWebInstanceCount(),
+		WorkerDiskSizeGB:      client.config.GetWorkerDiskSizeGB(),
+		WorkerMaxTasks:        client.config.GetWorkerMaxTasks(),
+		WorkerNodeGroup:       client.config.GetWorkerNodeGroup(),
+		WorkerStemcellOS:      client.config.GetWorkerStemcellOS(),
+		WorkerStemcellVersion: client.config.GetWorkerStemcellVersion(),
+		WorkerZones:           client.config.GetWorkerZones(),
+		Zone:                  client.provider.Zone("", ""),
+	}
+}
+
 func (client *GCPClient) buildTagsYaml(project interface{}, component string) (string, error) {
 	var b strings.Builder
 
@@ -115,12 +115,13 @@ func (client *GCPClient) createEnv(bosh boshcli.ICLI, state, creds []byte, custo
 		Network:            network,
 		PublicSubnetwork:   publicSubnetwork,
 		PrivateSubnetwork:  privateSubnetwork,
-		Tags:               "[internal]",
 		ProjectID:          project,
 		GcpCredentialsJSON: credentialsPath,
 		ExternalIP:         directorPublicIP,
 		Spot:               client.config.IsSpot(),
 		PublicKey:          client.config.GetPublicKey(),
+		MbusPort:           client.config.GetMbusPort(),
+		NATSPort:           client.config.GetNATSPort(),
 		CustomOperations:   customOps,
 	}, client.config.GetDirectorPassword(), client.config.GetDirectorCert(), client.config.GetDirectorKey(), client.config.GetDirectorCACert(), tags)
 	if err1 != nil {
@@ -195,27 +196,27 @@ func (client *GCPClient) updateCloudConfig(bosh boshcli.ICLI) error {
 	if err != nil {
 		return err
 	}
-	return bosh.UpdateCloudConfig(gcp.Environment{
-		PublicCIDR:          client.config.GetPublicCIDR(),
-		PublicCIDRGateway:   publicCIDRGateway,
-		PublicCIDRStatic:    publicCIDRStatic,
-		PublicCIDRReserved:  publicCIDRReserved,
-		PrivateCIDRGateway:  privateCIDRGateway,
-		PrivateCIDRReserved: privateCIDRReserved,
-		PrivateCIDR:         client.config.GetPrivateCIDR(),
-		Spot:                client.config.IsSpot(),
-		PublicSubnetwork:    publicSubnetwork,
-		PrivateSubnetwork:   privateSubnetwork,
-		Zone:                zone,
-		Network:             network,
-	}, directorPublicIP, client.config.GetDirectorPassword(), client.config.GetDirectorCACert())
+	env := client.concourseEnvironment()
+	env.PublicCIDR = client.config.GetPublicCIDR()
+	env.PublicCIDRGateway = publicCIDRGateway
+	env.PublicCIDRStatic = publicCIDRStatic
+	env.PublicCIDRReserved = publicCIDRReserved
+	env.PrivateCIDRGateway = privateCIDRGateway
+	env.PrivateCIDRReserved = privateCIDRReserved
+	env.PrivateCIDR = client.config.GetPrivateCIDR()
+	env.Spot = client.config.IsSpot()
+	env.PublicSubnetwork = publicSubnetwork
+	env.PrivateSubnetwork = privateSubnetwork
+	env.Zone = zone
+	env.Network = network
+	return bosh.UpdateCloudConfig(env, directorPublicIP, client.config.GetDirectorPassword(), client.config.GetDirectorCACert())
 }
 func (client *GCPClient) uploadConcourseStemcell(bosh boshcli.ICLI) error {
 	directorPublicIP, err := client.outputs.Get("DirectorPublicIP")
 	if err != nil {
 		return err
 	}
-	return bosh.UploadConcourseStemcell(gcp.Environment{
-		ExternalIP: directorPublicIP,
-	}, directorPublicIP, client.config.GetDirectorPassword(), client.config.GetDirectorCACert())
+	env := client.concourseEnvironment()
+	env.ExternalIP = directorPublicIP
+	return bosh.UploadConcourseStemcell(env, directorPublicIP, client.config.GetDirectorPassword(), client.config.GetDirectorCACert())
 }
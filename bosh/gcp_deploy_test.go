@@ -0,0 +1,75 @@
+package bosh
+
+import (
+	"io/ioutil"
+
+	"github.com/EngineerBetter/control-tower/bosh/internal/boshcli/boshclifakes"
+	"github.com/EngineerBetter/control-tower/bosh/internal/gcp"
+	"github.com/EngineerBetter/control-tower/bosh/internal/workingdir"
+	"github.com/EngineerBetter/control-tower/config"
+	"github.com/EngineerBetter/control-tower/iaas/iaasfakes"
+	"github.com/EngineerBetter/control-tower/terraform/terraformfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GCPClient", func() {
+	var client *GCPClient
+	var boshCLI *boshclifakes.FakeICLI
+	var workingDir workingdir.IClient
+
+	BeforeEach(func() {
+		boshCLI = &boshclifakes.FakeICLI{}
+		outputs := &terraformfakes.FakeOutputs{}
+		outputs.GetReturns("1.2.3.4", nil)
+		provider := &iaasfakes.FakeProvider{}
+		provider.ZoneReturns("europe-west1-b")
+		var err error
+		workingDir, err = workingdir.New()
+		Expect(err).ToNot(HaveOccurred())
+		client = &GCPClient{
+			config: config.Config{
+				ConcourseWorkerSize: "xlarge",
+				PrivateCIDR:         "10.0.1.0/24",
+				PublicCIDR:          "10.0.0.0/24",
+				WebInstanceCount:    2,
+				WorkerNodeGroup:     "build-farm",
+				WorkerZones:         []string{"europe-west1-c"},
+			},
+			outputs:    outputs,
+			workingdir: workingDir,
+			provider:   provider,
+			boshCLI:    boshCLI,
+		}
+	})
+
+	AfterEach(func() {
+		Expect(workingDir.Cleanup()).To(Succeed())
+	})
+
+	It("renders the cloud config with the worker zones and vm extension the concourse ops refer to", func() {
+		Expect(client.updateCloudConfig(boshCLI)).To(Succeed())
+		env, _, _, _ := boshCLI.UpdateCloudConfigArgsForCall(0)
+		Expect(env.(gcp.Environment).WorkerNodeGroup).To(Equal("build-farm"))
+		Expect(env.(gcp.Environment).WorkerZones).To(Equal([]string{"europe-west1-c"}))
+	})
+
+	It("passes the concourse ops rendered from the config to bosh deploy", func() {
+		_, err := client.deployConcourse([]byte("creds"), false)
+		Expect(err).ToNot(HaveOccurred())
+		action, _, _, _, _, _, flags := boshCLI.RunAuthenticatedCommandArgsForCall(0)
+		Expect(action).To(Equal("deploy"))
+		opsPath := workingDir.PathInWorkingDir(concourseOpsFilename)
+		Expect(flags).To(ContainElement(opsPath))
+		for i, flag := range flags {
+			if flag == opsPath {
+				Expect(flags[i-1]).To(Equal("--ops-file"))
+			}
+		}
+		ops, err := ioutil.ReadFile(opsPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(ops)).To(ContainSubstring("path: /instance_groups/name=web/instances\n  type: replace\n  value: 2\n"))
+		Expect(string(ops)).To(ContainSubstring("value: worker-placement"))
+		Expect(string(ops)).To(ContainSubstring("path: /instance_groups/name=worker/azs\n  type: replace\n  value:\n  - z1\n  - z2\n"))
+	})
+})
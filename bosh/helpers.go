@@ -37,6 +37,21 @@ func (s temporaryStore) Get(key string) ([]byte, error) {
 	return s[key], nil
 }
 
+func (s temporaryStore) GetMany(keys []string) (map[string][]byte, error) {
+	values := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		values[key] = append([]byte{}, s[key]...)
+	}
+	return values, nil
+}
+
+func (s temporaryStore) SetMany(values map[string][]byte) error {
+	for key, value := range values {
+		s[key] = value
+	}
+	return nil
+}
+
 func splitTags(ts []string) (map[string]string, error) {
 	m := make(map[string]string)
 	for _, t := range ts {
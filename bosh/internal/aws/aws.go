@@ -2,68 +2,191 @@ package aws
 
 import (
 	"bytes"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
 
+	"github.com/EngineerBetter/control-tower/bosh/internal/batch"
+	"github.com/EngineerBetter/control-tower/bosh/internal/bucketcheck"
+	"github.com/EngineerBetter/control-tower/bosh/internal/concourseops"
+	"github.com/EngineerBetter/control-tower/bosh/internal/directorvars"
+	"github.com/EngineerBetter/control-tower/bosh/internal/meta"
+	"github.com/EngineerBetter/control-tower/bosh/internal/stemcells"
+	"github.com/EngineerBetter/control-tower/bosh/internal/vmextensions"
 	"github.com/EngineerBetter/control-tower/iaas"
 	"github.com/EngineerBetter/control-tower/resource"
 	"github.com/EngineerBetter/control-tower/util"
 	"github.com/EngineerBetter/control-tower/util/yaml"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 )
 
 // Environment holds all the parameters AWS IAAS needs
 type Environment struct {
-	AccessKeyID           string
-	ATCSecurityGroup      string
-	AZ                    string
-	BlobstoreBucket       string
-	CustomOperations      string
-	DBCACert              string
-	DBHost                string
-	DBName                string
-	DBPassword            string
-	DBPort                string
-	DBUsername            string
-	DefaultKeyName        string
-	DefaultSecurityGroups []string
-	ExternalIP            string
-	InternalCIDR          string
-	InternalGateway       string
-	InternalIP            string
-	PrivateCIDR           string
-	PrivateCIDRGateway    string
-	PrivateCIDRReserved   string
-	PrivateKey            string
-	PrivateSubnetID       string
-	PublicCIDR            string
-	PublicCIDRGateway     string
-	PublicCIDRReserved    string
-	PublicCIDRStatic      string
-	PublicSubnetID        string
-	Region                string
-	S3AWSAccessKeyID      string
-	S3AWSSecretAccessKey  string
-	SecretAccessKey       string
-	Spot                  bool
-	VMSecurityGroup       string
-	WorkerType            string
-}
-
-var allOperations = resource.AWSCPIOps + resource.ExternalIPOps + resource.AWSDirectorCustomOps
+	AccessKeyID             string
+	ATCPort                 int
+	ATCPublicIP             string
+	ATCSecurityGroup        string
+	AZ                      string
+	BlobstoreBucket         string
+	BlobstoreNoProxy        []string
+	BlobstoreProxyURL       string
+	CustomOperations        string
+	DBCACert                string
+	DBDiskSizeGB            int
+	DBHost                  string
+	DBName                  string
+	DBPassword              string
+	DBPort                  string
+	DBUsername              string
+	DefaultKeyName          string
+	DefaultSecurityGroups   []string
+	DirectorAZ              string
+	DirectorMaxTasks        int
+	DirectorSubnetID        string
+	Domain                  string
+	EnableLocalDNS          bool
+	ExternalDBHost          string
+	ExternalDBName          string
+	ExternalDBPassword      string
+	ExternalDBPort          string
+	ExternalDBUser          string
+	ExternalIP              string
+	ExtraHosts              map[string]string
+	GCFailedGracePeriod     string
+	GCInterval              string
+	GCMissingGracePeriod    string
+	GCOneOffGracePeriod     string
+	InternalCIDR            string
+	InternalGateway         string
+	InternalIP              string
+	LetsEncrypt             bool
+	MbusPort                int
+	NATSPort                int
+	PrivateCIDR             string
+	PrivateCIDRGateway      string
+	PrivateCIDRReserved     string
+	PrivateKey              string
+	PrivateSubnetID         string
+	ProxyCACert             string
+	PublicCIDR              string
+	PublicCIDRGateway       string
+	PublicCIDRReserved      string
+	PublicCIDRStatic        string
+	PublicSubnetID          string
+	Region                  string
+	RequireIMDSv2           bool
+	S3AWSAccessKeyID        string
+	S3AWSSecretAccessKey    string
+	SecretAccessKey         string
+	Spot                    bool
+	StemcellArchitecture    string
+	TaskRetentionDays       int
+	Tenancy                 string
+	TokenSigningKey         string
+	UpdateStrategy          string
+	VMExtensions            []string
+	VMSecurityGroup         string
+	WebDiskSizeGB           int
+	WebInstanceCount        int
+	WorkerDiskKMSKeyID      string
+	WorkerDiskSizeGB        int
+	WorkerDiskType          string
+	WorkerDrainTimeout      string
+	WorkerMaxTasks          int
+	WorkerPlacementGroup    string
+	WorkerPools             []concourseops.WorkerPool
+	WorkerRebalanceInterval string
+	WorkerRegistryCAs       []string
+	WorkerRuntime           string
+	WorkerSize              string
+	WorkerStemcellOS        string
+	WorkerStemcellVersion   string
+	WorkerType              string
+}
+
+func (e Environment) operations() string {
+	ops := resource.AWSCPIOps + resource.ExternalIPOps + resource.AWSDirectorCustomOps
+	if e.EnableLocalDNS {
+		ops += resource.LocalDNSOps
+	}
+	if e.RequireIMDSv2 {
+		ops += resource.AWSDirectorIMDSv2Ops
+	}
+	if e.BlobstoreProxyURL != "" {
+		ops += resource.DirectorBlobstoreProxyOps
+	}
+	if e.ProxyCACert != "" {
+		ops += resource.DirectorTrustedCertsOps
+	}
+	if e.DirectorMaxTasks > 0 {
+		ops += resource.DirectorMaxTasksOps
+	}
+	if e.TaskRetentionDays > 0 {
+		ops += resource.DirectorTaskRetentionOps
+	}
+	return ops + e.CustomOperations
+}
+
+// checkTaskRetention validates the limits on the tasks kept by the director,
+// which default to those of the director release when unset
+func (e Environment) checkTaskRetention() error {
+	if e.DirectorMaxTasks < 0 {
+		return fmt.Errorf("director max tasks must be positive, got %d", e.DirectorMaxTasks)
+	}
+	if e.TaskRetentionDays < 0 {
+		return fmt.Errorf("director task retention must be a positive number of days, got %d", e.TaskRetentionDays)
+	}
+	return nil
+}
+
+// directorPlacement returns the AZ and subnet of the director VM, each falling back to the one
+// of the workers when DirectorAZ or DirectorSubnetID is unset. A DirectorSubnetID in another AZ
+// needs DirectorAZ too, and InternalCIDR and InternalIP must lie within the director's subnet
+func (e Environment) directorPlacement() (string, string) {
+	az, subnetID := e.AZ, e.PublicSubnetID
+	if e.DirectorAZ != "" {
+		az = e.DirectorAZ
+	}
+	if e.DirectorSubnetID != "" {
+		subnetID = e.DirectorSubnetID
+	}
+	return az, subnetID
+}
 
 // ConfigureDirectorManifestCPI interpolates all the Environment parameters and
 // required release versions into ready to use Director manifest
 func (e Environment) ConfigureDirectorManifestCPI() (string, error) {
+	if err := e.checkTaskRetention(); err != nil {
+		return "", err
+	}
+	mbusPort, natsPort, err := directorvars.AgentPorts(e.MbusPort, e.NATSPort)
+	if err != nil {
+		return "", err
+	}
+	noProxy, err := directorvars.NoProxy(e.BlobstoreProxyURL, e.InternalIP, e.BlobstoreNoProxy)
+	if err != nil {
+		return "", err
+	}
+	// custom-ops.yml already trusts the DB CA, which is kept alongside the proxy CA
+	trustedCerts, err := directorvars.TrustedCerts(e.ProxyCACert, e.DBCACert)
+	if err != nil {
+		return "", err
+	}
+	az, subnetID := e.directorPlacement()
 	cpiResource := resource.Get(resource.AWSCPI)
 	stemcellResource := resource.Get(resource.AWSStemcell)
 
-	return yaml.Interpolate(resource.DirectorManifest, allOperations+e.CustomOperations, map[string]interface{}{
+	return yaml.Interpolate(resource.DirectorManifest, e.operations(), map[string]interface{}{
 		"cpi_url":                  cpiResource.URL,
 		"cpi_version":              cpiResource.Version,
 		"cpi_sha1":                 cpiResource.SHA1,
@@ -75,11 +198,11 @@ func (e Environment) ConfigureDirectorManifestCPI() (string, error) {
 		"access_key_id":            e.AccessKeyID,
 		"secret_access_key":        e.SecretAccessKey,
 		"region":                   e.Region,
-		"az":                       e.AZ,
+		"az":                       az,
 		"default_key_name":         e.DefaultKeyName,
 		"default_security_groups":  e.DefaultSecurityGroups,
 		"private_key":              e.PrivateKey,
-		"subnet_id":                e.PublicSubnetID,
+		"subnet_id":                subnetID,
 		"external_ip":              e.ExternalIP,
 		"blobstore_bucket":         e.BlobstoreBucket,
 		"db_ca_cert":               e.DBCACert,
@@ -90,17 +213,29 @@ func (e Environment) ConfigureDirectorManifestCPI() (string, error) {
 		"db_username":              e.DBUsername,
 		"s3_aws_access_key_id":     e.S3AWSAccessKeyID,
 		"s3_aws_secret_access_key": e.S3AWSSecretAccessKey,
+		"max_tasks":                e.DirectorMaxTasks,
+		"task_retention_days":      e.TaskRetentionDays,
+		"mbus_port":                mbusPort,
+		"nats_port":                natsPort,
+		"blobstore_proxy_url":      e.BlobstoreProxyURL,
+		"blobstore_no_proxy":       noProxy,
+		"trusted_certs":            trustedCerts,
 	})
 }
 
 type awsCloudConfigParams struct {
 	ATCSecurityGroupID  string
 	AvailabilityZone    string
+	Graviton            bool
+	InstanceStorage     bool
 	PrivateSubnetID     string
 	PublicSubnetID      string
 	Spot                bool
+	VMExtensions        string
 	VMsSecurityGroupID  string
+	WorkerDiskKMSKeyID  string
 	WorkerType          string
+	WorkerFamily        string
 	PublicCIDR          string
 	PublicCIDRStatic    string
 	PublicCIDRReserved  string
@@ -108,6 +243,8 @@ type awsCloudConfigParams struct {
 	PrivateCIDR         string
 	PrivateCIDRGateway  string
 	PrivateCIDRReserved string
+	WorkerPoolVMTypes   string
+	DiskTypes           string
 }
 
 // IAASCheck returns the IAAS provider
@@ -117,8 +254,51 @@ func (e Environment) IAASCheck() iaas.Name {
 
 // ConfigureDirectorCloudConfig inserts values from the environment into the config template passed as argument
 func (e Environment) ConfigureDirectorCloudConfig() (string, error) {
+	arch, err := e.stemcellArchitecture()
+	if err != nil {
+		return "", err
+	}
+	instanceStorage, err := e.instanceStorage()
+	if err != nil {
+		return "", err
+	}
+	// Worker types other than m4 and m5 are rendered from their family name
+	var workerFamily string
+	if arch == archARM64 || instanceStorage {
+		workerFamily = e.WorkerType
+	}
+	if workerFamily != "" && e.Spot {
+		return "", fmt.Errorf("spot instances are not supported for worker type %q", e.WorkerType)
+	}
+	if err = e.checkWorkerSize(arch, workerFamily); err != nil {
+		return "", err
+	}
+	if err = e.checkWorkerDiskKMSKey(instanceStorage); err != nil {
+		return "", err
+	}
+	definitions, err := e.vmExtensions()
+	if err != nil {
+		return "", err
+	}
+	vmExtensions, _, err := vmextensions.Render(definitions)
+	if err != nil {
+		return "", err
+	}
+	workerPoolVMTypes, err := concourseops.RenderWorkerPoolVMTypes(e.WorkerPools, e.workerPoolCloudProperties)
+	if err != nil {
+		return "", err
+	}
+	diskTypes, err := concourseops.RenderDiskTypes(e.diskSizes(), e.diskCloudProperties)
+	if err != nil {
+		return "", err
+	}
 	templateParams := awsCloudConfigParams{
 		AvailabilityZone:    e.AZ,
+		Graviton:            arch == archARM64,
+		InstanceStorage:     instanceStorage,
+		VMExtensions:        vmExtensions,
+		WorkerDiskKMSKeyID:  e.WorkerDiskKMSKeyID,
+		WorkerFamily:        workerFamily,
 		VMsSecurityGroupID:  e.VMSecurityGroup,
 		ATCSecurityGroupID:  e.ATCSecurityGroup,
 		PublicSubnetID:      e.PublicSubnetID,
@@ -132,6 +312,8 @@ func (e Environment) ConfigureDirectorCloudConfig() (string, error) {
 		PrivateCIDR:         e.PrivateCIDR,
 		PrivateCIDRGateway:  e.PrivateCIDRGateway,
 		PrivateCIDRReserved: e.PrivateCIDRReserved,
+		WorkerPoolVMTypes:   workerPoolVMTypes,
+		DiskTypes:           strings.TrimSuffix(diskTypes, "\n"),
 	}
 
 	cc, err := util.RenderTemplate("cloud-config", resource.AWSDirectorCloudConfig, templateParams)
@@ -141,51 +323,415 @@ func (e Environment) ConfigureDirectorCloudConfig() (string, error) {
 	return string(cc), err
 }
 
-// ConfigureConcourseStemcell returns the stemcell location string for an AWS specific stemcell for the required concourse version
-func (e Environment) ConfigureConcourseStemcell() (string, error) {
-	var ops []struct {
-		Path  string
-		Value json.RawMessage
+const (
+	archAMD64 = "amd64"
+	archARM64 = "arm64"
+)
+
+// defaultTenancy runs workers on shared hardware
+const defaultTenancy = "default"
+
+var gravitonWorkerType = regexp.MustCompile(`^[a-z]+[0-9]+g[a-z]*$`)
+
+var instanceStoreWorkerType = regexp.MustCompile(`^[a-z]+[0-9]+[a-z]*d[a-z]*$`)
+
+// workerPoolCloudProperties returns the cloud_properties of the vm_type of a worker pool, which
+// uses an EBS disk like the default workers. Spot is not applied as there is no known bid price
+func (e Environment) workerPoolCloudProperties(pool concourseops.WorkerPool) map[string]interface{} {
+	disk := map[string]interface{}{"size": 200000, "type": defaultEBSType, "encrypted": true}
+	if e.WorkerDiskKMSKeyID != "" {
+		disk["kms_key_arn"] = e.WorkerDiskKMSKeyID
 	}
-	err := json.Unmarshal([]byte(resource.AWSReleaseVersions), &ops)
-	if err != nil {
-		return "", err
+	cloudProperties := map[string]interface{}{
+		"instance_type":   pool.InstanceType,
+		"ephemeral_disk":  disk,
+		"security_groups": []string{e.VMSecurityGroup},
+	}
+	if e.RequireIMDSv2 {
+		cloudProperties["metadata_options"] = imdsv2MetadataOptions()
 	}
-	var version string
-	for _, op := range ops {
-		if op.Path != "/stemcells/alias=xenial/version" {
-			continue
+	return cloudProperties
+}
+
+// imdsv2MetadataOptions returns the cloud_properties metadata options rejecting IMDSv1 requests
+func imdsv2MetadataOptions() map[string]interface{} {
+	return map[string]interface{}{"http_tokens": "required"}
+}
+
+// familyMissingSizes are the worker sizes with no instance type in families rendered from their name, by
+// stemcell architecture. No such family has 10xlarge instances and Graviton ones stop at 16xlarge
+var familyMissingSizes = map[string][]string{
+	archAMD64: {"10xlarge"},
+	archARM64: {"10xlarge", "24xlarge"},
+}
+
+// checkWorkerSize errors if WorkerSize has no instance type in workerFamily, rather than
+// deploying workers of another size
+func (e Environment) checkWorkerSize(arch, workerFamily string) error {
+	if workerFamily == "" {
+		return nil
+	}
+	for _, size := range familyMissingSizes[arch] {
+		if size == e.WorkerSize {
+			return fmt.Errorf("worker type %q has no %s instances, choose another worker size", e.WorkerType, e.WorkerSize)
 		}
-		err := json.Unmarshal(op.Value, &version)
-		if err != nil {
-			return "", err
+	}
+	return nil
+}
+
+// defaultEBSType is the volume type of EBS disks
+const defaultEBSType = "gp2"
+
+// diskCloudProperties returns the cloud_properties of the persistent disk_type of instanceGroup.
+// The worker disk follows the KMS key of the worker VMs
+func (e Environment) diskCloudProperties(instanceGroup string) map[string]interface{} {
+	cloudProperties := map[string]interface{}{"type": defaultEBSType, "encrypted": true}
+	if instanceGroup == "worker" && e.WorkerDiskKMSKeyID != "" {
+		cloudProperties["kms_key_arn"] = e.WorkerDiskKMSKeyID
+	}
+	return cloudProperties
+}
+
+// instanceStorage reports whether workers use instance store disks rather than EBS,
+// erroring if the worker type has no instance store. WorkerDiskType is ebs or instance-store
+func (e Environment) instanceStorage() (bool, error) {
+	switch e.WorkerDiskType {
+	case "", "ebs":
+		return false, nil
+	case "instance-store":
+		if !instanceStoreWorkerType.MatchString(e.WorkerType) {
+			return false, fmt.Errorf("worker type %q does not support instance-store disks, use a family such as m5d or m6gd", e.WorkerType)
+		}
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown worker disk type %q, must be ebs or instance-store", e.WorkerDiskType)
+	}
+}
+
+// stemcellArchitecture returns the stemcell architecture required by the worker type,
+// erroring if StemcellArchitecture is set to a different one
+func (e Environment) stemcellArchitecture() (string, error) {
+	arch := archAMD64
+	if gravitonWorkerType.MatchString(e.WorkerType) {
+		arch = archARM64
+	}
+	switch e.StemcellArchitecture {
+	case "", arch:
+		return arch, nil
+	case archAMD64, archARM64:
+		return "", fmt.Errorf("worker type %q requires a %s stemcell, not %s", e.WorkerType, arch, e.StemcellArchitecture)
+	default:
+		return "", fmt.Errorf("unknown stemcell architecture %q", e.StemcellArchitecture)
+	}
+}
+
+// vmExtensions returns the user defined vm_extension definitions along with the one
+// constraining worker placement when WorkerPlacementGroup or a dedicated Tenancy is set,
+// which also carries the metadata options of the workers when RequireIMDSv2 is set.
+// The placement group must already exist, the AWS CPI launches the workers into it but
+// does not create it
+func (e Environment) vmExtensions() ([]string, error) {
+	tenancy, err := e.tenancy()
+	if err != nil {
+		return nil, err
+	}
+	cloudProperties := map[string]interface{}{}
+	if tenancy != defaultTenancy {
+		cloudProperties["tenancy"] = tenancy
+	}
+	if e.RequireIMDSv2 {
+		cloudProperties["metadata_options"] = imdsv2MetadataOptions()
+	}
+	if e.WorkerPlacementGroup != "" {
+		if err := checkPlacementGroup(e.WorkerPlacementGroup); err != nil {
+			return nil, err
 		}
+		cloudProperties["placement_group"] = e.WorkerPlacementGroup
+	}
+	if len(cloudProperties) == 0 {
+		return e.VMExtensions, nil
+	}
+	placement, err := vmextensions.Placement(cloudProperties)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]string{}, e.VMExtensions...), placement), nil
+}
+
+// maxPlacementGroupLength is the longest name AWS allows for a placement group
+const maxPlacementGroupLength = 255
+
+// checkPlacementGroup validates the name of the placement group the workers are launched into
+func checkPlacementGroup(name string) error {
+	if strings.TrimSpace(name) != name || name == "" {
+		return fmt.Errorf("worker placement group %q must not be blank or have surrounding whitespace", name)
+	}
+	if len(name) > maxPlacementGroupLength {
+		return fmt.Errorf("worker placement group %q is longer than %d characters", name, maxPlacementGroupLength)
+	}
+	return nil
+}
+
+// checkWorkerDiskKMSKey validates the KMS key encrypting the EBS disks of the workers,
+// which the AWS CPI requires as an ARN
+func (e Environment) checkWorkerDiskKMSKey(instanceStorage bool) error {
+	if e.WorkerDiskKMSKeyID == "" {
+		return nil
+	}
+	if instanceStorage {
+		return errors.New("a worker disk KMS key cannot be used with instance-store disks")
+	}
+	if !strings.HasPrefix(e.WorkerDiskKMSKeyID, "arn:aws") || !strings.Contains(e.WorkerDiskKMSKeyID, ":kms:") {
+		return fmt.Errorf("worker disk KMS key %q must be a KMS key ARN", e.WorkerDiskKMSKeyID)
+	}
+	return nil
+}
+
+// tenancy returns the tenancy of the worker instances. The director is left on
+// shared hardware as its burstable instance type cannot be run with dedicated tenancy
+func (e Environment) tenancy() (string, error) {
+	switch e.Tenancy {
+	case "":
+		return defaultTenancy, nil
+	case defaultTenancy, "dedicated":
+		return e.Tenancy, nil
+	}
+	return "", fmt.Errorf("unknown tenancy %q, must be default or dedicated", e.Tenancy)
+}
+
+// diskSizes returns the sizes of the persistent disks of the concourse instance groups
+func (e Environment) diskSizes() concourseops.DiskSizes {
+	return concourseops.DiskSizes{DB: e.DBDiskSizeGB, Web: e.WebDiskSizeGB, Worker: e.WorkerDiskSizeGB}
+}
+
+// ConfigureConcourseOps returns the operations that customise the concourse deployment for the Environment
+func (e Environment) ConfigureConcourseOps() (string, error) {
+	definitions, err := e.vmExtensions()
+	if err != nil {
+		return "", err
+	}
+	workerStemcell, err := e.workerStemcell()
+	if err != nil {
+		return "", err
+	}
+	_, vmExtensions, err := vmextensions.Render(definitions)
+	if err != nil {
+		return "", err
+	}
+	gc := concourseops.GC{
+		Interval:           e.GCInterval,
+		OneOffGracePeriod:  e.GCOneOffGracePeriod,
+		MissingGracePeriod: e.GCMissingGracePeriod,
+		FailedGracePeriod:  e.GCFailedGracePeriod,
 	}
-	if version == "" {
-		return "", errors.New("did not find stemcell version in versions.json")
+	return concourseops.Render(concourseops.Params{
+		ATCPort:     e.ATCPort,
+		ATCPublicIP: e.ATCPublicIP,
+		DiskSizes:   e.diskSizes(),
+		Domain:      e.Domain,
+		ExternalDB: concourseops.ExternalDB{
+			Host:     e.ExternalDBHost,
+			Port:     e.ExternalDBPort,
+			Name:     e.ExternalDBName,
+			User:     e.ExternalDBUser,
+			Password: e.ExternalDBPassword,
+		},
+		ExtraHosts:              e.ExtraHosts,
+		GC:                      gc,
+		LetsEncrypt:             e.LetsEncrypt,
+		TokenSigningKey:         e.TokenSigningKey,
+		UpdateStrategy:          e.UpdateStrategy,
+		WebInstances:            e.WebInstanceCount,
+		WorkerDrainTimeout:      e.WorkerDrainTimeout,
+		WorkerMaxTasks:          e.WorkerMaxTasks,
+		WorkerPools:             e.WorkerPools,
+		WorkerRebalanceInterval: e.WorkerRebalanceInterval,
+		WorkerRegistryCAs:       e.WorkerRegistryCAs,
+		WorkerRuntime:           e.WorkerRuntime,
+		WorkerStemcell:          workerStemcell,
+		WorkerVMExtensions:      vmExtensions,
+	})
+}
+
+// ConfigureConcourseStemcell returns the stemcell location string for an AWS specific stemcell for the required concourse version.
+// It is always amd64, Graviton workers run on the stemcell returned by ConfigureWorkerStemcell
+func (e Environment) ConfigureConcourseStemcell() (string, error) {
+	version, err := stemcells.Version(iaas.AWS, resource.AWSReleaseVersions)
+	if err != nil {
+		return "", err
 	}
-	return fmt.Sprintf("https://s3.amazonaws.com/bosh-aws-light-stemcells/%s/light-bosh-stemcell-%s-aws-xen-hvm-ubuntu-xenial-go_agent.tgz", version, version), nil
+	return stemcellURL("ubuntu-xenial", version, archAMD64), nil
+}
+
+// RenderAll writes the director manifest, cloud config, concourse ops and stemcell URL
+// of the Environment into dir, without contacting the IAAS or the director, so that
+// they can be deployed by other tooling. The concourse ops are only written when
+// the Environment customises the concourse deployment, and the worker stemcell URL
+// when the workers run on their own stemcell
+func (e Environment) RenderAll(dir string) error {
+	director, err := e.ConfigureDirectorManifestCPI()
+	if err != nil {
+		return fmt.Errorf("failed to render the director manifest: [%v]", err)
+	}
+	cloudConfig, err := e.ConfigureDirectorCloudConfig()
+	if err != nil {
+		return fmt.Errorf("failed to render the cloud config: [%v]", err)
+	}
+	concourseOps, err := e.ConfigureConcourseOps()
+	if err != nil {
+		return fmt.Errorf("failed to render the concourse ops: [%v]", err)
+	}
+	stemcell, err := e.ConfigureConcourseStemcell()
+	if err != nil {
+		return fmt.Errorf("failed to resolve the stemcell: [%v]", err)
+	}
+	files := map[string]string{
+		"director.yml":     director,
+		"cloud-config.yml": cloudConfig,
+		"stemcell-url.txt": stemcell + "\n",
+	}
+	if concourseOps != "" {
+		files["concourse-ops.yml"] = concourseOps
+	}
+	workerStemcell, err := e.ConfigureWorkerStemcell()
+	if err != nil {
+		return fmt.Errorf("failed to resolve the worker stemcell: [%v]", err)
+	}
+	if workerStemcell != "" {
+		files["worker-stemcell-url.txt"] = workerStemcell + "\n"
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0600); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Store holds the abstraction of a aws storage artifact
 type Store struct {
-	s3     s3iface.S3API
-	bucket string
+	s3        s3iface.S3API
+	session   *session.Session
+	bucket    string
+	keyPrefix string
 }
 
-// NewStore returns a reference to a new Store
-func NewStore(s3 s3iface.S3API, bucket string) *Store {
-	return &Store{
-		s3:     s3,
-		bucket: bucket,
+// StoreOption configures a Store at construction
+type StoreOption func(*Store) error
+
+// CheckBucket returns a StoreOption which fails Store construction when the
+// bucket does not exist or is not accessible, rather than letting Get treat
+// every key as missing
+func CheckBucket() StoreOption {
+	return func(s *Store) error {
+		return bucketcheck.Exists(s.s3, s.bucket)
+	}
+}
+
+// CheckWrite returns a StoreOption which fails Store construction when the
+// credentials can not write to the bucket, see CanWrite
+func CheckWrite() StoreOption {
+	return func(s *Store) error {
+		return s.CanWrite()
 	}
 }
 
+// RecordMeta returns a StoreOption which records the bucket, region and IAAS
+// of the Store the first time it is used, so that later runs can read them with Meta
+func RecordMeta(region string) StoreOption {
+	return func(s *Store) error {
+		return meta.Record(meta.Meta{
+			Bucket: s.bucket,
+			Region: region,
+			IAAS:   iaas.Name(iaas.AWS).String(),
+		}, s.Get, s.Set)
+	}
+}
+
+// Endpoint returns a StoreOption which sends the S3 requests of the Store to url
+// using path style addressing, such as a LocalStack endpoint used in testing.
+// It only applies to a Store built by NewStoreFromInstanceProfile and must come
+// before any other StoreOption that contacts S3
+func Endpoint(url string) StoreOption {
+	return func(s *Store) error {
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			return fmt.Errorf("S3 endpoint %q must be an http or https URL", url)
+		}
+		if s.session == nil {
+			return errors.New("an S3 endpoint can only be set on a Store built from a session")
+		}
+		s.s3 = s3.New(s.session, aws.NewConfig().WithEndpoint(url).WithS3ForcePathStyle(true))
+		return nil
+	}
+}
+
+// NewStore returns a reference to a new Store. keyPrefix is prepended to
+// every key so that several environments can share a bucket.
+func NewStore(s3 s3iface.S3API, bucket, keyPrefix string, opts ...StoreOption) (*Store, error) {
+	return newStore(&Store{
+		s3:        s3,
+		bucket:    bucket,
+		keyPrefix: keyPrefix,
+	}, opts)
+}
+
+func newStore(s *Store, opts []StoreOption) (*Store, error) {
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// NewStoreFromInstanceProfile returns a reference to a new Store whose S3 client
+// uses the default credential chain: environment variables, then the shared
+// config, then the IAM role of the EC2 instance it is running on. When region is
+// empty it is taken from the environment or shared config, and failing that from
+// the metadata of the instance. keyPrefix is prepended to every key, as with NewStore
+func NewStoreFromInstanceProfile(region, bucket, keyPrefix string, opts ...StoreOption) (*Store, error) {
+	config := aws.NewConfig()
+	if region != "" {
+		config = config.WithRegion(region)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *config,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if aws.StringValue(sess.Config.Region) == "" {
+		if region, err = instanceRegion(sess); err != nil {
+			return nil, fmt.Errorf("no region given and failed to read it from the instance metadata: [%v]", err)
+		}
+		sess = sess.Copy(aws.NewConfig().WithRegion(region))
+	}
+	return newStore(&Store{
+		s3:        s3.New(sess),
+		session:   sess,
+		bucket:    bucket,
+		keyPrefix: keyPrefix,
+	}, opts)
+}
+
+// instanceRegion returns the region of the EC2 instance it is running on, it is replaced in tests
+var instanceRegion = func(sess *session.Session) (string, error) {
+	return ec2metadata.New(sess).Region()
+}
+
+func (s *Store) objectKey(key string) string {
+	return path.Join(s.keyPrefix, key)
+}
+
 // Get returns the contents of a Store element identified with a key
 func (s *Store) Get(key string) ([]byte, error) {
 	result, err := s.s3.GetObject(&s3.GetObjectInput{
 		Bucket: aws.String(s.bucket),
-		Key:    aws.String(key),
+		Key:    aws.String(s.objectKey(key)),
 	})
 	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == s3.ErrCodeNoSuchKey {
 		return nil, nil
@@ -202,7 +748,30 @@ func (s *Store) Set(key string, value []byte) error {
 	_, err := s.s3.PutObject(&s3.PutObjectInput{
 		Body:   bytes.NewReader(value),
 		Bucket: aws.String(s.bucket),
-		Key:    aws.String(key),
+		Key:    aws.String(s.objectKey(key)),
 	})
 	return err
 }
+
+// Meta returns the metadata recorded by RecordMeta. found is false
+// when the Store has not been used before
+func (s *Store) Meta() (m meta.Meta, found bool, err error) {
+	return meta.Read(s.Get)
+}
+
+// GetMany returns the contents of the Store elements identified with keys
+func (s *Store) GetMany(keys []string) (map[string][]byte, error) {
+	return batch.Get(keys, s.Get)
+}
+
+// SetMany stores the contents of several Store elements identified with their keys
+func (s *Store) SetMany(values map[string][]byte) error {
+	return batch.Set(values, s.Set)
+}
+
+// CanWrite writes and deletes a sentinel key, returning an error when the credentials
+// can not write to the bucket. Checking before a deploy avoids it failing to upload
+// its state once the director has already been created
+func (s *Store) CanWrite() error {
+	return bucketcheck.Writable(s.s3, s.bucket, s.objectKey(bucketcheck.WriteCheckKey))
+}
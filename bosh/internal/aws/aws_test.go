@@ -1,18 +1,29 @@
 package aws
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"text/template"
 	"text/template/parse"
+	"time"
 
+	"github.com/EngineerBetter/control-tower/bosh/internal/concourseops"
+	"github.com/EngineerBetter/control-tower/bosh/internal/cost"
 	"github.com/EngineerBetter/control-tower/resource"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 )
@@ -20,17 +31,37 @@ import (
 type mockS3API struct {
 	s3iface.S3API
 	getObjectOutput *s3.GetObjectOutput
+	headBucketErr   error
+	putObjectErr    error
+	deleteInput     *s3.DeleteObjectInput
+	getObjectInput  *s3.GetObjectInput
+	putObjectInput  *s3.PutObjectInput
 	err             error
 }
 
 func (m *mockS3API) GetObject(in *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	m.getObjectInput = in
 	return m.getObjectOutput, m.err
 }
 
 func (m *mockS3API) PutObject(in *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	m.putObjectInput = in
+	if m.putObjectErr != nil {
+		return nil, m.putObjectErr
+	}
 	return nil, m.err
 }
 
+func (m *mockS3API) DeleteObject(in *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	m.deleteInput = in
+	return &s3.DeleteObjectOutput{}, nil
+
+}
+
+func (m *mockS3API) HeadBucket(in *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+	return &s3.HeadBucketOutput{}, m.headBucketErr
+}
+
 func TestStore_Get(t *testing.T) {
 	type fields struct {
 		s3     s3iface.S3API
@@ -159,6 +190,141 @@ func TestStore_Set(t *testing.T) {
 	}
 }
 
+func TestNewStore_CheckWrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr string
+	}{
+		{
+			name: "bucket writable",
+		},
+		{
+			name:    "bucket read only",
+			err:     awserr.NewRequestFailure(awserr.New("AccessDenied", "Access Denied", nil), 403, "request-id"),
+			wantErr: `bucket "my bucket" is not writable with the current credentials`,
+		},
+		{
+			name:    "other failure",
+			err:     errors.New("an error"),
+			wantErr: `failed to write to bucket "my bucket": [an error]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &mockS3API{putObjectErr: tt.err}
+			_, err := NewStore(m, "my bucket", "env", CheckWrite())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("NewStore() error = %v", err)
+				}
+				if m.deleteInput == nil || *m.deleteInput.Key != "env/.control-tower-write-check" {
+					t.Errorf("NewStore() expected to delete the write check key, deleted %v", m.deleteInput)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("NewStore() error = %v, want %v", err, tt.wantErr)
+			}
+			if m.deleteInput != nil {
+				t.Errorf("NewStore() deleted %v after failing to write it", *m.deleteInput.Key)
+			}
+		})
+	}
+}
+
+func TestNewStore_CheckBucket(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr string
+	}{
+		{
+			name: "bucket present",
+		},
+		{
+			name:    "bucket absent",
+			err:     awserr.NewRequestFailure(awserr.New("NotFound", "Not Found", nil), 404, "request-id"),
+			wantErr: `bucket "my bucket" does not exist`,
+		},
+		{
+			name:    "bucket not accessible",
+			err:     awserr.NewRequestFailure(awserr.New("Forbidden", "Forbidden", nil), 403, "request-id"),
+			wantErr: `bucket "my bucket" is not accessible with the current credentials`,
+		},
+		{
+			name:    "other failure",
+			err:     errors.New("an error"),
+			wantErr: `failed to check bucket "my bucket": [an error]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewStore(&mockS3API{headBucketErr: tt.err}, "my bucket", "", CheckBucket())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("NewStore() error = %v", err)
+				}
+				if s == nil {
+					t.Errorf("NewStore() returned a nil Store")
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("NewStore() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStore_KeyPrefix(t *testing.T) {
+	tests := []struct {
+		name      string
+		keyPrefix string
+		want      string
+	}{
+		{
+			name: "no prefix",
+			want: "state.json",
+		},
+		{
+			name:      "prefix",
+			keyPrefix: "my-env",
+			want:      "my-env/state.json",
+		},
+		{
+			name:      "prefix with trailing slash",
+			keyPrefix: "my-env/",
+			want:      "my-env/state.json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &mockS3API{
+				getObjectOutput: &s3.GetObjectOutput{
+					Body: ioutil.NopCloser(strings.NewReader("my object body")),
+				},
+			}
+			s, err := NewStore(m, "my bucket", tt.keyPrefix)
+			if err != nil {
+				t.Fatalf("NewStore() error = %v", err)
+			}
+			if _, err := s.Get("state.json"); err != nil {
+				t.Fatalf("Store.Get() error = %v", err)
+			}
+			if got := *m.getObjectInput.Key; got != tt.want {
+				t.Errorf("Store.Get() key = %v, want %v", got, tt.want)
+			}
+			if err := s.Set("state.json", []byte("{}")); err != nil {
+				t.Fatalf("Store.Set() error = %v", err)
+			}
+			if got := *m.putObjectInput.Key; got != tt.want {
+				t.Errorf("Store.Set() key = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestEnvironment_ConfigureDirectorCloudConfig(t *testing.T) {
 
 	fullTemplateParams := Environment{
@@ -232,6 +398,241 @@ func TestEnvironment_ConfigureDirectorCloudConfig(t *testing.T) {
 				return a == b, fmt.Sprintf("templating failed while rendering without spots")
 			},
 		},
+		{
+			name:    "Success- vm extensions rendered",
+			fields:  fullTemplateParams,
+			want:    getFixture("../fixtures/aws_cloud_config_vm_extensions.yml"),
+			wantErr: false,
+			init: func(e Environment) Environment {
+				n := e
+				n.VMExtensions = []string{"name: placement\ncloud_properties:\n  placement_group: concourse-workers\n", "name: worker-profile\ncloud_properties:\n  iam_instance_profile: concourse-worker\n"}
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return a == b, fmt.Sprintf("templating failed while rendering vm extensions")
+			},
+		},
+		{
+			name:    "Success- worker pools rendered",
+			fields:  fullTemplateParams,
+			want:    getFixture("../fixtures/aws_cloud_config_worker_pools.yml"),
+			wantErr: false,
+			init: func(e Environment) Environment {
+				n := e
+				n.WorkerPools = []concourseops.WorkerPool{
+					{Name: "general", InstanceType: "m5.xlarge", Count: 3},
+					{Name: "big", InstanceType: "p3.2xlarge", Count: 1, Tags: []string{"big"}},
+				}
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return a == b, fmt.Sprintf("templating failed while rendering worker pools")
+			},
+		},
+		{
+			name:    "Failure- worker pool without instance type",
+			fields:  fullTemplateParams,
+			wantErr: true,
+			init: func(e Environment) Environment {
+				n := e
+				n.WorkerPools = []concourseops.WorkerPool{{Name: "general", Count: 1}}
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return true, ""
+			},
+		},
+		{
+			name:    "Success- worker placement group rendered",
+			fields:  fullTemplateParams,
+			want:    getFixture("../fixtures/aws_cloud_config_placement_group.yml"),
+			wantErr: false,
+			init: func(e Environment) Environment {
+				n := e
+				n.WorkerPlacementGroup = "build-farm"
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return a == b, fmt.Sprintf("templating failed while rendering worker placement group")
+			},
+		},
+		{
+			name:    "Failure- worker placement group with surrounding whitespace",
+			fields:  fullTemplateParams,
+			wantErr: true,
+			init: func(e Environment) Environment {
+				n := e
+				n.WorkerPlacementGroup = " build-farm"
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return true, ""
+			},
+		},
+		{
+			name:    "Success- dedicated tenancy rendered",
+			fields:  fullTemplateParams,
+			want:    getFixture("../fixtures/aws_cloud_config_dedicated_tenancy.yml"),
+			wantErr: false,
+			init: func(e Environment) Environment {
+				n := e
+				n.Tenancy = "dedicated"
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return a == b, fmt.Sprintf("templating failed while rendering dedicated tenancy")
+			},
+		},
+		{
+			name:    "Success- per component disk sizes rendered",
+			fields:  fullTemplateParams,
+			want:    getFixture("../fixtures/aws_cloud_config_disk_sizes.yml"),
+			wantErr: false,
+			init: func(e Environment) Environment {
+				n := e
+				n.DBDiskSizeGB = 20
+				n.WebDiskSizeGB = 10
+				n.WorkerDiskSizeGB = 100
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return a == b, fmt.Sprintf("templating failed while rendering per component disk sizes")
+			},
+		},
+		{
+			name:    "Success- worker disks follow the worker disk KMS key",
+			fields:  fullTemplateParams,
+			wantErr: false,
+			init: func(e Environment) Environment {
+				n := e
+				n.WorkerDiskKMSKeyID = "arn:aws:kms:eu-west-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+				n.DBDiskSizeGB = 20
+				n.WorkerDiskSizeGB = 100
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				want := []string{
+					"- name: db-disk\n  disk_size: 20480\n  cloud_properties:\n    encrypted: true\n    type: gp2\n",
+					"- name: worker-disk\n  disk_size: 102400\n  cloud_properties:\n    encrypted: true\n    kms_key_arn: arn:aws:kms:eu-west-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab\n    type: gp2\n",
+					"      size: 200_000\n      type: gp2\n      encrypted: true\n      kms_key_arn:",
+				}
+				for _, w := range want {
+					if !strings.Contains(a, w) {
+						return false, fmt.Sprintf("expected the cloud config to contain %q", w)
+					}
+				}
+				return true, ""
+			},
+		},
+		{
+			name:    "Failure- negative disk size",
+			fields:  fullTemplateParams,
+			wantErr: true,
+			init: func(e Environment) Environment {
+				n := e
+				n.WebDiskSizeGB = -10
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return true, ""
+			},
+		},
+		{
+			name:    "Success- IMDSv2 required",
+			fields:  fullTemplateParams,
+			want:    getFixture("../fixtures/aws_cloud_config_imdsv2.yml"),
+			wantErr: false,
+			init: func(e Environment) Environment {
+				n := e
+				n.RequireIMDSv2 = true
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return a == b, fmt.Sprintf("templating failed while rendering IMDSv2 metadata options")
+			},
+		},
+		{
+			name:    "Success- default tenancy rendered",
+			fields:  fullTemplateParams,
+			want:    getFixture("../fixtures/aws_cloud_config_full.yml"),
+			wantErr: false,
+			init: func(e Environment) Environment {
+				n := e
+				n.Tenancy = "default"
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return a == b, fmt.Sprintf("templating failed while rendering default tenancy")
+			},
+		},
+		{
+			name:    "Failure- unknown tenancy",
+			fields:  fullTemplateParams,
+			wantErr: true,
+			init: func(e Environment) Environment {
+				n := e
+				n.Tenancy = "host"
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return true, ""
+			},
+		},
+		{
+			name:    "Success- worker disk KMS key rendered",
+			fields:  fullTemplateParams,
+			want:    getFixture("../fixtures/aws_cloud_config_worker_disk_kms_key.yml"),
+			wantErr: false,
+			init: func(e Environment) Environment {
+				n := e
+				n.WorkerDiskKMSKeyID = "arn:aws:kms:eu-west-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return a == b, fmt.Sprintf("templating failed while rendering the worker disk KMS key")
+			},
+		},
+		{
+			name:    "Failure- worker disk KMS key is not an ARN",
+			fields:  fullTemplateParams,
+			wantErr: true,
+			init: func(e Environment) Environment {
+				n := e
+				n.WorkerDiskKMSKeyID = "1234abcd-12ab-34cd-56ef-1234567890ab"
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return true, ""
+			},
+		},
+		{
+			name:    "Failure- worker disk KMS key with instance-store disks",
+			fields:  fullTemplateParams,
+			wantErr: true,
+			init: func(e Environment) Environment {
+				n := e
+				n.WorkerType = "m5d"
+				n.WorkerDiskType = "instance-store"
+				n.WorkerDiskKMSKeyID = "arn:aws:kms:eu-west-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return true, ""
+			},
+		},
+		{
+			name:    "Failure- invalid vm extension",
+			fields:  fullTemplateParams,
+			wantErr: true,
+			init: func(e Environment) Environment {
+				n := e
+				n.VMExtensions = []string{"cloud_properties: {}"}
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return true, ""
+			},
+		},
 		{
 			name:    "Success- worker type is m5",
 			fields:  fullTemplateParams,
@@ -260,33 +661,164 @@ func TestEnvironment_ConfigureDirectorCloudConfig(t *testing.T) {
 				return a == b, fmt.Sprintf("m4 worker templating failed")
 			},
 		},
+		{
+			name:    "Success- worker type is Graviton m6g",
+			fields:  fullTemplateParams,
+			want:    getFixture("../fixtures/aws_cloud_config_graviton.yml"),
+			wantErr: false,
+			init: func(e Environment) Environment {
+				n := e
+				n.WorkerType = "m6g"
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				if !strings.Contains(a, "- name: concourse-web-small\n  cloud_properties:\n    instance_type: t2.small") {
+					return false, "graviton workers changed the web vm_types from t2"
+				}
+				return a == b, fmt.Sprintf("graviton worker templating failed")
+			},
+		},
+		{
+			name:    "Success- instance store worker disks",
+			fields:  fullTemplateParams,
+			want:    getFixture("../fixtures/aws_cloud_config_instance_store.yml"),
+			wantErr: false,
+			init: func(e Environment) Environment {
+				n := e
+				n.WorkerType = "m5d"
+				n.WorkerDiskType = "instance-store"
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return a == b, fmt.Sprintf("instance store worker templating failed")
+			},
+		},
+		{
+			name:    "Failure- instance store with a worker type without instance store",
+			fields:  fullTemplateParams,
+			wantErr: true,
+			init: func(e Environment) Environment {
+				n := e
+				n.WorkerType = "m5"
+				n.WorkerDiskType = "instance-store"
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return true, ""
+			},
+		},
+		{
+			name:    "Failure- unknown worker disk type",
+			fields:  fullTemplateParams,
+			wantErr: true,
+			init: func(e Environment) Environment {
+				n := e
+				n.WorkerDiskType = "nfs"
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return true, ""
+			},
+		},
+		{
+			name:    "Failure- Graviton worker type with amd64 stemcell",
+			fields:  fullTemplateParams,
+			wantErr: true,
+			init: func(e Environment) Environment {
+				n := e
+				n.WorkerType = "c7g"
+				n.StemcellArchitecture = "amd64"
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return true, ""
+			},
+		},
+		{
+			name:    "Success- Graviton worker type with a size it has",
+			fields:  fullTemplateParams,
+			want:    getFixture("../fixtures/aws_cloud_config_graviton.yml"),
+			wantErr: false,
+			init: func(e Environment) Environment {
+				n := e
+				n.WorkerType = "m6g"
+				n.WorkerSize = "12xlarge"
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return a == b, fmt.Sprintf("graviton worker templating failed")
+			},
+		},
+		{
+			name:    "Failure- Graviton worker type without the worker size",
+			fields:  fullTemplateParams,
+			wantErr: true,
+			init: func(e Environment) Environment {
+				n := e
+				n.WorkerType = "m6g"
+				n.WorkerSize = "24xlarge"
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return true, ""
+			},
+		},
+		{
+			name:    "Failure- instance store worker type without the worker size",
+			fields:  fullTemplateParams,
+			wantErr: true,
+			init: func(e Environment) Environment {
+				n := e
+				n.WorkerType = "m5d"
+				n.WorkerDiskType = "instance-store"
+				n.WorkerSize = "10xlarge"
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return true, ""
+			},
+		},
+		{
+			name:    "Failure- x86 worker type with arm64 stemcell",
+			fields:  fullTemplateParams,
+			wantErr: true,
+			init: func(e Environment) Environment {
+				n := e
+				n.WorkerType = "m5"
+				n.StemcellArchitecture = "arm64"
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return true, ""
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := tt.init(tt.fields)
+			got, err := e.ConfigureDirectorCloudConfig()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Environment.ConfigureDirectorCloudConfig()\nerror expected:  %v\nreceived error:  %v", tt.wantErr, err)
+				return
+			}
+			passed, message := tt.validate(got, tt.want)
+			if !passed {
+				t.Errorf(message)
+			}
+		})
+	}
+}
+
+func listTemplFields(t *template.Template) map[string]int {
+	m := make(map[string]int)
+	return listNodeFields(t.Tree.Root, m)
+}
+
+func listNodeFields(node parse.Node, res map[string]int) map[string]int {
+	if node.Type() == parse.NodeIf {
+		var re = regexp.MustCompile(`{{(if|if eq)?\s\.(\w+)(}}|\s)`)
+		res[re.FindStringSubmatch(node.String())[2]] = 1
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			e := tt.init(tt.fields)
-			got, err := e.ConfigureDirectorCloudConfig()
-			if (err != nil) != tt.wantErr {
-				t.Errorf("Environment.ConfigureDirectorCloudConfig()\nerror expected:  %v\nreceived error:  %v", tt.wantErr, err)
-				return
-			}
-			passed, message := tt.validate(got, tt.want)
-			if !passed {
-				t.Errorf(message)
-			}
-		})
-	}
-}
-
-func listTemplFields(t *template.Template) map[string]int {
-	m := make(map[string]int)
-	return listNodeFields(t.Tree.Root, m)
-}
-
-func listNodeFields(node parse.Node, res map[string]int) map[string]int {
-	if node.Type() == parse.NodeIf {
-		var re = regexp.MustCompile(`{{(if|if eq)?\s\.(\w+)(}}|\s)`)
-		res[re.FindStringSubmatch(node.String())[2]] = 1
-	}
 
 	if node.Type() == parse.NodeAction {
 		var re = regexp.MustCompile(`{{\.(.*)}}`)
@@ -336,10 +868,11 @@ func getStemcellFixture(fixture string) string {
 
 func TestEnvironment_ConfigureConcourseStemcell(t *testing.T) {
 	tests := []struct {
-		name    string
-		want    string
-		wantErr bool
-		fixture string
+		name       string
+		want       string
+		wantErr    bool
+		fixture    string
+		workerType string
 	}{
 		{
 			name:    "parse versions and provide a valid stemcell url",
@@ -347,6 +880,13 @@ func TestEnvironment_ConfigureConcourseStemcell(t *testing.T) {
 			wantErr: false,
 			fixture: "stemcell_version",
 		},
+		{
+			name:       "keep the amd64 stemcell url for Graviton workers",
+			want:       "https://s3.amazonaws.com/bosh-aws-light-stemcells/5/light-bosh-stemcell-5-aws-xen-hvm-ubuntu-xenial-go_agent.tgz",
+			wantErr:    false,
+			fixture:    "stemcell_version",
+			workerType: "m6g",
+		},
 		{
 			name:    "parse versions and indicate no stemcell was found",
 			want:    "",
@@ -356,7 +896,7 @@ func TestEnvironment_ConfigureConcourseStemcell(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			e := Environment{}
+			e := Environment{WorkerType: tt.workerType}
 			resource.AWSReleaseVersions = getStemcellFixture(tt.fixture)
 			got, err := e.ConfigureConcourseStemcell()
 			if (err != nil) != tt.wantErr {
@@ -370,3 +910,865 @@ func TestEnvironment_ConfigureConcourseStemcell(t *testing.T) {
 		})
 	}
 }
+
+func TestEnvironment_ConfigureWorkerStemcell(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     Environment
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "workers run on the concourse stemcell",
+			env:  Environment{},
+		},
+		{
+			name: "resolve the worker stemcell",
+			env:  Environment{WorkerStemcellOS: "ubuntu-bionic", WorkerStemcellVersion: "1.10"},
+			want: "https://s3.amazonaws.com/bosh-aws-light-stemcells/1.10/light-bosh-stemcell-1.10-aws-xen-hvm-ubuntu-bionic-go_agent.tgz",
+		},
+		{
+			name: "resolve an arm64 worker stemcell for Graviton workers",
+			env:  Environment{WorkerStemcellOS: "ubuntu-bionic", WorkerStemcellVersion: "1.10", WorkerType: "m6g"},
+			want: "https://s3.amazonaws.com/bosh-aws-light-stemcells/1.10/light-bosh-stemcell-1.10-aws-xen-hvm-ubuntu-bionic-arm64-go_agent.tgz",
+		},
+		{
+			name:    "Graviton workers without a worker stemcell",
+			env:     Environment{WorkerType: "m6g"},
+			wantErr: true,
+		},
+		{
+			name:    "worker stemcell without OS",
+			env:     Environment{WorkerStemcellVersion: "1.10"},
+			wantErr: true,
+		},
+		{
+			name:    "worker stemcell with invalid version",
+			env:     Environment{WorkerStemcellOS: "ubuntu-bionic", WorkerStemcellVersion: "latest"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.env.ConfigureWorkerStemcell()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Environment.ConfigureWorkerStemcell() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Environment.ConfigureWorkerStemcell() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvironment_CheckStemcellURLs(t *testing.T) {
+	defer func(v string) { resource.AWSReleaseVersions = v }(resource.AWSReleaseVersions)
+	resource.AWSReleaseVersions = getStemcellFixture("stemcell_version")
+	defer func(f func(string) error) { headStemcell = f }(headStemcell)
+	const (
+		concourseURL = "https://s3.amazonaws.com/bosh-aws-light-stemcells/5/light-bosh-stemcell-5-aws-xen-hvm-ubuntu-xenial-go_agent.tgz"
+		workerURL    = "https://s3.amazonaws.com/bosh-aws-light-stemcells/1.10/light-bosh-stemcell-1.10-aws-xen-hvm-ubuntu-bionic-go_agent.tgz"
+	)
+	tests := []struct {
+		name     string
+		env      Environment
+		missing  map[string]bool
+		wantHead []string
+		wantErr  string
+	}{
+		{
+			name:     "concourse stemcell only",
+			env:      Environment{},
+			wantHead: []string{concourseURL},
+		},
+		{
+			name:     "distinct concourse and worker stemcells",
+			env:      Environment{WorkerStemcellOS: "ubuntu-bionic", WorkerStemcellVersion: "1.10"},
+			wantHead: []string{concourseURL, workerURL},
+		},
+		{
+			name:     "missing worker stemcell",
+			env:      Environment{WorkerStemcellOS: "ubuntu-bionic", WorkerStemcellVersion: "1.10"},
+			missing:  map[string]bool{workerURL: true},
+			wantHead: []string{concourseURL, workerURL},
+			wantErr:  "failed to resolve 1 stemcell(s): [worker " + workerURL + ": got status 404 Not Found]",
+		},
+		{
+			name:     "invalid worker stemcell",
+			env:      Environment{WorkerStemcellOS: "ubuntu-bionic"},
+			wantHead: []string{concourseURL},
+			wantErr:  "failed to resolve 1 stemcell(s): [worker: invalid worker stemcell version",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var heads []string
+			headStemcell = func(url string) error {
+				heads = append(heads, url)
+				if tt.missing[url] {
+					return errors.New("got status 404 Not Found")
+				}
+				return nil
+			}
+			err := tt.env.CheckStemcellURLs()
+			if !reflect.DeepEqual(heads, tt.wantHead) {
+				t.Errorf("Environment.CheckStemcellURLs() checked %v, want %v", heads, tt.wantHead)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Environment.CheckStemcellURLs() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("Environment.CheckStemcellURLs() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnvironment_ConfigureConcourseOps(t *testing.T) {
+	e := Environment{
+		ExtraHosts:    map[string]string{"artifacts.internal": "10.0.1.5"},
+		WorkerRuntime: "containerd",
+	}
+	got, err := e.ConfigureConcourseOps()
+	if err != nil {
+		t.Fatalf("Environment.ConfigureConcourseOps() error = %v", err)
+	}
+	if !strings.Contains(got, "10.0.1.5 artifacts.internal") {
+		t.Errorf("Environment.ConfigureConcourseOps() = %s\nexpected to contain the extra host", got)
+	}
+	if !strings.Contains(got, "value: containerd") {
+		t.Errorf("Environment.ConfigureConcourseOps() = %s\nexpected to contain the worker runtime", got)
+	}
+
+	e.WorkerRuntime = "houdini"
+	if _, err := e.ConfigureConcourseOps(); err == nil {
+		t.Errorf("Environment.ConfigureConcourseOps() expected an error for an unknown worker runtime")
+	}
+
+	e.WorkerRuntime = ""
+	e.ExternalDBHost = "10.0.2.10"
+	e.ExternalDBPort = "5432"
+	e.ExternalDBName = "atc"
+	e.ExternalDBUser = "concourse"
+	e.ExternalDBPassword = "s3cret"
+	got, err = e.ConfigureConcourseOps()
+	if err != nil {
+		t.Fatalf("Environment.ConfigureConcourseOps() error = %v", err)
+	}
+	if !strings.Contains(got, "value: 10.0.2.10") {
+		t.Errorf("Environment.ConfigureConcourseOps() = %s\nexpected to contain the external database host", got)
+	}
+
+	e.ExternalDBPassword = ""
+	if _, err := e.ConfigureConcourseOps(); err == nil {
+		t.Errorf("Environment.ConfigureConcourseOps() expected an error for an incomplete external database")
+	}
+
+	e.ExternalDBHost = ""
+	e.ExternalDBPort = ""
+	e.ExternalDBName = ""
+	e.ExternalDBUser = ""
+	e.WorkerPlacementGroup = "build-farm"
+	e.WorkerDrainTimeout = "45m"
+	e.WorkerRebalanceInterval = "1h30m"
+	got, err = e.ConfigureConcourseOps()
+	if err != nil {
+		t.Fatalf("Environment.ConfigureConcourseOps() error = %v", err)
+	}
+	if !strings.Contains(got, "value: worker-placement") {
+		t.Errorf("Environment.ConfigureConcourseOps() = %s\nexpected to attach the worker placement vm extension", got)
+	}
+	if !strings.Contains(got, "value: 45m") || !strings.Contains(got, "value: 1h30m") {
+		t.Errorf("Environment.ConfigureConcourseOps() = %s\nexpected to contain the worker drain timeout and rebalance interval", got)
+	}
+
+	e.WorkerDrainTimeout = "forever"
+	if _, err := e.ConfigureConcourseOps(); err == nil {
+		t.Errorf("Environment.ConfigureConcourseOps() expected an error for an invalid worker drain timeout")
+	}
+
+	e.WorkerDrainTimeout = ""
+	e.WorkerMaxTasks = 5
+	got, err = e.ConfigureConcourseOps()
+	if err != nil {
+		t.Fatalf("Environment.ConfigureConcourseOps() error = %v", err)
+	}
+	if !strings.Contains(got, "path: /instance_groups/name=web/jobs/name=web/properties/max_active_tasks_per_worker?\n  type: replace\n  value: 5") {
+		t.Errorf("Environment.ConfigureConcourseOps() = %s\nexpected to contain the worker max tasks", got)
+	}
+
+	e.WorkerMaxTasks = -1
+	if _, err := e.ConfigureConcourseOps(); err == nil {
+		t.Errorf("Environment.ConfigureConcourseOps() expected an error for negative worker max tasks")
+	}
+
+	e.WorkerMaxTasks = 0
+	e.WebDiskSizeGB = 10
+	got, err = e.ConfigureConcourseOps()
+	if err != nil {
+		t.Fatalf("Environment.ConfigureConcourseOps() error = %v", err)
+	}
+	if !strings.Contains(got, "path: /instance_groups/name=web/persistent_disk_type?\n  type: replace\n  value: web-disk") {
+		t.Errorf("Environment.ConfigureConcourseOps() = %s\nexpected to give the web instance group its own disk type", got)
+	}
+
+	e.WebDiskSizeGB = 0
+	e.WebInstanceCount = 3
+	got, err = e.ConfigureConcourseOps()
+	if err != nil {
+		t.Fatalf("Environment.ConfigureConcourseOps() error = %v", err)
+	}
+	if !strings.Contains(got, "path: /instance_groups/name=web/instances\n  type: replace\n  value: 3") {
+		t.Errorf("Environment.ConfigureConcourseOps() = %s\nexpected to contain the web instance count", got)
+	}
+
+	e.WebInstanceCount = 0
+	e.ATCPort = 4443
+	e.ATCPublicIP = "34.1.2.3"
+	got, err = e.ConfigureConcourseOps()
+	if err != nil {
+		t.Fatalf("Environment.ConfigureConcourseOps() error = %v", err)
+	}
+	if !strings.Contains(got, "properties/tls_bind_port?\n  type: replace\n  value: 4443") || !strings.Contains(got, "value: https://34.1.2.3:4443") {
+		t.Errorf("Environment.ConfigureConcourseOps() = %s\nexpected to contain the ATC port", got)
+	}
+
+	e.ATCPort = 0
+	e.WorkerType = "m6g"
+	if _, err := e.ConfigureConcourseOps(); err == nil {
+		t.Errorf("Environment.ConfigureConcourseOps() expected an error for Graviton workers without a worker stemcell")
+	}
+
+	e.WorkerStemcellOS = "ubuntu-jammy"
+	e.WorkerStemcellVersion = "1.200"
+	got, err = e.ConfigureConcourseOps()
+	if err != nil {
+		t.Fatalf("Environment.ConfigureConcourseOps() error = %v", err)
+	}
+	if !strings.Contains(got, "ubuntu-jammy") {
+		t.Errorf("Environment.ConfigureConcourseOps() = %s\nexpected to run Graviton workers on the worker stemcell", got)
+	}
+}
+
+type mapS3API struct {
+	s3iface.S3API
+	mu         sync.Mutex
+	objects    map[string][]byte
+	versions   []*s3.ObjectVersion
+	failDelete string
+}
+
+func (m *mapS3API) GetObject(in *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	object, ok := m.objects[*in.Key]
+	if !ok {
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "no such key", nil)
+	}
+	return &s3.GetObjectOutput{Body: ioutil.NopCloser(bytes.NewReader(object))}, nil
+}
+
+func (m *mapS3API) PutObject(in *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	object, err := ioutil.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[*in.Key] = object
+	return nil, nil
+}
+
+// ListObjectVersions returns the versions matching the prefix two per page, the marker being
+// the index of the next version
+func (m *mapS3API) ListObjectVersions(in *s3.ListObjectVersionsInput) (*s3.ListObjectVersionsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var matching []*s3.ObjectVersion
+	for _, v := range m.versions {
+		if strings.HasPrefix(*v.Key, *in.Prefix) {
+			matching = append(matching, v)
+		}
+	}
+	start := 0
+	if in.KeyMarker != nil {
+		start, _ = strconv.Atoi(*in.KeyMarker)
+	}
+	end := start + 2
+	if end >= len(matching) {
+		return &s3.ListObjectVersionsOutput{Versions: matching[start:], IsTruncated: aws.Bool(false)}, nil
+	}
+	return &s3.ListObjectVersionsOutput{
+		Versions:            matching[start:end],
+		IsTruncated:         aws.Bool(true),
+		NextKeyMarker:       aws.String(strconv.Itoa(end)),
+		NextVersionIdMarker: aws.String(*matching[end].VersionId),
+	}, nil
+}
+
+func (m *mapS3API) DeleteObject(in *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if *in.VersionId == m.failDelete {
+		return nil, errors.New("AccessDenied")
+	}
+	for i, v := range m.versions {
+		if *v.Key == *in.Key && *v.VersionId == *in.VersionId {
+			m.versions = append(m.versions[:i], m.versions[i+1:]...)
+			break
+		}
+	}
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func TestStore_PruneVersions(t *testing.T) {
+	version := func(key, id string, age time.Duration, latest bool) *s3.ObjectVersion {
+		return &s3.ObjectVersion{
+			Key:          aws.String(key),
+			VersionId:    aws.String(id),
+			LastModified: aws.Time(time.Now().Add(-age)),
+			IsLatest:     aws.Bool(latest),
+		}
+	}
+	versions := func() []*s3.ObjectVersion {
+		return []*s3.ObjectVersion{
+			version("env/state.json", "v5", time.Hour, true),
+			version("env/state.json", "v4", 2*24*time.Hour, false),
+			version("env/state.json.bak", "b1", 90*24*time.Hour, true),
+			version("env/state.json", "v3", 10*24*time.Hour, false),
+			version("env/state.json", "v2", 40*24*time.Hour, false),
+			version("env/state.json", "v1", 60*24*time.Hour, false),
+		}
+	}
+	tests := []struct {
+		name        string
+		keep        int
+		maxAge      time.Duration
+		failDelete  string
+		wantDeleted []string
+		wantKept    []string
+		wantErr     string
+	}{
+		{
+			name:        "keeps the newest versions",
+			keep:        2,
+			wantDeleted: []string{"v3", "v2", "v1"},
+			wantKept:    []string{"v5", "v4", "b1"},
+		},
+		{
+			name:        "keeps versions younger than the max age",
+			keep:        1,
+			maxAge:      30 * 24 * time.Hour,
+			wantDeleted: []string{"v2", "v1"},
+			wantKept:    []string{"v5", "v4", "b1", "v3"},
+		},
+		{
+			name:        "carries on when a version fails to delete",
+			keep:        2,
+			failDelete:  "v2",
+			wantDeleted: []string{"v3", "v1"},
+			wantKept:    []string{"v5", "v4", "b1", "v2"},
+			wantErr:     "failed to delete 1 version(s) of state.json: [v2: AccessDenied]",
+		},
+		{
+			name:     "must keep the latest version",
+			keep:     0,
+			wantKept: []string{"v5", "v4", "b1", "v3", "v2", "v1"},
+			wantErr:  "at least the latest version must be kept",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &mapS3API{versions: versions(), failDelete: tt.failDelete}
+			s, err := NewStore(m, "my-bucket", "env")
+			if err != nil {
+				t.Fatalf("NewStore() error = %v", err)
+			}
+			deleted, err := s.PruneVersions("state.json", tt.keep, tt.maxAge)
+			if !reflect.DeepEqual(deleted, tt.wantDeleted) {
+				t.Errorf("Store.PruneVersions() = %v, want %v", deleted, tt.wantDeleted)
+			}
+			var kept []string
+			for _, v := range m.versions {
+				kept = append(kept, *v.VersionId)
+			}
+			if !reflect.DeepEqual(kept, tt.wantKept) {
+				t.Errorf("Store.PruneVersions() kept %v, want %v", kept, tt.wantKept)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Store.PruneVersions() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Store.PruneVersions() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStore_SetManyGetMany(t *testing.T) {
+	m := &mapS3API{objects: map[string][]byte{}}
+	s, err := NewStore(m, "my bucket", "")
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	values := map[string][]byte{
+		"state.json": []byte("{}"),
+		"vars.yaml":  []byte("admin_password: secret"),
+	}
+	if err := s.SetMany(values); err != nil {
+		t.Fatalf("Store.SetMany() error = %v", err)
+	}
+	got, err := s.GetMany([]string{"state.json", "vars.yaml", "missing"})
+	if err != nil {
+		t.Fatalf("Store.GetMany() error = %v", err)
+	}
+	want := map[string][]byte{
+		"state.json": []byte("{}"),
+		"vars.yaml":  []byte("admin_password: secret"),
+		"missing":    {},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Store.GetMany() = %v, want %v", got, want)
+	}
+}
+
+func TestNewStoreFromInstanceProfile(t *testing.T) {
+	s, err := NewStoreFromInstanceProfile("eu-west-2", "my bucket", "my-env")
+	if err != nil {
+		t.Fatalf("NewStoreFromInstanceProfile() error = %v", err)
+	}
+	client, ok := s.s3.(*s3.S3)
+	if !ok {
+		t.Fatalf("NewStoreFromInstanceProfile() s3 client is a %T", s.s3)
+	}
+	if got := aws.StringValue(client.Config.Region); got != "eu-west-2" {
+		t.Errorf("NewStoreFromInstanceProfile() region = %v, want %v", got, "eu-west-2")
+	}
+	if s.bucket != "my bucket" {
+		t.Errorf("NewStoreFromInstanceProfile() bucket = %v, want %v", s.bucket, "my bucket")
+	}
+	if got := s.objectKey("vars.yaml"); got != "my-env/vars.yaml" {
+		t.Errorf("NewStoreFromInstanceProfile() key = %v, want %v", got, "my-env/vars.yaml")
+	}
+}
+
+func TestNewStoreFromInstanceProfileInstanceRegion(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	t.Setenv("AWS_CONFIG_FILE", os.DevNull)
+	defer func(f func(*session.Session) (string, error)) { instanceRegion = f }(instanceRegion)
+
+	instanceRegion = func(*session.Session) (string, error) { return "ap-south-1", nil }
+	s, err := NewStoreFromInstanceProfile("", "my-bucket", "")
+	if err != nil {
+		t.Fatalf("NewStoreFromInstanceProfile() error = %v", err)
+	}
+	if got := aws.StringValue(s.s3.(*s3.S3).Config.Region); got != "ap-south-1" {
+		t.Errorf("NewStoreFromInstanceProfile() region = %v, want %v", got, "ap-south-1")
+	}
+
+	instanceRegion = func(*session.Session) (string, error) { return "", errors.New("not on EC2") }
+	if _, err = NewStoreFromInstanceProfile("", "my-bucket", ""); err == nil || !strings.Contains(err.Error(), "not on EC2") {
+		t.Errorf("NewStoreFromInstanceProfile() error = %v, want the instance metadata error", err)
+	}
+}
+
+func TestNewStoreFromInstanceProfileWithEndpoint(t *testing.T) {
+	s, err := NewStoreFromInstanceProfile("eu-west-1", "my-bucket", "", Endpoint("http://localhost:4566"))
+	if err != nil {
+		t.Fatalf("NewStoreFromInstanceProfile() error = %v", err)
+	}
+	client, ok := s.s3.(*s3.S3)
+	if !ok {
+		t.Fatalf("NewStoreFromInstanceProfile() s3 client is a %T", s.s3)
+	}
+	if client.Endpoint != "http://localhost:4566" {
+		t.Errorf("NewStoreFromInstanceProfile() endpoint = %v, want %v", client.Endpoint, "http://localhost:4566")
+	}
+	if !aws.BoolValue(client.Config.S3ForcePathStyle) {
+		t.Errorf("NewStoreFromInstanceProfile() expected path style addressing")
+	}
+
+	if _, err = NewStoreFromInstanceProfile("eu-west-1", "my-bucket", "", Endpoint("localhost:4566")); err == nil {
+		t.Errorf("NewStoreFromInstanceProfile() expected an error for an endpoint without a scheme")
+	}
+	if _, err = NewStore(&mapS3API{}, "my-bucket", "", Endpoint("http://localhost:4566")); err == nil {
+		t.Errorf("NewStore() expected an error setting the endpoint of a given client")
+	}
+}
+
+func TestEnvironment_ConfigureDirectorManifestCPI(t *testing.T) {
+	contents, err := ioutil.ReadFile("../fixtures/proxy_ca.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyCA := string(contents)
+
+	tests := []struct {
+		name            string
+		enableLocalDNS  bool
+		maxTasks        int
+		retentionDays   int
+		mbusPort        int
+		natsPort        int
+		blobstoreProxy  string
+		noProxy         []string
+		requireIMDSv2   bool
+		directorAZ      string
+		directorSubnet  string
+		dbCACert        string
+		proxyCA         string
+		wantContains    []string
+		wantNotContains []string
+	}{
+		{
+			name:            "local DNS is disabled by default",
+			wantNotContains: []string{"use_dns_addresses"},
+		},
+		{
+			name:           "local DNS enabled",
+			enableLocalDNS: true,
+			wantContains:   []string{"use_dns_addresses: true"},
+		},
+		{
+			name:            "task retention defaults to the director release",
+			wantNotContains: []string{"max_tasks", "tasks_retention_period"},
+		},
+		{
+			name:          "task retention",
+			maxTasks:      500,
+			retentionDays: 30,
+			wantContains:  []string{"max_tasks: 500", "tasks_retention_period: 30"},
+		},
+		{
+			name:         "default agent ports",
+			wantContains: []string{"@10.0.0.6:4222\n", "port: 4222\n", "@1.2.3.4:6868\n", "@0.0.0.0:6868\n"},
+		},
+		{
+			name:            "custom agent ports",
+			mbusPort:        8443,
+			natsPort:        4443,
+			wantContains:    []string{"@10.0.0.6:4443\n", "port: 4443\n", "@1.2.3.4:8443\n", "@0.0.0.0:8443\n"},
+			wantNotContains: []string{"4222", "6868"},
+		},
+		{
+			name:         "director placed with the workers by default",
+			wantContains: []string{"availability_zone: eu-west-1a\n", "subnet: subnet-public\n"},
+		},
+		{
+			name:            "director in a dedicated subnet",
+			directorAZ:      "eu-west-1b",
+			directorSubnet:  "subnet-management",
+			wantContains:    []string{"availability_zone: eu-west-1b\n", "subnet: subnet-management\n"},
+			wantNotContains: []string{"eu-west-1a", "subnet-public"},
+		},
+		{
+			name:            "IMDSv1 allowed by default",
+			wantNotContains: []string{"metadata_options"},
+		},
+		{
+			name:          "IMDSv2 required",
+			requireIMDSv2: true,
+			wantContains:  []string{"metadata_options:\n      http_tokens: required\n"},
+		},
+		{
+			name:            "no blobstore proxy by default",
+			wantNotContains: []string{"http_proxy", "no_proxy"},
+		},
+		{
+			name:           "blobstore proxy",
+			blobstoreProxy: "http://proxy.internal:3128",
+			noProxy:        []string{"10.0.1.0/24"},
+			wantContains: []string{
+				"env:\n      http_proxy: http://proxy.internal:3128\n      https_proxy: http://proxy.internal:3128\n",
+				"no_proxy: localhost,127.0.0.1,10.0.0.6,10.0.1.0/24\n",
+			},
+		},
+		{
+			name:         "only the DB CA is trusted by default",
+			dbCACert:     "db-ca",
+			wantContains: []string{"trusted_certs: db-ca\n"},
+		},
+		{
+			name:     "proxy CA trusted alongside the DB CA",
+			dbCACert: "db-ca",
+			proxyCA:  proxyCA,
+			wantContains: []string{
+				"trusted_certs: |\n        db-ca\n        -----BEGIN CERTIFICATE-----\n",
+				strings.Replace(strings.TrimSpace(proxyCA), "\n", "\n        ", -1),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Environment{
+				AZ:                "eu-west-1a",
+				BlobstoreProxyURL: tt.blobstoreProxy,
+				BlobstoreNoProxy:  tt.noProxy,
+				DBCACert:          tt.dbCACert,
+				DirectorAZ:        tt.directorAZ,
+				DirectorMaxTasks:  tt.maxTasks,
+				DirectorSubnetID:  tt.directorSubnet,
+				EnableLocalDNS:    tt.enableLocalDNS,
+				ExternalIP:        "1.2.3.4",
+				InternalCIDR:      "10.0.0.0/24",
+				InternalIP:        "10.0.0.6",
+				TaskRetentionDays: tt.retentionDays,
+				MbusPort:          tt.mbusPort,
+				NATSPort:          tt.natsPort,
+				ProxyCACert:       tt.proxyCA,
+				PublicSubnetID:    "subnet-public",
+				RequireIMDSv2:     tt.requireIMDSv2,
+			}
+			got, err := e.ConfigureDirectorManifestCPI()
+			if err != nil {
+				t.Fatalf("Environment.ConfigureDirectorManifestCPI() error = %v", err)
+			}
+			for _, s := range tt.wantContains {
+				if !strings.Contains(got, s) {
+					t.Errorf("Environment.ConfigureDirectorManifestCPI() = %s\nexpected to contain %q", got, s)
+				}
+			}
+			for _, s := range tt.wantNotContains {
+				if strings.Contains(got, s) {
+					t.Errorf("Environment.ConfigureDirectorManifestCPI() expected not to contain %q", s)
+				}
+			}
+		})
+	}
+}
+
+func TestEnvironment_ConfigureDirectorManifestCPITaskRetention(t *testing.T) {
+	for _, e := range []Environment{
+		{DirectorMaxTasks: -1}, {TaskRetentionDays: -7}, {MbusPort: 70000}, {NATSPort: -1}, {MbusPort: 4222},
+		{BlobstoreProxyURL: "proxy.internal:3128"}, {BlobstoreProxyURL: "ftp://proxy.internal"}, {BlobstoreNoProxy: []string{"10.0.1.0/24"}},
+		{ProxyCACert: "not a cert"}, {ProxyCACert: "-----BEGIN CERTIFICATE-----\nbm90IGEgY2VydA==\n-----END CERTIFICATE-----\n"},
+	} {
+		if _, err := e.ConfigureDirectorManifestCPI(); err == nil {
+			t.Errorf("Environment.ConfigureDirectorManifestCPI() expected an error for %+v", e)
+		}
+	}
+}
+
+func TestEnvironment_RenderAll(t *testing.T) {
+	defer func(v string) { resource.AWSReleaseVersions = v }(resource.AWSReleaseVersions)
+	resource.AWSReleaseVersions = getStemcellFixture("stemcell_version")
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	e := Environment{
+		AZ:              "az",
+		ExternalIP:      "1.2.3.4",
+		InternalCIDR:    "10.0.0.0/24",
+		InternalIP:      "10.0.0.6",
+		PrivateSubnetID: "private_subnet_id",
+		PublicSubnetID:  "public_subnet_id",
+		WorkerRuntime:   "containerd",
+		WorkerType:      "m5",
+	}
+	if err := e.RenderAll(filepath.Join(dir, "rendered")); err != nil {
+		t.Fatalf("Environment.RenderAll() error = %v", err)
+	}
+
+	director, err := e.ConfigureDirectorManifestCPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cloudConfig, err := e.ConfigureDirectorCloudConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	concourseOps, err := e.ConfigureConcourseOps()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"director.yml":      director,
+		"cloud-config.yml":  cloudConfig,
+		"concourse-ops.yml": concourseOps,
+		"stemcell-url.txt":  "https://s3.amazonaws.com/bosh-aws-light-stemcells/5/light-bosh-stemcell-5-aws-xen-hvm-ubuntu-xenial-go_agent.tgz\n",
+	} {
+		got, err := ioutil.ReadFile(filepath.Join(dir, "rendered", name))
+		if err != nil {
+			t.Errorf("Environment.RenderAll() did not write %s: %v", name, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("Environment.RenderAll() wrote %s = %s\nwant %s", name, got, want)
+		}
+	}
+}
+
+func TestStore_RecordMeta(t *testing.T) {
+	m := &mapS3API{objects: map[string][]byte{}}
+	fresh, err := NewStore(m, "my bucket", "prod")
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	if _, found, err := fresh.Meta(); err != nil || found {
+		t.Fatalf("Store.Meta() found = %v, error = %v, want a fresh environment", found, err)
+	}
+
+	s, err := NewStore(m, "my bucket", "prod", RecordMeta("eu-west-1"))
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	if _, ok := m.objects["prod/meta.json"]; !ok {
+		t.Fatalf("RecordMeta() did not write %s", "prod/meta.json")
+	}
+	got, found, err := s.Meta()
+	if err != nil || !found {
+		t.Fatalf("Store.Meta() found = %v, error = %v", found, err)
+	}
+	if got.Bucket != "my bucket" || got.Region != "eu-west-1" || got.IAAS != "AWS" || got.CreatedAt.IsZero() {
+		t.Errorf("Store.Meta() = %+v", got)
+	}
+}
+
+type fakeEC2API struct {
+	err error
+}
+
+func (f fakeEC2API) DescribeAvailabilityZones(*ec2.DescribeAvailabilityZonesInput) (*ec2.DescribeAvailabilityZonesOutput, error) {
+	return &ec2.DescribeAvailabilityZonesOutput{}, f.err
+}
+
+type fakeListBucketsS3API struct {
+	s3iface.S3API
+	err error
+}
+
+func (f fakeListBucketsS3API) ListBuckets(*s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
+	return &s3.ListBucketsOutput{}, f.err
+}
+
+func TestEnvironment_Preflight(t *testing.T) {
+	tests := []struct {
+		name    string
+		s3Err   error
+		ec2Err  error
+		wantErr string
+	}{
+		{
+			name: "every API reachable",
+		},
+		{
+			name:    "EC2 unreachable",
+			ec2Err:  errors.New("UnauthorizedOperation"),
+			wantErr: "failed to reach 1 AWS API(s): [EC2: UnauthorizedOperation]",
+		},
+		{
+			name:    "S3 and EC2 unreachable",
+			s3Err:   errors.New("InvalidAccessKeyId"),
+			ec2Err:  errors.New("AuthFailure"),
+			wantErr: "failed to reach 2 AWS API(s): [S3: InvalidAccessKeyId, EC2: AuthFailure]",
+		},
+	}
+	defer func(f func(Environment) (s3iface.S3API, ec2API, error)) { newPreflightClients = f }(newPreflightClients)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newPreflightClients = func(Environment) (s3iface.S3API, ec2API, error) {
+				return fakeListBucketsS3API{err: tt.s3Err}, fakeEC2API{err: tt.ec2Err}, nil
+			}
+			err := Environment{Region: "eu-west-1"}.Preflight()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Environment.Preflight() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Environment.Preflight() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnvironment_EstimateMonthlyCost(t *testing.T) {
+	groups := []cost.VMGroup{
+		{Name: "web", InstanceType: "t2.small", Count: 1},
+		{Name: "worker", InstanceType: "m5.xlarge", Count: 2},
+	}
+	pricingAPI := func(instanceType string) (float64, error) {
+		if instanceType == "m5.xlarge" {
+			return 0, errors.New("ThrottlingException")
+		}
+		return 0.025, nil
+	}
+	got, err := Environment{}.EstimateMonthlyCost(groups, pricingAPI)
+	if err != nil {
+		t.Fatalf("Environment.EstimateMonthlyCost() error = %v", err)
+	}
+	want := cost.Estimate{
+		Groups: []cost.GroupCost{
+			{VMGroup: groups[0], HourlyPrice: 0.025, MonthlyCost: 18.25},
+			{VMGroup: groups[1], HourlyPrice: 0.214, MonthlyCost: 312.44, Bundled: true},
+		},
+		MonthlyTotal: 330.69,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Environment.EstimateMonthlyCost() = %+v, want %+v", got, want)
+	}
+}
+
+type fakeSnapshotAPI struct {
+	failVolume string
+}
+
+func (f fakeSnapshotAPI) CreateSnapshot(input *ec2.CreateSnapshotInput) (*ec2.Snapshot, error) {
+	if *input.VolumeId == f.failVolume {
+		return nil, errors.New("IncorrectState")
+	}
+	return &ec2.Snapshot{SnapshotId: aws.String(strings.Replace(*input.VolumeId, "vol", "snap", 1))}, nil
+}
+
+func TestEnvironment_SnapshotDisks(t *testing.T) {
+	tests := []struct {
+		name       string
+		failVolume string
+		want       map[string]string
+		wantErr    string
+	}{
+		{
+			name: "every disk snapshotted",
+			want: map[string]string{"vol-0a1": "snap-0a1", "vol-0b2": "snap-0b2", "vol-0c3": "snap-0c3"},
+		},
+		{
+			name:       "a disk failing to snapshot",
+			failVolume: "vol-0b2",
+			want:       map[string]string{"vol-0a1": "snap-0a1", "vol-0c3": "snap-0c3"},
+			wantErr:    "failed to snapshot 1 disk(s): [vol-0b2: IncorrectState], snapshotted [vol-0a1:snap-0a1, vol-0c3:snap-0c3]",
+		},
+	}
+	defer func(f func(Environment) (snapshotAPI, error)) { newSnapshotClient = f }(newSnapshotClient)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newSnapshotClient = func(Environment) (snapshotAPI, error) {
+				return fakeSnapshotAPI{failVolume: tt.failVolume}, nil
+			}
+			got, err := Environment{Region: "eu-west-1"}.SnapshotDisks([]string{"vol-0a1", "vol-0b2", "vol-0c3"})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Environment.SnapshotDisks() = %v, want %v", got, tt.want)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Environment.SnapshotDisks() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Environment.SnapshotDisks() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package aws
+
+import "github.com/EngineerBetter/control-tower/bosh/internal/cost"
+
+// onDemandPrices are the hourly Linux on-demand prices in USD of the instance types in the cloud config,
+// used when the pricing source is unavailable. They match the on-demand prices noted in the cloud config
+var onDemandPrices = map[string]float64{
+	"t2.small":    0.023,
+	"t2.medium":   0.0464,
+	"t2.large":    0.0928,
+	"t2.xlarge":   0.1856,
+	"t2.2xlarge":  0.3712,
+	"m4.large":    0.111,
+	"m4.xlarge":   0.222,
+	"m4.2xlarge":  0.444,
+	"m4.4xlarge":  0.888,
+	"m4.10xlarge": 2.22,
+	"m4.16xlarge": 3.55,
+	"m5.large":    0.107,
+	"m5.xlarge":   0.214,
+	"m5.2xlarge":  0.428,
+	"m5.4xlarge":  0.856,
+	"m5.12xlarge": 2.57,
+	"m5.24xlarge": 5.14,
+}
+
+// EstimateMonthlyCost returns the estimated monthly on-demand cost of groups, priced by source
+// and falling back to a bundled price table for instance types source fails to price or when it is nil
+func (e Environment) EstimateMonthlyCost(groups []cost.VMGroup, source cost.PriceSource) (cost.Estimate, error) {
+	return cost.Monthly(groups, source, onDemandPrices)
+}
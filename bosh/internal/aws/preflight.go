@@ -0,0 +1,67 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// ec2API is the part of the EC2 API used by Preflight
+type ec2API interface {
+	DescribeAvailabilityZones(*ec2.DescribeAvailabilityZonesInput) (*ec2.DescribeAvailabilityZonesOutput, error)
+}
+
+// newPreflightClients builds the clients Preflight calls with the credentials
+// of the Environment, it is replaced in tests
+var newPreflightClients = func(e Environment) (s3iface.S3API, ec2API, error) {
+	sess, err := e.session()
+	if err != nil {
+		return nil, nil, err
+	}
+	return s3.New(sess), ec2.New(sess), nil
+}
+
+// session returns an AWS session authenticated with the static credentials of the Environment
+func (e Environment) session() (*session.Session, error) {
+	return session.NewSession(aws.NewConfig().
+		WithRegion(e.Region).
+		WithCredentials(credentials.NewStaticCredentials(e.AccessKeyID, e.SecretAccessKey, "")))
+}
+
+// Preflight makes a lightweight authenticated call to S3 and EC2, the AWS APIs the
+// director needs, returning a single error naming every API that could not be reached
+func (e Environment) Preflight() error {
+	s3Client, ec2Client, err := newPreflightClients(e)
+	if err != nil {
+		return fmt.Errorf("failed to create AWS clients: [%v]", err)
+	}
+	checks := []struct {
+		api  string
+		call func() error
+	}{
+		{"S3", func() error {
+			_, err := s3Client.ListBuckets(&s3.ListBucketsInput{})
+			return err
+		}},
+		{"EC2", func() error {
+			_, err := ec2Client.DescribeAvailabilityZones(&ec2.DescribeAvailabilityZonesInput{})
+			return err
+		}},
+	}
+	var failed []string
+	for _, check := range checks {
+		if err := check.call(); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", check.api, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to reach %d AWS API(s): [%s]", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
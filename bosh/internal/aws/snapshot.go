@@ -0,0 +1,63 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// snapshotAPI is the part of the EC2 API used by SnapshotDisks
+type snapshotAPI interface {
+	CreateSnapshot(*ec2.CreateSnapshotInput) (*ec2.Snapshot, error)
+}
+
+// newSnapshotClient builds the client SnapshotDisks calls with the credentials
+// of the Environment, it is replaced in tests
+var newSnapshotClient = func(e Environment) (snapshotAPI, error) {
+	sess, err := e.session()
+	if err != nil {
+		return nil, err
+	}
+	return ec2.New(sess), nil
+}
+
+// SnapshotDisks creates an EBS snapshot of each volume in diskCIDs, the persistent disk CIDs
+// reported by bosh, and returns the snapshot IDs by disk CID. The snapshots are taken at the
+// time of the call but complete asynchronously. Every disk is snapshotted even when an earlier
+// one fails, and the snapshots that were created are returned along with the error
+func (e Environment) SnapshotDisks(diskCIDs []string) (map[string]string, error) {
+	client, err := newSnapshotClient(e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS clients: [%v]", err)
+	}
+	snapshots := map[string]string{}
+	var failed []string
+	for _, cid := range diskCIDs {
+		snapshot, err := client.CreateSnapshot(&ec2.CreateSnapshotInput{
+			Description: aws.String(fmt.Sprintf("control-tower snapshot of %s", cid)),
+			VolumeId:    aws.String(cid),
+		})
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", cid, err))
+			continue
+		}
+		snapshots[cid] = aws.StringValue(snapshot.SnapshotId)
+	}
+	if len(failed) > 0 {
+		return snapshots, fmt.Errorf("failed to snapshot %d disk(s): [%s], snapshotted [%s]", len(failed), strings.Join(failed, ", "), describeSnapshots(snapshots))
+	}
+	return snapshots, nil
+}
+
+// describeSnapshots lists snapshots as disk:snapshot pairs ordered by disk
+func describeSnapshots(snapshots map[string]string) string {
+	var pairs []string
+	for cid, snapshot := range snapshots {
+		pairs = append(pairs, cid+":"+snapshot)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
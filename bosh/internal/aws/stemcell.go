@@ -0,0 +1,99 @@
+package aws
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/EngineerBetter/control-tower/bosh/internal/concourseops"
+	"github.com/EngineerBetter/control-tower/bosh/internal/stemcells"
+	"github.com/EngineerBetter/control-tower/iaas"
+)
+
+// stemcellURL returns the URL of the AWS light stemcell of os at version for arch
+func stemcellURL(os, version, arch string) string {
+	if arch == archARM64 {
+		os += "-arm64"
+	}
+	return stemcells.URL(stemcells.Key{IAAS: iaas.AWS, OS: os, Version: version}, func() string {
+		return fmt.Sprintf("https://s3.amazonaws.com/bosh-aws-light-stemcells/%s/light-bosh-stemcell-%s-aws-xen-hvm-%s-go_agent.tgz", version, version, os)
+	})
+}
+
+// workerStemcell returns the stemcell the workers run on, which is zero when they use the stemcell of the deployment.
+// Graviton workers need a stemcell of their own, as the deployment stemcell is amd64 and no arm64 one is published
+// for its OS
+func (e Environment) workerStemcell() (concourseops.WorkerStemcell, error) {
+	stemcell := concourseops.WorkerStemcell{OS: e.WorkerStemcellOS, Version: e.WorkerStemcellVersion}
+	arch, err := e.stemcellArchitecture()
+	if err != nil {
+		return stemcell, err
+	}
+	if arch == archARM64 && stemcell == (concourseops.WorkerStemcell{}) {
+		return stemcell, fmt.Errorf("worker type %q runs on an arm64 stemcell, set the worker stemcell OS and version of one", e.WorkerType)
+	}
+	return stemcell, nil
+}
+
+// ConfigureWorkerStemcell returns the URL of the stemcell the workers run on, for the architecture of
+// the worker type, or an empty string when they run on the one returned by ConfigureConcourseStemcell
+func (e Environment) ConfigureWorkerStemcell() (string, error) {
+	stemcell, err := e.workerStemcell()
+	if err != nil {
+		return "", err
+	}
+	if stemcell == (concourseops.WorkerStemcell{}) {
+		return "", nil
+	}
+	if err := stemcell.Validate(); err != nil {
+		return "", err
+	}
+	arch, err := e.stemcellArchitecture()
+	if err != nil {
+		return "", err
+	}
+	return stemcellURL(stemcell.OS, stemcell.Version, arch), nil
+}
+
+// headStemcell checks a stemcell can be downloaded from url, it is replaced in tests
+var headStemcell = func(url string) error {
+	resp, err := http.Head(url)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("got status %s", resp.Status)
+	}
+	return nil
+}
+
+// CheckStemcellURLs checks the stemcells of the deployment and of the workers both
+// resolve to stemcells that can be downloaded, naming every one that cannot
+func (e Environment) CheckStemcellURLs() error {
+	checks := []struct {
+		name string
+		url  func() (string, error)
+	}{
+		{"concourse", e.ConfigureConcourseStemcell},
+		{"worker", e.ConfigureWorkerStemcell},
+	}
+	var failed []string
+	for _, check := range checks {
+		url, err := check.url()
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", check.name, err))
+			continue
+		}
+		if url == "" {
+			continue
+		}
+		if err = headStemcell(url); err != nil {
+			failed = append(failed, fmt.Sprintf("%s %s: %v", check.name, url, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to resolve %d stemcell(s): [%s]", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
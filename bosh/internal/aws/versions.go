@@ -0,0 +1,96 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// Version is a version of a Store element kept by a versioned bucket
+type Version struct {
+	ID           string
+	LastModified time.Time
+	IsLatest     bool
+}
+
+// Versions returns the versions of the Store element identified with key, newest first.
+// A bucket without versioning has a single version with the ID "null"
+func (s *Store) Versions(key string) ([]Version, error) {
+	objectKey := s.objectKey(key)
+	input := &s3.ListObjectVersionsInput{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(objectKey),
+	}
+	var versions []Version
+	for {
+		out, err := s.s3.ListObjectVersions(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the versions of %s: [%v]", key, err)
+		}
+		for _, v := range out.Versions {
+			// The prefix also matches keys starting with objectKey
+			if aws.StringValue(v.Key) != objectKey {
+				continue
+			}
+			versions = append(versions, Version{
+				ID:           aws.StringValue(v.VersionId),
+				LastModified: aws.TimeValue(v.LastModified),
+				IsLatest:     aws.BoolValue(v.IsLatest),
+			})
+		}
+		if !aws.BoolValue(out.IsTruncated) {
+			break
+		}
+		input.KeyMarker = out.NextKeyMarker
+		input.VersionIdMarker = out.NextVersionIdMarker
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		if versions[i].IsLatest != versions[j].IsLatest {
+			return versions[i].IsLatest
+		}
+		return versions[i].LastModified.After(versions[j].LastModified)
+	})
+	return versions, nil
+}
+
+// PruneVersions deletes the versions of the Store element identified with key other than the
+// keep newest, skipping those modified within maxAge when it is not zero, and returns the IDs
+// of the deleted versions. The latest version is always kept. Every version is deleted even
+// when an earlier one fails, and the deleted versions are returned along with the error
+func (s *Store) PruneVersions(key string, keep int, maxAge time.Duration) ([]string, error) {
+	if keep < 1 {
+		return nil, errors.New("at least the latest version must be kept")
+	}
+	if maxAge < 0 {
+		return nil, fmt.Errorf("version max age must be positive, got %s", maxAge)
+	}
+	versions, err := s.Versions(key)
+	if err != nil {
+		return nil, err
+	}
+	var deleted, failed []string
+	for i, version := range versions {
+		if i < keep || version.IsLatest || (maxAge > 0 && time.Since(version.LastModified) < maxAge) {
+			continue
+		}
+		_, err := s.s3.DeleteObject(&s3.DeleteObjectInput{
+			Bucket:    aws.String(s.bucket),
+			Key:       aws.String(s.objectKey(key)),
+			VersionId: aws.String(version.ID),
+		})
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", version.ID, err))
+			continue
+		}
+		deleted = append(deleted, version.ID)
+	}
+	if len(failed) > 0 {
+		return deleted, fmt.Errorf("failed to delete %d version(s) of %s: [%s]", len(failed), key, strings.Join(failed, ", "))
+	}
+	return deleted, nil
+}
@@ -0,0 +1,65 @@
+// Package batch runs Store operations against many keys with bounded concurrency
+package batch
+
+import "sync"
+
+// Concurrency is the maximum number of operations run at the same time
+const Concurrency = 8
+
+// Get calls get for every key and returns the results by key. Keys that are
+// not present map to a zero length byte slice, matching the Store contract
+func Get(keys []string, get func(key string) ([]byte, error)) (map[string][]byte, error) {
+	var mu sync.Mutex
+	values := make(map[string][]byte, len(keys))
+	err := run(len(keys), func(i int) error {
+		value, err := get(keys[i])
+		if err != nil {
+			return err
+		}
+		if value == nil {
+			value = []byte{}
+		}
+		mu.Lock()
+		values[keys[i]] = value
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// Set calls set for every key and value
+func Set(values map[string][]byte, set func(key string, value []byte) error) error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	return run(len(keys), func(i int) error {
+		return set(keys[i], values[keys[i]])
+	})
+}
+
+// run calls f for 0..n-1, at most Concurrency at a time, and returns the first error
+func run(n int, f func(i int) error) error {
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	sem := make(chan struct{}, Concurrency)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := f(i); err != nil {
+				once.Do(func() { firstErr = err })
+			}
+		}(i)
+	}
+	wg.Wait()
+	return firstErr
+}
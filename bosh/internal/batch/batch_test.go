@@ -0,0 +1,124 @@
+package batch
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type concurrencyTracker struct {
+	mu      sync.Mutex
+	current int
+	max     int
+}
+
+func (c *concurrencyTracker) enter() {
+	c.mu.Lock()
+	c.current++
+	if c.current > c.max {
+		c.max = c.current
+	}
+	c.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+}
+
+func (c *concurrencyTracker) leave() {
+	c.mu.Lock()
+	c.current--
+	c.mu.Unlock()
+}
+
+func keys(n int) []string {
+	var ks []string
+	for i := 0; i < n; i++ {
+		ks = append(ks, fmt.Sprintf("key-%d", i))
+	}
+	return ks
+}
+
+func TestGet(t *testing.T) {
+	tracker := &concurrencyTracker{}
+	got, err := Get(append(keys(3*Concurrency), "missing"), func(key string) ([]byte, error) {
+		tracker.enter()
+		defer tracker.leave()
+		if key == "missing" {
+			return nil, nil
+		}
+		return []byte(key), nil
+	})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(got) != 3*Concurrency+1 {
+		t.Errorf("Get() returned %d values, want %d", len(got), 3*Concurrency+1)
+	}
+	if v := got["key-1"]; string(v) != "key-1" {
+		t.Errorf("Get() key-1 = %q, want %q", v, "key-1")
+	}
+	if v, ok := got["missing"]; !ok || v == nil || len(v) != 0 {
+		t.Errorf("Get() missing = %#v, want a zero length slice", v)
+	}
+	if tracker.max > Concurrency {
+		t.Errorf("Get() ran %d operations at once, want at most %d", tracker.max, Concurrency)
+	}
+	if tracker.max < 2 {
+		t.Errorf("Get() ran operations sequentially")
+	}
+}
+
+func TestGetError(t *testing.T) {
+	_, err := Get(keys(Concurrency), func(key string) ([]byte, error) {
+		if key == "key-3" {
+			return nil, errors.New("an error")
+		}
+		return []byte(key), nil
+	})
+	if err == nil {
+		t.Errorf("Get() expected an error")
+	}
+}
+
+func TestSet(t *testing.T) {
+	tracker := &concurrencyTracker{}
+	values := map[string][]byte{}
+	for _, k := range keys(3 * Concurrency) {
+		values[k] = []byte(k)
+	}
+	var mu sync.Mutex
+	stored := map[string][]byte{}
+	err := Set(values, func(key string, value []byte) error {
+		tracker.enter()
+		defer tracker.leave()
+		mu.Lock()
+		stored[key] = value
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if !reflect.DeepEqual(stored, values) {
+		t.Errorf("Set() stored %v, want %v", stored, values)
+	}
+	if tracker.max > Concurrency {
+		t.Errorf("Set() ran %d operations at once, want at most %d", tracker.max, Concurrency)
+	}
+	if tracker.max < 2 {
+		t.Errorf("Set() ran operations sequentially")
+	}
+}
+
+func TestSetError(t *testing.T) {
+	err := Set(map[string][]byte{"a": nil, "b": nil}, func(key string, value []byte) error {
+		if key == "b" {
+			return errors.New("an error")
+		}
+		return nil
+	})
+	if err == nil {
+		t.Errorf("Set() expected an error")
+	}
+}
@@ -1,36 +1,101 @@
 package boshcli
 
 import (
-	"bufio"
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/EngineerBetter/control-tower/certs"
 	"github.com/EngineerBetter/control-tower/iaas"
 	"github.com/EngineerBetter/control-tower/resource"
+	"github.com/EngineerBetter/control-tower/util"
 	"github.com/EngineerBetter/control-tower/util/yaml"
+	goyaml "gopkg.in/yaml.v2"
 )
 
 //go:generate counterfeiter . ICLI
 type ICLI interface {
 	CreateEnv(store Store, config IAASEnvironment, password, cert, key, ca string, tags map[string]string) error
+	RecreateEnv(store Store, config IAASEnvironment, password, cert, key, ca string, tags map[string]string) error
 	DeleteEnv(store Store, config IAASEnvironment, password, cert, key, ca string, tags map[string]string) error
 	RunAuthenticatedCommand(action, ip, password, ca string, detach bool, stdout io.Writer, flags ...string) error
+	DeployWithProgress(ip, password, ca string, flags ...string) (<-chan ProgressEvent, <-chan error)
+	DeployManifest(config IAASEnvironment, ip, password, ca, manifestPath string) error
 	Locks(config IAASEnvironment, ip, password, ca string) ([]byte, error)
+	Deployments(config IAASEnvironment, ip, password, ca string) ([]byte, error)
+	Events(config IAASEnvironment, ip, password, ca string, limit int) ([]byte, error)
+	LastTaskOutput(config IAASEnvironment, ip, password, ca string) ([]byte, error)
 	Recreate(config IAASEnvironment, ip, password, ca string) error
+	RecreateInstance(config IAASEnvironment, ip, password, ca, instanceGroup string) error
+	VMs(config IAASEnvironment, ip, password, ca string) ([]byte, error)
+	PersistentDisks(config IAASEnvironment, ip, password, ca string) ([]PersistentDisk, error)
+	RecreateFailing(config IAASEnvironment, ip, password, ca string) ([]string, error)
+	Pause(config IAASEnvironment, ip, password, ca string) error
+	Resume(config IAASEnvironment, ip, password, ca string) error
+	CleanUp(config IAASEnvironment, ip, password, ca string, all bool) error
+	FetchLogs(config IAASEnvironment, ip, password, ca, instanceGroup string, dest string) error
 	UpdateCloudConfig(config IAASEnvironment, ip, password, ca string) error
+	CloudConfigDiff(config IAASEnvironment, ip, password, ca string) (string, error)
+	CloudConfigDrift(config IAASEnvironment, ip, password, ca string) (CloudConfigDrift, error)
 	UploadConcourseStemcell(config IAASEnvironment, ip, password, ca string) error
+	MissingReleases(config IAASEnvironment, ip, password, ca string, manifest []byte) ([]string, error)
+	ConcourseCredentials(store Store, host string) (ConcourseCredentials, error)
+	CredHubImport(store Store, prefix string) ([]byte, error)
+	CheckStateConsistency(store Store) error
+	ImportState(store Store, ip, statePath, varsPath string) error
+	ForceUnlock(store Store) error
+	DirectorManifestDiff(store Store, config IAASEnvironment, password, cert, key, ca string, tags map[string]string) (string, error)
+	ManifestFingerprint(config IAASEnvironment, password, cert, key, ca string, tags map[string]string) (string, error)
+	CheckDirectorVersion(ip, password, ca string) (string, error)
+	DirectorUUID(config IAASEnvironment, ip, password, ca string) (string, error)
+	RotateDirectorCert(store Store, config IAASEnvironment, password, oldCA string, tags map[string]string, generate CertGenerator) (*certs.Certs, error)
+	NewSession(config IAASEnvironment, ip, password, ca string) (*Session, error)
 }
 
 // CLI struct holds the abstraction of execCmd
 type CLI struct {
-	execCmd  func(string, ...string) *exec.Cmd
-	boshPath string
+	ctx           context.Context
+	execCmd       func(string, ...string) *exec.Cmd
+	boshPath      string
+	boshRelease   *resource.Resource
+	bpmRelease    *resource.Resource
+	verifyUploads bool
+	debug         bool
+	passphrase    string
+	clientName    string
+	maxInFlight   string
+	canaries      string
+	localStemcell string
+	skipStemcell  bool
+	cloudConfigW  io.Writer
+	teeOutputPath string
+	flushLines    bool
+	tempDir       string
+	tempFileMode  os.FileMode
+	transform     ManifestTransform
+
+	stemcellRetries int
+	stemcellBackoff time.Duration
+	lockTTL         time.Duration
+	stateFilename   string
+	varsFilename    string
+	downloadRelease Downloader
+
+	hook        Hook
+	environment string
 }
 
 // Option defines the arbitary element of Options for New
@@ -53,11 +118,254 @@ func DownloadBOSH() Option {
 	}
 }
 
+// BOSHRelease returns an Option that overrides the embedded BOSH release
+func BOSHRelease(r resource.Resource) Option {
+	return func(c *CLI) error {
+		c.boshRelease = &r
+		return nil
+	}
+}
+
+// BPMRelease returns an Option that overrides the embedded BPM release
+func BPMRelease(r resource.Resource) Option {
+	return func(c *CLI) error {
+		c.bpmRelease = &r
+		return nil
+	}
+}
+
+// VerifyUploads returns an Option that reads back the state files uploaded to
+// the Store after create-env and delete-env and checks they were not corrupted
+func VerifyUploads() Option {
+	return func(c *CLI) error {
+		c.verifyUploads = true
+		return nil
+	}
+}
+
+// ClientName returns an Option that overrides the director client used to
+// authenticate commands, which defaults to admin
+func ClientName(name string) Option {
+	return func(c *CLI) error {
+		if name == "" {
+			return errors.New("client name must not be empty")
+		}
+		c.clientName = name
+		return nil
+	}
+}
+
+// MaxInFlight returns an Option that sets --max-in-flight when deploying,
+// as either a positive number of instances or a percentage
+func MaxInFlight(value string) Option {
+	return func(c *CLI) error {
+		if err := validateUpdateValue(value); err != nil {
+			return fmt.Errorf("invalid max in flight: [%v]", err)
+		}
+		c.maxInFlight = value
+		return nil
+	}
+}
+
+// Canaries returns an Option that sets --canaries when deploying,
+// as either a positive number of instances or a percentage
+func Canaries(value string) Option {
+	return func(c *CLI) error {
+		if err := validateUpdateValue(value); err != nil {
+			return fmt.Errorf("invalid canaries: [%v]", err)
+		}
+		c.canaries = value
+		return nil
+	}
+}
+
+// Default retries of the stemcell upload, which is safe to retry as bosh skips stemcells already uploaded
+const (
+	defaultStemcellRetries = 3
+	defaultStemcellBackoff = 5 * time.Second
+)
+
+// StemcellUploadRetries returns an Option retrying a failed stemcell upload up to retries times,
+// waiting backoff before the first retry and doubling the wait before each following one
+func StemcellUploadRetries(retries int, backoff time.Duration) Option {
+	return func(c *CLI) error {
+		if retries < 0 {
+			return fmt.Errorf("stemcell upload retries must not be negative, got %d", retries)
+		}
+		c.stemcellRetries = retries
+		c.stemcellBackoff = backoff
+		return nil
+	}
+}
+
+// WithTempDir returns an Option writing the temporary files holding state, manifests and
+// certificates under dir rather than the OS temp directory, which may be small or noexec
+func WithTempDir(dir string) Option {
+	return func(c *CLI) error {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("invalid temp dir: [%v]", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("invalid temp dir: [%s is not a directory]", dir)
+		}
+		f, err := ioutil.TempFile(dir, "")
+		if err != nil {
+			return fmt.Errorf("invalid temp dir: [%v]", err)
+		}
+		f.Close()
+		os.Remove(f.Name())
+		c.tempDir = dir
+		return nil
+	}
+}
+
+// defaultTempFileMode is the mode of the temporary files when TempFileMode is not set
+const defaultTempFileMode os.FileMode = 0600
+
+// TempFileMode returns an Option setting the mode of the temporary files holding state, vars,
+// manifests and certificates, such as 0640 to let a group read them. The owner must be able to read
+// and write them, and modes making them executable or writable by others are rejected
+func TempFileMode(mode os.FileMode) Option {
+	return func(c *CLI) error {
+		switch {
+		case mode&^os.ModePerm != 0:
+			return fmt.Errorf("invalid temp file mode %#o: [only permission bits can be set]", mode)
+		case mode&0600 != 0600:
+			return fmt.Errorf("invalid temp file mode %#o: [the owner must be able to read and write]", mode)
+		case mode&0111 != 0:
+			return fmt.Errorf("invalid temp file mode %#o: [temp files must not be executable]", mode)
+		case mode&0002 != 0:
+			return fmt.Errorf("invalid temp file mode %#o: [temp files must not be writable by others]", mode)
+		}
+		c.tempFileMode = mode
+		return nil
+	}
+}
+
+// Default Store keys of the bosh state and vars files
+const (
+	defaultStateFilename = "state.json"
+	defaultVarsFilename  = "vars.yaml"
+)
+
+// StateFilenames returns an Option setting the Store keys the bosh state and vars
+// files are read from and written to, so that several directors can share a Store
+func StateFilenames(state, vars string) Option {
+	return func(c *CLI) error {
+		if state == "" || vars == "" {
+			return errors.New("state and vars filenames must not be empty")
+		}
+		if state == vars {
+			return fmt.Errorf("state and vars filenames must differ, both are %q", state)
+		}
+		c.stateFilename = state
+		c.varsFilename = vars
+		return nil
+	}
+}
+
+func validateUpdateValue(value string) error {
+	n, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+	if err != nil || n < 1 {
+		return fmt.Errorf("%q is not a positive integer or percentage", value)
+	}
+	if strings.HasSuffix(value, "%") && n > 100 {
+		return fmt.Errorf("%q is more than 100%%", value)
+	}
+	return nil
+}
+
+// LocalStemcellPath returns an Option that uploads the stemcell tarball at path
+// instead of downloading the stemcell for the IAAS, for environments without internet access
+func LocalStemcellPath(path string) Option {
+	return func(c *CLI) error {
+		if err := validateTarball(path); err != nil {
+			return fmt.Errorf("invalid local stemcell %q: [%v]", path, err)
+		}
+		c.localStemcell = path
+		return nil
+	}
+}
+
+// SkipStemcellUpload returns an Option that makes UploadConcourseStemcell do nothing, for directors
+// the stemcell has already been uploaded to. It takes precedence over LocalStemcellPath
+func SkipStemcellUpload() Option {
+	return func(c *CLI) error {
+		c.skipStemcell = true
+		return nil
+	}
+}
+
+func validateTarball(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	_, err = tar.NewReader(gz).Next()
+	return err
+}
+
+// CloudConfigWriter returns an Option that writes the rendered cloud config
+// to w before UpdateCloudConfig applies it
+func CloudConfigWriter(w io.Writer) Option {
+	return func(c *CLI) error {
+		c.cloudConfigW = w
+		return nil
+	}
+}
+
+// EncryptVars returns an Option that encrypts vars.yaml with passphrase before
+// it is uploaded to the Store. Existing plaintext vars are encrypted on the next upload
+func EncryptVars(passphrase string) Option {
+	return func(c *CLI) error {
+		if passphrase == "" {
+			return errors.New("passphrase must not be empty")
+		}
+		c.passphrase = passphrase
+		return nil
+	}
+}
+
+// Context returns an Option killing the running bosh command when ctx is done, such as when
+// the process is interrupted. The state and vars written by create-env are still uploaded
+func Context(ctx context.Context) Option {
+	return func(c *CLI) error {
+		if ctx == nil {
+			return errors.New("context must not be nil")
+		}
+		c.ctx = ctx
+		return nil
+	}
+}
+
+// Debug returns an Option that runs bosh with debug logging and includes the
+// tail of its output in the error when a command fails
+func Debug(enabled bool) Option {
+	return func(c *CLI) error {
+		c.debug = enabled
+		return nil
+	}
+}
+
 // New provides a new CLI
 func New(ops ...Option) (ICLI, error) {
 	c := &CLI{
-		execCmd:  exec.Command,
-		boshPath: "bosh",
+		ctx:             context.Background(),
+		execCmd:         exec.Command,
+		boshPath:        "bosh",
+		clientName:      "admin",
+		stemcellRetries: defaultStemcellRetries,
+		stemcellBackoff: defaultStemcellBackoff,
+		lockTTL:         defaultLockTTL,
+		stateFilename:   defaultStateFilename,
+		varsFilename:    defaultVarsFilename,
 	}
 	for _, op := range ops {
 		if err := op(c); err != nil {
@@ -72,6 +380,7 @@ type IAASEnvironment interface {
 	ConfigureDirectorManifestCPI() (string, error)
 	ConfigureDirectorCloudConfig() (string, error)
 	ConfigureConcourseStemcell() (string, error)
+	ConfigureWorkerStemcell() (string, error)
 	IAASCheck() iaas.Name
 }
 
@@ -80,19 +389,89 @@ type Store interface {
 	Set(key string, value []byte) error
 	// Get must return a zero length byte slice and a nil error when the key is not present in the store
 	Get(string) ([]byte, error)
+	// GetMany must map keys that are not present in the store to zero length byte slices
+	GetMany(keys []string) (map[string][]byte, error)
+	SetMany(values map[string][]byte) error
 }
 
-func (c *CLI) xEnv(action string, store Store, config IAASEnvironment, password, cert, key, ca string, tags map[string]string) error {
-	const stateFilename = "state.json"
-	const varsFilename = "vars.yaml"
+// xEnv runs action, create-env or delete-env, against the director. recreate passes --recreate to create-env
+func (c *CLI) xEnv(action string, recreate bool, store Store, config IAASEnvironment, password, cert, key, ca string, tags map[string]string) (err error) {
+	stateFilename := c.stateFilename
+	varsFilename := c.varsFilename
 
-	manifest, err := config.ConfigureDirectorManifestCPI()
+	done := c.emit(action)
+	defer func() { done(err) }()
+
+	manifest, err := c.directorManifest(config, password, cert, key, ca, tags)
+	if err != nil {
+		return err
+	}
+	if action == "create-env" && c.downloadRelease != nil {
+		if err = c.verifyReleases(); err != nil {
+			return err
+		}
+	}
+	unlock, err := c.lock(store)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if unlockErr := unlock(); err == nil {
+			err = unlockErr
+		}
+	}()
+	statePath, uploadState, err := c.writeToDisk(store, stateFilename, false)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if uploadErr := uploadState(); err == nil {
+			err = uploadErr
+		}
+	}()
+	varsPath, uploadVars, err := c.writeToDisk(store, varsFilename, c.passphrase != "")
 	if err != nil {
 		return err
 	}
+	defer func() {
+		if uploadErr := uploadVars(); err == nil {
+			err = uploadErr
+		}
+	}()
+	manifestPath, err := c.writeTempFile([]byte(manifest))
+	if err != nil {
+		return err
+	}
+	defer util.RemoveTemp(manifestPath)
+
+	args := []string{action, "--state=" + statePath, "--vars-store=" + varsPath, manifestPath}
+	if recreate {
+		args = append(args, "--recreate")
+	}
+	cmd := c.command(args...)
+	cmd.Stderr = os.Stderr
+	cmd.Stdout = os.Stdout
+	if err = c.run(cmd); err != nil {
+		return err
+	}
+	if action == "delete-env" {
+		manifest = ""
+	}
+	return c.recordDirectorManifest(store, manifest)
+}
 
-	boshResource := resource.Get(resource.BOSHRelease)
-	bpmResource := resource.Get(resource.BPMRelease)
+// directorManifest renders the director manifest for config, leaving the
+// variables generated by create-env to be resolved from the vars store
+func (c *CLI) directorManifest(config IAASEnvironment, password, cert, key, ca string, tags map[string]string) (string, error) {
+	manifest, err := config.ConfigureDirectorManifestCPI()
+	if err != nil {
+		return "", err
+	}
+	if err = checkYAML("director manifest template", manifest); err != nil {
+		return "", err
+	}
+
+	boshResource, bpmResource := c.releases()
 
 	vars := map[string]interface{}{
 		"director_name":            "bosh",
@@ -110,208 +489,579 @@ func (c *CLI) xEnv(action string, store Store, config IAASEnvironment, password,
 	}
 	manifest, err = yaml.Interpolate(manifest, "", vars)
 	if err != nil {
-		return err
+		return "", err
 	}
-	statePath, uploadState, err := writeToDisk(store, stateFilename)
+	if manifest, err = c.transformManifest(manifest); err != nil {
+		return "", err
+	}
+	if err = checkYAML("rendered director manifest", manifest); err != nil {
+		return "", err
+	}
+	return manifest, nil
+}
+
+// UpdateCloudConfig generates cloud config from template and use it to update bosh cloud config
+func (c *CLI) UpdateCloudConfig(config IAASEnvironment, ip, password, ca string) error {
+	s, err := c.NewSession(config, ip, password, ca)
 	if err != nil {
 		return err
 	}
-	defer uploadState()
-	varsPath, uploadVars, err := writeToDisk(store, varsFilename)
+	defer s.Close()
+	return s.UpdateCloudConfig()
+}
+
+// CloudConfigDiff returns a diff from the cloud config of the director to the rendered one, without applying it
+func (c *CLI) CloudConfigDiff(config IAASEnvironment, ip, password, ca string) (string, error) {
+	s, err := c.NewSession(config, ip, password, ca)
 	if err != nil {
-		return err
+		return "", err
 	}
-	defer uploadVars()
-	manifestPath, err := writeTempFile([]byte(manifest))
+	defer s.Close()
+	return s.CloudConfigDiff()
+}
+
+// Locks runs bosh locks
+func (c *CLI) Locks(config IAASEnvironment, ip, password, ca string) ([]byte, error) {
+	s, err := c.NewSession(config, ip, password, ca)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer os.Remove(manifestPath)
+	defer s.Close()
+	return s.Locks()
+}
 
-	cmd := c.execCmd(c.boshPath, action, "--state="+statePath, "--vars-store="+varsPath, manifestPath)
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
-	return cmd.Run()
+// Deployments runs bosh deployments, listing every deployment of the director
+func (c *CLI) Deployments(config IAASEnvironment, ip, password, ca string) ([]byte, error) {
+	s, err := c.NewSession(config, ip, password, ca)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	return s.Deployments()
 }
 
-// UpdateCloudConfig generates cloud config from template and use it to update bosh cloud config
-func (c *CLI) UpdateCloudConfig(config IAASEnvironment, ip, password, ca string) error {
-	var cloudConfig string
-	var err error
+// Events runs bosh events, keeping at most limit events when limit is positive
+func (c *CLI) Events(config IAASEnvironment, ip, password, ca string, limit int) ([]byte, error) {
+	s, err := c.NewSession(config, ip, password, ca)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	return s.Events(limit)
+}
+
+// LastTaskOutput returns the combined debug output of the most recent task of the concourse deployment
+func (c *CLI) LastTaskOutput(config IAASEnvironment, ip, password, ca string) ([]byte, error) {
+	s, err := c.NewSession(config, ip, password, ca)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	return s.LastTaskOutput()
+}
+
+// limitRows truncates the rows of every table in bosh --json output, since
+// bosh events has no flag to limit the number of events it returns
+func limitRows(data []byte, limit int) ([]byte, error) {
+	var output map[string]json.RawMessage
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, err
+	}
+	var tables []map[string]json.RawMessage
+	if err := json.Unmarshal(output["Tables"], &tables); err != nil {
+		return nil, err
+	}
+	for _, table := range tables {
+		var rows []json.RawMessage
+		if err := json.Unmarshal(table["Rows"], &rows); err != nil {
+			return nil, err
+		}
+		if len(rows) <= limit {
+			continue
+		}
+		b, err := json.Marshal(rows[:limit])
+		if err != nil {
+			return nil, err
+		}
+		table["Rows"] = b
+	}
+	b, err := json.Marshal(tables)
+	if err != nil {
+		return nil, err
+	}
+	output["Tables"] = b
+	return json.Marshal(output)
+}
 
-	cloudConfig, err = config.ConfigureDirectorCloudConfig()
+// UploadConcourseStemcell uploads a stemcell for the chosen IAAS, and the worker stemcell when
+// the workers run on their own, unless SkipStemcellUpload is set
+func (c *CLI) UploadConcourseStemcell(config IAASEnvironment, ip, password, ca string) error {
+	if c.skipStemcell {
+		return nil
+	}
+	s, err := c.NewSession(config, ip, password, ca)
 	if err != nil {
 		return err
 	}
-	cloudConfigPath, err := writeTempFile([]byte(cloudConfig))
+	defer s.Close()
+	return s.UploadConcourseStemcell()
+}
+
+// Recreate runs BOSH recreate
+func (c *CLI) Recreate(config IAASEnvironment, ip, password, ca string) error {
+	s, err := c.NewSession(config, ip, password, ca)
 	if err != nil {
 		return err
 	}
-	defer os.Remove(cloudConfigPath)
-	caPath, err := writeTempFile([]byte(ca))
+	defer s.Close()
+	return s.Recreate()
+}
+
+// RecreateInstance runs BOSH recreate against a single instance group
+func (c *CLI) RecreateInstance(config IAASEnvironment, ip, password, ca, instanceGroup string) error {
+	s, err := c.NewSession(config, ip, password, ca)
 	if err != nil {
 		return err
 	}
-	defer os.Remove(caPath)
-	ip = fmt.Sprintf("https://%s", ip)
-	cmd := c.execCmd(c.boshPath, "--non-interactive", "--environment", ip, "--ca-cert", caPath, "--client", "admin", "--client-secret", password, "update-cloud-config", cloudConfigPath)
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
-	return cmd.Run()
+	defer s.Close()
+	return s.RecreateInstance(instanceGroup)
 }
 
-// Locks runs bosh locks
-func (c *CLI) Locks(config IAASEnvironment, ip, password, ca string) ([]byte, error) {
-	var out bytes.Buffer
-	caPath, err := writeTempFile([]byte(ca))
+// VMs runs bosh vms against the concourse deployment
+func (c *CLI) VMs(config IAASEnvironment, ip, password, ca string) ([]byte, error) {
+	s, err := c.NewSession(config, ip, password, ca)
 	if err != nil {
 		return nil, err
 	}
-	defer os.Remove(caPath)
-	cmd := c.execCmd(c.boshPath, "--environment", ip, "--ca-cert", caPath, "--client", "admin", "--client-secret", password, "locks", "--json")
-	cmd.Stdout = &out
-	err = cmd.Run()
+	defer s.Close()
+	return s.VMs()
+}
+
+// PersistentDisks returns the persistent disks of the instances of the concourse deployment
+func (c *CLI) PersistentDisks(config IAASEnvironment, ip, password, ca string) ([]PersistentDisk, error) {
+	s, err := c.NewSession(config, ip, password, ca)
 	if err != nil {
 		return nil, err
 	}
-	return out.Bytes(), nil
+	defer s.Close()
+	return s.PersistentDisks()
 }
 
-// UploadConcourseStemcell uploads a stemcell for the chosen IAAS
-func (c *CLI) UploadConcourseStemcell(config IAASEnvironment, ip, password, ca string) error {
-	var (
-		stemcell string
-		err      error
-	)
+// RecreateFailing runs BOSH recreate against every instance of the concourse deployment
+// whose processes are not running, returning the instances that were recreated
+func (c *CLI) RecreateFailing(config IAASEnvironment, ip, password, ca string) ([]string, error) {
+	s, err := c.NewSession(config, ip, password, ca)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	return s.RecreateFailing()
+}
 
-	stemcell, err = config.ConfigureConcourseStemcell()
+// Pause stops the web instances of the concourse deployment so that no new builds are scheduled
+func (c *CLI) Pause(config IAASEnvironment, ip, password, ca string) error {
+	s, err := c.NewSession(config, ip, password, ca)
 	if err != nil {
 		return err
 	}
+	defer s.Close()
+	return s.Pause()
+}
 
-	caPath, err := writeTempFile([]byte(ca))
+// Resume starts the web instances of the concourse deployment stopped by Pause
+func (c *CLI) Resume(config IAASEnvironment, ip, password, ca string) error {
+	s, err := c.NewSession(config, ip, password, ca)
 	if err != nil {
 		return err
 	}
-	defer os.Remove(caPath)
-	ip = fmt.Sprintf("https://%s", ip)
-	cmd := c.execCmd(c.boshPath, "--non-interactive", "--environment", ip, "--ca-cert", caPath, "--client", "admin", "--client-secret", password, "upload-stemcell", stemcell)
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
-	return cmd.Run()
+	defer s.Close()
+	return s.Resume()
 }
 
-// Recreate runs BOSH recreate
-func (c *CLI) Recreate(config IAASEnvironment, ip, password, ca string) error {
-	caPath, err := writeTempFile([]byte(ca))
+// CleanUp runs BOSH clean-up to remove unused releases and stemcells from the director.
+// all also removes orphaned disks and unused compiled packages
+func (c *CLI) CleanUp(config IAASEnvironment, ip, password, ca string, all bool) error {
+	s, err := c.NewSession(config, ip, password, ca)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	return s.CleanUp(all)
+}
+
+// FetchLogs runs BOSH logs against an instance group of the concourse deployment
+// and writes the downloaded tarball to dest
+func (c *CLI) FetchLogs(config IAASEnvironment, ip, password, ca, instanceGroup string, dest string) error {
+	s, err := c.NewSession(config, ip, password, ca)
 	if err != nil {
 		return err
 	}
-	defer os.Remove(caPath)
-	ip = fmt.Sprintf("https://%s", ip)
-	cmd := c.execCmd(c.boshPath, "--non-interactive", "--environment", ip, "--ca-cert", caPath, "--client", "admin", "--client-secret", password, "--deployment", "concourse", "recreate")
+	defer s.Close()
+	return s.FetchLogs(instanceGroup, dest)
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err1 := out.Close(); err == nil {
+		err = err1
+	}
+	return err
+}
+
+// ConcourseCredentials holds the URL and initial admin credentials of the Concourse ATC
+type ConcourseCredentials struct {
+	URL      string
+	Username string
+	Password string
+}
+
+// ConcourseCredentials returns the ATC URL for host, which may be an IP or a domain,
+// along with the admin password read from vars.yaml in the Store
+func (c *CLI) ConcourseCredentials(store Store, host string) (ConcourseCredentials, error) {
+	varsFilename := c.varsFilename
+	if host == "" {
+		return ConcourseCredentials{}, errors.New("host must not be empty")
+	}
+	data, err := store.Get(varsFilename)
+	if err != nil {
+		return ConcourseCredentials{}, err
+	}
+	if len(data) == 0 {
+		return ConcourseCredentials{}, fmt.Errorf("%s not found in store, has the deployment completed?", varsFilename)
+	}
+	if c.passphrase != "" {
+		if data, err = decrypt(c.passphrase, data); err != nil {
+			return ConcourseCredentials{}, fmt.Errorf("failed to read %s: [%v]", varsFilename, err)
+		}
+	}
+	password, err := yaml.Path(data, "atc_password")
+	if err != nil {
+		return ConcourseCredentials{}, fmt.Errorf("failed to find the Concourse admin password in %s: [%v]", varsFilename, err)
+	}
+	return ConcourseCredentials{
+		URL:      fmt.Sprintf("https://%s", host),
+		Username: "admin",
+		Password: strings.TrimSpace(password),
+	}, nil
+}
+
+// CheckStateConsistency cross-checks state.json and vars.yaml in the Store,
+// returning an error when they no longer describe the same director
+func (c *CLI) CheckStateConsistency(store Store) error {
+	stateFilename := c.stateFilename
+	varsFilename := c.varsFilename
+
+	stateData, err := store.Get(stateFilename)
+	if err != nil {
+		return err
+	}
+	varsData, err := store.Get(varsFilename)
+	if err != nil {
+		return err
+	}
+	if c.passphrase != "" {
+		if varsData, err = decrypt(c.passphrase, varsData); err != nil {
+			return fmt.Errorf("failed to read %s: [%v]", varsFilename, err)
+		}
+	}
+
+	_, err = c.checkConsistency(stateData, varsData)
+	return err
+}
+
+// checkConsistency reports whether stateData describes a director, erroring
+// when varsData does not match it
+func (c *CLI) checkConsistency(stateData, varsData []byte) (bool, error) {
+	stateFilename := c.stateFilename
+	varsFilename := c.varsFilename
+
+	if len(stateData) == 0 {
+		if len(varsData) == 0 {
+			return false, nil
+		}
+		return false, fmt.Errorf("%s exists but %s is missing, the director may have been partially deleted", varsFilename, stateFilename)
+	}
+
+	var state struct {
+		DirectorID   string `json:"director_id"`
+		CurrentVMCID string `json:"current_vm_cid"`
+	}
+	if err := json.Unmarshal(stateData, &state); err != nil {
+		return false, fmt.Errorf("failed to parse %s: [%v]", stateFilename, err)
+	}
+	if state.DirectorID == "" && state.CurrentVMCID == "" {
+		if len(varsData) == 0 {
+			return false, nil
+		}
+		return false, fmt.Errorf("%s describes no director but %s exists, the director may have been partially deleted", stateFilename, varsFilename)
+	}
+	if len(varsData) == 0 {
+		return false, fmt.Errorf("%s describes director %s but %s is missing", stateFilename, state.DirectorID, varsFilename)
+	}
+	for _, key := range []string{"admin_password", "director_ssl"} {
+		if _, err := yaml.Path(varsData, key); err != nil {
+			return false, fmt.Errorf("%s describes director %s but %s has no %s", stateFilename, state.DirectorID, varsFilename, key)
+		}
+	}
+	return true, nil
+}
+
+// ImportState adopts an existing director by uploading its state and vars files
+// to the Store, after checking they are consistent and that the director at ip
+// accepts the credentials in vars. It refuses to overwrite existing state
+func (c *CLI) ImportState(store Store, ip, statePath, varsPath string) error {
+	stateFilename := c.stateFilename
+	varsFilename := c.varsFilename
+
+	existing, err := store.Get(stateFilename)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return fmt.Errorf("%s already exists in the store, refusing to overwrite it", stateFilename)
+	}
+	stateData, err := ioutil.ReadFile(statePath)
+	if err != nil {
+		return err
+	}
+	varsData, err := ioutil.ReadFile(varsPath)
+	if err != nil {
+		return err
+	}
+	hasDirector, err := c.checkConsistency(stateData, varsData)
+	if err != nil {
+		return err
+	}
+	if !hasDirector {
+		return fmt.Errorf("%s does not describe a director", statePath)
+	}
+	var vars struct {
+		AdminPassword string `yaml:"admin_password"`
+		DirectorSSL   struct {
+			CA string `yaml:"ca"`
+		} `yaml:"director_ssl"`
+	}
+	if err = goyaml.Unmarshal(varsData, &vars); err != nil {
+		return fmt.Errorf("failed to parse %s: [%v]", varsFilename, err)
+	}
+	if vars.DirectorSSL.CA == "" {
+		return fmt.Errorf("failed to find the director CA in %s", varsFilename)
+	}
+	caPath, err := c.writeTempFile([]byte(vars.DirectorSSL.CA))
+	if err != nil {
+		return err
+	}
+	defer util.RemoveTemp(caPath)
+	cmd := c.command("--non-interactive", "--environment", fmt.Sprintf("https://%s", ip), "--ca-cert", caPath, "--client", c.clientName, "--client-secret", vars.AdminPassword, "env")
 	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
-	return cmd.Run()
+	cmd.Stdout = ioutil.Discard
+	if err = c.run(cmd); err != nil {
+		return fmt.Errorf("failed to reach the director at %s: [%v]", ip, err)
+	}
+
+	if c.passphrase != "" {
+		if varsData, err = encrypt(c.passphrase, varsData); err != nil {
+			return err
+		}
+	}
+	return store.SetMany(map[string][]byte{
+		stateFilename: stateData,
+		varsFilename:  varsData,
+	})
 }
 
 func (c *CLI) DeleteEnv(store Store, config IAASEnvironment, password, cert, key, ca string, tags map[string]string) error {
-	return c.xEnv("delete-env", store, config, password, cert, key, ca, tags)
+	return c.xEnv("delete-env", false, store, config, password, cert, key, ca, tags)
 }
 
 func (c *CLI) CreateEnv(store Store, config IAASEnvironment, password, cert, key, ca string, tags map[string]string) error {
 
-	return c.xEnv("create-env", store, config, password, cert, key, ca, tags)
+	return c.xEnv("create-env", false, store, config, password, cert, key, ca, tags)
+}
+
+// RecreateEnv runs create-env with --recreate, which rebuilds the director VM even when it is
+// healthy, such as after its disks have been left in a bad state
+func (c *CLI) RecreateEnv(store Store, config IAASEnvironment, password, cert, key, ca string, tags map[string]string) error {
+	return c.xEnv("create-env", true, store, config, password, cert, key, ca, tags)
 }
 
 // RunAuthenticatedCommand runs the bosh command `action` with flags `flags`
 // specifying `detach` will cause the task to detach once a deployment starts
 // `detach` is currently only implemented with the action `deploy`
 func (c *CLI) RunAuthenticatedCommand(action, ip, password, ca string, detach bool, stdout io.Writer, flags ...string) error {
-	caPath, err := writeTempFile([]byte(ca))
+	s, err := c.NewSession(nil, ip, password, ca)
 	if err != nil {
 		return err
 	}
-	defer os.Remove(caPath)
-	ip = fmt.Sprintf("https://%s", ip)
-
-	authFlags := []string{"--non-interactive", "--environment", ip, "--ca-cert", caPath, "--client", "admin", "--client-secret", password, "--deployment", "concourse", action}
-	flags = append(authFlags, flags...)
-	if detach && action == "deploy" {
-		return c.detachedBoshCommand(stdout, flags...)
-	}
-	return c.boshCommand(stdout, flags...)
+	defer s.Close()
+	return s.RunAuthenticatedCommand(action, detach, stdout, flags...)
 }
 
 func (c *CLI) boshCommand(stdout io.Writer, flags ...string) error {
-	cmd := c.execCmd(c.boshPath, flags...)
-	cmd.Stderr = os.Stderr
+	stdout, finishLines := c.flushLinesTo(stdout)
+	stdout, stderr, closeTee := c.tee(stdout, os.Stderr)
+	defer closeTee()
+	cmd := c.command(flags...)
+	cmd.Stderr = stderr
 	cmd.Stdout = stdout
-	return cmd.Run()
+	if err := c.run(cmd); err != nil {
+		finishLines()
+		return err
+	}
+	return finishLines()
 }
 
+// detachedBoshCommand runs a bosh command until its task has started, then stops following its output.
+// The task carries on running on the director
 func (c *CLI) detachedBoshCommand(stdout io.Writer, flags ...string) error {
-	cmd := c.execCmd(c.boshPath, flags...)
-	cmd.Stderr = os.Stderr
+	stdout, stderr, closeTee := c.tee(stdout, os.Stderr)
+	defer closeTee()
+	cmd := c.command(flags...)
+	cmd.Stderr = stderr
+	detach := &detachWriter{w: stdout, marker: "Preparing deployment", detached: make(chan struct{})}
+	cmd.Stdout = detach
 
-	cmdReader, err := cmd.StdoutPipe()
-	if err != nil {
+	if err := c.runDetachable(cmd, detach.detached); err != nil {
 		return err
 	}
+	select {
+	case <-detach.detached:
+		return nil
+	default:
+	}
+	return fmt.Errorf("Didn't detect successful task start in BOSH comand: bosh-cli %s", redactArgs(flags))
+}
 
-	scanner := bufio.NewScanner(cmdReader)
+// detachWriter passes the lines written to it on to w until one contains marker, after which
+// it closes detached and discards the rest
+type detachWriter struct {
+	w        io.Writer
+	marker   string
+	detached chan struct{}
+	partial  []byte
+}
 
-	if err := cmd.Start(); err != nil {
-		return err
+func (d *detachWriter) Write(p []byte) (int, error) {
+	select {
+	case <-d.detached:
+		return len(p), nil
+	default:
 	}
-
-	for scanner.Scan() {
-		text := scanner.Text()
-		if _, err := stdout.Write([]byte(fmt.Sprintf("%s\n", text))); err != nil {
-			return err
+	data := append(d.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
 		}
-		if strings.Contains(text, "Preparing deployment") {
-			stdout.Write([]byte("Task started, detaching output\n"))
-			return nil
+		line := data[:i+1]
+		data = data[i+1:]
+		if _, err := d.w.Write(line); err != nil {
+			return 0, err
+		}
+		if bytes.Contains(line, []byte(d.marker)) {
+			d.w.Write([]byte("Task started, detaching output\n"))
+			close(d.detached)
+			return len(p), nil
 		}
 	}
-
-	return fmt.Errorf("Didn't detect successful task start in BOSH comand: bosh-cli %s", strings.Join(flags, " "))
+	d.partial = append([]byte(nil), data...)
+	return len(p), nil
 }
 
-func writeToDisk(store Store, key string) (filename string, upload func() error, err error) {
+func (c *CLI) writeToDisk(store Store, key string, encrypted bool) (filename string, upload func() error, err error) {
 	data, err := store.Get(key)
 	if err != nil {
 		return "", nil, err
 	}
+	if encrypted {
+		data, err = decrypt(c.passphrase, data)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read %s: [%v]", key, err)
+		}
+	}
+	// The state and vars are not removed on interrupt, as they are the only copy until uploaded
 	var path string
+	remove := func() error { return os.Remove(path) }
 	if len(data) == 0 {
-		path, err = ioutil.TempDir("", "")
-		path = filepath.Join(path, key)
+		var dir string
+		dir, err = ioutil.TempDir(c.tempDir, "")
+		path = filepath.Join(dir, filepath.Base(key))
+		remove = func() error { return os.RemoveAll(dir) }
 	} else {
-		path, err = writeTempFile(data)
+		path, err = c.writeFile(data)
 	}
 	if err != nil {
 		return "", nil, err
 	}
 	upload = func() error {
-		defer os.Remove(path)
 		data, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			return remove()
+		}
 		if err != nil {
 			return err
 		}
-		return store.Set(key, data)
+		if encrypted {
+			if data, err = encrypt(c.passphrase, data); err != nil {
+				return err
+			}
+		}
+		if err = store.Set(key, data); err != nil {
+			return fmt.Errorf("failed to upload %s, a copy is kept at %s: [%v]", key, path, err)
+		}
+		if c.verifyUploads {
+			if err = verifyUpload(store, key, data); err != nil {
+				return fmt.Errorf("%v, a copy is kept at %s", err, path)
+			}
+		}
+		return remove()
 	}
 	return path, upload, nil
 }
 
-func writeTempFile(data []byte) (string, error) {
-	f, err := ioutil.TempFile("", "")
+func verifyUpload(store Store, key string, data []byte) error {
+	stored, err := store.Get(key)
+	if err != nil {
+		return fmt.Errorf("failed to read back %s: [%v]", key, err)
+	}
+	if sha256.Sum256(stored) != sha256.Sum256(data) {
+		return fmt.Errorf("checksum of uploaded %s does not match: wrote %d bytes, read back %d bytes", key, len(data), len(stored))
+	}
+	return nil
+}
+
+// writeTempFile writes data to a temporary file which is removed if the process is interrupted
+func (c *CLI) writeTempFile(data []byte) (string, error) {
+	name, err := c.writeFile(data)
+	if err != nil {
+		return "", err
+	}
+	util.RemoveOnInterrupt(name)
+	return name, nil
+}
+
+// writeFile writes data to a new file in the temp dir of the CLI
+func (c *CLI) writeFile(data []byte) (string, error) {
+	f, err := ioutil.TempFile(c.tempDir, "")
 	if err != nil {
 		return "", err
 	}
 	name := f.Name()
-	_, err = f.Write(data)
+	if c.tempFileMode != 0 && c.tempFileMode != defaultTempFileMode {
+		err = f.Chmod(c.tempFileMode)
+	}
+	if err == nil {
+		_, err = f.Write(data)
+	}
 	if err1 := f.Close(); err == nil {
 		err = err1
 	}
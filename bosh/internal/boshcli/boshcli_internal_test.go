@@ -1,6 +1,11 @@
 package boshcli
 
-import "os/exec"
+import (
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+)
 
 func FakeExec(execCmd func(string, ...string) *exec.Cmd) Option {
 	return func(c *CLI) error {
@@ -8,3 +13,21 @@ func FakeExec(execCmd func(string, ...string) *exec.Cmd) Option {
 		return nil
 	}
 }
+
+func TestTailWriterConcurrentWrites(t *testing.T) {
+	tail := &tailWriter{max: 1000}
+	var wg sync.WaitGroup
+	for _, stream := range []string{"stdout", "stderr"} {
+		wg.Add(1)
+		go func(line string) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				tail.Write([]byte(line + "\n"))
+			}
+		}(stream)
+	}
+	wg.Wait()
+	if got := strings.Count(tail.String(), "\n") + 1; got != 200 {
+		t.Errorf("tailWriter kept %d lines, want 200", got)
+	}
+}
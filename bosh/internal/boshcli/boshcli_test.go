@@ -1,16 +1,43 @@
 package boshcli_test
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/EngineerBetter/control-tower/bosh/internal/boshcli"
+	"github.com/EngineerBetter/control-tower/certs"
 	"github.com/EngineerBetter/control-tower/iaas"
 	"github.com/EngineerBetter/control-tower/internal/fakeexec"
+	"github.com/EngineerBetter/control-tower/resource"
 	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
 )
 
 func TestExecCommandHelper(t *testing.T) {
@@ -33,6 +60,21 @@ func (s mockStore) Get(key string) ([]byte, error) {
 	return s[key], nil
 }
 
+func (s mockStore) GetMany(keys []string) (map[string][]byte, error) {
+	values := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		values[key] = append([]byte{}, s[key]...)
+	}
+	return values, nil
+}
+
+func (s mockStore) SetMany(values map[string][]byte) error {
+	for key, value := range values {
+		s[key] = value
+	}
+	return nil
+}
+
 type mockIAASConfig struct {
 }
 
@@ -51,6 +93,10 @@ func (c mockIAASConfig) ConfigureConcourseStemcell() (string, error) {
 	return "a Stemcell", nil
 }
 
+func (c mockIAASConfig) ConfigureWorkerStemcell() (string, error) {
+	return "", nil
+}
+
 func TestCLI_CreateEnv(t *testing.T) {
 	e := fakeexec.New(t)
 	defer e.Finish()
@@ -70,6 +116,167 @@ func TestCLI_CreateEnv(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestCLI_RecreateEnv(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+	require.NoError(t, err)
+	store := make(mockStore)
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "create-env", args[0])
+		require.Equal(t, []string{"--recreate"}, args[4:])
+	})
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "create-env", args[0])
+		require.NotContains(t, args, "--recreate")
+	})
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "delete-env", args[0])
+		require.NotContains(t, args, "--recreate")
+	})
+	require.NoError(t, c.RecreateEnv(store, mockIAASConfig{}, "password", "cert", "key", "ca", map[string]string{}))
+	require.NoError(t, c.CreateEnv(store, mockIAASConfig{}, "password", "cert", "key", "ca", map[string]string{}))
+	require.NoError(t, c.DeleteEnv(store, mockIAASConfig{}, "password", "cert", "key", "ca", map[string]string{}))
+}
+
+type releasesIAASConfig struct {
+	mockIAASConfig
+}
+
+func (c releasesIAASConfig) ConfigureDirectorManifestCPI() (string, error) {
+	return `releases:
+- name: bosh
+  version: ((bosh_version))
+  url: ((bosh_url))
+  sha1: ((bosh_sha1))
+- name: bpm
+  version: ((bpm_version))
+  url: ((bpm_url))
+  sha1: ((bpm_sha1))
+`, nil
+}
+
+func TestCLI_CreateEnvWithReleaseOverrides(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(
+		boshcli.FakeExec(e.Cmd()),
+		boshcli.BOSHRelease(resource.Resource{URL: "https://example.com/bosh.tgz", Version: "999.0.0", SHA1: "boshsha"}),
+		boshcli.BPMRelease(resource.Resource{URL: "https://example.com/bpm.tgz", Version: "888.0.0", SHA1: "bpmsha"}),
+	)
+	require.NoError(t, err)
+	store := make(mockStore)
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "create-env", args[0])
+		manifest, err := ioutil.ReadFile(args[3])
+		require.NoError(t, err)
+		for _, s := range []string{"https://example.com/bosh.tgz", "999.0.0", "boshsha", "https://example.com/bpm.tgz", "888.0.0", "bpmsha"} {
+			require.Contains(t, string(manifest), s)
+		}
+	})
+	err = c.CreateEnv(store, releasesIAASConfig{}, "password", "cert", "key", "ca", map[string]string{})
+	require.NoError(t, err)
+}
+
+func TestCLI_CreateEnvTransformManifest(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	var received string
+	c, err := boshcli.New(
+		boshcli.FakeExec(e.Cmd()),
+		boshcli.TransformManifest(func(manifest string) (string, error) {
+			received = manifest
+			return manifest + "tags:\n  policy: enforced\n", nil
+		}),
+	)
+	require.NoError(t, err)
+	store := make(mockStore)
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "create-env", args[0])
+		manifest, err := ioutil.ReadFile(args[3])
+		require.NoError(t, err)
+		require.Contains(t, string(manifest), "tags:\n  policy: enforced\n")
+	})
+	err = c.CreateEnv(store, releasesIAASConfig{}, "password", "cert", "key", "ca", map[string]string{})
+	require.NoError(t, err)
+	require.NotContains(t, received, "((bosh_version))", "the transform should see the interpolated manifest")
+}
+
+func TestCLI_CreateEnvTransformManifestError(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(
+		boshcli.FakeExec(e.Cmd()),
+		boshcli.TransformManifest(func(string) (string, error) {
+			return "", errors.New("denied by policy")
+		}),
+	)
+	require.NoError(t, err)
+	err = c.CreateEnv(make(mockStore), releasesIAASConfig{}, "password", "cert", "key", "ca", map[string]string{})
+	require.EqualError(t, err, "failed to transform the director manifest: [denied by policy]")
+
+	_, err = boshcli.New(boshcli.TransformManifest(nil))
+	require.Error(t, err)
+}
+
+func TestCLI_CreateEnvVerifyReleases(t *testing.T) {
+	releases := map[string]string{
+		"https://example.com/bosh.tgz": "bosh release",
+		"https://example.com/bpm.tgz":  "bpm release",
+	}
+	download := func(url string) (io.ReadCloser, error) {
+		data, ok := releases[url]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		return ioutil.NopCloser(strings.NewReader(data)), nil
+	}
+	sha := func(data string) string {
+		sum := sha1.Sum([]byte(data))
+		return hex.EncodeToString(sum[:])
+	}
+
+	tests := []struct {
+		name    string
+		boshSHA string
+		wantErr string
+	}{
+		{
+			name:    "matching SHA1",
+			boshSHA: sha("bosh release"),
+		},
+		{
+			name:    "mismatching SHA1",
+			boshSHA: sha("tampered release"),
+			wantErr: "bosh release 999.0.0 downloaded from https://example.com/bosh.tgz has SHA1 " + sha("bosh release") + " but " + sha("tampered release") + " was expected",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := fakeexec.New(t)
+			defer e.Finish()
+			c, err := boshcli.New(
+				boshcli.FakeExec(e.Cmd()),
+				boshcli.BOSHRelease(resource.Resource{URL: "https://example.com/bosh.tgz", Version: "999.0.0", SHA1: tt.boshSHA}),
+				boshcli.BPMRelease(resource.Resource{URL: "https://example.com/bpm.tgz", Version: "888.0.0", SHA1: sha("bpm release")}),
+				boshcli.VerifyReleases(download),
+			)
+			require.NoError(t, err)
+			if tt.wantErr == "" {
+				e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+					require.Equal(t, "create-env", args[0])
+				})
+			}
+			err = c.CreateEnv(make(mockStore), releasesIAASConfig{}, "password", "cert", "key", "ca", map[string]string{})
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
 func expectPathNotToExistButBeWriteable(t testing.TB, path string) {
 	t.Helper()
 	if _, err := os.Stat(path); !os.IsNotExist(err) {
@@ -121,3 +328,2626 @@ func TestCLI_UploadConcourseStemcell(t *testing.T) {
 	require.NoError(t, err)
 
 }
+
+type workerStemcellIAASConfig struct {
+	mockIAASConfig
+}
+
+func (c workerStemcellIAASConfig) ConfigureWorkerStemcell() (string, error) {
+	return "a Worker Stemcell", nil
+}
+
+func TestCLI_UploadConcourseStemcellWithWorkerStemcell(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+	require.NoError(t, err)
+	for _, stemcell := range []string{"a Stemcell", "a Worker Stemcell"} {
+		stemcell := stemcell
+		e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+			require.Equal(t, []string{"upload-stemcell", stemcell}, args[9:])
+		})
+	}
+	err = c.UploadConcourseStemcell(workerStemcellIAASConfig{}, "ip", "password", "ca")
+	require.NoError(t, err)
+}
+
+func TestCLI_UploadConcourseStemcellRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		retries  int
+		failures int
+		output   string
+		wantErr  bool
+		wantRuns int
+	}{
+		{
+			name:     "succeeds after a transient failure",
+			retries:  2,
+			failures: 1,
+			wantRuns: 2,
+		},
+		{
+			name:     "gives up once retries are exhausted",
+			retries:  2,
+			failures: 3,
+			wantErr:  true,
+			wantRuns: 3,
+		},
+		{
+			name:     "does not retry without retries",
+			retries:  0,
+			failures: 1,
+			wantErr:  true,
+			wantRuns: 1,
+		},
+		{
+			name:     "does not retry authentication failures",
+			retries:  2,
+			failures: 1,
+			output:   "Getting token: Bad credentials",
+			wantErr:  true,
+			wantRuns: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := fakeexec.New(t)
+			c, err := boshcli.New(boshcli.FakeExec(e.Cmd()), boshcli.StemcellUploadRetries(tt.retries, time.Millisecond))
+			require.NoError(t, err)
+			runs := 0
+			for i := 0; i < tt.wantRuns; i++ {
+				expect := e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+					require.Equal(t, "upload-stemcell", args[9])
+					runs++
+				})
+				if i < tt.failures {
+					expect.Outputs(tt.output)
+					expect.Exits(1)
+				}
+			}
+			err = c.UploadConcourseStemcell(mockIAASConfig{}, "ip", "password", "ca")
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantRuns, runs)
+			e.Finish()
+		})
+	}
+}
+
+func TestCLI_StemcellUploadRetriesMustNotBeNegative(t *testing.T) {
+	_, err := boshcli.New(boshcli.StemcellUploadRetries(-1, time.Second))
+	require.Error(t, err)
+}
+
+const eventsJSON = `{"Tables":[{"Content":"events","Rows":[{"id":"3","action":"delete"},{"id":"2","action":"update"},{"id":"1","action":"create"}]}]}`
+
+func TestCLI_Events(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+	require.NoError(t, err)
+	config := mockIAASConfig{}
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "bosh", command)
+
+		require.Equal(t, "--environment", args[0])
+		require.Equal(t, "ip", args[1])
+		require.Equal(t, "--client-secret", args[6])
+		require.Equal(t, "password", args[7])
+		require.Equal(t, []string{"events", "--json"}, args[8:])
+	}).Outputs(eventsJSON)
+	out, err := c.Events(config, "ip", "password", "ca", 0)
+	require.NoError(t, err)
+	require.Equal(t, eventsJSON, string(out))
+}
+
+const deploymentsJSON = `{"Tables":[{"Content":"deployments","Rows":[{"name":"concourse","release_s":"bpm/1.1.9\nconcourse/6.7.2","stemcell_s":"bosh-aws-xen-hvm-ubuntu-xenial-go_agent/621.94","team_s":"","cloud_config":"latest"},{"name":"vault","release_s":"vault/1.1.3","stemcell_s":"bosh-aws-xen-hvm-ubuntu-xenial-go_agent/621.94","team_s":"","cloud_config":"latest"}]}]}`
+
+func TestCLI_Deployments(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+	require.NoError(t, err)
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "bosh", command)
+
+		require.Equal(t, "--environment", args[0])
+		require.Equal(t, "ip", args[1])
+		require.Equal(t, "--client-secret", args[6])
+		require.Equal(t, "password", args[7])
+		require.Equal(t, []string{"deployments", "--json"}, args[8:])
+	}).Outputs(deploymentsJSON)
+	out, err := c.Deployments(mockIAASConfig{}, "ip", "password", "ca")
+	require.NoError(t, err)
+	require.Equal(t, deploymentsJSON, string(out))
+}
+
+func TestCLI_EventsWithLimit(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+	require.NoError(t, err)
+	config := mockIAASConfig{}
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, []string{"events", "--json"}, args[8:])
+	}).Outputs(eventsJSON)
+	out, err := c.Events(config, "ip", "password", "ca", 2)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"Tables":[{"Content":"events","Rows":[{"id":"3","action":"delete"},{"id":"2","action":"update"}]}]}`, string(out))
+}
+
+type corruptingStore struct {
+	mockStore
+}
+
+func (s corruptingStore) Get(key string) ([]byte, error) {
+	value, err := s.mockStore.Get(key)
+	if len(value) == 0 {
+		return value, err
+	}
+	return value[:len(value)-1], err
+}
+
+func TestCLI_CreateEnvVerifiesUploads(t *testing.T) {
+	tests := []struct {
+		name    string
+		store   boshcli.Store
+		wantErr bool
+	}{
+		{
+			name:  "upload matches",
+			store: mockStore{"state.json": []byte("{}")},
+		},
+		{
+			name:    "upload is corrupted",
+			store:   corruptingStore{mockStore{"state.json": []byte("{}")}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := fakeexec.New(t)
+			defer e.Finish()
+			c, err := boshcli.New(boshcli.FakeExec(e.Cmd()), boshcli.VerifyUploads())
+			require.NoError(t, err)
+			e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+				require.Equal(t, "create-env", args[0])
+			})
+			err = c.CreateEnv(tt.store, mockIAASConfig{}, "password", "cert", "key", "ca", map[string]string{})
+			if tt.wantErr {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "state.json")
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCLI_RecreateInstance(t *testing.T) {
+	for _, instanceGroup := range []string{"worker", "web"} {
+		t.Run(instanceGroup, func(t *testing.T) {
+			e := fakeexec.New(t)
+			defer e.Finish()
+			c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+			require.NoError(t, err)
+			e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+				require.Equal(t, "bosh", command)
+
+				require.Equal(t, "--non-interactive", args[0])
+				require.Equal(t, "https://ip", args[2])
+				require.Equal(t, "password", args[8])
+				require.Equal(t, []string{"--deployment", "concourse", "recreate", instanceGroup}, args[9:])
+			})
+			err = c.RecreateInstance(mockIAASConfig{}, "ip", "password", "ca", instanceGroup)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCLI_RecreateInstanceRequiresInstanceGroup(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+	require.NoError(t, err)
+	err = c.RecreateInstance(mockIAASConfig{}, "ip", "password", "ca", "")
+	require.Error(t, err)
+}
+
+func TestCLI_FetchLogs(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+	require.NoError(t, err)
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "web-logs.tgz")
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "bosh", command)
+
+		require.Equal(t, "--non-interactive", args[0])
+		require.Equal(t, "https://ip", args[2])
+		require.Equal(t, "password", args[8])
+		require.Equal(t, []string{"--deployment", "concourse", "logs", "web", "--dir"}, args[9:14])
+		err := ioutil.WriteFile(filepath.Join(args[14], "concourse.web-20201016-120000.tgz"), []byte("logs"), 0600)
+		require.NoError(t, err)
+	})
+	err = c.FetchLogs(mockIAASConfig{}, "ip", "password", "ca", "web", dest)
+	require.NoError(t, err)
+	logs, err := ioutil.ReadFile(dest)
+	require.NoError(t, err)
+	require.Equal(t, "logs", string(logs))
+}
+
+func TestCLI_FetchLogsErrors(t *testing.T) {
+	t.Run("no instance group", func(t *testing.T) {
+		c, err := boshcli.New(boshcli.FakeExec(fakeexec.New(t).Cmd()))
+		require.NoError(t, err)
+		err = c.FetchLogs(mockIAASConfig{}, "ip", "password", "ca", "", "logs.tgz")
+		require.Error(t, err)
+	})
+	t.Run("no destination", func(t *testing.T) {
+		c, err := boshcli.New(boshcli.FakeExec(fakeexec.New(t).Cmd()))
+		require.NoError(t, err)
+		err = c.FetchLogs(mockIAASConfig{}, "ip", "password", "ca", "web", "")
+		require.Error(t, err)
+	})
+	t.Run("no tarball downloaded", func(t *testing.T) {
+		e := fakeexec.New(t)
+		defer e.Finish()
+		c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+		require.NoError(t, err)
+		e.ExpectFunc(func(t testing.TB, command string, args ...string) {})
+		dir, err := ioutil.TempDir("", "")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+		dest := filepath.Join(dir, "web-logs.tgz")
+		err = c.FetchLogs(mockIAASConfig{}, "ip", "password", "ca", "web", dest)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "found 0")
+		_, err = os.Stat(dest)
+		require.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestCLI_Debug(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	var cmd *exec.Cmd
+	fakeCmd := e.Cmd()
+	c, err := boshcli.New(boshcli.FakeExec(func(command string, args ...string) *exec.Cmd {
+		cmd = fakeCmd(command, args...)
+		return cmd
+	}), boshcli.Debug(true))
+	require.NoError(t, err)
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "update-cloud-config", args[9])
+		require.Equal(t, "--tty", args[len(args)-1])
+	})
+	err = c.UpdateCloudConfig(mockIAASConfig{}, "ip", "password", "ca")
+	require.NoError(t, err)
+	require.Contains(t, cmd.Env, "BOSH_LOG_LEVEL=debug")
+}
+
+func TestCLI_DebugIncludesOutputOnFailure(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()), boshcli.Debug(true))
+	require.NoError(t, err)
+	expect := e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "recreate", args[11])
+	})
+	expect.Outputs("Task 42\nError: instance failed to start\n")
+	expect.Exits(1)
+	err = c.Recreate(mockIAASConfig{}, "ip", "password", "ca")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Error: instance failed to start")
+}
+
+func TestCLI_DebugRedactsSecretsOnFailure(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()), boshcli.Debug(true))
+	require.NoError(t, err)
+	expect := e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Contains(t, args, "s3cret-director-password")
+	})
+	expect.Outputs("Error: instance failed to start\n")
+	expect.Exits(1)
+	err = c.Recreate(mockIAASConfig{}, "ip", "s3cret-director-password", "ca")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "--client-secret [REDACTED]")
+	require.NotContains(t, err.Error(), "s3cret-director-password")
+}
+
+func TestCLI_CreateEnvEncryptsVars(t *testing.T) {
+	const vars = "admin_password: secret\n"
+	store := make(mockStore)
+
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()), boshcli.EncryptVars("passphrase"))
+	require.NoError(t, err)
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		v := strings.TrimPrefix(args[2], "--vars-store=")
+		require.NoError(t, ioutil.WriteFile(v, []byte(vars), 0600))
+	})
+	err = c.CreateEnv(store, mockIAASConfig{}, "password", "cert", "key", "ca", map[string]string{})
+	require.NoError(t, err)
+	require.NotEmpty(t, store["vars.yaml"])
+	require.NotContains(t, string(store["vars.yaml"]), "secret")
+
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		v := strings.TrimPrefix(args[2], "--vars-store=")
+		data, err := ioutil.ReadFile(v)
+		require.NoError(t, err)
+		require.Equal(t, vars, string(data))
+	})
+	err = c.CreateEnv(store, mockIAASConfig{}, "password", "cert", "key", "ca", map[string]string{})
+	require.NoError(t, err)
+
+	c, err = boshcli.New(boshcli.FakeExec(e.Cmd()), boshcli.EncryptVars("wrong"))
+	require.NoError(t, err)
+	err = c.CreateEnv(store, mockIAASConfig{}, "password", "cert", "key", "ca", map[string]string{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "vars.yaml")
+}
+
+func TestCLI_ConcourseCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		store   mockStore
+		want    boshcli.ConcourseCredentials
+		wantErr string
+	}{
+		{
+			name:  "credentials present",
+			store: mockStore{"vars.yaml": []byte("admin_password: director\natc_password: concourse\n")},
+			want:  boshcli.ConcourseCredentials{URL: "https://ci.example.com", Username: "admin", Password: "concourse"},
+		},
+		{
+			name:    "vars absent",
+			store:   mockStore{},
+			wantErr: "vars.yaml not found",
+		},
+		{
+			name:    "password absent",
+			store:   mockStore{"vars.yaml": []byte("admin_password: director\n")},
+			wantErr: "Concourse admin password",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := boshcli.New()
+			require.NoError(t, err)
+			got, err := c.ConcourseCredentials(tt.store, "ci.example.com")
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCLI_ClientName(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(c boshcli.ICLI) error
+	}{
+		{
+			name: "UpdateCloudConfig",
+			run: func(c boshcli.ICLI) error {
+				return c.UpdateCloudConfig(mockIAASConfig{}, "ip", "password", "ca")
+			},
+		},
+		{
+			name: "Locks",
+			run: func(c boshcli.ICLI) error {
+				_, err := c.Locks(mockIAASConfig{}, "ip", "password", "ca")
+				return err
+			},
+		},
+		{
+			name: "Recreate",
+			run: func(c boshcli.ICLI) error {
+				return c.Recreate(mockIAASConfig{}, "ip", "password", "ca")
+			},
+		},
+		{
+			name: "RunAuthenticatedCommand",
+			run: func(c boshcli.ICLI) error {
+				return c.RunAuthenticatedCommand("deploy", "ip", "password", "ca", false, ioutil.Discard)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := fakeexec.New(t)
+			defer e.Finish()
+			c, err := boshcli.New(boshcli.FakeExec(e.Cmd()), boshcli.ClientName("ops"))
+			require.NoError(t, err)
+			e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+				for i, arg := range args {
+					if arg == "--client" {
+						require.Equal(t, "ops", args[i+1])
+						return
+					}
+				}
+				t.Errorf("expected --client in %v", args)
+			})
+			require.NoError(t, tt.run(c))
+		})
+	}
+}
+
+func TestCLI_CleanUp(t *testing.T) {
+	tests := []struct {
+		name string
+		all  bool
+		want []string
+	}{
+		{
+			name: "unused releases and stemcells",
+			want: []string{"clean-up"},
+		},
+		{
+			name: "everything",
+			all:  true,
+			want: []string{"clean-up", "--all"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := fakeexec.New(t)
+			defer e.Finish()
+			c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+			require.NoError(t, err)
+			e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+				require.Equal(t, "bosh", command)
+
+				require.Equal(t, "--non-interactive", args[0])
+				require.Equal(t, "https://ip", args[2])
+				require.Equal(t, "password", args[8])
+				require.Equal(t, tt.want, args[9:])
+			})
+			err = c.CleanUp(mockIAASConfig{}, "ip", "password", "ca", tt.all)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCLI_RunAuthenticatedCommandUpdateFlags(t *testing.T) {
+	tests := []struct {
+		name   string
+		action string
+		want   []string
+	}{
+		{
+			name:   "deploy",
+			action: "deploy",
+			want:   []string{"deploy", "manifest.yml", "--max-in-flight=25%", "--canaries=2"},
+		},
+		{
+			name:   "other actions",
+			action: "delete-deployment",
+			want:   []string{"delete-deployment", "manifest.yml"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := fakeexec.New(t)
+			defer e.Finish()
+			c, err := boshcli.New(boshcli.FakeExec(e.Cmd()), boshcli.MaxInFlight("25%"), boshcli.Canaries("2"))
+			require.NoError(t, err)
+			e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+				require.Equal(t, tt.want, args[11:])
+			})
+			err = c.RunAuthenticatedCommand(tt.action, "ip", "password", "ca", false, ioutil.Discard, "manifest.yml")
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCLI_UpdateFlagsValidation(t *testing.T) {
+	for _, value := range []string{"", "0", "-1", "abc", "0%", "101%"} {
+		_, err := boshcli.New(boshcli.MaxInFlight(value))
+		require.Error(t, err, value)
+		_, err = boshcli.New(boshcli.Canaries(value))
+		require.Error(t, err, value)
+	}
+	_, err := boshcli.New(boshcli.MaxInFlight("100%"), boshcli.Canaries("1"))
+	require.NoError(t, err)
+}
+
+const (
+	directorState = `{"director_id":"2f5d8a51-1a3b-4c07-8a31-5b1b0a6a9d11","current_vm_cid":"i-0123456789abcdef0"}`
+	emptyState    = `{"director_id":"","current_vm_cid":""}`
+	directorVars  = "admin_password: secret\ndirector_ssl:\n  ca: ca\n  certificate: cert\n  private_key: key\n"
+)
+
+func TestCLI_CheckStateConsistency(t *testing.T) {
+	tests := []struct {
+		name    string
+		store   mockStore
+		wantErr string
+	}{
+		{
+			name:  "nothing deployed",
+			store: mockStore{},
+		},
+		{
+			name:  "director deployed",
+			store: mockStore{"state.json": []byte(directorState), "vars.yaml": []byte(directorVars)},
+		},
+		{
+			name:  "director deleted",
+			store: mockStore{"state.json": []byte(emptyState)},
+		},
+		{
+			name:    "state missing",
+			store:   mockStore{"vars.yaml": []byte(directorVars)},
+			wantErr: "state.json is missing",
+		},
+		{
+			name:    "state describes no director",
+			store:   mockStore{"state.json": []byte(emptyState), "vars.yaml": []byte(directorVars)},
+			wantErr: "describes no director",
+		},
+		{
+			name:    "vars missing",
+			store:   mockStore{"state.json": []byte(directorState)},
+			wantErr: "vars.yaml is missing",
+		},
+		{
+			name:    "vars missing credentials",
+			store:   mockStore{"state.json": []byte(directorState), "vars.yaml": []byte("admin_password: secret\n")},
+			wantErr: "has no director_ssl",
+		},
+		{
+			name:    "state corrupted",
+			store:   mockStore{"state.json": []byte("{"), "vars.yaml": []byte(directorVars)},
+			wantErr: "failed to parse state.json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := boshcli.New()
+			require.NoError(t, err)
+			err = c.CheckStateConsistency(tt.store)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func writeStemcellTarball(t *testing.T) string {
+	t.Helper()
+	f, err := ioutil.TempFile("", "stemcell-*.tgz")
+	require.NoError(t, err)
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	manifest := []byte("name: bosh-aws-xen-hvm-ubuntu-xenial-go_agent\n")
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "stemcell.MF", Mode: 0644, Size: int64(len(manifest))}))
+	_, err = tw.Write(manifest)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return f.Name()
+}
+
+func TestCLI_UploadConcourseStemcellFromLocalPath(t *testing.T) {
+	stemcell := writeStemcellTarball(t)
+	defer os.Remove(stemcell)
+
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()), boshcli.LocalStemcellPath(stemcell))
+	require.NoError(t, err)
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, []string{"upload-stemcell", stemcell}, args[9:])
+	})
+	err = c.UploadConcourseStemcell(mockIAASConfig{}, "ip", "password", "ca")
+	require.NoError(t, err)
+}
+
+func TestCLI_SkipStemcellUpload(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()), boshcli.SkipStemcellUpload())
+	require.NoError(t, err)
+	err = c.UploadConcourseStemcell(mockIAASConfig{}, "ip", "password", "ca")
+	require.NoError(t, err)
+}
+
+func TestCLI_LocalStemcellPathValidation(t *testing.T) {
+	notATarball, err := writeTempFile([]byte("not a tarball"))
+	require.NoError(t, err)
+	defer os.Remove(notATarball)
+
+	for _, path := range []string{"/does/not/exist.tgz", notATarball} {
+		_, err := boshcli.New(boshcli.LocalStemcellPath(path))
+		require.Error(t, err, path)
+	}
+}
+
+func writeTempFile(data []byte) (string, error) {
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	_, err = f.Write(data)
+	return f.Name(), err
+}
+
+func TestCLI_UpdateCloudConfigWritesCloudConfig(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	var out bytes.Buffer
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()), boshcli.CloudConfigWriter(&out))
+	require.NoError(t, err)
+	config := mockIAASConfig{}
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "update-cloud-config", args[9])
+	})
+	err = c.UpdateCloudConfig(config, "ip", "password", "ca")
+	require.NoError(t, err)
+	want, err := config.ConfigureDirectorCloudConfig()
+	require.NoError(t, err)
+	require.Equal(t, want, out.String())
+}
+
+func TestCLI_ImportState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	statePath := filepath.Join(dir, "state.json")
+	varsPath := filepath.Join(dir, "vars.yaml")
+	require.NoError(t, ioutil.WriteFile(varsPath, []byte(directorVars), 0600))
+
+	t.Run("valid state", func(t *testing.T) {
+		require.NoError(t, ioutil.WriteFile(statePath, []byte(directorState), 0600))
+		e := fakeexec.New(t)
+		defer e.Finish()
+		c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+		require.NoError(t, err)
+		e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+			require.Equal(t, "https://10.0.0.6", args[2])
+			ca, err := ioutil.ReadFile(args[4])
+			require.NoError(t, err)
+			require.Equal(t, "ca", string(ca))
+			require.Equal(t, "secret", args[8])
+			require.Equal(t, "env", args[9])
+		})
+		store := mockStore{}
+		err = c.ImportState(store, "10.0.0.6", statePath, varsPath)
+		require.NoError(t, err)
+		require.Equal(t, directorState, string(store["state.json"]))
+		require.Equal(t, directorVars, string(store["vars.yaml"]))
+	})
+
+	t.Run("malformed state", func(t *testing.T) {
+		require.NoError(t, ioutil.WriteFile(statePath, []byte("{"), 0600))
+		c, err := boshcli.New()
+		require.NoError(t, err)
+		store := mockStore{}
+		err = c.ImportState(store, "10.0.0.6", statePath, varsPath)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse state.json")
+		require.Empty(t, store)
+	})
+
+	t.Run("existing state", func(t *testing.T) {
+		require.NoError(t, ioutil.WriteFile(statePath, []byte(directorState), 0600))
+		c, err := boshcli.New()
+		require.NoError(t, err)
+		err = c.ImportState(mockStore{"state.json": []byte("{}")}, "10.0.0.6", statePath, varsPath)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "refusing to overwrite")
+	})
+}
+
+func TestCLI_LastTaskOutput(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+	require.NoError(t, err)
+	config := mockIAASConfig{}
+	tasks := e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "bosh", command)
+
+		require.Equal(t, "--environment", args[0])
+		require.Equal(t, "ip", args[1])
+		require.Equal(t, "password", args[7])
+		require.Equal(t, []string{"--deployment", "concourse", "tasks", "--recent=1", "--json"}, args[8:])
+	})
+	tasks.Outputs(`{"Tables":[{"Content":"tasks","Rows":[{"id":"42","state":"error","description":"create deployment"}]}]}`)
+	task := e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, []string{"--deployment", "concourse", "task", "42", "--debug"}, args[8:])
+	})
+	task.Outputs("D, [2019-05-01T10:00:00] DEBUG -- DirectorJobRunner: failed to compile\n")
+	out, err := c.LastTaskOutput(config, "ip", "password", "ca")
+	require.NoError(t, err)
+	require.Contains(t, string(out), "failed to compile")
+}
+
+func TestCLI_LastTaskOutputWithNoTasks(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+	require.NoError(t, err)
+	tasks := e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "tasks", args[10])
+	})
+	tasks.Outputs(`{"Tables":[{"Content":"tasks","Rows":[]}]}`)
+	_, err = c.LastTaskOutput(mockIAASConfig{}, "ip", "password", "ca")
+	require.Error(t, err)
+}
+
+func TestCLI_CategorisesFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   error
+	}{
+		{
+			name:   "bad credentials",
+			output: "Fetching info:\n  Performing request GET 'https://10.0.0.6:25555/info':\n    Getting token: Bad credentials\n\nExit code 1\n",
+			want:   boshcli.ErrAuthFailed,
+		},
+		{
+			name:   "invalid client",
+			output: "Getting token: Performing request POST 'https://10.0.0.6:8443/oauth/token': invalid_client\n",
+			want:   boshcli.ErrAuthFailed,
+		},
+		{
+			name:   "deployment lock",
+			output: "Task 42 | 10:00:00 | Error: Deployment 'concourse' is already locked by task 41\n",
+			want:   boshcli.ErrLockHeld,
+		},
+		{
+			name:   "aws instance limit",
+			output: "Error: CPI error 'Bosh::Clouds::CloudError' with message 'InstanceLimitExceeded: You have requested more instances (21) than your current instance limit of 20 allows'\n",
+			want:   boshcli.ErrQuotaExceeded,
+		},
+		{
+			name:   "gcp quota",
+			output: "Error: CPI error 'Bosh::Clouds::VMCreationFailed' with message 'Quota 'CPUS' exceeded. Limit: 24.0 in region europe-west1.'\n",
+			want:   boshcli.ErrQuotaExceeded,
+		},
+		{
+			name:   "unknown",
+			output: "Task 42 | 10:00:00 | Error: instance failed to start\n",
+			want:   boshcli.ErrUnknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := fakeexec.New(t)
+			defer e.Finish()
+			c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+			require.NoError(t, err)
+			expect := e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+				require.Equal(t, "recreate", args[11])
+			})
+			expect.Outputs(tt.output)
+			expect.Exits(1)
+			err = c.Recreate(mockIAASConfig{}, "ip", "password", "ca")
+			require.Error(t, err)
+			require.True(t, errors.Is(err, tt.want), "expected %v to be %v", err, tt.want)
+			var cmdErr *boshcli.CommandError
+			require.True(t, errors.As(err, &cmdErr))
+			require.Equal(t, tt.want, cmdErr.Category)
+			var exitErr *exec.ExitError
+			require.True(t, errors.As(err, &exitErr))
+		})
+	}
+}
+
+func generateDirectorCerts(t *testing.T, notAfter time.Time) (string, tls.Certificate) {
+	t.Helper()
+	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "director-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "director"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caTemplate, &key.PublicKey, caKey)
+	require.NoError(t, err)
+	ca := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}))
+	return ca, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestCheckDirectorCert(t *testing.T) {
+	const window = 30 * 24 * time.Hour
+	tests := []struct {
+		name        string
+		notAfter    time.Time
+		wantWarning bool
+	}{
+		{
+			name:     "expires after the window",
+			notAfter: time.Now().Add(90 * 24 * time.Hour).Truncate(time.Second),
+		},
+		{
+			name:        "expires within the window",
+			notAfter:    time.Now().Add(7 * 24 * time.Hour).Truncate(time.Second),
+			wantWarning: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ca, cert := generateDirectorCerts(t, tt.notAfter)
+			server := httptest.NewUnstartedServer(http.NotFoundHandler())
+			server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+			server.StartTLS()
+			defer server.Close()
+
+			expiry, err := boshcli.CheckDirectorCert(server.Listener.Addr().String(), ca, window)
+			require.True(t, tt.notAfter.Equal(expiry), "expiry = %v, want %v", expiry, tt.notAfter)
+			if !tt.wantWarning {
+				require.NoError(t, err)
+				return
+			}
+			var warning *boshcli.CertExpiryWarning
+			require.True(t, errors.As(err, &warning), "expected a CertExpiryWarning, got %v", err)
+			require.Contains(t, err.Error(), "rotate it")
+		})
+	}
+}
+
+func TestCheckDirectorCertErrors(t *testing.T) {
+	ca, cert := generateDirectorCerts(t, time.Now().Add(-time.Minute))
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	server.StartTLS()
+	defer server.Close()
+	addr := server.Listener.Addr().String()
+
+	t.Run("expired certificate", func(t *testing.T) {
+		_, err := boshcli.CheckDirectorCert(addr, ca, time.Hour)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to verify the director certificate")
+	})
+	t.Run("untrusted certificate", func(t *testing.T) {
+		otherCA, _ := generateDirectorCerts(t, time.Now().Add(time.Hour))
+		_, err := boshcli.CheckDirectorCert(addr, otherCA, time.Hour)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to verify the director certificate")
+	})
+	t.Run("invalid CA", func(t *testing.T) {
+		_, err := boshcli.CheckDirectorCert(addr, "not a certificate", time.Hour)
+		require.Error(t, err)
+	})
+}
+
+func TestCLI_NewSessionWritesCAOnce(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+	require.NoError(t, err)
+	s, err := c.NewSession(mockIAASConfig{}, "ip", "password", "ca")
+	require.NoError(t, err)
+
+	var caPaths []string
+	recordCA := func(t testing.TB, command string, args ...string) {
+		for i, arg := range args {
+			if arg == "--ca-cert" {
+				caPaths = append(caPaths, args[i+1])
+				ca, err := ioutil.ReadFile(args[i+1])
+				require.NoError(t, err)
+				require.Equal(t, "ca", string(ca))
+			}
+		}
+	}
+	e.ExpectFunc(recordCA).Outputs(`{"Tables":[{"Rows":[]}]}`)
+	e.ExpectFunc(recordCA)
+	e.ExpectFunc(recordCA)
+	_, err = s.Locks()
+	require.NoError(t, err)
+	require.NoError(t, s.Recreate())
+	require.NoError(t, s.CleanUp(false))
+
+	require.Len(t, caPaths, 3)
+	require.Equal(t, caPaths[0], caPaths[1])
+	require.Equal(t, caPaths[0], caPaths[2])
+
+	require.NoError(t, s.Close())
+	_, err = os.Stat(caPaths[0])
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestCLI_CredHubImport(t *testing.T) {
+	const vars = `admin_password: secret
+director_ssl:
+  ca: ca-cert
+  certificate: director-cert
+  private_key: director-key
+jumpbox_ssh:
+  private_key: ssh-private
+  public_key: ssh-public
+  public_key_fingerprint: fingerprint
+nats_keys:
+  private_key: rsa-private
+  public_key: rsa-public
+`
+	const want = `credentials:
+- name: /control-tower/admin_password
+  type: password
+  value: secret
+- name: /control-tower/director_ssl
+  type: certificate
+  value:
+    ca: ca-cert
+    certificate: director-cert
+    private_key: director-key
+- name: /control-tower/jumpbox_ssh
+  type: ssh
+  value:
+    private_key: ssh-private
+    public_key: ssh-public
+    public_key_fingerprint: fingerprint
+- name: /control-tower/nats_keys
+  type: rsa
+  value:
+    private_key: rsa-private
+    public_key: rsa-public
+`
+	c, err := boshcli.New(boshcli.FakeExec(fakeexec.New(t).Cmd()))
+	require.NoError(t, err)
+	got, err := c.CredHubImport(mockStore{"vars.yaml": []byte(vars)}, "control-tower")
+	require.NoError(t, err)
+	require.Equal(t, want, string(got))
+}
+
+func TestCLI_CredHubImportMissingVars(t *testing.T) {
+	c, err := boshcli.New(boshcli.FakeExec(fakeexec.New(t).Cmd()))
+	require.NoError(t, err)
+	_, err = c.CredHubImport(mockStore{}, "control-tower")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "vars.yaml not found in store")
+}
+
+func TestCLI_CreateEnvHoldsDeployLock(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+	require.NoError(t, err)
+	store := mockStore{}
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "create-env", args[0])
+		require.Contains(t, string(store["deploy.lock"]), `"owner"`)
+	})
+	err = c.CreateEnv(store, mockIAASConfig{}, "password", "cert", "key", "ca", map[string]string{})
+	require.NoError(t, err)
+	require.Empty(t, store["deploy.lock"])
+}
+
+func TestCLI_CreateEnvInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := mockStore{}
+	c, err := boshcli.New(boshcli.Context(ctx), boshcli.FakeExec(func(command string, args ...string) *exec.Cmd {
+		state := strings.TrimPrefix(args[1], "--state=")
+		require.NoError(t, ioutil.WriteFile(state, []byte(`{"director_id":"1"}`), 0600))
+		time.AfterFunc(100*time.Millisecond, cancel)
+		return exec.Command("sleep", "60")
+	}))
+	require.NoError(t, err)
+
+	start := time.Now()
+	err = c.CreateEnv(store, mockIAASConfig{}, "password", "cert", "key", "ca", map[string]string{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "context canceled")
+	require.True(t, time.Since(start) < 30*time.Second, "create-env was not killed")
+	require.Equal(t, `{"director_id":"1"}`, string(store["state.json"]))
+	require.Empty(t, store["deploy.lock"])
+}
+
+func TestCLI_CreateEnvKeepsStateWhenUploadFails(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+	require.NoError(t, err)
+	var statePath string
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		statePath = strings.TrimPrefix(args[1], "--state=")
+		require.NoError(t, ioutil.WriteFile(statePath, []byte(`{"director_id":"1"}`), 0600))
+	})
+	store := failingStore{mockStore: make(mockStore), failKey: "state.json"}
+	err = c.CreateEnv(store, mockIAASConfig{}, "password", "cert", "key", "ca", map[string]string{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "a copy is kept at "+statePath)
+	defer os.RemoveAll(filepath.Dir(statePath))
+	data, err := ioutil.ReadFile(statePath)
+	require.NoError(t, err)
+	require.Equal(t, `{"director_id":"1"}`, string(data))
+}
+
+func TestCLI_DeployLock(t *testing.T) {
+	tests := []struct {
+		name     string
+		acquired time.Time
+		wantErr  string
+	}{
+		{
+			name:     "held by an active run",
+			acquired: time.Now().Add(-10 * time.Minute),
+			wantErr:  "environment is locked by ci-worker (pid 42)",
+		},
+		{
+			name:     "the error says how to release the lock",
+			acquired: time.Now(),
+			wantErr:  "or delete deploy.lock from the store if it has died",
+		},
+		{
+			name:     "stale lock is taken over",
+			acquired: time.Now().Add(-2 * time.Hour),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := fakeexec.New(t)
+			defer e.Finish()
+			c, err := boshcli.New(boshcli.FakeExec(e.Cmd()), boshcli.DeployLockTTL(time.Hour))
+			require.NoError(t, err)
+			store := mockStore{
+				"deploy.lock": []byte(fmt.Sprintf(`{"owner":"ci-worker (pid 42)","acquired":%q}`, tt.acquired.Format(time.RFC3339))),
+			}
+			if tt.wantErr == "" {
+				e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+					require.Equal(t, "delete-env", args[0])
+					require.NotContains(t, string(store["deploy.lock"]), "ci-worker")
+				})
+			}
+			err = c.DeleteEnv(store, mockIAASConfig{}, "password", "cert", "key", "ca", map[string]string{})
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+				require.Contains(t, string(store["deploy.lock"]), "ci-worker")
+				return
+			}
+			require.NoError(t, err)
+			require.Empty(t, store["deploy.lock"])
+		})
+	}
+}
+
+func TestCLI_ForceUnlock(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+	require.NoError(t, err)
+	store := mockStore{
+		"deploy.lock": []byte(fmt.Sprintf(`{"owner":"ci-worker (pid 42)","acquired":%q}`, time.Now().Format(time.RFC3339))),
+	}
+	require.NoError(t, c.ForceUnlock(store))
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "create-env", args[0])
+	})
+	err = c.CreateEnv(store, mockIAASConfig{}, "password", "cert", "key", "ca", map[string]string{})
+	require.NoError(t, err)
+}
+
+type manifestIAASConfig struct {
+	mockIAASConfig
+	instanceType string
+}
+
+func (c manifestIAASConfig) ConfigureDirectorManifestCPI() (string, error) {
+	return fmt.Sprintf(`name: ((director_name))
+resource_pools:
+- name: vms
+  cloud_properties:
+    instance_type: %s
+`, c.instanceType), nil
+}
+
+func TestCLI_DirectorManifestDiff(t *testing.T) {
+	tests := []struct {
+		name         string
+		instanceType string
+		want         []string
+	}{
+		{
+			name:         "unchanged manifest",
+			instanceType: "t2.small",
+		},
+		{
+			name:         "changed manifest",
+			instanceType: "t2.medium",
+			want: []string{
+				"--- deployed\n+++ rendered\n",
+				"-    instance_type: t2.small\n",
+				"+    instance_type: t2.medium\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := fakeexec.New(t)
+			defer e.Finish()
+			c, err := boshcli.New(boshcli.FakeExec(e.Cmd()), boshcli.EncryptVars("a passphrase"))
+			require.NoError(t, err)
+			store := mockStore{}
+			e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+				require.Equal(t, "create-env", args[0])
+			})
+			err = c.CreateEnv(store, manifestIAASConfig{instanceType: "t2.small"}, "password", "cert", "key", "ca", map[string]string{})
+			require.NoError(t, err)
+			require.NotContains(t, string(store["director-manifest.yml"]), "t2.small")
+
+			diff, err := c.DirectorManifestDiff(store, manifestIAASConfig{instanceType: tt.instanceType}, "password", "cert", "key", "ca", map[string]string{})
+			require.NoError(t, err)
+			if len(tt.want) == 0 {
+				require.Empty(t, diff)
+				return
+			}
+			for _, want := range tt.want {
+				require.Contains(t, diff, want)
+			}
+		})
+	}
+}
+
+func TestCLI_DirectorManifestDiffWithoutDeployedManifest(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+	require.NoError(t, err)
+	_, err = c.DirectorManifestDiff(mockStore{}, manifestIAASConfig{}, "password", "cert", "key", "ca", map[string]string{})
+	require.EqualError(t, err, "director-manifest.yml not found in store, has the director been deployed by this version of control-tower?")
+}
+
+type fingerprintIAASConfig struct {
+	mockIAASConfig
+	manifest string
+}
+
+func (c fingerprintIAASConfig) ConfigureDirectorManifestCPI() (string, error) {
+	return c.manifest, nil
+}
+
+func TestCLI_ManifestFingerprint(t *testing.T) {
+	const manifest = `name: ((director_name))
+resource_pools:
+- name: vms
+  cloud_properties:
+    instance_type: t2.small
+instance_groups:
+- name: bosh
+  properties:
+    director:
+      ssl: {cert: ((director_ssl.certificate)), key: ((director_ssl.private_key))}
+    user_management:
+      local:
+        users: [{name: admin, password: ((admin_password))}]
+`
+	fingerprint := func(config boshcli.IAASEnvironment, password, key string) string {
+		c, err := boshcli.New()
+		require.NoError(t, err)
+		got, err := c.ManifestFingerprint(config, password, "cert", key, "ca", map[string]string{})
+		require.NoError(t, err)
+		require.Len(t, got, 64)
+		return got
+	}
+	want := fingerprint(fingerprintIAASConfig{manifest: manifest}, "password", "key")
+
+	require.Equal(t, want, fingerprint(fingerprintIAASConfig{manifest: manifest}, "password", "key"))
+	parts := strings.SplitN(manifest, "instance_groups:\n", 2)
+	reordered := "# comment\ninstance_groups:\n" + parts[1] + parts[0]
+	require.Equal(t, want, fingerprint(fingerprintIAASConfig{manifest: reordered}, "password", "key"), "key order and comments are ignored")
+	require.NotEqual(t, want, fingerprint(fingerprintIAASConfig{manifest: manifest}, "another password", "key"), "a rotated password changes the fingerprint")
+	require.NotEqual(t, want, fingerprint(fingerprintIAASConfig{manifest: manifest}, "password", "another key"), "a rotated key changes the fingerprint")
+
+	changed := strings.Replace(manifest, "t2.small", "t2.medium", 1)
+	require.NotEqual(t, want, fingerprint(fingerprintIAASConfig{manifest: changed}, "password", "key"))
+}
+
+func TestCLI_ManifestFingerprintInvalidManifest(t *testing.T) {
+	c, err := boshcli.New()
+	require.NoError(t, err)
+	_, err = c.ManifestFingerprint(fingerprintIAASConfig{manifest: "name: [bosh\n"}, "password", "cert", "key", "ca", map[string]string{})
+	require.Error(t, err)
+}
+
+func TestCLI_DeleteEnvClearsDeployedManifest(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+	require.NoError(t, err)
+	store := mockStore{"director-manifest.yml": []byte("name: bosh\n")}
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "delete-env", args[0])
+	})
+	err = c.DeleteEnv(store, mockIAASConfig{}, "password", "cert", "key", "ca", map[string]string{})
+	require.NoError(t, err)
+	require.Empty(t, store["director-manifest.yml"])
+}
+
+type recordingServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	events []boshcli.Event
+}
+
+func newRecordingServer(t *testing.T) *recordingServer {
+	r := &recordingServer{}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, http.MethodPost, req.Method)
+		require.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		var event boshcli.Event
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&event))
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.events = append(r.events, event)
+	}))
+	return r
+}
+
+func TestCLI_EventHook(t *testing.T) {
+	tests := []struct {
+		name        string
+		fail        bool
+		run         func(c boshcli.ICLI) error
+		wantAction  string
+		wantOutcome string
+	}{
+		{
+			name: "create-env succeeds",
+			run: func(c boshcli.ICLI) error {
+				return c.CreateEnv(mockStore{}, mockIAASConfig{}, "password", "cert", "key", "ca", map[string]string{})
+			},
+			wantAction:  "create-env",
+			wantOutcome: boshcli.OutcomeSucceeded,
+		},
+		{
+			name: "delete-env fails",
+			fail: true,
+			run: func(c boshcli.ICLI) error {
+				return c.DeleteEnv(mockStore{}, mockIAASConfig{}, "password", "cert", "key", "ca", map[string]string{})
+			},
+			wantAction:  "delete-env",
+			wantOutcome: boshcli.OutcomeFailed,
+		},
+		{
+			name: "deploy succeeds",
+			run: func(c boshcli.ICLI) error {
+				return c.RunAuthenticatedCommand("deploy", "ip", "password", "ca", false, ioutil.Discard, "manifest.yml")
+			},
+			wantAction:  "deploy",
+			wantOutcome: boshcli.OutcomeSucceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newRecordingServer(t)
+			defer server.Close()
+			hook, err := boshcli.NewWebhook(server.URL)
+			require.NoError(t, err)
+
+			e := fakeexec.New(t)
+			defer e.Finish()
+			c, err := boshcli.New(boshcli.FakeExec(e.Cmd()), boshcli.EventHook("prod", hook))
+			require.NoError(t, err)
+			expect := e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+				require.Len(t, server.events, 1)
+			})
+			if tt.fail {
+				expect.Exits(1)
+			}
+			err = tt.run(c)
+			require.Equal(t, tt.fail, err != nil)
+
+			require.Len(t, server.events, 2)
+			require.Equal(t, boshcli.Event{Action: tt.wantAction, Environment: "prod", Outcome: boshcli.OutcomeStarted}, server.events[0])
+			ended := server.events[1]
+			require.Equal(t, tt.wantAction, ended.Action)
+			require.Equal(t, "prod", ended.Environment)
+			require.Equal(t, tt.wantOutcome, ended.Outcome)
+			require.True(t, ended.Duration > 0)
+		})
+	}
+}
+
+func TestCLI_EventHookFailureDoesNotFailAction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	hook, err := boshcli.NewWebhook(server.URL)
+	require.NoError(t, err)
+	require.EqualError(t, hook.Emit(boshcli.Event{Action: "deploy"}), "webhook responded with 500 Internal Server Error")
+
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()), boshcli.EventHook("prod", hook))
+	require.NoError(t, err)
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {})
+	err = c.RunAuthenticatedCommand("deploy", "ip", "password", "ca", false, ioutil.Discard, "manifest.yml")
+	require.NoError(t, err)
+}
+
+func TestNewWebhookValidatesURL(t *testing.T) {
+	for _, rawURL := range []string{"", "example.com/hook", "ftp://example.com/hook"} {
+		_, err := boshcli.NewWebhook(rawURL)
+		require.Error(t, err, rawURL)
+	}
+}
+
+func TestCLI_CheckDirectorVersion(t *testing.T) {
+	expected := resource.Get(resource.BOSHRelease).Version
+	tests := []struct {
+		name    string
+		output  string
+		want    string
+		wantErr string
+		skew    bool
+	}{
+		{
+			name:   "matching version",
+			output: fmt.Sprintf(`{"Tables":[{"Rows":[{"name":"bosh","version":"%s (00000000)"}]}]}`, expected),
+			want:   expected,
+		},
+		{
+			name:   "mismatched version",
+			output: `{"Tables":[{"Rows":[{"name":"bosh","version":"1.0.0 (00000000)"}]}]}`,
+			want:   "1.0.0",
+			skew:   true,
+		},
+		{
+			name:    "no director",
+			output:  `{"Tables":[]}`,
+			wantErr: "bosh env output holds no director",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := fakeexec.New(t)
+			defer e.Finish()
+			c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+			require.NoError(t, err)
+			e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+				require.Equal(t, []string{"env", "--json"}, args[8:])
+			}).Outputs(tt.output)
+			got, err := c.CheckDirectorVersion("ip", "password", "ca")
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.Equal(t, tt.want, got)
+			if !tt.skew {
+				require.NoError(t, err)
+				return
+			}
+			var warning *boshcli.VersionSkewWarning
+			require.True(t, errors.As(err, &warning))
+			require.Equal(t, &boshcli.VersionSkewWarning{Deployed: "1.0.0", Expected: expected}, warning)
+		})
+	}
+}
+
+func TestCLI_DirectorUUID(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "director",
+			output: `{"Tables":[{"Content":"","Header":{"cpi":"CPI","name":"Name","uuid":"UUID","version":"Version"},"Rows":[{"cpi":"aws_cpi","features":"compiled_package_cache: disabled","name":"bosh","user":"admin","uuid":"5a7e9f0c-2d1b-4c3a-9e8f-7b6a5d4c3b2a","version":"270.2.0 (00000000)"}],"Notes":null}],"Blocks":null,"Lines":["Using environment 'https://ip' as client 'admin'","Succeeded"]}`,
+			want:   "5a7e9f0c-2d1b-4c3a-9e8f-7b6a5d4c3b2a",
+		},
+		{
+			name:    "no director",
+			output:  `{"Tables":[]}`,
+			wantErr: "bosh env output holds no director",
+		},
+		{
+			name:    "no uuid",
+			output:  `{"Tables":[{"Rows":[{"name":"bosh","version":"270.2.0 (00000000)"}]}]}`,
+			wantErr: "bosh env output holds no director UUID",
+		},
+		{
+			name:    "invalid output",
+			output:  `Using environment`,
+			wantErr: "failed to parse bosh env output",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := fakeexec.New(t)
+			defer e.Finish()
+			c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+			require.NoError(t, err)
+			e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+				require.Equal(t, "ip", args[1])
+				require.Equal(t, []string{"env", "--json"}, args[8:])
+			}).Outputs(tt.output)
+			got, err := c.DirectorUUID(mockIAASConfig{}, "ip", "password", "ca")
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCLI_TeeOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	logPath := filepath.Join(dir, "bosh.log")
+	require.NoError(t, ioutil.WriteFile(logPath, []byte("previous run\n"), 0600))
+
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()), boshcli.TeeOutput(logPath))
+	require.NoError(t, err)
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {}).Outputs("Preparing deployment\n")
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {}).Outputs("Succeeded\n")
+
+	var console bytes.Buffer
+	require.NoError(t, c.RunAuthenticatedCommand("deploy", "ip", "password", "ca", true, &console, "manifest.yml"))
+	require.NoError(t, c.RunAuthenticatedCommand("delete-deployment", "ip", "password", "ca", false, &console))
+
+	require.Equal(t, "Preparing deployment\nTask started, detaching output\nSucceeded\n", console.String())
+	log, err := ioutil.ReadFile(logPath)
+	require.NoError(t, err)
+	require.Equal(t, "previous run\n"+console.String(), string(log))
+}
+
+func TestCLI_DetachedDeploy(t *testing.T) {
+	t.Run("detaches once the task has started", func(t *testing.T) {
+		c, err := boshcli.New(boshcli.FakeExec(func(command string, args ...string) *exec.Cmd {
+			return exec.Command("sh", "-c", "echo 'Task 42 | Preparing deployment'; exec sleep 60")
+		}))
+		require.NoError(t, err)
+		var console bytes.Buffer
+		start := time.Now()
+		require.NoError(t, c.RunAuthenticatedCommand("deploy", "ip", "password", "ca", true, &console, "manifest.yml"))
+		require.True(t, time.Since(start) < 30*time.Second, "deploy was not detached")
+		require.Equal(t, "Task 42 | Preparing deployment\nTask started, detaching output\n", console.String())
+	})
+
+	t.Run("categorises a failure before the task starts", func(t *testing.T) {
+		e := fakeexec.New(t)
+		defer e.Finish()
+		c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+		require.NoError(t, err)
+		expect := e.ExpectFunc(func(t testing.TB, command string, args ...string) {})
+		expect.Outputs("Getting token: Bad credentials\n")
+		expect.Exits(1)
+		err = c.RunAuthenticatedCommand("deploy", "ip", "s3cret-director-password", "ca", true, ioutil.Discard, "manifest.yml")
+		require.True(t, errors.Is(err, boshcli.ErrAuthFailed), "expected %v to be %v", err, boshcli.ErrAuthFailed)
+	})
+
+	t.Run("does not leak the client secret when the task does not start", func(t *testing.T) {
+		e := fakeexec.New(t)
+		defer e.Finish()
+		c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+		require.NoError(t, err)
+		e.ExpectFunc(func(t testing.TB, command string, args ...string) {}).Outputs("Using environment 'ip'\n")
+		err = c.RunAuthenticatedCommand("deploy", "ip", "s3cret-director-password", "ca", true, ioutil.Discard, "manifest.yml")
+		require.Error(t, err)
+		require.NotContains(t, err.Error(), "s3cret-director-password")
+	})
+
+	t.Run("is killed when interrupted", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		c, err := boshcli.New(boshcli.Context(ctx), boshcli.FakeExec(func(command string, args ...string) *exec.Cmd {
+			time.AfterFunc(100*time.Millisecond, cancel)
+			return exec.Command("sleep", "60")
+		}))
+		require.NoError(t, err)
+		start := time.Now()
+		err = c.RunAuthenticatedCommand("deploy", "ip", "password", "ca", true, ioutil.Discard, "manifest.yml")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "context canceled")
+		require.True(t, time.Since(start) < 30*time.Second, "deploy was not killed")
+	})
+}
+
+// flushRecorder records what had been written each time it is flushed
+type flushRecorder struct {
+	bytes.Buffer
+	flushed []string
+}
+
+func (f *flushRecorder) Flush() error {
+	f.flushed = append(f.flushed, f.String())
+	return nil
+}
+
+func TestCLI_FlushLines(t *testing.T) {
+	for _, flushLines := range []bool{false, true} {
+		t.Run(fmt.Sprintf("flush lines %t", flushLines), func(t *testing.T) {
+			e := fakeexec.New(t)
+			defer e.Finish()
+			options := []boshcli.Option{boshcli.FakeExec(e.Cmd())}
+			if flushLines {
+				options = append(options, boshcli.FlushLines())
+			}
+			c, err := boshcli.New(options...)
+			require.NoError(t, err)
+			e.ExpectFunc(func(t testing.TB, command string, args ...string) {}).Outputs("Task 1\nTask 1 | Updating instance web\nTask 1 done")
+
+			var console flushRecorder
+			require.NoError(t, c.RunAuthenticatedCommand("deploy", "ip", "password", "ca", false, &console, "manifest.yml"))
+
+			require.Equal(t, "Task 1\nTask 1 | Updating instance web\nTask 1 done", console.String())
+			if !flushLines {
+				require.Empty(t, console.flushed)
+				return
+			}
+			require.Equal(t, []string{
+				"Task 1\n",
+				"Task 1\nTask 1 | Updating instance web\n",
+				"Task 1\nTask 1 | Updating instance web\nTask 1 done",
+			}, console.flushed)
+		})
+	}
+}
+
+func TestCLI_TeeOutputOpenError(t *testing.T) {
+	_, err := boshcli.New(boshcli.TeeOutput(filepath.Join("does", "not", "exist", "bosh.log")))
+	require.EqualError(t, err, "failed to open output log does/not/exist/bosh.log: [open does/not/exist/bosh.log: no such file or directory]")
+}
+
+func TestCLI_WithTempDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()), boshcli.WithTempDir(dir))
+	require.NoError(t, err)
+	underDir := func(t testing.TB, path string) {
+		t.Helper()
+		rel, err := filepath.Rel(dir, path)
+		require.NoError(t, err)
+		require.False(t, strings.HasPrefix(rel, ".."), "%s is not under %s", path, dir)
+	}
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "create-env", args[0])
+		underDir(t, strings.TrimPrefix(args[1], "--state="))
+		underDir(t, strings.TrimPrefix(args[2], "--vars-store="))
+		underDir(t, args[3])
+	})
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "--ca-cert", args[3])
+		underDir(t, args[4])
+		require.Equal(t, "update-cloud-config", args[9])
+		underDir(t, args[10])
+	})
+	require.NoError(t, c.CreateEnv(make(mockStore), mockIAASConfig{}, "password", "cert", "key", "ca", map[string]string{}))
+	require.NoError(t, c.UpdateCloudConfig(mockIAASConfig{}, "ip", "password", "ca"))
+}
+
+func TestCLI_WithTempDirInvalid(t *testing.T) {
+	file, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+	file.Close()
+	defer os.Remove(file.Name())
+
+	_, err = boshcli.New(boshcli.WithTempDir(filepath.Join("does", "not", "exist")))
+	require.EqualError(t, err, "invalid temp dir: [stat does/not/exist: no such file or directory]")
+	_, err = boshcli.New(boshcli.WithTempDir(file.Name()))
+	require.EqualError(t, err, "invalid temp dir: ["+file.Name()+" is not a directory]")
+}
+
+func TestCLI_TempFileMode(t *testing.T) {
+	for _, mode := range []os.FileMode{0, 0600, 0640} {
+		t.Run(fmt.Sprintf("%#o", mode), func(t *testing.T) {
+			want := mode
+			options := []boshcli.Option{}
+			if mode == 0 {
+				want = 0600
+			} else {
+				options = append(options, boshcli.TempFileMode(mode))
+			}
+			e := fakeexec.New(t)
+			defer e.Finish()
+			c, err := boshcli.New(append(options, boshcli.FakeExec(e.Cmd()))...)
+			require.NoError(t, err)
+			requireMode := func(t testing.TB, path string) {
+				t.Helper()
+				info, err := os.Stat(path)
+				require.NoError(t, err)
+				require.Equal(t, want, info.Mode().Perm(), path)
+			}
+			e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+				require.Equal(t, "create-env", args[0])
+				requireMode(t, strings.TrimPrefix(args[1], "--state="))
+				requireMode(t, strings.TrimPrefix(args[2], "--vars-store="))
+				requireMode(t, args[3])
+			})
+			store := mockStore{"state.json": []byte("{}"), "vars.yaml": []byte("admin_password: director\n")}
+			require.NoError(t, c.CreateEnv(store, mockIAASConfig{}, "password", "cert", "key", "ca", map[string]string{}))
+		})
+	}
+}
+
+func TestCLI_TempFileModeInvalid(t *testing.T) {
+	for _, mode := range []os.FileMode{0400, 0700, 0644 | os.ModeSetuid, 0666, 0200} {
+		_, err := boshcli.New(boshcli.TempFileMode(mode))
+		require.Error(t, err, "%#o", mode)
+		require.Contains(t, err.Error(), "invalid temp file mode")
+	}
+}
+
+func TestCLI_RecreateFailing(t *testing.T) {
+	const vms = `{"Tables":[{"Content":"vms","Rows":[
+{"instance":"web/1a2b","process_state":"running","az":"z1","ips":"10.0.0.5"},
+{"instance":"worker/3c4d","process_state":"failing","az":"z1","ips":"10.0.1.5"},
+{"instance":"worker/5e6f","process_state":"running","az":"z1","ips":"10.0.1.6"},
+{"instance":"worker/7a8b","process_state":"unresponsive agent","az":"z1","ips":"10.0.1.7"}
+]}]}`
+	tests := []struct {
+		name          string
+		failRecreate  bool
+		wantRecreated []string
+		wantErr       string
+	}{
+		{
+			name:          "recreates the instances that are not running",
+			wantRecreated: []string{"worker/3c4d", "worker/7a8b"},
+		},
+		{
+			name:          "carries on when an instance fails to recreate",
+			failRecreate:  true,
+			wantRecreated: []string{"worker/7a8b"},
+			wantErr:       "failed to recreate 1 instance(s): [worker/3c4d: ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := fakeexec.New(t)
+			defer e.Finish()
+			c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+			require.NoError(t, err)
+			e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+				require.Equal(t, "--environment", args[0])
+				require.Equal(t, []string{"--deployment", "concourse", "vms", "--json"}, args[8:])
+			}).Outputs(vms)
+			for i, instance := range []string{"worker/3c4d", "worker/7a8b"} {
+				instance := instance
+				expect := e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+					require.Equal(t, "--non-interactive", args[0])
+					require.Equal(t, []string{"--deployment", "concourse", "recreate", instance}, args[9:])
+				})
+				if tt.failRecreate && i == 0 {
+					expect.Exits(1)
+				}
+			}
+			recreated, err := c.RecreateFailing(mockIAASConfig{}, "ip", "password", "ca")
+			require.Equal(t, tt.wantRecreated, recreated)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCLI_PersistentDisks(t *testing.T) {
+	const instances = `{"Tables":[{"Content":"instances","Rows":[
+{"instance":"db/1a2b","process_state":"running","disk_cids":"vol-0a1"},
+{"instance":"web/3c4d","process_state":"running","disk_cids":""},
+{"instance":"worker/5e6f","process_state":"running","disk_cids":"vol-0b2\nvol-0c3"}
+]}]}`
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+	require.NoError(t, err)
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "--environment", args[0])
+		require.Equal(t, []string{"--deployment", "concourse", "instances", "--details", "--json"}, args[8:])
+	}).Outputs(instances)
+	disks, err := c.PersistentDisks(mockIAASConfig{}, "ip", "password", "ca")
+	require.NoError(t, err)
+	require.Equal(t, []boshcli.PersistentDisk{
+		{Instance: "db/1a2b", CID: "vol-0a1"},
+		{Instance: "worker/5e6f", CID: "vol-0b2"},
+		{Instance: "worker/5e6f", CID: "vol-0c3"},
+	}, disks)
+}
+
+type invalidYAMLIAASConfig struct {
+	mockIAASConfig
+}
+
+func (c invalidYAMLIAASConfig) ConfigureDirectorManifestCPI() (string, error) {
+	return `name: bosh
+releases:
+- name: bosh
+  version: [unclosed
+resource_pools:
+- name: vms
+`, nil
+}
+
+func TestCLI_CreateEnvRejectsInvalidYAML(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+	require.NoError(t, err)
+	store := mockStore{}
+	err = c.CreateEnv(store, invalidYAMLIAASConfig{}, "password", "cert", "key", "ca", map[string]string{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "director manifest template is not valid YAML")
+	require.Contains(t, err.Error(), "yaml: line 4:")
+	require.Contains(t, err.Error(), ">    4 |   version: [unclosed\n")
+	require.Empty(t, store["deploy.lock"])
+}
+
+func TestCLI_PauseResume(t *testing.T) {
+	tests := []struct {
+		name   string
+		run    func(c boshcli.ICLI) error
+		action string
+	}{
+		{
+			name: "pause",
+			run: func(c boshcli.ICLI) error {
+				return c.Pause(mockIAASConfig{}, "ip", "password", "ca")
+			},
+			action: "stop",
+		},
+		{
+			name: "resume",
+			run: func(c boshcli.ICLI) error {
+				return c.Resume(mockIAASConfig{}, "ip", "password", "ca")
+			},
+			action: "start",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := fakeexec.New(t)
+			defer e.Finish()
+			c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+			require.NoError(t, err)
+			e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+				require.Equal(t, "--non-interactive", args[0])
+				require.Equal(t, "https://ip", args[2])
+				require.Equal(t, []string{"--deployment", "concourse", tt.action, "web"}, args[9:])
+			})
+			require.NoError(t, tt.run(c))
+		})
+	}
+}
+
+func TestCLI_StateFilenames(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()), boshcli.StateFilenames("prod/state.json", "prod/vars.yaml"))
+	require.NoError(t, err)
+	store := mockStore{
+		"prod/vars.yaml": []byte("admin_password: secret\n"),
+	}
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "create-env", args[0])
+		statePath := strings.TrimPrefix(args[1], "--state=")
+		expectPathNotToExistButBeWriteable(t, statePath)
+		require.NoError(t, ioutil.WriteFile(statePath, []byte(`{"director_id":"abc"}`), 0600))
+		vars, err := ioutil.ReadFile(strings.TrimPrefix(args[2], "--vars-store="))
+		require.NoError(t, err)
+		require.Equal(t, "admin_password: secret\n", string(vars))
+	})
+	err = c.CreateEnv(store, mockIAASConfig{}, "password", "cert", "key", "ca", map[string]string{})
+	require.NoError(t, err)
+	require.Equal(t, `{"director_id":"abc"}`, string(store["prod/state.json"]))
+	require.Equal(t, "admin_password: secret\n", string(store["prod/vars.yaml"]))
+	require.NotContains(t, store, "state.json")
+	require.NotContains(t, store, "vars.yaml")
+}
+
+func TestCLI_StateFilenamesValidation(t *testing.T) {
+	_, err := boshcli.New(boshcli.StateFilenames("", "vars.yaml"))
+	require.EqualError(t, err, "state and vars filenames must not be empty")
+	_, err = boshcli.New(boshcli.StateFilenames("state", "state"))
+	require.EqualError(t, err, `state and vars filenames must differ, both are "state"`)
+}
+
+func fakeConcourseAPI(t *testing.T, password string) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/sky/token":
+			require.NoError(t, req.ParseForm())
+			if req.PostForm.Get("username") != "admin" || req.PostForm.Get("password") != password {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"access_token":"a-token","token_type":"Bearer"}`)
+		case "/api/v1/workers":
+			if req.Header.Get("Authorization") != "Bearer a-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `[
+{"name":"worker-1","state":"running","active_containers":12,"active_volumes":40,"team":""},
+{"name":"worker-2","state":"running","active_containers":3,"active_volumes":9,"team":""},
+{"name":"worker-3","state":"stalled","active_containers":5,"active_volumes":11,"team":""},
+{"name":"worker-4","state":"landing","active_containers":0,"active_volumes":2,"team":"main"}
+]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestSummariseWorkers(t *testing.T) {
+	server := fakeConcourseAPI(t, "secret")
+	defer server.Close()
+	creds := boshcli.ConcourseCredentials{URL: server.URL, Username: "admin", Password: "secret"}
+	summary, err := boshcli.SummariseWorkers(creds, server.Client())
+	require.NoError(t, err)
+	require.Equal(t, 2, summary.Active)
+	require.Equal(t, 1, summary.Stalled)
+	require.Equal(t, 20, summary.Containers)
+	require.Len(t, summary.Workers, 4)
+	require.Equal(t, boshcli.WorkerStatus{Name: "worker-4", State: "landing", Volumes: 2, Team: "main"}, summary.Workers[3])
+}
+
+func TestSummariseWorkersAuthFailure(t *testing.T) {
+	server := fakeConcourseAPI(t, "secret")
+	defer server.Close()
+	creds := boshcli.ConcourseCredentials{URL: server.URL, Username: "admin", Password: "wrong"}
+	_, err := boshcli.SummariseWorkers(creds, server.Client())
+	require.True(t, errors.Is(err, boshcli.ErrConcourseAuth))
+	require.Contains(t, err.Error(), "/sky/token as admin responded with 401 Unauthorized")
+}
+
+// fakeSmokeTestATC serves the Concourse API used by SmokeTest, finishing builds with buildStatus or failing
+// to trigger them when it is empty. The requests made to the pipeline are recorded in order
+func fakeSmokeTestATC(t *testing.T, pipelineExists bool, buildStatus string) (*httptest.Server, *[]string) {
+	var requests []string
+	pipeline := "/api/v1/teams/main/pipelines/" + boshcli.SmokeTestPipeline
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/sky/token" {
+			require.NoError(t, req.ParseForm())
+			if req.PostForm.Get("password") != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"access_token":"a-token","token_type":"Bearer"}`)
+			return
+		}
+		if req.Header.Get("Authorization") != "Bearer a-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		requests = append(requests, req.Method+" "+strings.TrimPrefix(req.URL.Path, pipeline))
+		switch {
+		case req.Method == http.MethodGet && req.URL.Path == pipeline+"/config":
+			if !pipelineExists {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("X-Concourse-Config-Version", "7")
+			fmt.Fprint(w, `{"config":{}}`)
+		case req.Method == http.MethodPut && req.URL.Path == pipeline+"/config":
+			body, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+			require.Contains(t, string(body), "name: smoke-test")
+			require.Equal(t, "application/x-yaml", req.Header.Get("Content-Type"))
+			if pipelineExists {
+				require.Equal(t, "7", req.Header.Get("X-Concourse-Config-Version"))
+			} else {
+				require.Empty(t, req.Header.Get("X-Concourse-Config-Version"))
+			}
+		case req.Method == http.MethodPut && req.URL.Path == pipeline+"/unpause":
+		case req.Method == http.MethodPost && req.URL.Path == pipeline+"/jobs/"+boshcli.SmokeTestJob+"/builds":
+			if buildStatus == "" {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			fmt.Fprint(w, `{"id":42,"status":"pending"}`)
+		case req.Method == http.MethodGet && req.URL.Path == "/api/v1/builds/42":
+			fmt.Fprintf(w, `{"id":42,"status":%q}`, buildStatus)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	return server, &requests
+}
+
+func TestSmokeTest(t *testing.T) {
+	for _, exists := range []bool{false, true} {
+		server, requests := fakeSmokeTestATC(t, exists, "succeeded")
+		creds := boshcli.ConcourseCredentials{URL: server.URL, Username: "admin", Password: "secret"}
+		require.NoError(t, boshcli.SmokeTest(creds, server.Client()))
+		require.Equal(t, []string{
+			"GET /config",
+			"PUT /config",
+			"PUT /unpause",
+			"POST /jobs/smoke-test/builds",
+			"GET /api/v1/builds/42",
+		}, *requests)
+		server.Close()
+	}
+}
+
+func TestSmokeTestBuildFailure(t *testing.T) {
+	server, _ := fakeSmokeTestATC(t, true, "failed")
+	defer server.Close()
+	creds := boshcli.ConcourseCredentials{URL: server.URL, Username: "admin", Password: "secret"}
+	err := boshcli.SmokeTest(creds, server.Client())
+	var failure *boshcli.SmokeTestFailure
+	require.True(t, errors.As(err, &failure))
+	require.Equal(t, &boshcli.SmokeTestFailure{BuildID: 42, Status: "failed"}, failure)
+	require.EqualError(t, err, "smoke test build 42 of control-tower-smoke-test/smoke-test failed")
+}
+
+func TestSmokeTestAuthFailure(t *testing.T) {
+	server, _ := fakeSmokeTestATC(t, true, "succeeded")
+	defer server.Close()
+	creds := boshcli.ConcourseCredentials{URL: server.URL, Username: "admin", Password: "wrong"}
+	err := boshcli.SmokeTest(creds, server.Client())
+	require.True(t, errors.Is(err, boshcli.ErrConcourseAuth))
+}
+
+func TestSmokeTestAPIError(t *testing.T) {
+	server, _ := fakeSmokeTestATC(t, true, "")
+	defer server.Close()
+	creds := boshcli.ConcourseCredentials{URL: server.URL, Username: "admin", Password: "secret"}
+	err := boshcli.SmokeTest(creds, server.Client())
+	require.EqualError(t, err, "failed to trigger the smoke test: ["+server.URL+"/api/v1/teams/main/pipelines/control-tower-smoke-test/jobs/smoke-test/builds responded with 500 Internal Server Error]")
+	require.False(t, errors.Is(err, boshcli.ErrConcourseAuth))
+	var failure *boshcli.SmokeTestFailure
+	require.False(t, errors.As(err, &failure))
+}
+
+type failingStore struct {
+	mockStore
+	failKey string
+}
+
+func (s failingStore) Set(key string, value []byte) error {
+	if key == s.failKey {
+		return errors.New("access denied")
+	}
+	return s.mockStore.Set(key, value)
+}
+
+func TestMigrateStore(t *testing.T) {
+	src := mockStore{"state.json": []byte(`{"director_id":"1"}`), "vars.yaml": []byte("admin_password: pw\n")}
+	dst := make(mockStore)
+	err := boshcli.MigrateStore(src, dst, []string{"state.json", "vars.yaml", "director-manifest.yml"})
+	require.NoError(t, err)
+	require.Equal(t, mockStore{"state.json": src["state.json"], "vars.yaml": src["vars.yaml"]}, dst)
+}
+
+func TestMigrateStorePartialCopy(t *testing.T) {
+	src := mockStore{"state.json": []byte("{}"), "vars.yaml": []byte("admin_password: pw\n")}
+	dst := failingStore{mockStore: make(mockStore), failKey: "vars.yaml"}
+	err := boshcli.MigrateStore(src, dst, []string{"state.json", "vars.yaml"})
+	require.EqualError(t, err, "failed to write vars.yaml: [access denied], after copying [state.json]")
+	require.Equal(t, mockStore{"state.json": []byte("{}")}, dst.mockStore)
+}
+
+func TestMigrateStoreVerifiesCopies(t *testing.T) {
+	src := mockStore{"state.json": []byte("{}")}
+	err := boshcli.MigrateStore(src, corruptingStore{make(mockStore)}, []string{"state.json"})
+	require.EqualError(t, err, "checksum of uploaded state.json does not match: wrote 2 bytes, read back 1 bytes")
+}
+
+type directorSSLIAASConfig struct {
+	mockIAASConfig
+}
+
+func (c directorSSLIAASConfig) ConfigureDirectorManifestCPI() (string, error) {
+	return "ca: ((director_ssl.ca))\ncert: ((director_ssl.certificate))\n", nil
+}
+
+func directorCertsPEM(t *testing.T, ca string, cert tls.Certificate) *certs.Certs {
+	t.Helper()
+	return &certs.Certs{
+		CACert: []byte(ca),
+		Key:    pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(cert.PrivateKey.(*rsa.PrivateKey))}),
+		Cert:   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}),
+	}
+}
+
+// directorSSLVars is the director_ssl and director_ssl_next entries of vars.yaml
+type directorSSLVars struct {
+	AdminPassword string `yaml:"admin_password"`
+	DirectorSSL   struct {
+		CA          string `yaml:"ca"`
+		Certificate string `yaml:"certificate"`
+		PrivateKey  string `yaml:"private_key"`
+	} `yaml:"director_ssl"`
+	Next *struct {
+		CA          string `yaml:"ca"`
+		Certificate string `yaml:"certificate"`
+		PrivateKey  string `yaml:"private_key"`
+	} `yaml:"director_ssl_next"`
+}
+
+// verifyDirectorCert verifies the certificate in a manifest rendered by directorSSLIAASConfig against a
+// client trusting only ca
+func verifyDirectorCert(t testing.TB, manifestPath, ca string) error {
+	data, err := ioutil.ReadFile(manifestPath)
+	require.NoError(t, err)
+	var manifest struct {
+		Cert string `yaml:"cert"`
+	}
+	require.NoError(t, yaml.Unmarshal(data, &manifest))
+	block, _ := pem.Decode([]byte(manifest.Cert))
+	require.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM([]byte(ca)))
+	_, err = cert.Verify(x509.VerifyOptions{Roots: pool})
+	return err
+}
+
+func TestCLI_RotateDirectorCert(t *testing.T) {
+	oldCA, oldCert := generateDirectorCerts(t, time.Now().Add(time.Hour))
+	current := directorCertsPEM(t, oldCA, oldCert)
+	newCA, cert := generateDirectorCerts(t, time.Now().Add(365*24*time.Hour))
+	generated := directorCertsPEM(t, newCA, cert)
+	initial, err := yaml.Marshal(map[string]interface{}{
+		"admin_password": "secret",
+		"director_ssl":   map[string]string{"ca": oldCA, "certificate": string(current.Cert), "private_key": string(current.Key)},
+	})
+	require.NoError(t, err)
+	store := mockStore{"vars.yaml": initial}
+
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+	require.NoError(t, err)
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "create-env", args[0])
+		require.NoError(t, verifyDirectorCert(t, args[3], oldCA), "clients trusting only the old CA still trust the director")
+	})
+	trusted, err := c.RotateDirectorCert(store, directorSSLIAASConfig{}, "password", oldCA, nil, func() (*certs.Certs, error) {
+		return generated, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, newCA+oldCA, string(trusted.CACert))
+	require.Equal(t, current.Cert, trusted.Cert, "the first phase keeps the current certificate")
+	require.Equal(t, current.Key, trusted.Key)
+
+	var vars directorSSLVars
+	require.NoError(t, yaml.Unmarshal(store["vars.yaml"], &vars))
+	require.Equal(t, "secret", vars.AdminPassword)
+	require.Equal(t, string(trusted.CACert), vars.DirectorSSL.CA)
+	require.Equal(t, string(current.Cert), vars.DirectorSSL.Certificate)
+	require.NotNil(t, vars.Next, "the generated certificate is staged")
+	require.Equal(t, string(generated.Cert), vars.Next.Certificate)
+	require.Equal(t, string(generated.Key), vars.Next.PrivateKey)
+
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "create-env", args[0])
+		require.Error(t, verifyDirectorCert(t, args[3], oldCA))
+		require.NoError(t, verifyDirectorCert(t, args[3], string(trusted.CACert)), "clients given the bundle trust the new certificate")
+	})
+	rotated, err := c.RotateDirectorCert(store, directorSSLIAASConfig{}, "password", string(trusted.CACert), nil, func() (*certs.Certs, error) {
+		t.Fatal("the staged certificate is used")
+		return nil, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, newCA+oldCA, string(rotated.CACert))
+	require.Equal(t, generated.Cert, rotated.Cert)
+	require.Equal(t, generated.Key, rotated.Key)
+
+	vars = directorSSLVars{}
+	require.NoError(t, yaml.Unmarshal(store["vars.yaml"], &vars))
+	require.Equal(t, "secret", vars.AdminPassword)
+	require.Equal(t, string(rotated.CACert), vars.DirectorSSL.CA)
+	require.Equal(t, string(generated.Cert), vars.DirectorSSL.Certificate)
+	require.Equal(t, string(generated.Key), vars.DirectorSSL.PrivateKey)
+	require.Nil(t, vars.Next, "the staged certificate is removed")
+
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {})
+	again, err := c.RotateDirectorCert(store, directorSSLIAASConfig{}, "password", string(rotated.CACert), nil, func() (*certs.Certs, error) {
+		return generated, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, newCA, string(again.CACert), "the old CA is only kept once")
+}
+
+func TestCLI_RotateDirectorCertErrors(t *testing.T) {
+	oldCA, _ := generateDirectorCerts(t, time.Now().Add(time.Hour))
+	newCA, cert := generateDirectorCerts(t, time.Now().Add(365*24*time.Hour))
+	_, otherCert := generateDirectorCerts(t, time.Now().Add(365*24*time.Hour))
+	current, err := yaml.Marshal(map[string]interface{}{
+		"admin_password": "secret",
+		"director_ssl":   map[string]string{"ca": oldCA, "certificate": "cert", "private_key": "key"},
+	})
+	require.NoError(t, err)
+	vars := string(current)
+
+	tests := []struct {
+		name     string
+		generate boshcli.CertGenerator
+		oldCA    string
+		vars     string
+		wantErr  string
+		runsBosh bool
+	}{
+		{
+			name:    "no generator",
+			oldCA:   oldCA,
+			wantErr: "cert generator must not be nil",
+		},
+		{
+			name:     "generator fails",
+			generate: func() (*certs.Certs, error) { return nil, errors.New("rate limited") },
+			oldCA:    oldCA,
+			wantErr:  "failed to generate the director certificate: [rate limited]",
+		},
+		{
+			name: "key does not match certificate",
+			generate: func() (*certs.Certs, error) {
+				generated := directorCertsPEM(t, newCA, cert)
+				generated.Key = directorCertsPEM(t, newCA, otherCert).Key
+				return generated, nil
+			},
+			oldCA:   oldCA,
+			wantErr: "generated director certificate is invalid",
+		},
+		{
+			name:     "invalid current CA",
+			generate: func() (*certs.Certs, error) { return directorCertsPEM(t, newCA, cert), nil },
+			oldCA:    "not a certificate",
+			wantErr:  "current director CA is not a PEM encoded certificate",
+		},
+		{
+			name:     "create-env fails",
+			generate: func() (*certs.Certs, error) { return directorCertsPEM(t, newCA, cert), nil },
+			oldCA:    oldCA,
+			wantErr:  "exit status 1",
+			runsBosh: true,
+		},
+		{
+			name:     "no current certificate",
+			generate: func() (*certs.Certs, error) { return directorCertsPEM(t, newCA, cert), nil },
+			oldCA:    oldCA,
+			vars:     "admin_password: secret\n",
+			wantErr:  "vars.yaml has no current director certificate",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := fakeexec.New(t)
+			defer e.Finish()
+			c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+			require.NoError(t, err)
+			if tt.runsBosh {
+				e.ExpectFunc(func(t testing.TB, command string, args ...string) {}).Exits(1)
+			}
+			vars := vars
+			if tt.vars != "" {
+				vars = tt.vars
+			}
+			store := mockStore{"vars.yaml": []byte(vars)}
+			_, err = c.RotateDirectorCert(store, directorSSLIAASConfig{}, "password", tt.oldCA, nil, tt.generate)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.wantErr)
+			require.Equal(t, vars, string(store["vars.yaml"]))
+		})
+	}
+}
+
+func TestCLI_CloudConfigDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		live     []string
+		exitCode int
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name: "changes",
+			live: []string{"vm_types:\n- name: concourse-large\n  cloud_properties:\n    instance_type: m5.xlarge\n    ephemeral_disk: {size: 200_000, type: gp2}\n"},
+			want: []string{"--- deployed\n+++ rendered\n", "-    instance_type: m5.xlarge\n", "+    instance_type: m5.large\n"},
+		},
+		{
+			name: "no changes",
+			live: []string{"# applied by control-tower\nvm_types:\n- cloud_properties:\n    ephemeral_disk:\n      size: 200000\n      type: gp2\n    instance_type: m5.large\n  name: concourse-large\n"},
+		},
+		{
+			name: "no cloud config",
+			want: []string{"--- deployed\n+++ rendered\n", "+vm_types:\n", "+    instance_type: m5.large\n"},
+		},
+		{
+			name:     "director unreachable",
+			exitCode: 1,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := json.Marshal(map[string]interface{}{"Tables": []interface{}{}, "Blocks": tt.live})
+			require.NoError(t, err)
+			e := fakeexec.New(t)
+			defer e.Finish()
+			c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+			require.NoError(t, err)
+			expect := e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+				require.Equal(t, []string{"cloud-config", "--json"}, args[8:], "the cloud config is only read, never updated")
+			})
+			expect.Outputs(string(output))
+			expect.Exits(tt.exitCode)
+			got, err := c.CloudConfigDiff(cloudConfigIAASConfig{}, "ip", "password", "ca")
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.want == nil {
+				require.Empty(t, got)
+			}
+			for _, want := range tt.want {
+				require.Contains(t, got, want)
+			}
+		})
+	}
+}
+
+type cloudConfigIAASConfig struct {
+	mockIAASConfig
+}
+
+func (c cloudConfigIAASConfig) ConfigureDirectorCloudConfig() (string, error) {
+	return "vm_types:\n- name: concourse-large\n  cloud_properties:\n    instance_type: m5.large\n    ephemeral_disk: {size: 200_000, type: gp2}\n", nil
+}
+
+func TestCLI_CloudConfigDrift(t *testing.T) {
+	tests := []struct {
+		name        string
+		live        []string
+		exitCode    int
+		wantDrifted bool
+		wantDiff    []string
+		wantErr     string
+	}{
+		{
+			name: "matching cloud config",
+			live: []string{"# applied by control-tower\nvm_types:\n- cloud_properties:\n    ephemeral_disk:\n      size: 200000\n      type: gp2\n    instance_type: m5.large\n  name: concourse-large\n"},
+		},
+		{
+			name:        "drifted cloud config",
+			live:        []string{"vm_types:\n- name: concourse-large\n  cloud_properties:\n    instance_type: m5.xlarge\n    ephemeral_disk: {size: 200_000, type: gp2}\n"},
+			wantDrifted: true,
+			wantDiff:    []string{"--- deployed\n+++ rendered\n", "-    instance_type: m5.xlarge\n", "+    instance_type: m5.large\n"},
+		},
+		{
+			name:    "no cloud config",
+			wantErr: "director has no cloud config",
+		},
+		{
+			name:     "director unreachable",
+			exitCode: 1,
+			wantErr:  "exit status 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := json.Marshal(map[string]interface{}{"Tables": []interface{}{}, "Blocks": tt.live})
+			require.NoError(t, err)
+			e := fakeexec.New(t)
+			defer e.Finish()
+			c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+			require.NoError(t, err)
+			expect := e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+				require.Equal(t, []string{"cloud-config", "--json"}, args[8:])
+			})
+			expect.Outputs(string(output))
+			expect.Exits(tt.exitCode)
+
+			drift, err := c.CloudConfigDrift(cloudConfigIAASConfig{}, "ip", "password", "ca")
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDrifted, drift.Drifted)
+			if !tt.wantDrifted {
+				require.Empty(t, drift.Diff)
+			}
+			for _, s := range tt.wantDiff {
+				require.Contains(t, drift.Diff, s)
+			}
+		})
+	}
+}
+
+func TestCLI_DeployManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	manifestPath := filepath.Join(dir, "concourse.yml")
+	err = ioutil.WriteFile(manifestPath, []byte("name: concourse\ninstance_groups: []\n"), 0600)
+	require.NoError(t, err)
+
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()), boshcli.MaxInFlight("2"))
+	require.NoError(t, err)
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "bosh", command)
+
+		require.Equal(t, "--non-interactive", args[0])
+		require.Equal(t, "https://ip", args[2])
+		require.Equal(t, "password", args[8])
+		require.Equal(t, []string{"--deployment", "concourse", "deploy", manifestPath, "--max-in-flight=2"}, args[9:])
+	})
+	err = c.DeployManifest(mockIAASConfig{}, "ip", "password", "ca", manifestPath)
+	require.NoError(t, err)
+}
+
+func TestCLI_DeployManifestErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		name     string
+		manifest string
+		wantErr  string
+	}{
+		{name: "missing manifest", wantErr: "failed to read manifest"},
+		{name: "invalid yaml", manifest: "name: [concourse\n", wantErr: "invalid manifest"},
+		{name: "empty manifest", manifest: "# nothing\n", wantErr: "is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifestPath := filepath.Join(dir, strings.Replace(tt.name, " ", "-", -1)+".yml")
+			if tt.manifest != "" {
+				err := ioutil.WriteFile(manifestPath, []byte(tt.manifest), 0600)
+				require.NoError(t, err)
+			}
+			c, err := boshcli.New(boshcli.FakeExec(fakeexec.New(t).Cmd()))
+			require.NoError(t, err)
+			err = c.DeployManifest(mockIAASConfig{}, "ip", "password", "ca", manifestPath)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestCLI_MissingReleases(t *testing.T) {
+	manifest := []byte(`name: concourse
+releases:
+- name: concourse
+  version: 6.7.2
+- name: bpm
+  version: 1.1.9
+- name: postgres
+  version: "43"
+- name: os-conf
+  version: latest
+`)
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{
+			name:   "all releases uploaded",
+			output: `{"Tables":[{"Rows":[{"name":"bpm","version":"1.1.9*","commit_hash":"a1b2c3d"},{"name":"concourse","version":"6.7.2*","commit_hash":"d4e5f6a"},{"name":"concourse","version":"6.7.1","commit_hash":"b7c8d9e"},{"name":"os-conf","version":"22.1.0","commit_hash":"f0a1b2c"},{"name":"postgres","version":"43","commit_hash":"c3d4e5f+"}]}]}`,
+		},
+		{
+			name:   "some releases missing",
+			output: `{"Tables":[{"Rows":[{"name":"bpm","version":"1.1.9*","commit_hash":"a1b2c3d"},{"name":"concourse","version":"6.7.1*","commit_hash":"b7c8d9e"}]}]}`,
+			want:   []string{"concourse/6.7.2", "postgres/43", "os-conf/latest"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := fakeexec.New(t)
+			defer e.Finish()
+			c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+			require.NoError(t, err)
+			e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+				require.Equal(t, []string{"releases", "--json"}, args[8:])
+			}).Outputs(tt.output)
+			got, err := c.MissingReleases(mockIAASConfig{}, "ip", "password", "ca", manifest)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCLI_MissingReleasesErrors(t *testing.T) {
+	t.Run("invalid manifest", func(t *testing.T) {
+		c, err := boshcli.New(boshcli.FakeExec(fakeexec.New(t).Cmd()))
+		require.NoError(t, err)
+		_, err = c.MissingReleases(mockIAASConfig{}, "ip", "password", "ca", []byte("releases: {"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse manifest")
+	})
+	t.Run("invalid releases output", func(t *testing.T) {
+		e := fakeexec.New(t)
+		defer e.Finish()
+		c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+		require.NoError(t, err)
+		e.ExpectFunc(func(t testing.TB, command string, args ...string) {}).Outputs("Using environment")
+		_, err = c.MissingReleases(mockIAASConfig{}, "ip", "password", "ca", []byte("releases: []"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse bosh releases output")
+	})
+}
+
+func TestCLI_DeployWithProgress(t *testing.T) {
+	output := `Using environment 'https://ip' as client 'admin'
+
+Using deployment 'concourse'
+
+Task 42
+
+Task 42 | 10:00:00 | Preparing deployment: Preparing deployment
+Task 42 | 10:00:01 | Preparing deployment: Preparing deployment (00:00:01)
+Task 42 | 10:00:02 | Compiling packages: golang-1-linux/8fe3a1b6 (00:00:00)
+Task 42 | 10:00:02 | Updating instance web: web/0c0d8c4e (0) (canary)
+Task 42 | 10:00:02 | Updating instance worker: worker/6a1b7d2f (0) (canary)
+Task 42 | 10:00:02 | Updating instance worker: worker/9e4c3b5a (1)
+Task 42 | 10:00:40 | Updating instance web: web/0c0d8c4e (0) (canary) (00:00:38)
+Task 42 | 10:01:10 | Updating instance worker: worker/6a1b7d2f (0) (canary) (00:01:08)
+Task 42 | 10:01:20 | Updating instance worker: worker/9e4c3b5a (1) (00:01:18)
+
+Task 42 Started  Fri Oct 16 10:00:00 UTC 2026
+Task 42 Finished Fri Oct 16 10:01:20 UTC 2026
+Task 42 Duration 00:01:20
+Task 42 done
+
+Succeeded
+`
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()), boshcli.Canaries("1"))
+	require.NoError(t, err)
+	e.ExpectFunc(func(t testing.TB, command string, args ...string) {
+		require.Equal(t, "https://ip", args[2])
+		require.Equal(t, []string{"--deployment", "concourse", "deploy", "manifest.yml", "--canaries=1"}, args[9:])
+	}).Outputs(output)
+
+	events, errc := c.DeployWithProgress("ip", "password", "ca", "manifest.yml")
+	var got []boshcli.ProgressEvent
+	for event := range events {
+		got = append(got, event)
+	}
+	require.NoError(t, <-errc)
+	require.Equal(t, []boshcli.ProgressEvent{
+		{Stage: "Preparing deployment", Task: "Preparing deployment", Percent: 0},
+		{Stage: "Preparing deployment", Task: "Preparing deployment", Percent: 100, Done: true},
+		{Stage: "Compiling packages", Task: "golang-1-linux/8fe3a1b6", Percent: 100, Done: true},
+		{Stage: "Updating instance web", Task: "web/0c0d8c4e (0) (canary)", Percent: 0},
+		{Stage: "Updating instance worker", Task: "worker/6a1b7d2f (0) (canary)", Percent: 0},
+		{Stage: "Updating instance worker", Task: "worker/9e4c3b5a (1)", Percent: 0},
+		{Stage: "Updating instance web", Task: "web/0c0d8c4e (0) (canary)", Percent: 100, Done: true},
+		{Stage: "Updating instance worker", Task: "worker/6a1b7d2f (0) (canary)", Percent: 50, Done: true},
+		{Stage: "Updating instance worker", Task: "worker/9e4c3b5a (1)", Percent: 100, Done: true},
+	}, got)
+}
+
+func TestCLI_DeployWithProgressFailure(t *testing.T) {
+	e := fakeexec.New(t)
+	defer e.Finish()
+	c, err := boshcli.New(boshcli.FakeExec(e.Cmd()))
+	require.NoError(t, err)
+	expect := e.ExpectFunc(func(t testing.TB, command string, args ...string) {})
+	expect.Outputs("Task 43 | 10:00:00 | Preparing deployment: Preparing deployment\nTask 43 | 10:00:01 | Error: Instance group 'web' references an unknown vm type\n")
+	expect.Exits(1)
+
+	events, errc := c.DeployWithProgress("ip", "password", "ca", "manifest.yml")
+	var got []boshcli.ProgressEvent
+	for event := range events {
+		got = append(got, event)
+	}
+	require.Equal(t, []boshcli.ProgressEvent{{Stage: "Preparing deployment", Task: "Preparing deployment"}}, got)
+	err = <-errc
+	require.Error(t, err)
+	require.True(t, errors.Is(err, boshcli.ErrUnknown))
+}
+
+func startATC(t *testing.T, cert tls.Certificate) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/":
+			http.Redirect(w, req, "/login", http.StatusFound)
+		case "/login":
+			fmt.Fprint(w, "Concourse")
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	server.StartTLS()
+	return server
+}
+
+func TestCheckATCReachable(t *testing.T) {
+	ca, cert := generateDirectorCerts(t, time.Now().Add(time.Hour))
+	server := startATC(t, cert)
+	defer server.Close()
+	expiredCA, expiredCert := generateDirectorCerts(t, time.Now().Add(-time.Minute))
+	expiredServer := startATC(t, expiredCert)
+	defer expiredServer.Close()
+	otherCA, _ := generateDirectorCerts(t, time.Now().Add(time.Hour))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedURL := "https://" + listener.Addr().String()
+	listener.Close()
+
+	tests := []struct {
+		name     string
+		url      string
+		ca       string
+		wantErr  string
+		category error
+	}{
+		{name: "redirect to login", url: server.URL, ca: ca},
+		{name: "login page", url: server.URL + "/login", ca: ca},
+		{name: "error status", url: server.URL + "/api", ca: ca, wantErr: "responded with 502 Bad Gateway"},
+		{name: "untrusted certificate", url: server.URL, ca: otherCA, category: boshcli.ErrATCTLS},
+		{name: "expired certificate", url: expiredServer.URL, ca: expiredCA, category: boshcli.ErrATCTLS},
+		{name: "connection refused", url: closedURL, ca: ca, category: boshcli.ErrATCConnectionRefused},
+		{name: "invalid CA", url: server.URL, ca: "not a CA", wantErr: "failed to parse the deployment CA"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := boshcli.CheckATCReachable(tt.url, tt.ca)
+			switch {
+			case tt.category != nil:
+				require.Error(t, err)
+				require.True(t, errors.Is(err, tt.category), "got %v", err)
+				for _, category := range []error{boshcli.ErrATCTLS, boshcli.ErrATCConnectionRefused} {
+					if category != tt.category {
+						require.False(t, errors.Is(err, category))
+					}
+				}
+			case tt.wantErr != "":
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+			default:
+				require.NoError(t, err)
+			}
+		})
+	}
+}
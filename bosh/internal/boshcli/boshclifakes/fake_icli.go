@@ -6,9 +6,97 @@ import (
 	"sync"
 
 	"github.com/EngineerBetter/control-tower/bosh/internal/boshcli"
+	"github.com/EngineerBetter/control-tower/certs"
 )
 
 type FakeICLI struct {
+	CheckDirectorVersionStub        func(string, string, string) (string, error)
+	checkDirectorVersionMutex       sync.RWMutex
+	checkDirectorVersionArgsForCall []struct {
+		arg1 string
+		arg2 string
+		arg3 string
+	}
+	checkDirectorVersionReturns struct {
+		result1 string
+		result2 error
+	}
+	checkDirectorVersionReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	CheckStateConsistencyStub        func(boshcli.Store) error
+	checkStateConsistencyMutex       sync.RWMutex
+	checkStateConsistencyArgsForCall []struct {
+		arg1 boshcli.Store
+	}
+	checkStateConsistencyReturns struct {
+		result1 error
+	}
+	checkStateConsistencyReturnsOnCall map[int]struct {
+		result1 error
+	}
+	CleanUpStub        func(boshcli.IAASEnvironment, string, string, string, bool) error
+	cleanUpMutex       sync.RWMutex
+	cleanUpArgsForCall []struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+		arg5 bool
+	}
+	cleanUpReturns struct {
+		result1 error
+	}
+	cleanUpReturnsOnCall map[int]struct {
+		result1 error
+	}
+	CloudConfigDiffStub        func(boshcli.IAASEnvironment, string, string, string) (string, error)
+	cloudConfigDiffMutex       sync.RWMutex
+	cloudConfigDiffArgsForCall []struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+	}
+	cloudConfigDiffReturns struct {
+		result1 string
+		result2 error
+	}
+	cloudConfigDiffReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	CloudConfigDriftStub        func(boshcli.IAASEnvironment, string, string, string) (boshcli.CloudConfigDrift, error)
+	cloudConfigDriftMutex       sync.RWMutex
+	cloudConfigDriftArgsForCall []struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+	}
+	cloudConfigDriftReturns struct {
+		result1 boshcli.CloudConfigDrift
+		result2 error
+	}
+	cloudConfigDriftReturnsOnCall map[int]struct {
+		result1 boshcli.CloudConfigDrift
+		result2 error
+	}
+	ConcourseCredentialsStub        func(boshcli.Store, string) (boshcli.ConcourseCredentials, error)
+	concourseCredentialsMutex       sync.RWMutex
+	concourseCredentialsArgsForCall []struct {
+		arg1 boshcli.Store
+		arg2 string
+	}
+	concourseCredentialsReturns struct {
+		result1 boshcli.ConcourseCredentials
+		result2 error
+	}
+	concourseCredentialsReturnsOnCall map[int]struct {
+		result1 boshcli.ConcourseCredentials
+		result2 error
+	}
 	CreateEnvStub        func(boshcli.Store, boshcli.IAASEnvironment, string, string, string, string, map[string]string) error
 	createEnvMutex       sync.RWMutex
 	createEnvArgsForCall []struct {
@@ -26,6 +114,20 @@ type FakeICLI struct {
 	createEnvReturnsOnCall map[int]struct {
 		result1 error
 	}
+	CredHubImportStub        func(boshcli.Store, string) ([]byte, error)
+	credHubImportMutex       sync.RWMutex
+	credHubImportArgsForCall []struct {
+		arg1 boshcli.Store
+		arg2 string
+	}
+	credHubImportReturns struct {
+		result1 []byte
+		result2 error
+	}
+	credHubImportReturnsOnCall map[int]struct {
+		result1 []byte
+		result2 error
+	}
 	DeleteEnvStub        func(boshcli.Store, boshcli.IAASEnvironment, string, string, string, string, map[string]string) error
 	deleteEnvMutex       sync.RWMutex
 	deleteEnvArgsForCall []struct {
@@ -43,6 +145,162 @@ type FakeICLI struct {
 	deleteEnvReturnsOnCall map[int]struct {
 		result1 error
 	}
+	DeployManifestStub        func(boshcli.IAASEnvironment, string, string, string, string) error
+	deployManifestMutex       sync.RWMutex
+	deployManifestArgsForCall []struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+		arg5 string
+	}
+	deployManifestReturns struct {
+		result1 error
+	}
+	deployManifestReturnsOnCall map[int]struct {
+		result1 error
+	}
+	DeployWithProgressStub        func(string, string, string, ...string) (<-chan boshcli.ProgressEvent, <-chan error)
+	deployWithProgressMutex       sync.RWMutex
+	deployWithProgressArgsForCall []struct {
+		arg1 string
+		arg2 string
+		arg3 string
+		arg4 []string
+	}
+	deployWithProgressReturns struct {
+		result1 <-chan boshcli.ProgressEvent
+		result2 <-chan error
+	}
+	deployWithProgressReturnsOnCall map[int]struct {
+		result1 <-chan boshcli.ProgressEvent
+		result2 <-chan error
+	}
+	DeploymentsStub        func(boshcli.IAASEnvironment, string, string, string) ([]byte, error)
+	deploymentsMutex       sync.RWMutex
+	deploymentsArgsForCall []struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+	}
+	deploymentsReturns struct {
+		result1 []byte
+		result2 error
+	}
+	deploymentsReturnsOnCall map[int]struct {
+		result1 []byte
+		result2 error
+	}
+	DirectorManifestDiffStub        func(boshcli.Store, boshcli.IAASEnvironment, string, string, string, string, map[string]string) (string, error)
+	directorManifestDiffMutex       sync.RWMutex
+	directorManifestDiffArgsForCall []struct {
+		arg1 boshcli.Store
+		arg2 boshcli.IAASEnvironment
+		arg3 string
+		arg4 string
+		arg5 string
+		arg6 string
+		arg7 map[string]string
+	}
+	directorManifestDiffReturns struct {
+		result1 string
+		result2 error
+	}
+	directorManifestDiffReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	DirectorUUIDStub        func(boshcli.IAASEnvironment, string, string, string) (string, error)
+	directorUUIDMutex       sync.RWMutex
+	directorUUIDArgsForCall []struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+	}
+	directorUUIDReturns struct {
+		result1 string
+		result2 error
+	}
+	directorUUIDReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	EventsStub        func(boshcli.IAASEnvironment, string, string, string, int) ([]byte, error)
+	eventsMutex       sync.RWMutex
+	eventsArgsForCall []struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+		arg5 int
+	}
+	eventsReturns struct {
+		result1 []byte
+		result2 error
+	}
+	eventsReturnsOnCall map[int]struct {
+		result1 []byte
+		result2 error
+	}
+	FetchLogsStub        func(boshcli.IAASEnvironment, string, string, string, string, string) error
+	fetchLogsMutex       sync.RWMutex
+	fetchLogsArgsForCall []struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+		arg5 string
+		arg6 string
+	}
+	fetchLogsReturns struct {
+		result1 error
+	}
+	fetchLogsReturnsOnCall map[int]struct {
+		result1 error
+	}
+	ForceUnlockStub        func(boshcli.Store) error
+	forceUnlockMutex       sync.RWMutex
+	forceUnlockArgsForCall []struct {
+		arg1 boshcli.Store
+	}
+	forceUnlockReturns struct {
+		result1 error
+	}
+	forceUnlockReturnsOnCall map[int]struct {
+		result1 error
+	}
+	ImportStateStub        func(boshcli.Store, string, string, string) error
+	importStateMutex       sync.RWMutex
+	importStateArgsForCall []struct {
+		arg1 boshcli.Store
+		arg2 string
+		arg3 string
+		arg4 string
+	}
+	importStateReturns struct {
+		result1 error
+	}
+	importStateReturnsOnCall map[int]struct {
+		result1 error
+	}
+	LastTaskOutputStub        func(boshcli.IAASEnvironment, string, string, string) ([]byte, error)
+	lastTaskOutputMutex       sync.RWMutex
+	lastTaskOutputArgsForCall []struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+	}
+	lastTaskOutputReturns struct {
+		result1 []byte
+		result2 error
+	}
+	lastTaskOutputReturnsOnCall map[int]struct {
+		result1 []byte
+		result2 error
+	}
 	LocksStub        func(boshcli.IAASEnvironment, string, string, string) ([]byte, error)
 	locksMutex       sync.RWMutex
 	locksArgsForCall []struct {
@@ -59,6 +317,87 @@ type FakeICLI struct {
 		result1 []byte
 		result2 error
 	}
+	ManifestFingerprintStub        func(boshcli.IAASEnvironment, string, string, string, string, map[string]string) (string, error)
+	manifestFingerprintMutex       sync.RWMutex
+	manifestFingerprintArgsForCall []struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+		arg5 string
+		arg6 map[string]string
+	}
+	manifestFingerprintReturns struct {
+		result1 string
+		result2 error
+	}
+	manifestFingerprintReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	MissingReleasesStub        func(boshcli.IAASEnvironment, string, string, string, []byte) ([]string, error)
+	missingReleasesMutex       sync.RWMutex
+	missingReleasesArgsForCall []struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+		arg5 []byte
+	}
+	missingReleasesReturns struct {
+		result1 []string
+		result2 error
+	}
+	missingReleasesReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
+	NewSessionStub        func(boshcli.IAASEnvironment, string, string, string) (*boshcli.Session, error)
+	newSessionMutex       sync.RWMutex
+	newSessionArgsForCall []struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+	}
+	newSessionReturns struct {
+		result1 *boshcli.Session
+		result2 error
+	}
+	newSessionReturnsOnCall map[int]struct {
+		result1 *boshcli.Session
+		result2 error
+	}
+	PauseStub        func(boshcli.IAASEnvironment, string, string, string) error
+	pauseMutex       sync.RWMutex
+	pauseArgsForCall []struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+	}
+	pauseReturns struct {
+		result1 error
+	}
+	pauseReturnsOnCall map[int]struct {
+		result1 error
+	}
+	PersistentDisksStub        func(boshcli.IAASEnvironment, string, string, string) ([]boshcli.PersistentDisk, error)
+	persistentDisksMutex       sync.RWMutex
+	persistentDisksArgsForCall []struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+	}
+	persistentDisksReturns struct {
+		result1 []boshcli.PersistentDisk
+		result2 error
+	}
+	persistentDisksReturnsOnCall map[int]struct {
+		result1 []boshcli.PersistentDisk
+		result2 error
+	}
 	RecreateStub        func(boshcli.IAASEnvironment, string, string, string) error
 	recreateMutex       sync.RWMutex
 	recreateArgsForCall []struct {
@@ -73,6 +412,86 @@ type FakeICLI struct {
 	recreateReturnsOnCall map[int]struct {
 		result1 error
 	}
+	RecreateEnvStub        func(boshcli.Store, boshcli.IAASEnvironment, string, string, string, string, map[string]string) error
+	recreateEnvMutex       sync.RWMutex
+	recreateEnvArgsForCall []struct {
+		arg1 boshcli.Store
+		arg2 boshcli.IAASEnvironment
+		arg3 string
+		arg4 string
+		arg5 string
+		arg6 string
+		arg7 map[string]string
+	}
+	recreateEnvReturns struct {
+		result1 error
+	}
+	recreateEnvReturnsOnCall map[int]struct {
+		result1 error
+	}
+	RecreateFailingStub        func(boshcli.IAASEnvironment, string, string, string) ([]string, error)
+	recreateFailingMutex       sync.RWMutex
+	recreateFailingArgsForCall []struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+	}
+	recreateFailingReturns struct {
+		result1 []string
+		result2 error
+	}
+	recreateFailingReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
+	RecreateInstanceStub        func(boshcli.IAASEnvironment, string, string, string, string) error
+	recreateInstanceMutex       sync.RWMutex
+	recreateInstanceArgsForCall []struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+		arg5 string
+	}
+	recreateInstanceReturns struct {
+		result1 error
+	}
+	recreateInstanceReturnsOnCall map[int]struct {
+		result1 error
+	}
+	ResumeStub        func(boshcli.IAASEnvironment, string, string, string) error
+	resumeMutex       sync.RWMutex
+	resumeArgsForCall []struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+	}
+	resumeReturns struct {
+		result1 error
+	}
+	resumeReturnsOnCall map[int]struct {
+		result1 error
+	}
+	RotateDirectorCertStub        func(boshcli.Store, boshcli.IAASEnvironment, string, string, map[string]string, boshcli.CertGenerator) (*certs.Certs, error)
+	rotateDirectorCertMutex       sync.RWMutex
+	rotateDirectorCertArgsForCall []struct {
+		arg1 boshcli.Store
+		arg2 boshcli.IAASEnvironment
+		arg3 string
+		arg4 string
+		arg5 map[string]string
+		arg6 boshcli.CertGenerator
+	}
+	rotateDirectorCertReturns struct {
+		result1 *certs.Certs
+		result2 error
+	}
+	rotateDirectorCertReturnsOnCall map[int]struct {
+		result1 *certs.Certs
+		result2 error
+	}
 	RunAuthenticatedCommandStub        func(string, string, string, string, bool, io.Writer, ...string) error
 	runAuthenticatedCommandMutex       sync.RWMutex
 	runAuthenticatedCommandArgsForCall []struct {
@@ -118,13 +537,414 @@ type FakeICLI struct {
 	uploadConcourseStemcellReturnsOnCall map[int]struct {
 		result1 error
 	}
-	invocations      map[string][][]interface{}
-	invocationsMutex sync.RWMutex
-}
-
-func (fake *FakeICLI) CreateEnv(arg1 boshcli.Store, arg2 boshcli.IAASEnvironment, arg3 string, arg4 string, arg5 string, arg6 string, arg7 map[string]string) error {
-	fake.createEnvMutex.Lock()
-	ret, specificReturn := fake.createEnvReturnsOnCall[len(fake.createEnvArgsForCall)]
+	VMsStub        func(boshcli.IAASEnvironment, string, string, string) ([]byte, error)
+	vMsMutex       sync.RWMutex
+	vMsArgsForCall []struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+	}
+	vMsReturns struct {
+		result1 []byte
+		result2 error
+	}
+	vMsReturnsOnCall map[int]struct {
+		result1 []byte
+		result2 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeICLI) CheckDirectorVersion(arg1 string, arg2 string, arg3 string) (string, error) {
+	fake.checkDirectorVersionMutex.Lock()
+	ret, specificReturn := fake.checkDirectorVersionReturnsOnCall[len(fake.checkDirectorVersionArgsForCall)]
+	fake.checkDirectorVersionArgsForCall = append(fake.checkDirectorVersionArgsForCall, struct {
+		arg1 string
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("CheckDirectorVersion", []interface{}{arg1, arg2, arg3})
+	fake.checkDirectorVersionMutex.Unlock()
+	if fake.CheckDirectorVersionStub != nil {
+		return fake.CheckDirectorVersionStub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.checkDirectorVersionReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeICLI) CheckDirectorVersionCallCount() int {
+	fake.checkDirectorVersionMutex.RLock()
+	defer fake.checkDirectorVersionMutex.RUnlock()
+	return len(fake.checkDirectorVersionArgsForCall)
+}
+
+func (fake *FakeICLI) CheckDirectorVersionCalls(stub func(string, string, string) (string, error)) {
+	fake.checkDirectorVersionMutex.Lock()
+	defer fake.checkDirectorVersionMutex.Unlock()
+	fake.CheckDirectorVersionStub = stub
+}
+
+func (fake *FakeICLI) CheckDirectorVersionArgsForCall(i int) (string, string, string) {
+	fake.checkDirectorVersionMutex.RLock()
+	defer fake.checkDirectorVersionMutex.RUnlock()
+	argsForCall := fake.checkDirectorVersionArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeICLI) CheckDirectorVersionReturns(result1 string, result2 error) {
+	fake.checkDirectorVersionMutex.Lock()
+	defer fake.checkDirectorVersionMutex.Unlock()
+	fake.CheckDirectorVersionStub = nil
+	fake.checkDirectorVersionReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) CheckDirectorVersionReturnsOnCall(i int, result1 string, result2 error) {
+	fake.checkDirectorVersionMutex.Lock()
+	defer fake.checkDirectorVersionMutex.Unlock()
+	fake.CheckDirectorVersionStub = nil
+	if fake.checkDirectorVersionReturnsOnCall == nil {
+		fake.checkDirectorVersionReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.checkDirectorVersionReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) CheckStateConsistency(arg1 boshcli.Store) error {
+	fake.checkStateConsistencyMutex.Lock()
+	ret, specificReturn := fake.checkStateConsistencyReturnsOnCall[len(fake.checkStateConsistencyArgsForCall)]
+	fake.checkStateConsistencyArgsForCall = append(fake.checkStateConsistencyArgsForCall, struct {
+		arg1 boshcli.Store
+	}{arg1})
+	fake.recordInvocation("CheckStateConsistency", []interface{}{arg1})
+	fake.checkStateConsistencyMutex.Unlock()
+	if fake.CheckStateConsistencyStub != nil {
+		return fake.CheckStateConsistencyStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.checkStateConsistencyReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeICLI) CheckStateConsistencyCallCount() int {
+	fake.checkStateConsistencyMutex.RLock()
+	defer fake.checkStateConsistencyMutex.RUnlock()
+	return len(fake.checkStateConsistencyArgsForCall)
+}
+
+func (fake *FakeICLI) CheckStateConsistencyCalls(stub func(boshcli.Store) error) {
+	fake.checkStateConsistencyMutex.Lock()
+	defer fake.checkStateConsistencyMutex.Unlock()
+	fake.CheckStateConsistencyStub = stub
+}
+
+func (fake *FakeICLI) CheckStateConsistencyArgsForCall(i int) boshcli.Store {
+	fake.checkStateConsistencyMutex.RLock()
+	defer fake.checkStateConsistencyMutex.RUnlock()
+	argsForCall := fake.checkStateConsistencyArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeICLI) CheckStateConsistencyReturns(result1 error) {
+	fake.checkStateConsistencyMutex.Lock()
+	defer fake.checkStateConsistencyMutex.Unlock()
+	fake.CheckStateConsistencyStub = nil
+	fake.checkStateConsistencyReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeICLI) CheckStateConsistencyReturnsOnCall(i int, result1 error) {
+	fake.checkStateConsistencyMutex.Lock()
+	defer fake.checkStateConsistencyMutex.Unlock()
+	fake.CheckStateConsistencyStub = nil
+	if fake.checkStateConsistencyReturnsOnCall == nil {
+		fake.checkStateConsistencyReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.checkStateConsistencyReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeICLI) CleanUp(arg1 boshcli.IAASEnvironment, arg2 string, arg3 string, arg4 string, arg5 bool) error {
+	fake.cleanUpMutex.Lock()
+	ret, specificReturn := fake.cleanUpReturnsOnCall[len(fake.cleanUpArgsForCall)]
+	fake.cleanUpArgsForCall = append(fake.cleanUpArgsForCall, struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+		arg5 bool
+	}{arg1, arg2, arg3, arg4, arg5})
+	fake.recordInvocation("CleanUp", []interface{}{arg1, arg2, arg3, arg4, arg5})
+	fake.cleanUpMutex.Unlock()
+	if fake.CleanUpStub != nil {
+		return fake.CleanUpStub(arg1, arg2, arg3, arg4, arg5)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.cleanUpReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeICLI) CleanUpCallCount() int {
+	fake.cleanUpMutex.RLock()
+	defer fake.cleanUpMutex.RUnlock()
+	return len(fake.cleanUpArgsForCall)
+}
+
+func (fake *FakeICLI) CleanUpCalls(stub func(boshcli.IAASEnvironment, string, string, string, bool) error) {
+	fake.cleanUpMutex.Lock()
+	defer fake.cleanUpMutex.Unlock()
+	fake.CleanUpStub = stub
+}
+
+func (fake *FakeICLI) CleanUpArgsForCall(i int) (boshcli.IAASEnvironment, string, string, string, bool) {
+	fake.cleanUpMutex.RLock()
+	defer fake.cleanUpMutex.RUnlock()
+	argsForCall := fake.cleanUpArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeICLI) CleanUpReturns(result1 error) {
+	fake.cleanUpMutex.Lock()
+	defer fake.cleanUpMutex.Unlock()
+	fake.CleanUpStub = nil
+	fake.cleanUpReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeICLI) CleanUpReturnsOnCall(i int, result1 error) {
+	fake.cleanUpMutex.Lock()
+	defer fake.cleanUpMutex.Unlock()
+	fake.CleanUpStub = nil
+	if fake.cleanUpReturnsOnCall == nil {
+		fake.cleanUpReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.cleanUpReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeICLI) CloudConfigDiff(arg1 boshcli.IAASEnvironment, arg2 string, arg3 string, arg4 string) (string, error) {
+	fake.cloudConfigDiffMutex.Lock()
+	ret, specificReturn := fake.cloudConfigDiffReturnsOnCall[len(fake.cloudConfigDiffArgsForCall)]
+	fake.cloudConfigDiffArgsForCall = append(fake.cloudConfigDiffArgsForCall, struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("CloudConfigDiff", []interface{}{arg1, arg2, arg3, arg4})
+	fake.cloudConfigDiffMutex.Unlock()
+	if fake.CloudConfigDiffStub != nil {
+		return fake.CloudConfigDiffStub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.cloudConfigDiffReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeICLI) CloudConfigDiffCallCount() int {
+	fake.cloudConfigDiffMutex.RLock()
+	defer fake.cloudConfigDiffMutex.RUnlock()
+	return len(fake.cloudConfigDiffArgsForCall)
+}
+
+func (fake *FakeICLI) CloudConfigDiffCalls(stub func(boshcli.IAASEnvironment, string, string, string) (string, error)) {
+	fake.cloudConfigDiffMutex.Lock()
+	defer fake.cloudConfigDiffMutex.Unlock()
+	fake.CloudConfigDiffStub = stub
+}
+
+func (fake *FakeICLI) CloudConfigDiffArgsForCall(i int) (boshcli.IAASEnvironment, string, string, string) {
+	fake.cloudConfigDiffMutex.RLock()
+	defer fake.cloudConfigDiffMutex.RUnlock()
+	argsForCall := fake.cloudConfigDiffArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeICLI) CloudConfigDiffReturns(result1 string, result2 error) {
+	fake.cloudConfigDiffMutex.Lock()
+	defer fake.cloudConfigDiffMutex.Unlock()
+	fake.CloudConfigDiffStub = nil
+	fake.cloudConfigDiffReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) CloudConfigDiffReturnsOnCall(i int, result1 string, result2 error) {
+	fake.cloudConfigDiffMutex.Lock()
+	defer fake.cloudConfigDiffMutex.Unlock()
+	fake.CloudConfigDiffStub = nil
+	if fake.cloudConfigDiffReturnsOnCall == nil {
+		fake.cloudConfigDiffReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.cloudConfigDiffReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) CloudConfigDrift(arg1 boshcli.IAASEnvironment, arg2 string, arg3 string, arg4 string) (boshcli.CloudConfigDrift, error) {
+	fake.cloudConfigDriftMutex.Lock()
+	ret, specificReturn := fake.cloudConfigDriftReturnsOnCall[len(fake.cloudConfigDriftArgsForCall)]
+	fake.cloudConfigDriftArgsForCall = append(fake.cloudConfigDriftArgsForCall, struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("CloudConfigDrift", []interface{}{arg1, arg2, arg3, arg4})
+	fake.cloudConfigDriftMutex.Unlock()
+	if fake.CloudConfigDriftStub != nil {
+		return fake.CloudConfigDriftStub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.cloudConfigDriftReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeICLI) CloudConfigDriftCallCount() int {
+	fake.cloudConfigDriftMutex.RLock()
+	defer fake.cloudConfigDriftMutex.RUnlock()
+	return len(fake.cloudConfigDriftArgsForCall)
+}
+
+func (fake *FakeICLI) CloudConfigDriftCalls(stub func(boshcli.IAASEnvironment, string, string, string) (boshcli.CloudConfigDrift, error)) {
+	fake.cloudConfigDriftMutex.Lock()
+	defer fake.cloudConfigDriftMutex.Unlock()
+	fake.CloudConfigDriftStub = stub
+}
+
+func (fake *FakeICLI) CloudConfigDriftArgsForCall(i int) (boshcli.IAASEnvironment, string, string, string) {
+	fake.cloudConfigDriftMutex.RLock()
+	defer fake.cloudConfigDriftMutex.RUnlock()
+	argsForCall := fake.cloudConfigDriftArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeICLI) CloudConfigDriftReturns(result1 boshcli.CloudConfigDrift, result2 error) {
+	fake.cloudConfigDriftMutex.Lock()
+	defer fake.cloudConfigDriftMutex.Unlock()
+	fake.CloudConfigDriftStub = nil
+	fake.cloudConfigDriftReturns = struct {
+		result1 boshcli.CloudConfigDrift
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) CloudConfigDriftReturnsOnCall(i int, result1 boshcli.CloudConfigDrift, result2 error) {
+	fake.cloudConfigDriftMutex.Lock()
+	defer fake.cloudConfigDriftMutex.Unlock()
+	fake.CloudConfigDriftStub = nil
+	if fake.cloudConfigDriftReturnsOnCall == nil {
+		fake.cloudConfigDriftReturnsOnCall = make(map[int]struct {
+			result1 boshcli.CloudConfigDrift
+			result2 error
+		})
+	}
+	fake.cloudConfigDriftReturnsOnCall[i] = struct {
+		result1 boshcli.CloudConfigDrift
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) ConcourseCredentials(arg1 boshcli.Store, arg2 string) (boshcli.ConcourseCredentials, error) {
+	fake.concourseCredentialsMutex.Lock()
+	ret, specificReturn := fake.concourseCredentialsReturnsOnCall[len(fake.concourseCredentialsArgsForCall)]
+	fake.concourseCredentialsArgsForCall = append(fake.concourseCredentialsArgsForCall, struct {
+		arg1 boshcli.Store
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("ConcourseCredentials", []interface{}{arg1, arg2})
+	fake.concourseCredentialsMutex.Unlock()
+	if fake.ConcourseCredentialsStub != nil {
+		return fake.ConcourseCredentialsStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.concourseCredentialsReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeICLI) ConcourseCredentialsCallCount() int {
+	fake.concourseCredentialsMutex.RLock()
+	defer fake.concourseCredentialsMutex.RUnlock()
+	return len(fake.concourseCredentialsArgsForCall)
+}
+
+func (fake *FakeICLI) ConcourseCredentialsCalls(stub func(boshcli.Store, string) (boshcli.ConcourseCredentials, error)) {
+	fake.concourseCredentialsMutex.Lock()
+	defer fake.concourseCredentialsMutex.Unlock()
+	fake.ConcourseCredentialsStub = stub
+}
+
+func (fake *FakeICLI) ConcourseCredentialsArgsForCall(i int) (boshcli.Store, string) {
+	fake.concourseCredentialsMutex.RLock()
+	defer fake.concourseCredentialsMutex.RUnlock()
+	argsForCall := fake.concourseCredentialsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeICLI) ConcourseCredentialsReturns(result1 boshcli.ConcourseCredentials, result2 error) {
+	fake.concourseCredentialsMutex.Lock()
+	defer fake.concourseCredentialsMutex.Unlock()
+	fake.ConcourseCredentialsStub = nil
+	fake.concourseCredentialsReturns = struct {
+		result1 boshcli.ConcourseCredentials
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) ConcourseCredentialsReturnsOnCall(i int, result1 boshcli.ConcourseCredentials, result2 error) {
+	fake.concourseCredentialsMutex.Lock()
+	defer fake.concourseCredentialsMutex.Unlock()
+	fake.ConcourseCredentialsStub = nil
+	if fake.concourseCredentialsReturnsOnCall == nil {
+		fake.concourseCredentialsReturnsOnCall = make(map[int]struct {
+			result1 boshcli.ConcourseCredentials
+			result2 error
+		})
+	}
+	fake.concourseCredentialsReturnsOnCall[i] = struct {
+		result1 boshcli.ConcourseCredentials
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) CreateEnv(arg1 boshcli.Store, arg2 boshcli.IAASEnvironment, arg3 string, arg4 string, arg5 string, arg6 string, arg7 map[string]string) error {
+	fake.createEnvMutex.Lock()
+	ret, specificReturn := fake.createEnvReturnsOnCall[len(fake.createEnvArgsForCall)]
 	fake.createEnvArgsForCall = append(fake.createEnvArgsForCall, struct {
 		arg1 boshcli.Store
 		arg2 boshcli.IAASEnvironment
@@ -188,6 +1008,70 @@ func (fake *FakeICLI) CreateEnvReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeICLI) CredHubImport(arg1 boshcli.Store, arg2 string) ([]byte, error) {
+	fake.credHubImportMutex.Lock()
+	ret, specificReturn := fake.credHubImportReturnsOnCall[len(fake.credHubImportArgsForCall)]
+	fake.credHubImportArgsForCall = append(fake.credHubImportArgsForCall, struct {
+		arg1 boshcli.Store
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("CredHubImport", []interface{}{arg1, arg2})
+	fake.credHubImportMutex.Unlock()
+	if fake.CredHubImportStub != nil {
+		return fake.CredHubImportStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.credHubImportReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeICLI) CredHubImportCallCount() int {
+	fake.credHubImportMutex.RLock()
+	defer fake.credHubImportMutex.RUnlock()
+	return len(fake.credHubImportArgsForCall)
+}
+
+func (fake *FakeICLI) CredHubImportCalls(stub func(boshcli.Store, string) ([]byte, error)) {
+	fake.credHubImportMutex.Lock()
+	defer fake.credHubImportMutex.Unlock()
+	fake.CredHubImportStub = stub
+}
+
+func (fake *FakeICLI) CredHubImportArgsForCall(i int) (boshcli.Store, string) {
+	fake.credHubImportMutex.RLock()
+	defer fake.credHubImportMutex.RUnlock()
+	argsForCall := fake.credHubImportArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeICLI) CredHubImportReturns(result1 []byte, result2 error) {
+	fake.credHubImportMutex.Lock()
+	defer fake.credHubImportMutex.Unlock()
+	fake.CredHubImportStub = nil
+	fake.credHubImportReturns = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) CredHubImportReturnsOnCall(i int, result1 []byte, result2 error) {
+	fake.credHubImportMutex.Lock()
+	defer fake.credHubImportMutex.Unlock()
+	fake.CredHubImportStub = nil
+	if fake.credHubImportReturnsOnCall == nil {
+		fake.credHubImportReturnsOnCall = make(map[int]struct {
+			result1 []byte
+			result2 error
+		})
+	}
+	fake.credHubImportReturnsOnCall[i] = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeICLI) DeleteEnv(arg1 boshcli.Store, arg2 boshcli.IAASEnvironment, arg3 string, arg4 string, arg5 string, arg6 string, arg7 map[string]string) error {
 	fake.deleteEnvMutex.Lock()
 	ret, specificReturn := fake.deleteEnvReturnsOnCall[len(fake.deleteEnvArgsForCall)]
@@ -196,126 +1080,1113 @@ func (fake *FakeICLI) DeleteEnv(arg1 boshcli.Store, arg2 boshcli.IAASEnvironment
 		arg2 boshcli.IAASEnvironment
 		arg3 string
 		arg4 string
-		arg5 string
-		arg6 string
-		arg7 map[string]string
-	}{arg1, arg2, arg3, arg4, arg5, arg6, arg7})
-	fake.recordInvocation("DeleteEnv", []interface{}{arg1, arg2, arg3, arg4, arg5, arg6, arg7})
-	fake.deleteEnvMutex.Unlock()
-	if fake.DeleteEnvStub != nil {
-		return fake.DeleteEnvStub(arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+		arg5 string
+		arg6 string
+		arg7 map[string]string
+	}{arg1, arg2, arg3, arg4, arg5, arg6, arg7})
+	fake.recordInvocation("DeleteEnv", []interface{}{arg1, arg2, arg3, arg4, arg5, arg6, arg7})
+	fake.deleteEnvMutex.Unlock()
+	if fake.DeleteEnvStub != nil {
+		return fake.DeleteEnvStub(arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.deleteEnvReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeICLI) DeleteEnvCallCount() int {
+	fake.deleteEnvMutex.RLock()
+	defer fake.deleteEnvMutex.RUnlock()
+	return len(fake.deleteEnvArgsForCall)
+}
+
+func (fake *FakeICLI) DeleteEnvCalls(stub func(boshcli.Store, boshcli.IAASEnvironment, string, string, string, string, map[string]string) error) {
+	fake.deleteEnvMutex.Lock()
+	defer fake.deleteEnvMutex.Unlock()
+	fake.DeleteEnvStub = stub
+}
+
+func (fake *FakeICLI) DeleteEnvArgsForCall(i int) (boshcli.Store, boshcli.IAASEnvironment, string, string, string, string, map[string]string) {
+	fake.deleteEnvMutex.RLock()
+	defer fake.deleteEnvMutex.RUnlock()
+	argsForCall := fake.deleteEnvArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5, argsForCall.arg6, argsForCall.arg7
+}
+
+func (fake *FakeICLI) DeleteEnvReturns(result1 error) {
+	fake.deleteEnvMutex.Lock()
+	defer fake.deleteEnvMutex.Unlock()
+	fake.DeleteEnvStub = nil
+	fake.deleteEnvReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeICLI) DeleteEnvReturnsOnCall(i int, result1 error) {
+	fake.deleteEnvMutex.Lock()
+	defer fake.deleteEnvMutex.Unlock()
+	fake.DeleteEnvStub = nil
+	if fake.deleteEnvReturnsOnCall == nil {
+		fake.deleteEnvReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteEnvReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeICLI) DeployManifest(arg1 boshcli.IAASEnvironment, arg2 string, arg3 string, arg4 string, arg5 string) error {
+	fake.deployManifestMutex.Lock()
+	ret, specificReturn := fake.deployManifestReturnsOnCall[len(fake.deployManifestArgsForCall)]
+	fake.deployManifestArgsForCall = append(fake.deployManifestArgsForCall, struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+		arg5 string
+	}{arg1, arg2, arg3, arg4, arg5})
+	fake.recordInvocation("DeployManifest", []interface{}{arg1, arg2, arg3, arg4, arg5})
+	fake.deployManifestMutex.Unlock()
+	if fake.DeployManifestStub != nil {
+		return fake.DeployManifestStub(arg1, arg2, arg3, arg4, arg5)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.deployManifestReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeICLI) DeployManifestCallCount() int {
+	fake.deployManifestMutex.RLock()
+	defer fake.deployManifestMutex.RUnlock()
+	return len(fake.deployManifestArgsForCall)
+}
+
+func (fake *FakeICLI) DeployManifestCalls(stub func(boshcli.IAASEnvironment, string, string, string, string) error) {
+	fake.deployManifestMutex.Lock()
+	defer fake.deployManifestMutex.Unlock()
+	fake.DeployManifestStub = stub
+}
+
+func (fake *FakeICLI) DeployManifestArgsForCall(i int) (boshcli.IAASEnvironment, string, string, string, string) {
+	fake.deployManifestMutex.RLock()
+	defer fake.deployManifestMutex.RUnlock()
+	argsForCall := fake.deployManifestArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeICLI) DeployManifestReturns(result1 error) {
+	fake.deployManifestMutex.Lock()
+	defer fake.deployManifestMutex.Unlock()
+	fake.DeployManifestStub = nil
+	fake.deployManifestReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeICLI) DeployManifestReturnsOnCall(i int, result1 error) {
+	fake.deployManifestMutex.Lock()
+	defer fake.deployManifestMutex.Unlock()
+	fake.DeployManifestStub = nil
+	if fake.deployManifestReturnsOnCall == nil {
+		fake.deployManifestReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deployManifestReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeICLI) DeployWithProgress(arg1 string, arg2 string, arg3 string, arg4 ...string) (<-chan boshcli.ProgressEvent, <-chan error) {
+	fake.deployWithProgressMutex.Lock()
+	ret, specificReturn := fake.deployWithProgressReturnsOnCall[len(fake.deployWithProgressArgsForCall)]
+	fake.deployWithProgressArgsForCall = append(fake.deployWithProgressArgsForCall, struct {
+		arg1 string
+		arg2 string
+		arg3 string
+		arg4 []string
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("DeployWithProgress", []interface{}{arg1, arg2, arg3, arg4})
+	fake.deployWithProgressMutex.Unlock()
+	if fake.DeployWithProgressStub != nil {
+		return fake.DeployWithProgressStub(arg1, arg2, arg3, arg4...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.deployWithProgressReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeICLI) DeployWithProgressCallCount() int {
+	fake.deployWithProgressMutex.RLock()
+	defer fake.deployWithProgressMutex.RUnlock()
+	return len(fake.deployWithProgressArgsForCall)
+}
+
+func (fake *FakeICLI) DeployWithProgressCalls(stub func(string, string, string, ...string) (<-chan boshcli.ProgressEvent, <-chan error)) {
+	fake.deployWithProgressMutex.Lock()
+	defer fake.deployWithProgressMutex.Unlock()
+	fake.DeployWithProgressStub = stub
+}
+
+func (fake *FakeICLI) DeployWithProgressArgsForCall(i int) (string, string, string, []string) {
+	fake.deployWithProgressMutex.RLock()
+	defer fake.deployWithProgressMutex.RUnlock()
+	argsForCall := fake.deployWithProgressArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeICLI) DeployWithProgressReturns(result1 <-chan boshcli.ProgressEvent, result2 <-chan error) {
+	fake.deployWithProgressMutex.Lock()
+	defer fake.deployWithProgressMutex.Unlock()
+	fake.DeployWithProgressStub = nil
+	fake.deployWithProgressReturns = struct {
+		result1 <-chan boshcli.ProgressEvent
+		result2 <-chan error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) DeployWithProgressReturnsOnCall(i int, result1 <-chan boshcli.ProgressEvent, result2 <-chan error) {
+	fake.deployWithProgressMutex.Lock()
+	defer fake.deployWithProgressMutex.Unlock()
+	fake.DeployWithProgressStub = nil
+	if fake.deployWithProgressReturnsOnCall == nil {
+		fake.deployWithProgressReturnsOnCall = make(map[int]struct {
+			result1 <-chan boshcli.ProgressEvent
+			result2 <-chan error
+		})
+	}
+	fake.deployWithProgressReturnsOnCall[i] = struct {
+		result1 <-chan boshcli.ProgressEvent
+		result2 <-chan error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) Deployments(arg1 boshcli.IAASEnvironment, arg2 string, arg3 string, arg4 string) ([]byte, error) {
+	fake.deploymentsMutex.Lock()
+	ret, specificReturn := fake.deploymentsReturnsOnCall[len(fake.deploymentsArgsForCall)]
+	fake.deploymentsArgsForCall = append(fake.deploymentsArgsForCall, struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("Deployments", []interface{}{arg1, arg2, arg3, arg4})
+	fake.deploymentsMutex.Unlock()
+	if fake.DeploymentsStub != nil {
+		return fake.DeploymentsStub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.deploymentsReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeICLI) DeploymentsCallCount() int {
+	fake.deploymentsMutex.RLock()
+	defer fake.deploymentsMutex.RUnlock()
+	return len(fake.deploymentsArgsForCall)
+}
+
+func (fake *FakeICLI) DeploymentsCalls(stub func(boshcli.IAASEnvironment, string, string, string) ([]byte, error)) {
+	fake.deploymentsMutex.Lock()
+	defer fake.deploymentsMutex.Unlock()
+	fake.DeploymentsStub = stub
+}
+
+func (fake *FakeICLI) DeploymentsArgsForCall(i int) (boshcli.IAASEnvironment, string, string, string) {
+	fake.deploymentsMutex.RLock()
+	defer fake.deploymentsMutex.RUnlock()
+	argsForCall := fake.deploymentsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeICLI) DeploymentsReturns(result1 []byte, result2 error) {
+	fake.deploymentsMutex.Lock()
+	defer fake.deploymentsMutex.Unlock()
+	fake.DeploymentsStub = nil
+	fake.deploymentsReturns = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) DeploymentsReturnsOnCall(i int, result1 []byte, result2 error) {
+	fake.deploymentsMutex.Lock()
+	defer fake.deploymentsMutex.Unlock()
+	fake.DeploymentsStub = nil
+	if fake.deploymentsReturnsOnCall == nil {
+		fake.deploymentsReturnsOnCall = make(map[int]struct {
+			result1 []byte
+			result2 error
+		})
+	}
+	fake.deploymentsReturnsOnCall[i] = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) DirectorManifestDiff(arg1 boshcli.Store, arg2 boshcli.IAASEnvironment, arg3 string, arg4 string, arg5 string, arg6 string, arg7 map[string]string) (string, error) {
+	fake.directorManifestDiffMutex.Lock()
+	ret, specificReturn := fake.directorManifestDiffReturnsOnCall[len(fake.directorManifestDiffArgsForCall)]
+	fake.directorManifestDiffArgsForCall = append(fake.directorManifestDiffArgsForCall, struct {
+		arg1 boshcli.Store
+		arg2 boshcli.IAASEnvironment
+		arg3 string
+		arg4 string
+		arg5 string
+		arg6 string
+		arg7 map[string]string
+	}{arg1, arg2, arg3, arg4, arg5, arg6, arg7})
+	fake.recordInvocation("DirectorManifestDiff", []interface{}{arg1, arg2, arg3, arg4, arg5, arg6, arg7})
+	fake.directorManifestDiffMutex.Unlock()
+	if fake.DirectorManifestDiffStub != nil {
+		return fake.DirectorManifestDiffStub(arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.directorManifestDiffReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeICLI) DirectorManifestDiffCallCount() int {
+	fake.directorManifestDiffMutex.RLock()
+	defer fake.directorManifestDiffMutex.RUnlock()
+	return len(fake.directorManifestDiffArgsForCall)
+}
+
+func (fake *FakeICLI) DirectorManifestDiffCalls(stub func(boshcli.Store, boshcli.IAASEnvironment, string, string, string, string, map[string]string) (string, error)) {
+	fake.directorManifestDiffMutex.Lock()
+	defer fake.directorManifestDiffMutex.Unlock()
+	fake.DirectorManifestDiffStub = stub
+}
+
+func (fake *FakeICLI) DirectorManifestDiffArgsForCall(i int) (boshcli.Store, boshcli.IAASEnvironment, string, string, string, string, map[string]string) {
+	fake.directorManifestDiffMutex.RLock()
+	defer fake.directorManifestDiffMutex.RUnlock()
+	argsForCall := fake.directorManifestDiffArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5, argsForCall.arg6, argsForCall.arg7
+}
+
+func (fake *FakeICLI) DirectorManifestDiffReturns(result1 string, result2 error) {
+	fake.directorManifestDiffMutex.Lock()
+	defer fake.directorManifestDiffMutex.Unlock()
+	fake.DirectorManifestDiffStub = nil
+	fake.directorManifestDiffReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) DirectorManifestDiffReturnsOnCall(i int, result1 string, result2 error) {
+	fake.directorManifestDiffMutex.Lock()
+	defer fake.directorManifestDiffMutex.Unlock()
+	fake.DirectorManifestDiffStub = nil
+	if fake.directorManifestDiffReturnsOnCall == nil {
+		fake.directorManifestDiffReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.directorManifestDiffReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) DirectorUUID(arg1 boshcli.IAASEnvironment, arg2 string, arg3 string, arg4 string) (string, error) {
+	fake.directorUUIDMutex.Lock()
+	ret, specificReturn := fake.directorUUIDReturnsOnCall[len(fake.directorUUIDArgsForCall)]
+	fake.directorUUIDArgsForCall = append(fake.directorUUIDArgsForCall, struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("DirectorUUID", []interface{}{arg1, arg2, arg3, arg4})
+	fake.directorUUIDMutex.Unlock()
+	if fake.DirectorUUIDStub != nil {
+		return fake.DirectorUUIDStub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.directorUUIDReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeICLI) DirectorUUIDCallCount() int {
+	fake.directorUUIDMutex.RLock()
+	defer fake.directorUUIDMutex.RUnlock()
+	return len(fake.directorUUIDArgsForCall)
+}
+
+func (fake *FakeICLI) DirectorUUIDCalls(stub func(boshcli.IAASEnvironment, string, string, string) (string, error)) {
+	fake.directorUUIDMutex.Lock()
+	defer fake.directorUUIDMutex.Unlock()
+	fake.DirectorUUIDStub = stub
+}
+
+func (fake *FakeICLI) DirectorUUIDArgsForCall(i int) (boshcli.IAASEnvironment, string, string, string) {
+	fake.directorUUIDMutex.RLock()
+	defer fake.directorUUIDMutex.RUnlock()
+	argsForCall := fake.directorUUIDArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeICLI) DirectorUUIDReturns(result1 string, result2 error) {
+	fake.directorUUIDMutex.Lock()
+	defer fake.directorUUIDMutex.Unlock()
+	fake.DirectorUUIDStub = nil
+	fake.directorUUIDReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) DirectorUUIDReturnsOnCall(i int, result1 string, result2 error) {
+	fake.directorUUIDMutex.Lock()
+	defer fake.directorUUIDMutex.Unlock()
+	fake.DirectorUUIDStub = nil
+	if fake.directorUUIDReturnsOnCall == nil {
+		fake.directorUUIDReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.directorUUIDReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) Events(arg1 boshcli.IAASEnvironment, arg2 string, arg3 string, arg4 string, arg5 int) ([]byte, error) {
+	fake.eventsMutex.Lock()
+	ret, specificReturn := fake.eventsReturnsOnCall[len(fake.eventsArgsForCall)]
+	fake.eventsArgsForCall = append(fake.eventsArgsForCall, struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+		arg5 int
+	}{arg1, arg2, arg3, arg4, arg5})
+	fake.recordInvocation("Events", []interface{}{arg1, arg2, arg3, arg4, arg5})
+	fake.eventsMutex.Unlock()
+	if fake.EventsStub != nil {
+		return fake.EventsStub(arg1, arg2, arg3, arg4, arg5)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.eventsReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeICLI) EventsCallCount() int {
+	fake.eventsMutex.RLock()
+	defer fake.eventsMutex.RUnlock()
+	return len(fake.eventsArgsForCall)
+}
+
+func (fake *FakeICLI) EventsCalls(stub func(boshcli.IAASEnvironment, string, string, string, int) ([]byte, error)) {
+	fake.eventsMutex.Lock()
+	defer fake.eventsMutex.Unlock()
+	fake.EventsStub = stub
+}
+
+func (fake *FakeICLI) EventsArgsForCall(i int) (boshcli.IAASEnvironment, string, string, string, int) {
+	fake.eventsMutex.RLock()
+	defer fake.eventsMutex.RUnlock()
+	argsForCall := fake.eventsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeICLI) EventsReturns(result1 []byte, result2 error) {
+	fake.eventsMutex.Lock()
+	defer fake.eventsMutex.Unlock()
+	fake.EventsStub = nil
+	fake.eventsReturns = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) EventsReturnsOnCall(i int, result1 []byte, result2 error) {
+	fake.eventsMutex.Lock()
+	defer fake.eventsMutex.Unlock()
+	fake.EventsStub = nil
+	if fake.eventsReturnsOnCall == nil {
+		fake.eventsReturnsOnCall = make(map[int]struct {
+			result1 []byte
+			result2 error
+		})
+	}
+	fake.eventsReturnsOnCall[i] = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) FetchLogs(arg1 boshcli.IAASEnvironment, arg2 string, arg3 string, arg4 string, arg5 string, arg6 string) error {
+	fake.fetchLogsMutex.Lock()
+	ret, specificReturn := fake.fetchLogsReturnsOnCall[len(fake.fetchLogsArgsForCall)]
+	fake.fetchLogsArgsForCall = append(fake.fetchLogsArgsForCall, struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+		arg5 string
+		arg6 string
+	}{arg1, arg2, arg3, arg4, arg5, arg6})
+	fake.recordInvocation("FetchLogs", []interface{}{arg1, arg2, arg3, arg4, arg5, arg6})
+	fake.fetchLogsMutex.Unlock()
+	if fake.FetchLogsStub != nil {
+		return fake.FetchLogsStub(arg1, arg2, arg3, arg4, arg5, arg6)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.fetchLogsReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeICLI) FetchLogsCallCount() int {
+	fake.fetchLogsMutex.RLock()
+	defer fake.fetchLogsMutex.RUnlock()
+	return len(fake.fetchLogsArgsForCall)
+}
+
+func (fake *FakeICLI) FetchLogsCalls(stub func(boshcli.IAASEnvironment, string, string, string, string, string) error) {
+	fake.fetchLogsMutex.Lock()
+	defer fake.fetchLogsMutex.Unlock()
+	fake.FetchLogsStub = stub
+}
+
+func (fake *FakeICLI) FetchLogsArgsForCall(i int) (boshcli.IAASEnvironment, string, string, string, string, string) {
+	fake.fetchLogsMutex.RLock()
+	defer fake.fetchLogsMutex.RUnlock()
+	argsForCall := fake.fetchLogsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5, argsForCall.arg6
+}
+
+func (fake *FakeICLI) FetchLogsReturns(result1 error) {
+	fake.fetchLogsMutex.Lock()
+	defer fake.fetchLogsMutex.Unlock()
+	fake.FetchLogsStub = nil
+	fake.fetchLogsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeICLI) FetchLogsReturnsOnCall(i int, result1 error) {
+	fake.fetchLogsMutex.Lock()
+	defer fake.fetchLogsMutex.Unlock()
+	fake.FetchLogsStub = nil
+	if fake.fetchLogsReturnsOnCall == nil {
+		fake.fetchLogsReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.fetchLogsReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeICLI) ForceUnlock(arg1 boshcli.Store) error {
+	fake.forceUnlockMutex.Lock()
+	ret, specificReturn := fake.forceUnlockReturnsOnCall[len(fake.forceUnlockArgsForCall)]
+	fake.forceUnlockArgsForCall = append(fake.forceUnlockArgsForCall, struct {
+		arg1 boshcli.Store
+	}{arg1})
+	fake.recordInvocation("ForceUnlock", []interface{}{arg1})
+	fake.forceUnlockMutex.Unlock()
+	if fake.ForceUnlockStub != nil {
+		return fake.ForceUnlockStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.forceUnlockReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeICLI) ForceUnlockCallCount() int {
+	fake.forceUnlockMutex.RLock()
+	defer fake.forceUnlockMutex.RUnlock()
+	return len(fake.forceUnlockArgsForCall)
+}
+
+func (fake *FakeICLI) ForceUnlockCalls(stub func(boshcli.Store) error) {
+	fake.forceUnlockMutex.Lock()
+	defer fake.forceUnlockMutex.Unlock()
+	fake.ForceUnlockStub = stub
+}
+
+func (fake *FakeICLI) ForceUnlockArgsForCall(i int) boshcli.Store {
+	fake.forceUnlockMutex.RLock()
+	defer fake.forceUnlockMutex.RUnlock()
+	argsForCall := fake.forceUnlockArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeICLI) ForceUnlockReturns(result1 error) {
+	fake.forceUnlockMutex.Lock()
+	defer fake.forceUnlockMutex.Unlock()
+	fake.ForceUnlockStub = nil
+	fake.forceUnlockReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeICLI) ForceUnlockReturnsOnCall(i int, result1 error) {
+	fake.forceUnlockMutex.Lock()
+	defer fake.forceUnlockMutex.Unlock()
+	fake.ForceUnlockStub = nil
+	if fake.forceUnlockReturnsOnCall == nil {
+		fake.forceUnlockReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.forceUnlockReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeICLI) ImportState(arg1 boshcli.Store, arg2 string, arg3 string, arg4 string) error {
+	fake.importStateMutex.Lock()
+	ret, specificReturn := fake.importStateReturnsOnCall[len(fake.importStateArgsForCall)]
+	fake.importStateArgsForCall = append(fake.importStateArgsForCall, struct {
+		arg1 boshcli.Store
+		arg2 string
+		arg3 string
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("ImportState", []interface{}{arg1, arg2, arg3, arg4})
+	fake.importStateMutex.Unlock()
+	if fake.ImportStateStub != nil {
+		return fake.ImportStateStub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.importStateReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeICLI) ImportStateCallCount() int {
+	fake.importStateMutex.RLock()
+	defer fake.importStateMutex.RUnlock()
+	return len(fake.importStateArgsForCall)
+}
+
+func (fake *FakeICLI) ImportStateCalls(stub func(boshcli.Store, string, string, string) error) {
+	fake.importStateMutex.Lock()
+	defer fake.importStateMutex.Unlock()
+	fake.ImportStateStub = stub
+}
+
+func (fake *FakeICLI) ImportStateArgsForCall(i int) (boshcli.Store, string, string, string) {
+	fake.importStateMutex.RLock()
+	defer fake.importStateMutex.RUnlock()
+	argsForCall := fake.importStateArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeICLI) ImportStateReturns(result1 error) {
+	fake.importStateMutex.Lock()
+	defer fake.importStateMutex.Unlock()
+	fake.ImportStateStub = nil
+	fake.importStateReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeICLI) ImportStateReturnsOnCall(i int, result1 error) {
+	fake.importStateMutex.Lock()
+	defer fake.importStateMutex.Unlock()
+	fake.ImportStateStub = nil
+	if fake.importStateReturnsOnCall == nil {
+		fake.importStateReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.importStateReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeICLI) LastTaskOutput(arg1 boshcli.IAASEnvironment, arg2 string, arg3 string, arg4 string) ([]byte, error) {
+	fake.lastTaskOutputMutex.Lock()
+	ret, specificReturn := fake.lastTaskOutputReturnsOnCall[len(fake.lastTaskOutputArgsForCall)]
+	fake.lastTaskOutputArgsForCall = append(fake.lastTaskOutputArgsForCall, struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("LastTaskOutput", []interface{}{arg1, arg2, arg3, arg4})
+	fake.lastTaskOutputMutex.Unlock()
+	if fake.LastTaskOutputStub != nil {
+		return fake.LastTaskOutputStub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.lastTaskOutputReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeICLI) LastTaskOutputCallCount() int {
+	fake.lastTaskOutputMutex.RLock()
+	defer fake.lastTaskOutputMutex.RUnlock()
+	return len(fake.lastTaskOutputArgsForCall)
+}
+
+func (fake *FakeICLI) LastTaskOutputCalls(stub func(boshcli.IAASEnvironment, string, string, string) ([]byte, error)) {
+	fake.lastTaskOutputMutex.Lock()
+	defer fake.lastTaskOutputMutex.Unlock()
+	fake.LastTaskOutputStub = stub
+}
+
+func (fake *FakeICLI) LastTaskOutputArgsForCall(i int) (boshcli.IAASEnvironment, string, string, string) {
+	fake.lastTaskOutputMutex.RLock()
+	defer fake.lastTaskOutputMutex.RUnlock()
+	argsForCall := fake.lastTaskOutputArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeICLI) LastTaskOutputReturns(result1 []byte, result2 error) {
+	fake.lastTaskOutputMutex.Lock()
+	defer fake.lastTaskOutputMutex.Unlock()
+	fake.LastTaskOutputStub = nil
+	fake.lastTaskOutputReturns = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) LastTaskOutputReturnsOnCall(i int, result1 []byte, result2 error) {
+	fake.lastTaskOutputMutex.Lock()
+	defer fake.lastTaskOutputMutex.Unlock()
+	fake.LastTaskOutputStub = nil
+	if fake.lastTaskOutputReturnsOnCall == nil {
+		fake.lastTaskOutputReturnsOnCall = make(map[int]struct {
+			result1 []byte
+			result2 error
+		})
+	}
+	fake.lastTaskOutputReturnsOnCall[i] = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) Locks(arg1 boshcli.IAASEnvironment, arg2 string, arg3 string, arg4 string) ([]byte, error) {
+	fake.locksMutex.Lock()
+	ret, specificReturn := fake.locksReturnsOnCall[len(fake.locksArgsForCall)]
+	fake.locksArgsForCall = append(fake.locksArgsForCall, struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("Locks", []interface{}{arg1, arg2, arg3, arg4})
+	fake.locksMutex.Unlock()
+	if fake.LocksStub != nil {
+		return fake.LocksStub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.locksReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeICLI) LocksCallCount() int {
+	fake.locksMutex.RLock()
+	defer fake.locksMutex.RUnlock()
+	return len(fake.locksArgsForCall)
+}
+
+func (fake *FakeICLI) LocksCalls(stub func(boshcli.IAASEnvironment, string, string, string) ([]byte, error)) {
+	fake.locksMutex.Lock()
+	defer fake.locksMutex.Unlock()
+	fake.LocksStub = stub
+}
+
+func (fake *FakeICLI) LocksArgsForCall(i int) (boshcli.IAASEnvironment, string, string, string) {
+	fake.locksMutex.RLock()
+	defer fake.locksMutex.RUnlock()
+	argsForCall := fake.locksArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeICLI) LocksReturns(result1 []byte, result2 error) {
+	fake.locksMutex.Lock()
+	defer fake.locksMutex.Unlock()
+	fake.LocksStub = nil
+	fake.locksReturns = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) LocksReturnsOnCall(i int, result1 []byte, result2 error) {
+	fake.locksMutex.Lock()
+	defer fake.locksMutex.Unlock()
+	fake.LocksStub = nil
+	if fake.locksReturnsOnCall == nil {
+		fake.locksReturnsOnCall = make(map[int]struct {
+			result1 []byte
+			result2 error
+		})
+	}
+	fake.locksReturnsOnCall[i] = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) ManifestFingerprint(arg1 boshcli.IAASEnvironment, arg2 string, arg3 string, arg4 string, arg5 string, arg6 map[string]string) (string, error) {
+	fake.manifestFingerprintMutex.Lock()
+	ret, specificReturn := fake.manifestFingerprintReturnsOnCall[len(fake.manifestFingerprintArgsForCall)]
+	fake.manifestFingerprintArgsForCall = append(fake.manifestFingerprintArgsForCall, struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+		arg5 string
+		arg6 map[string]string
+	}{arg1, arg2, arg3, arg4, arg5, arg6})
+	fake.recordInvocation("ManifestFingerprint", []interface{}{arg1, arg2, arg3, arg4, arg5, arg6})
+	fake.manifestFingerprintMutex.Unlock()
+	if fake.ManifestFingerprintStub != nil {
+		return fake.ManifestFingerprintStub(arg1, arg2, arg3, arg4, arg5, arg6)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.manifestFingerprintReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeICLI) ManifestFingerprintCallCount() int {
+	fake.manifestFingerprintMutex.RLock()
+	defer fake.manifestFingerprintMutex.RUnlock()
+	return len(fake.manifestFingerprintArgsForCall)
+}
+
+func (fake *FakeICLI) ManifestFingerprintCalls(stub func(boshcli.IAASEnvironment, string, string, string, string, map[string]string) (string, error)) {
+	fake.manifestFingerprintMutex.Lock()
+	defer fake.manifestFingerprintMutex.Unlock()
+	fake.ManifestFingerprintStub = stub
+}
+
+func (fake *FakeICLI) ManifestFingerprintArgsForCall(i int) (boshcli.IAASEnvironment, string, string, string, string, map[string]string) {
+	fake.manifestFingerprintMutex.RLock()
+	defer fake.manifestFingerprintMutex.RUnlock()
+	argsForCall := fake.manifestFingerprintArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5, argsForCall.arg6
+}
+
+func (fake *FakeICLI) ManifestFingerprintReturns(result1 string, result2 error) {
+	fake.manifestFingerprintMutex.Lock()
+	defer fake.manifestFingerprintMutex.Unlock()
+	fake.ManifestFingerprintStub = nil
+	fake.manifestFingerprintReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) ManifestFingerprintReturnsOnCall(i int, result1 string, result2 error) {
+	fake.manifestFingerprintMutex.Lock()
+	defer fake.manifestFingerprintMutex.Unlock()
+	fake.ManifestFingerprintStub = nil
+	if fake.manifestFingerprintReturnsOnCall == nil {
+		fake.manifestFingerprintReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.manifestFingerprintReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) MissingReleases(arg1 boshcli.IAASEnvironment, arg2 string, arg3 string, arg4 string, arg5 []byte) ([]string, error) {
+	var arg5Copy []byte
+	if arg5 != nil {
+		arg5Copy = make([]byte, len(arg5))
+		copy(arg5Copy, arg5)
+	}
+	fake.missingReleasesMutex.Lock()
+	ret, specificReturn := fake.missingReleasesReturnsOnCall[len(fake.missingReleasesArgsForCall)]
+	fake.missingReleasesArgsForCall = append(fake.missingReleasesArgsForCall, struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+		arg5 []byte
+	}{arg1, arg2, arg3, arg4, arg5Copy})
+	fake.recordInvocation("MissingReleases", []interface{}{arg1, arg2, arg3, arg4, arg5Copy})
+	fake.missingReleasesMutex.Unlock()
+	if fake.MissingReleasesStub != nil {
+		return fake.MissingReleasesStub(arg1, arg2, arg3, arg4, arg5)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.missingReleasesReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeICLI) MissingReleasesCallCount() int {
+	fake.missingReleasesMutex.RLock()
+	defer fake.missingReleasesMutex.RUnlock()
+	return len(fake.missingReleasesArgsForCall)
+}
+
+func (fake *FakeICLI) MissingReleasesCalls(stub func(boshcli.IAASEnvironment, string, string, string, []byte) ([]string, error)) {
+	fake.missingReleasesMutex.Lock()
+	defer fake.missingReleasesMutex.Unlock()
+	fake.MissingReleasesStub = stub
+}
+
+func (fake *FakeICLI) MissingReleasesArgsForCall(i int) (boshcli.IAASEnvironment, string, string, string, []byte) {
+	fake.missingReleasesMutex.RLock()
+	defer fake.missingReleasesMutex.RUnlock()
+	argsForCall := fake.missingReleasesArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeICLI) MissingReleasesReturns(result1 []string, result2 error) {
+	fake.missingReleasesMutex.Lock()
+	defer fake.missingReleasesMutex.Unlock()
+	fake.MissingReleasesStub = nil
+	fake.missingReleasesReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) MissingReleasesReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.missingReleasesMutex.Lock()
+	defer fake.missingReleasesMutex.Unlock()
+	fake.MissingReleasesStub = nil
+	if fake.missingReleasesReturnsOnCall == nil {
+		fake.missingReleasesReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.missingReleasesReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) NewSession(arg1 boshcli.IAASEnvironment, arg2 string, arg3 string, arg4 string) (*boshcli.Session, error) {
+	fake.newSessionMutex.Lock()
+	ret, specificReturn := fake.newSessionReturnsOnCall[len(fake.newSessionArgsForCall)]
+	fake.newSessionArgsForCall = append(fake.newSessionArgsForCall, struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("NewSession", []interface{}{arg1, arg2, arg3, arg4})
+	fake.newSessionMutex.Unlock()
+	if fake.NewSessionStub != nil {
+		return fake.NewSessionStub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.newSessionReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeICLI) NewSessionCallCount() int {
+	fake.newSessionMutex.RLock()
+	defer fake.newSessionMutex.RUnlock()
+	return len(fake.newSessionArgsForCall)
+}
+
+func (fake *FakeICLI) NewSessionCalls(stub func(boshcli.IAASEnvironment, string, string, string) (*boshcli.Session, error)) {
+	fake.newSessionMutex.Lock()
+	defer fake.newSessionMutex.Unlock()
+	fake.NewSessionStub = stub
+}
+
+func (fake *FakeICLI) NewSessionArgsForCall(i int) (boshcli.IAASEnvironment, string, string, string) {
+	fake.newSessionMutex.RLock()
+	defer fake.newSessionMutex.RUnlock()
+	argsForCall := fake.newSessionArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeICLI) NewSessionReturns(result1 *boshcli.Session, result2 error) {
+	fake.newSessionMutex.Lock()
+	defer fake.newSessionMutex.Unlock()
+	fake.NewSessionStub = nil
+	fake.newSessionReturns = struct {
+		result1 *boshcli.Session
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) NewSessionReturnsOnCall(i int, result1 *boshcli.Session, result2 error) {
+	fake.newSessionMutex.Lock()
+	defer fake.newSessionMutex.Unlock()
+	fake.NewSessionStub = nil
+	if fake.newSessionReturnsOnCall == nil {
+		fake.newSessionReturnsOnCall = make(map[int]struct {
+			result1 *boshcli.Session
+			result2 error
+		})
+	}
+	fake.newSessionReturnsOnCall[i] = struct {
+		result1 *boshcli.Session
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) Pause(arg1 boshcli.IAASEnvironment, arg2 string, arg3 string, arg4 string) error {
+	fake.pauseMutex.Lock()
+	ret, specificReturn := fake.pauseReturnsOnCall[len(fake.pauseArgsForCall)]
+	fake.pauseArgsForCall = append(fake.pauseArgsForCall, struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("Pause", []interface{}{arg1, arg2, arg3, arg4})
+	fake.pauseMutex.Unlock()
+	if fake.PauseStub != nil {
+		return fake.PauseStub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.deleteEnvReturns
+	fakeReturns := fake.pauseReturns
 	return fakeReturns.result1
 }
 
-func (fake *FakeICLI) DeleteEnvCallCount() int {
-	fake.deleteEnvMutex.RLock()
-	defer fake.deleteEnvMutex.RUnlock()
-	return len(fake.deleteEnvArgsForCall)
+func (fake *FakeICLI) PauseCallCount() int {
+	fake.pauseMutex.RLock()
+	defer fake.pauseMutex.RUnlock()
+	return len(fake.pauseArgsForCall)
 }
 
-func (fake *FakeICLI) DeleteEnvCalls(stub func(boshcli.Store, boshcli.IAASEnvironment, string, string, string, string, map[string]string) error) {
-	fake.deleteEnvMutex.Lock()
-	defer fake.deleteEnvMutex.Unlock()
-	fake.DeleteEnvStub = stub
+func (fake *FakeICLI) PauseCalls(stub func(boshcli.IAASEnvironment, string, string, string) error) {
+	fake.pauseMutex.Lock()
+	defer fake.pauseMutex.Unlock()
+	fake.PauseStub = stub
 }
 
-func (fake *FakeICLI) DeleteEnvArgsForCall(i int) (boshcli.Store, boshcli.IAASEnvironment, string, string, string, string, map[string]string) {
-	fake.deleteEnvMutex.RLock()
-	defer fake.deleteEnvMutex.RUnlock()
-	argsForCall := fake.deleteEnvArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5, argsForCall.arg6, argsForCall.arg7
+func (fake *FakeICLI) PauseArgsForCall(i int) (boshcli.IAASEnvironment, string, string, string) {
+	fake.pauseMutex.RLock()
+	defer fake.pauseMutex.RUnlock()
+	argsForCall := fake.pauseArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
-func (fake *FakeICLI) DeleteEnvReturns(result1 error) {
-	fake.deleteEnvMutex.Lock()
-	defer fake.deleteEnvMutex.Unlock()
-	fake.DeleteEnvStub = nil
-	fake.deleteEnvReturns = struct {
+func (fake *FakeICLI) PauseReturns(result1 error) {
+	fake.pauseMutex.Lock()
+	defer fake.pauseMutex.Unlock()
+	fake.PauseStub = nil
+	fake.pauseReturns = struct {
 		result1 error
 	}{result1}
 }
 
-func (fake *FakeICLI) DeleteEnvReturnsOnCall(i int, result1 error) {
-	fake.deleteEnvMutex.Lock()
-	defer fake.deleteEnvMutex.Unlock()
-	fake.DeleteEnvStub = nil
-	if fake.deleteEnvReturnsOnCall == nil {
-		fake.deleteEnvReturnsOnCall = make(map[int]struct {
+func (fake *FakeICLI) PauseReturnsOnCall(i int, result1 error) {
+	fake.pauseMutex.Lock()
+	defer fake.pauseMutex.Unlock()
+	fake.PauseStub = nil
+	if fake.pauseReturnsOnCall == nil {
+		fake.pauseReturnsOnCall = make(map[int]struct {
 			result1 error
 		})
 	}
-	fake.deleteEnvReturnsOnCall[i] = struct {
+	fake.pauseReturnsOnCall[i] = struct {
 		result1 error
 	}{result1}
 }
 
-func (fake *FakeICLI) Locks(arg1 boshcli.IAASEnvironment, arg2 string, arg3 string, arg4 string) ([]byte, error) {
-	fake.locksMutex.Lock()
-	ret, specificReturn := fake.locksReturnsOnCall[len(fake.locksArgsForCall)]
-	fake.locksArgsForCall = append(fake.locksArgsForCall, struct {
+func (fake *FakeICLI) PersistentDisks(arg1 boshcli.IAASEnvironment, arg2 string, arg3 string, arg4 string) ([]boshcli.PersistentDisk, error) {
+	fake.persistentDisksMutex.Lock()
+	ret, specificReturn := fake.persistentDisksReturnsOnCall[len(fake.persistentDisksArgsForCall)]
+	fake.persistentDisksArgsForCall = append(fake.persistentDisksArgsForCall, struct {
 		arg1 boshcli.IAASEnvironment
 		arg2 string
 		arg3 string
 		arg4 string
 	}{arg1, arg2, arg3, arg4})
-	fake.recordInvocation("Locks", []interface{}{arg1, arg2, arg3, arg4})
-	fake.locksMutex.Unlock()
-	if fake.LocksStub != nil {
-		return fake.LocksStub(arg1, arg2, arg3, arg4)
+	fake.recordInvocation("PersistentDisks", []interface{}{arg1, arg2, arg3, arg4})
+	fake.persistentDisksMutex.Unlock()
+	if fake.PersistentDisksStub != nil {
+		return fake.PersistentDisksStub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.locksReturns
+	fakeReturns := fake.persistentDisksReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
-func (fake *FakeICLI) LocksCallCount() int {
-	fake.locksMutex.RLock()
-	defer fake.locksMutex.RUnlock()
-	return len(fake.locksArgsForCall)
+func (fake *FakeICLI) PersistentDisksCallCount() int {
+	fake.persistentDisksMutex.RLock()
+	defer fake.persistentDisksMutex.RUnlock()
+	return len(fake.persistentDisksArgsForCall)
 }
 
-func (fake *FakeICLI) LocksCalls(stub func(boshcli.IAASEnvironment, string, string, string) ([]byte, error)) {
-	fake.locksMutex.Lock()
-	defer fake.locksMutex.Unlock()
-	fake.LocksStub = stub
+func (fake *FakeICLI) PersistentDisksCalls(stub func(boshcli.IAASEnvironment, string, string, string) ([]boshcli.PersistentDisk, error)) {
+	fake.persistentDisksMutex.Lock()
+	defer fake.persistentDisksMutex.Unlock()
+	fake.PersistentDisksStub = stub
 }
 
-func (fake *FakeICLI) LocksArgsForCall(i int) (boshcli.IAASEnvironment, string, string, string) {
-	fake.locksMutex.RLock()
-	defer fake.locksMutex.RUnlock()
-	argsForCall := fake.locksArgsForCall[i]
+func (fake *FakeICLI) PersistentDisksArgsForCall(i int) (boshcli.IAASEnvironment, string, string, string) {
+	fake.persistentDisksMutex.RLock()
+	defer fake.persistentDisksMutex.RUnlock()
+	argsForCall := fake.persistentDisksArgsForCall[i]
 	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
-func (fake *FakeICLI) LocksReturns(result1 []byte, result2 error) {
-	fake.locksMutex.Lock()
-	defer fake.locksMutex.Unlock()
-	fake.LocksStub = nil
-	fake.locksReturns = struct {
-		result1 []byte
+func (fake *FakeICLI) PersistentDisksReturns(result1 []boshcli.PersistentDisk, result2 error) {
+	fake.persistentDisksMutex.Lock()
+	defer fake.persistentDisksMutex.Unlock()
+	fake.PersistentDisksStub = nil
+	fake.persistentDisksReturns = struct {
+		result1 []boshcli.PersistentDisk
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeICLI) LocksReturnsOnCall(i int, result1 []byte, result2 error) {
-	fake.locksMutex.Lock()
-	defer fake.locksMutex.Unlock()
-	fake.LocksStub = nil
-	if fake.locksReturnsOnCall == nil {
-		fake.locksReturnsOnCall = make(map[int]struct {
-			result1 []byte
+func (fake *FakeICLI) PersistentDisksReturnsOnCall(i int, result1 []boshcli.PersistentDisk, result2 error) {
+	fake.persistentDisksMutex.Lock()
+	defer fake.persistentDisksMutex.Unlock()
+	fake.PersistentDisksStub = nil
+	if fake.persistentDisksReturnsOnCall == nil {
+		fake.persistentDisksReturnsOnCall = make(map[int]struct {
+			result1 []boshcli.PersistentDisk
 			result2 error
 		})
 	}
-	fake.locksReturnsOnCall[i] = struct {
-		result1 []byte
+	fake.persistentDisksReturnsOnCall[i] = struct {
+		result1 []boshcli.PersistentDisk
 		result2 error
 	}{result1, result2}
 }
@@ -383,6 +2254,333 @@ func (fake *FakeICLI) RecreateReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeICLI) RecreateEnv(arg1 boshcli.Store, arg2 boshcli.IAASEnvironment, arg3 string, arg4 string, arg5 string, arg6 string, arg7 map[string]string) error {
+	fake.recreateEnvMutex.Lock()
+	ret, specificReturn := fake.recreateEnvReturnsOnCall[len(fake.recreateEnvArgsForCall)]
+	fake.recreateEnvArgsForCall = append(fake.recreateEnvArgsForCall, struct {
+		arg1 boshcli.Store
+		arg2 boshcli.IAASEnvironment
+		arg3 string
+		arg4 string
+		arg5 string
+		arg6 string
+		arg7 map[string]string
+	}{arg1, arg2, arg3, arg4, arg5, arg6, arg7})
+	fake.recordInvocation("RecreateEnv", []interface{}{arg1, arg2, arg3, arg4, arg5, arg6, arg7})
+	fake.recreateEnvMutex.Unlock()
+	if fake.RecreateEnvStub != nil {
+		return fake.RecreateEnvStub(arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.recreateEnvReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeICLI) RecreateEnvCallCount() int {
+	fake.recreateEnvMutex.RLock()
+	defer fake.recreateEnvMutex.RUnlock()
+	return len(fake.recreateEnvArgsForCall)
+}
+
+func (fake *FakeICLI) RecreateEnvCalls(stub func(boshcli.Store, boshcli.IAASEnvironment, string, string, string, string, map[string]string) error) {
+	fake.recreateEnvMutex.Lock()
+	defer fake.recreateEnvMutex.Unlock()
+	fake.RecreateEnvStub = stub
+}
+
+func (fake *FakeICLI) RecreateEnvArgsForCall(i int) (boshcli.Store, boshcli.IAASEnvironment, string, string, string, string, map[string]string) {
+	fake.recreateEnvMutex.RLock()
+	defer fake.recreateEnvMutex.RUnlock()
+	argsForCall := fake.recreateEnvArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5, argsForCall.arg6, argsForCall.arg7
+}
+
+func (fake *FakeICLI) RecreateEnvReturns(result1 error) {
+	fake.recreateEnvMutex.Lock()
+	defer fake.recreateEnvMutex.Unlock()
+	fake.RecreateEnvStub = nil
+	fake.recreateEnvReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeICLI) RecreateEnvReturnsOnCall(i int, result1 error) {
+	fake.recreateEnvMutex.Lock()
+	defer fake.recreateEnvMutex.Unlock()
+	fake.RecreateEnvStub = nil
+	if fake.recreateEnvReturnsOnCall == nil {
+		fake.recreateEnvReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.recreateEnvReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeICLI) RecreateFailing(arg1 boshcli.IAASEnvironment, arg2 string, arg3 string, arg4 string) ([]string, error) {
+	fake.recreateFailingMutex.Lock()
+	ret, specificReturn := fake.recreateFailingReturnsOnCall[len(fake.recreateFailingArgsForCall)]
+	fake.recreateFailingArgsForCall = append(fake.recreateFailingArgsForCall, struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("RecreateFailing", []interface{}{arg1, arg2, arg3, arg4})
+	fake.recreateFailingMutex.Unlock()
+	if fake.RecreateFailingStub != nil {
+		return fake.RecreateFailingStub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.recreateFailingReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeICLI) RecreateFailingCallCount() int {
+	fake.recreateFailingMutex.RLock()
+	defer fake.recreateFailingMutex.RUnlock()
+	return len(fake.recreateFailingArgsForCall)
+}
+
+func (fake *FakeICLI) RecreateFailingCalls(stub func(boshcli.IAASEnvironment, string, string, string) ([]string, error)) {
+	fake.recreateFailingMutex.Lock()
+	defer fake.recreateFailingMutex.Unlock()
+	fake.RecreateFailingStub = stub
+}
+
+func (fake *FakeICLI) RecreateFailingArgsForCall(i int) (boshcli.IAASEnvironment, string, string, string) {
+	fake.recreateFailingMutex.RLock()
+	defer fake.recreateFailingMutex.RUnlock()
+	argsForCall := fake.recreateFailingArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeICLI) RecreateFailingReturns(result1 []string, result2 error) {
+	fake.recreateFailingMutex.Lock()
+	defer fake.recreateFailingMutex.Unlock()
+	fake.RecreateFailingStub = nil
+	fake.recreateFailingReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) RecreateFailingReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.recreateFailingMutex.Lock()
+	defer fake.recreateFailingMutex.Unlock()
+	fake.RecreateFailingStub = nil
+	if fake.recreateFailingReturnsOnCall == nil {
+		fake.recreateFailingReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.recreateFailingReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) RecreateInstance(arg1 boshcli.IAASEnvironment, arg2 string, arg3 string, arg4 string, arg5 string) error {
+	fake.recreateInstanceMutex.Lock()
+	ret, specificReturn := fake.recreateInstanceReturnsOnCall[len(fake.recreateInstanceArgsForCall)]
+	fake.recreateInstanceArgsForCall = append(fake.recreateInstanceArgsForCall, struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+		arg5 string
+	}{arg1, arg2, arg3, arg4, arg5})
+	fake.recordInvocation("RecreateInstance", []interface{}{arg1, arg2, arg3, arg4, arg5})
+	fake.recreateInstanceMutex.Unlock()
+	if fake.RecreateInstanceStub != nil {
+		return fake.RecreateInstanceStub(arg1, arg2, arg3, arg4, arg5)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.recreateInstanceReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeICLI) RecreateInstanceCallCount() int {
+	fake.recreateInstanceMutex.RLock()
+	defer fake.recreateInstanceMutex.RUnlock()
+	return len(fake.recreateInstanceArgsForCall)
+}
+
+func (fake *FakeICLI) RecreateInstanceCalls(stub func(boshcli.IAASEnvironment, string, string, string, string) error) {
+	fake.recreateInstanceMutex.Lock()
+	defer fake.recreateInstanceMutex.Unlock()
+	fake.RecreateInstanceStub = stub
+}
+
+func (fake *FakeICLI) RecreateInstanceArgsForCall(i int) (boshcli.IAASEnvironment, string, string, string, string) {
+	fake.recreateInstanceMutex.RLock()
+	defer fake.recreateInstanceMutex.RUnlock()
+	argsForCall := fake.recreateInstanceArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeICLI) RecreateInstanceReturns(result1 error) {
+	fake.recreateInstanceMutex.Lock()
+	defer fake.recreateInstanceMutex.Unlock()
+	fake.RecreateInstanceStub = nil
+	fake.recreateInstanceReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeICLI) RecreateInstanceReturnsOnCall(i int, result1 error) {
+	fake.recreateInstanceMutex.Lock()
+	defer fake.recreateInstanceMutex.Unlock()
+	fake.RecreateInstanceStub = nil
+	if fake.recreateInstanceReturnsOnCall == nil {
+		fake.recreateInstanceReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.recreateInstanceReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeICLI) Resume(arg1 boshcli.IAASEnvironment, arg2 string, arg3 string, arg4 string) error {
+	fake.resumeMutex.Lock()
+	ret, specificReturn := fake.resumeReturnsOnCall[len(fake.resumeArgsForCall)]
+	fake.resumeArgsForCall = append(fake.resumeArgsForCall, struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("Resume", []interface{}{arg1, arg2, arg3, arg4})
+	fake.resumeMutex.Unlock()
+	if fake.ResumeStub != nil {
+		return fake.ResumeStub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.resumeReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeICLI) ResumeCallCount() int {
+	fake.resumeMutex.RLock()
+	defer fake.resumeMutex.RUnlock()
+	return len(fake.resumeArgsForCall)
+}
+
+func (fake *FakeICLI) ResumeCalls(stub func(boshcli.IAASEnvironment, string, string, string) error) {
+	fake.resumeMutex.Lock()
+	defer fake.resumeMutex.Unlock()
+	fake.ResumeStub = stub
+}
+
+func (fake *FakeICLI) ResumeArgsForCall(i int) (boshcli.IAASEnvironment, string, string, string) {
+	fake.resumeMutex.RLock()
+	defer fake.resumeMutex.RUnlock()
+	argsForCall := fake.resumeArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeICLI) ResumeReturns(result1 error) {
+	fake.resumeMutex.Lock()
+	defer fake.resumeMutex.Unlock()
+	fake.ResumeStub = nil
+	fake.resumeReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeICLI) ResumeReturnsOnCall(i int, result1 error) {
+	fake.resumeMutex.Lock()
+	defer fake.resumeMutex.Unlock()
+	fake.ResumeStub = nil
+	if fake.resumeReturnsOnCall == nil {
+		fake.resumeReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.resumeReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeICLI) RotateDirectorCert(arg1 boshcli.Store, arg2 boshcli.IAASEnvironment, arg3 string, arg4 string, arg5 map[string]string, arg6 boshcli.CertGenerator) (*certs.Certs, error) {
+	fake.rotateDirectorCertMutex.Lock()
+	ret, specificReturn := fake.rotateDirectorCertReturnsOnCall[len(fake.rotateDirectorCertArgsForCall)]
+	fake.rotateDirectorCertArgsForCall = append(fake.rotateDirectorCertArgsForCall, struct {
+		arg1 boshcli.Store
+		arg2 boshcli.IAASEnvironment
+		arg3 string
+		arg4 string
+		arg5 map[string]string
+		arg6 boshcli.CertGenerator
+	}{arg1, arg2, arg3, arg4, arg5, arg6})
+	fake.recordInvocation("RotateDirectorCert", []interface{}{arg1, arg2, arg3, arg4, arg5, arg6})
+	fake.rotateDirectorCertMutex.Unlock()
+	if fake.RotateDirectorCertStub != nil {
+		return fake.RotateDirectorCertStub(arg1, arg2, arg3, arg4, arg5, arg6)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.rotateDirectorCertReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeICLI) RotateDirectorCertCallCount() int {
+	fake.rotateDirectorCertMutex.RLock()
+	defer fake.rotateDirectorCertMutex.RUnlock()
+	return len(fake.rotateDirectorCertArgsForCall)
+}
+
+func (fake *FakeICLI) RotateDirectorCertCalls(stub func(boshcli.Store, boshcli.IAASEnvironment, string, string, map[string]string, boshcli.CertGenerator) (*certs.Certs, error)) {
+	fake.rotateDirectorCertMutex.Lock()
+	defer fake.rotateDirectorCertMutex.Unlock()
+	fake.RotateDirectorCertStub = stub
+}
+
+func (fake *FakeICLI) RotateDirectorCertArgsForCall(i int) (boshcli.Store, boshcli.IAASEnvironment, string, string, map[string]string, boshcli.CertGenerator) {
+	fake.rotateDirectorCertMutex.RLock()
+	defer fake.rotateDirectorCertMutex.RUnlock()
+	argsForCall := fake.rotateDirectorCertArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5, argsForCall.arg6
+}
+
+func (fake *FakeICLI) RotateDirectorCertReturns(result1 *certs.Certs, result2 error) {
+	fake.rotateDirectorCertMutex.Lock()
+	defer fake.rotateDirectorCertMutex.Unlock()
+	fake.RotateDirectorCertStub = nil
+	fake.rotateDirectorCertReturns = struct {
+		result1 *certs.Certs
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) RotateDirectorCertReturnsOnCall(i int, result1 *certs.Certs, result2 error) {
+	fake.rotateDirectorCertMutex.Lock()
+	defer fake.rotateDirectorCertMutex.Unlock()
+	fake.RotateDirectorCertStub = nil
+	if fake.rotateDirectorCertReturnsOnCall == nil {
+		fake.rotateDirectorCertReturnsOnCall = make(map[int]struct {
+			result1 *certs.Certs
+			result2 error
+		})
+	}
+	fake.rotateDirectorCertReturnsOnCall[i] = struct {
+		result1 *certs.Certs
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeICLI) RunAuthenticatedCommand(arg1 string, arg2 string, arg3 string, arg4 string, arg5 bool, arg6 io.Writer, arg7 ...string) error {
 	fake.runAuthenticatedCommandMutex.Lock()
 	ret, specificReturn := fake.runAuthenticatedCommandReturnsOnCall[len(fake.runAuthenticatedCommandArgsForCall)]
@@ -575,23 +2773,145 @@ func (fake *FakeICLI) UploadConcourseStemcellReturnsOnCall(i int, result1 error)
 	}{result1}
 }
 
+func (fake *FakeICLI) VMs(arg1 boshcli.IAASEnvironment, arg2 string, arg3 string, arg4 string) ([]byte, error) {
+	fake.vMsMutex.Lock()
+	ret, specificReturn := fake.vMsReturnsOnCall[len(fake.vMsArgsForCall)]
+	fake.vMsArgsForCall = append(fake.vMsArgsForCall, struct {
+		arg1 boshcli.IAASEnvironment
+		arg2 string
+		arg3 string
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("VMs", []interface{}{arg1, arg2, arg3, arg4})
+	fake.vMsMutex.Unlock()
+	if fake.VMsStub != nil {
+		return fake.VMsStub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.vMsReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeICLI) VMsCallCount() int {
+	fake.vMsMutex.RLock()
+	defer fake.vMsMutex.RUnlock()
+	return len(fake.vMsArgsForCall)
+}
+
+func (fake *FakeICLI) VMsCalls(stub func(boshcli.IAASEnvironment, string, string, string) ([]byte, error)) {
+	fake.vMsMutex.Lock()
+	defer fake.vMsMutex.Unlock()
+	fake.VMsStub = stub
+}
+
+func (fake *FakeICLI) VMsArgsForCall(i int) (boshcli.IAASEnvironment, string, string, string) {
+	fake.vMsMutex.RLock()
+	defer fake.vMsMutex.RUnlock()
+	argsForCall := fake.vMsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeICLI) VMsReturns(result1 []byte, result2 error) {
+	fake.vMsMutex.Lock()
+	defer fake.vMsMutex.Unlock()
+	fake.VMsStub = nil
+	fake.vMsReturns = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeICLI) VMsReturnsOnCall(i int, result1 []byte, result2 error) {
+	fake.vMsMutex.Lock()
+	defer fake.vMsMutex.Unlock()
+	fake.VMsStub = nil
+	if fake.vMsReturnsOnCall == nil {
+		fake.vMsReturnsOnCall = make(map[int]struct {
+			result1 []byte
+			result2 error
+		})
+	}
+	fake.vMsReturnsOnCall[i] = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeICLI) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.checkDirectorVersionMutex.RLock()
+	defer fake.checkDirectorVersionMutex.RUnlock()
+	fake.checkStateConsistencyMutex.RLock()
+	defer fake.checkStateConsistencyMutex.RUnlock()
+	fake.cleanUpMutex.RLock()
+	defer fake.cleanUpMutex.RUnlock()
+	fake.cloudConfigDiffMutex.RLock()
+	defer fake.cloudConfigDiffMutex.RUnlock()
+	fake.cloudConfigDriftMutex.RLock()
+	defer fake.cloudConfigDriftMutex.RUnlock()
+	fake.concourseCredentialsMutex.RLock()
+	defer fake.concourseCredentialsMutex.RUnlock()
 	fake.createEnvMutex.RLock()
 	defer fake.createEnvMutex.RUnlock()
+	fake.credHubImportMutex.RLock()
+	defer fake.credHubImportMutex.RUnlock()
 	fake.deleteEnvMutex.RLock()
 	defer fake.deleteEnvMutex.RUnlock()
+	fake.deployManifestMutex.RLock()
+	defer fake.deployManifestMutex.RUnlock()
+	fake.deployWithProgressMutex.RLock()
+	defer fake.deployWithProgressMutex.RUnlock()
+	fake.deploymentsMutex.RLock()
+	defer fake.deploymentsMutex.RUnlock()
+	fake.directorManifestDiffMutex.RLock()
+	defer fake.directorManifestDiffMutex.RUnlock()
+	fake.directorUUIDMutex.RLock()
+	defer fake.directorUUIDMutex.RUnlock()
+	fake.eventsMutex.RLock()
+	defer fake.eventsMutex.RUnlock()
+	fake.fetchLogsMutex.RLock()
+	defer fake.fetchLogsMutex.RUnlock()
+	fake.forceUnlockMutex.RLock()
+	defer fake.forceUnlockMutex.RUnlock()
+	fake.importStateMutex.RLock()
+	defer fake.importStateMutex.RUnlock()
+	fake.lastTaskOutputMutex.RLock()
+	defer fake.lastTaskOutputMutex.RUnlock()
 	fake.locksMutex.RLock()
 	defer fake.locksMutex.RUnlock()
+	fake.manifestFingerprintMutex.RLock()
+	defer fake.manifestFingerprintMutex.RUnlock()
+	fake.missingReleasesMutex.RLock()
+	defer fake.missingReleasesMutex.RUnlock()
+	fake.newSessionMutex.RLock()
+	defer fake.newSessionMutex.RUnlock()
+	fake.pauseMutex.RLock()
+	defer fake.pauseMutex.RUnlock()
+	fake.persistentDisksMutex.RLock()
+	defer fake.persistentDisksMutex.RUnlock()
 	fake.recreateMutex.RLock()
 	defer fake.recreateMutex.RUnlock()
+	fake.recreateEnvMutex.RLock()
+	defer fake.recreateEnvMutex.RUnlock()
+	fake.recreateFailingMutex.RLock()
+	defer fake.recreateFailingMutex.RUnlock()
+	fake.recreateInstanceMutex.RLock()
+	defer fake.recreateInstanceMutex.RUnlock()
+	fake.resumeMutex.RLock()
+	defer fake.resumeMutex.RUnlock()
+	fake.rotateDirectorCertMutex.RLock()
+	defer fake.rotateDirectorCertMutex.RUnlock()
 	fake.runAuthenticatedCommandMutex.RLock()
 	defer fake.runAuthenticatedCommandMutex.RUnlock()
 	fake.updateCloudConfigMutex.RLock()
 	defer fake.updateCloudConfigMutex.RUnlock()
 	fake.uploadConcourseStemcellMutex.RLock()
 	defer fake.uploadConcourseStemcellMutex.RUnlock()
+	fake.vMsMutex.RLock()
+	defer fake.vMsMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -0,0 +1,56 @@
+package boshcli
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	goyaml "gopkg.in/yaml.v2"
+)
+
+// yamlContextLines is the number of lines shown either side of the line a YAML error refers to
+const yamlContextLines = 2
+
+var yamlErrorLine = regexp.MustCompile(`line (\d+)`)
+
+// checkYAML returns an error describing where data is not valid YAML, so that a
+// template bug is reported before bosh fails to parse the file it is written to
+func checkYAML(name, data string) error {
+	var out interface{}
+	err := goyaml.Unmarshal([]byte(data), &out)
+	if err == nil {
+		return nil
+	}
+	message := fmt.Sprintf("%s is not valid YAML: [%v]", name, err)
+	if context := yamlContext(data, err); context != "" {
+		message += "\n" + context
+	}
+	return errors.New(message)
+}
+
+// yamlContext returns the lines of data around the line err refers to, marking that line
+func yamlContext(data string, err error) string {
+	match := yamlErrorLine.FindStringSubmatch(err.Error())
+	if match == nil {
+		return ""
+	}
+	line, _ := strconv.Atoi(match[1])
+	lines := strings.Split(data, "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	var b strings.Builder
+	for i := line - yamlContextLines; i <= line+yamlContextLines; i++ {
+		if i < 1 || i > len(lines) {
+			continue
+		}
+		marker := "  "
+		if i == line {
+			marker = "> "
+		}
+		fmt.Fprintf(&b, "%s%4d | %s\n", marker, i, lines[i-1])
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
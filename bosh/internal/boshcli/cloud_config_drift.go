@@ -0,0 +1,109 @@
+package boshcli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/pmezard/go-difflib/difflib"
+	goyaml "gopkg.in/yaml.v2"
+)
+
+// CloudConfigDrift compares the cloud config of the director with the one control-tower renders
+type CloudConfigDrift struct {
+	// Drifted is set when the cloud config of the director has been changed since control-tower last applied it
+	Drifted bool
+	// Diff is a unified diff from the cloud config of the director to the rendered one, empty when they match
+	Diff string
+}
+
+// CloudConfigDrift fetches the cloud config of the director at ip and compares it with the rendered one
+func (c *CLI) CloudConfigDrift(config IAASEnvironment, ip, password, ca string) (CloudConfigDrift, error) {
+	s, err := c.NewSession(config, ip, password, ca)
+	if err != nil {
+		return CloudConfigDrift{}, err
+	}
+	defer s.Close()
+	return s.CloudConfigDrift()
+}
+
+// CloudConfigDrift fetches the cloud config of the director and compares it with the rendered one.
+// Both are normalised first, so that formatting, comments and the order of keys are not reported as drift
+func (s *Session) CloudConfigDrift() (CloudConfigDrift, error) {
+	deployed, rendered, err := s.cloudConfigs()
+	if err != nil {
+		return CloudConfigDrift{}, err
+	}
+	if deployed == "" {
+		return CloudConfigDrift{}, errors.New("director has no cloud config")
+	}
+	diff, err := diffCloudConfigs(deployed, rendered)
+	if err != nil {
+		return CloudConfigDrift{}, err
+	}
+	return CloudConfigDrift{Drifted: diff != "", Diff: diff}, nil
+}
+
+// CloudConfigDiff renders the cloud config and returns a unified diff from the cloud config of the director
+// to it, without applying it. The diff is empty when they match, and adds the whole rendered cloud config when
+// the director has none
+func (s *Session) CloudConfigDiff() (string, error) {
+	deployed, rendered, err := s.cloudConfigs()
+	if err != nil {
+		return "", err
+	}
+	return diffCloudConfigs(deployed, rendered)
+}
+
+// cloudConfigs returns the normalised cloud configs of the director, empty when it has none, and of control-tower
+func (s *Session) cloudConfigs() (deployed, rendered string, err error) {
+	if rendered, err = s.config.ConfigureDirectorCloudConfig(); err != nil {
+		return "", "", err
+	}
+	var out bytes.Buffer
+	cmd := s.cli.command(append(s.queryFlags(), "cloud-config", "--json")...)
+	cmd.Stdout = &out
+	if err = s.cli.run(cmd); err != nil {
+		return "", "", err
+	}
+	var live struct {
+		Blocks []string
+	}
+	if err = json.Unmarshal(out.Bytes(), &live); err != nil {
+		return "", "", fmt.Errorf("failed to parse bosh cloud-config output: [%v]", err)
+	}
+	if len(live.Blocks) > 0 {
+		if deployed, err = normaliseYAML(live.Blocks[0]); err != nil {
+			return "", "", fmt.Errorf("failed to parse the cloud config of the director: [%v]", err)
+		}
+	}
+	if rendered, err = normaliseYAML(rendered); err != nil {
+		return "", "", fmt.Errorf("failed to parse the rendered cloud config: [%v]", err)
+	}
+	return deployed, rendered, nil
+}
+
+// diffCloudConfigs returns a unified diff from deployed to rendered, empty when they match
+func diffCloudConfigs(deployed, rendered string) (string, error) {
+	if deployed == rendered {
+		return "", nil
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(deployed),
+		B:        difflib.SplitLines(rendered),
+		FromFile: "deployed",
+		ToFile:   "rendered",
+		Context:  3,
+	})
+}
+
+// normaliseYAML re-marshals document, which sorts its keys and drops comments and formatting
+func normaliseYAML(document string) (string, error) {
+	var v interface{}
+	if err := goyaml.Unmarshal([]byte(document), &v); err != nil {
+		return "", err
+	}
+	b, err := goyaml.Marshal(v)
+	return string(b), err
+}
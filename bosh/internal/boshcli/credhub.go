@@ -0,0 +1,100 @@
+package boshcli
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	goyaml "gopkg.in/yaml.v2"
+)
+
+type credHubCredential struct {
+	Name  string      `yaml:"name"`
+	Type  string      `yaml:"type"`
+	Value interface{} `yaml:"value"`
+}
+
+// CredHubImport reads vars.yaml from the Store and returns it in the format of
+// credhub import, with every credential named under prefix
+func (c *CLI) CredHubImport(store Store, prefix string) ([]byte, error) {
+	varsFilename := c.varsFilename
+	data, err := store.Get(varsFilename)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%s not found in store, has the director been deployed?", varsFilename)
+	}
+	if c.passphrase != "" {
+		if data, err = decrypt(c.passphrase, data); err != nil {
+			return nil, fmt.Errorf("failed to read %s: [%v]", varsFilename, err)
+		}
+	}
+	var vars map[string]interface{}
+	if err = goyaml.Unmarshal(data, &vars); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: [%v]", varsFilename, err)
+	}
+	if len(vars) == 0 {
+		return nil, fmt.Errorf("%s holds no vars", varsFilename)
+	}
+
+	var names []string
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var export struct {
+		Credentials []credHubCredential `yaml:"credentials"`
+	}
+	for _, name := range names {
+		value := stringKeys(vars[name])
+		export.Credentials = append(export.Credentials, credHubCredential{
+			Name:  path.Join("/", prefix, name),
+			Type:  credHubType(value),
+			Value: value,
+		})
+	}
+	return goyaml.Marshal(export)
+}
+
+// credHubType returns the credhub type of a bosh variable from the keys of its value
+func credHubType(value interface{}) string {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		if _, ok := value.(string); ok {
+			return "password"
+		}
+		return "value"
+	}
+	has := func(key string) bool {
+		_, ok := m[key]
+		return ok
+	}
+	switch {
+	case has("certificate") && has("private_key"):
+		return "certificate"
+	case has("public_key_fingerprint"):
+		return "ssh"
+	case has("public_key") && has("private_key"):
+		return "rsa"
+	}
+	return "json"
+}
+
+// stringKeys converts the maps yaml.v2 unmarshals into maps keyed by strings
+func stringKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = stringKeys(value)
+		}
+		return m
+	case []interface{}:
+		for i := range v {
+			v[i] = stringKeys(v[i])
+		}
+	}
+	return value
+}
@@ -0,0 +1,180 @@
+package boshcli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// debugTailLines is the number of output lines included in the error of a failed command in debug mode
+const debugTailLines = 50
+
+// secretFlags are the flags whose values are replaced in the arguments included in the error of a failed command
+var secretFlags = []string{"--client-secret"}
+
+// command builds a bosh command, enabling debug logging when the CLI is in debug mode.
+// --tty is not added to commands producing --json output as it would corrupt it
+func (c *CLI) command(args ...string) *exec.Cmd {
+	if c.debug && !contains(args, "--json") {
+		args = append(args, "--tty")
+	}
+	cmd := c.execCmd(c.boshPath, args...)
+	if c.debug {
+		env := cmd.Env
+		if env == nil {
+			env = os.Environ()
+		}
+		cmd.Env = append(env, "BOSH_LOG_LEVEL=debug")
+	}
+	return cmd
+}
+
+// run runs cmd, returning a *CommandError categorised from the tail of its output when it fails.
+// The tail is included in the error message when the CLI is in debug mode
+func (c *CLI) run(cmd *exec.Cmd) error {
+	return c.runDetachable(cmd, nil)
+}
+
+// runDetachable runs cmd like run, but stops following it and returns nil once detach is closed
+func (c *CLI) runDetachable(cmd *exec.Cmd, detach <-chan struct{}) error {
+	tail := &tailWriter{max: debugTailLines}
+	cmd.Stdout = teeWriter(cmd.Stdout, tail)
+	cmd.Stderr = teeWriter(cmd.Stderr, tail)
+	if err := c.runUntilDone(cmd, detach); err != nil {
+		output := tail.String()
+		cmdErr := &CommandError{
+			Category: categorize(output),
+			Err:      err,
+			args:     redactArgs(cmd.Args[1:]),
+		}
+		if c.debug {
+			cmdErr.Output = output
+		}
+		return cmdErr
+	}
+	return nil
+}
+
+// runUntilDone runs cmd, killing it when the context of the CLI is done or detach is closed.
+// It returns nil when cmd was killed because detach was closed
+func (c *CLI) runUntilDone(cmd *exec.Cmd, detach <-chan struct{}) error {
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	exited := make(chan struct{})
+	defer close(exited)
+	go func() {
+		select {
+		case <-c.ctx.Done():
+			cmd.Process.Kill()
+		case <-detach:
+			cmd.Process.Kill()
+		case <-exited:
+		}
+	}()
+	err := cmd.Wait()
+	if ctxErr := c.ctx.Err(); err != nil && ctxErr != nil {
+		return fmt.Errorf("%v: [%v]", ctxErr, err)
+	}
+	select {
+	case <-detach:
+		return nil
+	default:
+	}
+	return err
+}
+
+// redactArgs joins args, replacing the values of secretFlags whether they are passed as
+// separate arguments or as --flag=value
+func redactArgs(args []string) string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		redacted[i] = arg
+		if i > 0 && contains(secretFlags, args[i-1]) {
+			redacted[i] = "[REDACTED]"
+			continue
+		}
+		for _, flag := range secretFlags {
+			if strings.HasPrefix(arg, flag+"=") {
+				redacted[i] = flag + "=[REDACTED]"
+			}
+		}
+	}
+	return strings.Join(redacted, " ")
+}
+
+func teeWriter(w io.Writer, tail io.Writer) io.Writer {
+	if w == nil {
+		return tail
+	}
+	return io.MultiWriter(w, tail)
+}
+
+// syncWriter serialises writes to w, for a writer shared by the stdout and stderr of a command
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+func contains(args []string, arg string) bool {
+	for _, a := range args {
+		if a == arg {
+			return true
+		}
+	}
+	return false
+}
+
+// tailWriter keeps the last max lines written to it. It is safe for concurrent use
+// as os/exec copies stdout and stderr from separate goroutines when they differ
+type tailWriter struct {
+	mu      sync.Mutex
+	max     int
+	lines   []string
+	partial []byte
+}
+
+func (t *tailWriter) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	data := append(t.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		t.lines = append(t.lines, string(data[:i]))
+		if len(t.lines) > t.max {
+			t.lines = t.lines[1:]
+		}
+		data = data[i+1:]
+	}
+	t.partial = append([]byte(nil), data...)
+	return len(p), nil
+}
+
+func (t *tailWriter) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	lines := t.lines
+	if len(t.partial) > 0 {
+		lines = append(append([]string(nil), lines...), string(t.partial))
+		if len(lines) > t.max {
+			lines = lines[1:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,37 @@
+package boshcli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	goyaml "gopkg.in/yaml.v2"
+)
+
+// DeployManifest deploys the concourse manifest at manifestPath as it is, without rendering it,
+// for manifests produced and committed outside of control-tower
+func (c *CLI) DeployManifest(config IAASEnvironment, ip, password, ca, manifestPath string) error {
+	s, err := c.NewSession(config, ip, password, ca)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	return s.DeployManifest(manifestPath)
+}
+
+// DeployManifest runs bosh deploy against the concourse deployment with the manifest at manifestPath,
+// which must be a YAML document. The --max-in-flight and --canaries options of the CLI are applied
+func (s *Session) DeployManifest(manifestPath string) error {
+	manifest, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return fmt.Errorf("failed to read manifest: [%v]", err)
+	}
+	var m map[string]interface{}
+	if err = goyaml.Unmarshal(manifest, &m); err != nil {
+		return fmt.Errorf("invalid manifest %s: [%v]", manifestPath, err)
+	}
+	if len(m) == 0 {
+		return fmt.Errorf("manifest %s is empty", manifestPath)
+	}
+	return s.RunAuthenticatedCommand("deploy", false, os.Stdout, manifestPath)
+}
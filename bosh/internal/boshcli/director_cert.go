@@ -0,0 +1,250 @@
+package boshcli
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/EngineerBetter/control-tower/certs"
+	goyaml "gopkg.in/yaml.v2"
+)
+
+// directorPort is the port of the director API
+const directorPort = "25555"
+
+// CertExpiryWarning is returned when the director certificate expires within the checked window
+type CertExpiryWarning struct {
+	Expiry time.Time
+}
+
+func (w *CertExpiryWarning) Error() string {
+	return fmt.Sprintf("director certificate expires on %s, in %s, rotate it before then", w.Expiry.Format(time.RFC3339), time.Until(w.Expiry).Round(time.Hour))
+}
+
+// CheckDirectorCert connects to the director at ip, which defaults to the director API port,
+// and verifies its certificate chain against ca. It returns the expiry of the director certificate
+// along with a *CertExpiryWarning when that is within window
+func CheckDirectorCert(ip, ca string, window time.Duration) (time.Time, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(ca)) {
+		return time.Time{}, errors.New("director CA is not a PEM encoded certificate")
+	}
+	addr := ip
+	if _, _, err := net.SplitHostPort(ip); err != nil {
+		addr = net.JoinHostPort(ip, directorPort)
+	}
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 10 * time.Second}, "tcp", addr, &tls.Config{
+		RootCAs: pool,
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to verify the director certificate at %s: [%v]", addr, err)
+	}
+	defer conn.Close()
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return time.Time{}, fmt.Errorf("director at %s presented no certificate", addr)
+	}
+	expiry := certs[0].NotAfter
+	if time.Until(expiry) < window {
+		return expiry, &CertExpiryWarning{Expiry: expiry}
+	}
+	return expiry, nil
+}
+
+// CertGenerator returns a new certificate, key and CA for the director, typically by calling certs.Generate
+type CertGenerator func() (*certs.Certs, error)
+
+// nextDirectorSSL is the vars.yaml entry holding the certificate staged by the first phase of a rotation
+const nextDirectorSSL = "director_ssl_next"
+
+// RotateDirectorCert replaces the director certificate, issued by oldCA, in two phases. The key of oldCA is
+// not kept, so the new certificate cannot be issued by it, and clients only trusting oldCA must be given the
+// new CA before the director presents a certificate issued by it.
+// The first call stages a certificate returned by generate in vars.yaml in the Store and re-runs create-env
+// with the current certificate and a CA bundle of the new CA and the first certificate of oldCA, so clients
+// still holding only oldCA trust the director. The next call, once the bundle has been rolled out, re-runs
+// create-env with the staged certificate and the same bundle, without calling generate. Both write the
+// director_ssl entry of vars.yaml and return the Certs the director then uses, which callers should persist
+// in place of the old ones
+func (c *CLI) RotateDirectorCert(store Store, config IAASEnvironment, password, oldCA string, tags map[string]string, generate CertGenerator) (*certs.Certs, error) {
+	if generate == nil {
+		return nil, errors.New("cert generator must not be nil")
+	}
+	vars, err := c.readVars(store)
+	if err != nil {
+		return nil, err
+	}
+	staged, ok := directorSSLEntry(vars, nextDirectorSSL)
+	if !ok {
+		return c.stageDirectorCert(store, vars, config, password, oldCA, tags, generate)
+	}
+	if err = c.CreateEnv(store, config, password, string(staged.Cert), string(staged.Key), string(staged.CACert), tags); err != nil {
+		return nil, err
+	}
+	if err = c.setDirectorSSL(store, staged, nil); err != nil {
+		return nil, err
+	}
+	return staged, nil
+}
+
+// stageDirectorCert is the first phase of RotateDirectorCert, which adds the CA of a generated certificate to
+// the CA bundle of the director while it keeps presenting its current certificate
+func (c *CLI) stageDirectorCert(store Store, vars goyaml.MapSlice, config IAASEnvironment, password, oldCA string, tags map[string]string, generate CertGenerator) (*certs.Certs, error) {
+	generated, err := generate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the director certificate: [%v]", err)
+	}
+	if _, err = tls.X509KeyPair(generated.Cert, generated.Key); err != nil {
+		return nil, fmt.Errorf("generated director certificate is invalid: [%v]", err)
+	}
+	ca, err := caBundle(generated.CACert, []byte(oldCA))
+	if err != nil {
+		return nil, err
+	}
+	current, ok := directorSSLEntry(vars, "director_ssl")
+	if !ok {
+		return nil, fmt.Errorf("%s has no current director certificate", c.varsFilename)
+	}
+	trusted := &certs.Certs{CACert: ca, Key: current.Key, Cert: current.Cert}
+	if err = c.CreateEnv(store, config, password, string(trusted.Cert), string(trusted.Key), string(trusted.CACert), tags); err != nil {
+		return nil, err
+	}
+	next := &certs.Certs{CACert: ca, Key: generated.Key, Cert: generated.Cert}
+	if err = c.setDirectorSSL(store, trusted, next); err != nil {
+		return nil, err
+	}
+	return trusted, nil
+}
+
+// directorSSLEntry returns the certificate held in the name entry of vars, if it has one
+func directorSSLEntry(vars goyaml.MapSlice, name string) (*certs.Certs, bool) {
+	for _, item := range vars {
+		if item.Key != name {
+			continue
+		}
+		entry, ok := item.Value.(goyaml.MapSlice)
+		if !ok {
+			return nil, false
+		}
+		found := &certs.Certs{}
+		for _, field := range entry {
+			value, _ := field.Value.(string)
+			switch field.Key {
+			case "ca":
+				found.CACert = []byte(value)
+			case "certificate":
+				found.Cert = []byte(value)
+			case "private_key":
+				found.Key = []byte(value)
+			}
+		}
+		return found, len(found.Cert) > 0 && len(found.Key) > 0
+	}
+	return nil, false
+}
+
+// caBundle returns newCA followed by the first certificate of oldCA, which issued the current director
+// certificate. Only that one is kept so the bundle does not grow with every rotation
+func caBundle(newCA, oldCA []byte) ([]byte, error) {
+	if !x509.NewCertPool().AppendCertsFromPEM(newCA) {
+		return nil, errors.New("generated director CA is not a PEM encoded certificate")
+	}
+	bundle := strings.TrimSpace(string(newCA)) + "\n"
+	if len(strings.TrimSpace(string(oldCA))) == 0 {
+		return []byte(bundle), nil
+	}
+	block, _ := pem.Decode(oldCA)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("current director CA is not a PEM encoded certificate")
+	}
+	old := string(pem.EncodeToMemory(block))
+	if strings.Contains(bundle, strings.TrimSpace(old)) {
+		return []byte(bundle), nil
+	}
+	return []byte(bundle + old), nil
+}
+
+// readVars returns vars.yaml from the Store, decrypting it when a passphrase is set
+func (c *CLI) readVars(store Store) (goyaml.MapSlice, error) {
+	varsFilename := c.varsFilename
+	data, err := store.Get(varsFilename)
+	if err != nil {
+		return nil, err
+	}
+	if c.passphrase != "" && len(data) > 0 {
+		if data, err = decrypt(c.passphrase, data); err != nil {
+			return nil, fmt.Errorf("failed to read %s: [%v]", varsFilename, err)
+		}
+	}
+	var vars goyaml.MapSlice
+	if err = goyaml.Unmarshal(data, &vars); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: [%v]", varsFilename, err)
+	}
+	return vars, nil
+}
+
+// setDirectorSSL writes current to the director_ssl entry of vars.yaml in the Store and next, when set, to the
+// nextDirectorSSL entry, which is removed otherwise. Other vars are left as they are
+func (c *CLI) setDirectorSSL(store Store, current, next *certs.Certs) error {
+	vars, err := c.readVars(store)
+	if err != nil {
+		return err
+	}
+	vars = setVar(vars, "director_ssl", directorSSLValue(current))
+	if next != nil {
+		vars = setVar(vars, nextDirectorSSL, directorSSLValue(next))
+	} else {
+		vars = deleteVar(vars, nextDirectorSSL)
+	}
+	varsFilename := c.varsFilename
+	data, err := goyaml.Marshal(vars)
+	if err != nil {
+		return err
+	}
+	if c.passphrase != "" {
+		if data, err = encrypt(c.passphrase, data); err != nil {
+			return err
+		}
+	}
+	if err = store.Set(varsFilename, data); err != nil {
+		return fmt.Errorf("failed to write %s: [%v]", varsFilename, err)
+	}
+	if !c.verifyUploads {
+		return nil
+	}
+	return verifyUpload(store, varsFilename, data)
+}
+
+func directorSSLValue(c *certs.Certs) goyaml.MapSlice {
+	return goyaml.MapSlice{
+		{Key: "ca", Value: string(c.CACert)},
+		{Key: "certificate", Value: string(c.Cert)},
+		{Key: "private_key", Value: string(c.Key)},
+	}
+}
+
+// setVar replaces the value of key in vars, appending it when vars has no such key
+func setVar(vars goyaml.MapSlice, key string, value interface{}) goyaml.MapSlice {
+	for i, item := range vars {
+		if item.Key == key {
+			vars[i].Value = value
+			return vars
+		}
+	}
+	return append(vars, goyaml.MapItem{Key: key, Value: value})
+}
+
+func deleteVar(vars goyaml.MapSlice, key string) goyaml.MapSlice {
+	kept := vars[:0]
+	for _, item := range vars {
+		if item.Key != key {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,25 @@
+package boshcli
+
+import "errors"
+
+// DirectorUUID returns the UUID of the director at ip
+func (c *CLI) DirectorUUID(config IAASEnvironment, ip, password, ca string) (string, error) {
+	s, err := c.NewSession(config, ip, password, ca)
+	if err != nil {
+		return "", err
+	}
+	defer s.Close()
+	return s.DirectorUUID()
+}
+
+// DirectorUUID returns the UUID of the director, as reported by bosh env
+func (s *Session) DirectorUUID() (string, error) {
+	env, err := s.directorEnv()
+	if err != nil {
+		return "", err
+	}
+	if env.UUID == "" {
+		return "", errors.New("bosh env output holds no director UUID")
+	}
+	return env.UUID, nil
+}
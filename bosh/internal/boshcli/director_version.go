@@ -0,0 +1,85 @@
+package boshcli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// VersionSkewWarning is returned when the deployed director is not running the BOSH release
+// version control-tower expects, usually because create-env has not been run since an upgrade
+type VersionSkewWarning struct {
+	Deployed string
+	Expected string
+}
+
+func (w *VersionSkewWarning) Error() string {
+	return fmt.Sprintf("director is running BOSH %s but control-tower expects %s, redeploy to upgrade it", w.Deployed, w.Expected)
+}
+
+// CheckDirectorVersion returns the BOSH version of the director at ip
+// along with a *VersionSkewWarning when it is not the expected version
+func (c *CLI) CheckDirectorVersion(ip, password, ca string) (string, error) {
+	s, err := c.NewSession(nil, ip, password, ca)
+	if err != nil {
+		return "", err
+	}
+	defer s.Close()
+	return s.CheckDirectorVersion()
+}
+
+// CheckDirectorVersion returns the BOSH version of the director
+// along with a *VersionSkewWarning when it is not the expected version
+func (s *Session) CheckDirectorVersion() (string, error) {
+	env, err := s.directorEnv()
+	if err != nil {
+		return "", err
+	}
+	// bosh env reports the version followed by the commit it was built from, eg 270.2.0 (00000000)
+	fields := strings.Fields(env.Version)
+	if len(fields) == 0 {
+		return "", errors.New("bosh env output holds no director version")
+	}
+	deployed := fields[0]
+	bosh, _ := s.cli.releases()
+	expected := bosh.Version
+	if deployed != expected {
+		return deployed, &VersionSkewWarning{Deployed: deployed, Expected: expected}
+	}
+	return deployed, nil
+}
+
+// directorEnv is the director described by bosh env --json
+type directorEnv struct {
+	UUID    string `json:"uuid"`
+	Version string `json:"version"`
+}
+
+// directorEnv runs bosh env against the director
+func (s *Session) directorEnv() (directorEnv, error) {
+	var out bytes.Buffer
+	cmd := s.cli.command(append(s.queryFlags(), "env", "--json")...)
+	cmd.Stdout = &out
+	if err := s.cli.run(cmd); err != nil {
+		return directorEnv{}, err
+	}
+	return parseDirectorEnv(out.Bytes())
+}
+
+// parseDirectorEnv returns the director from the output of bosh env --json
+func parseDirectorEnv(data []byte) (directorEnv, error) {
+	var env struct {
+		Tables []struct {
+			Rows []directorEnv
+		}
+	}
+	if err := json.Unmarshal(data, &env); err != nil {
+		return directorEnv{}, fmt.Errorf("failed to parse bosh env output: [%v]", err)
+	}
+	if len(env.Tables) == 0 || len(env.Tables[0].Rows) == 0 {
+		return directorEnv{}, errors.New("bosh env output holds no director")
+	}
+	return env.Tables[0].Rows[0], nil
+}
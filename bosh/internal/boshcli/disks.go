@@ -0,0 +1,46 @@
+package boshcli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// PersistentDisk is a persistent disk attached to an instance of the concourse deployment
+type PersistentDisk struct {
+	Instance string
+	CID      string
+}
+
+// PersistentDisks runs bosh instances --details against the concourse deployment and returns
+// the persistent disks of its instances, whose CIDs are the IDs of the disks on the IAAS
+func (s *Session) PersistentDisks() ([]PersistentDisk, error) {
+	var out bytes.Buffer
+	cmd := s.cli.command(append(s.queryFlags(), "--deployment", "concourse", "instances", "--details", "--json")...)
+	cmd.Stdout = &out
+	if err := s.cli.run(cmd); err != nil {
+		return nil, err
+	}
+	var instances struct {
+		Tables []struct {
+			Rows []struct {
+				Instance string `json:"instance"`
+				DiskCIDs string `json:"disk_cids"`
+			}
+		}
+	}
+	if err := json.Unmarshal(out.Bytes(), &instances); err != nil {
+		return nil, fmt.Errorf("failed to parse bosh instances output: [%v]", err)
+	}
+	var disks []PersistentDisk
+	for _, table := range instances.Tables {
+		for _, row := range table.Rows {
+			// Instances with several disks list them one per line
+			for _, cid := range strings.Fields(strings.Replace(row.DiskCIDs, ",", " ", -1)) {
+				disks = append(disks, PersistentDisk{Instance: row.Instance, CID: cid})
+			}
+		}
+	}
+	return disks, nil
+}
@@ -0,0 +1,73 @@
+package boshcli
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// encryptedPrefix marks data encrypted with a passphrase, so that plaintext
+// written before encryption was enabled can still be read
+var encryptedPrefix = []byte("control-tower-aes-gcm-v1:")
+
+const (
+	saltSize         = 16
+	keySize          = 32
+	pbkdf2Iterations = 100000
+)
+
+func gcmForPassphrase(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key := pbkdf2.Key([]byte(passphrase), salt, pbkdf2Iterations, keySize, sha256.New)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encrypt(passphrase string, plaintext []byte) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	gcm, err := gcmForPassphrase(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	out := append(append(append([]byte{}, encryptedPrefix...), salt...), nonce...)
+	return gcm.Seal(out, nonce, plaintext, nil), nil
+}
+
+func decrypt(passphrase string, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, encryptedPrefix) {
+		return data, nil
+	}
+	data = data[len(encryptedPrefix):]
+	if len(data) < saltSize {
+		return nil, errors.New("encrypted data is too short")
+	}
+	salt, data := data[:saltSize], data[saltSize:]
+	gcm, err := gcmForPassphrase(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("encrypted data is too short")
+	}
+	nonce, data := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, data, nil)
+	if err != nil {
+		return nil, errors.New("failed to decrypt, the passphrase may be wrong")
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,63 @@
+package boshcli
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// Categories of bosh command failure, matched with errors.Is on the error returned by a failed command
+var (
+	ErrAuthFailed    = errors.New("bosh authentication failed")
+	ErrLockHeld      = errors.New("bosh deployment lock held")
+	ErrQuotaExceeded = errors.New("IAAS quota exceeded")
+	ErrUnknown       = errors.New("bosh command failed")
+)
+
+var errorCategories = []struct {
+	category error
+	pattern  *regexp.Regexp
+}{
+	{ErrAuthFailed, regexp.MustCompile(`(?i)bad credentials|unauthorized|invalid_client|invalid_token|not authorized`)},
+	{ErrLockHeld, regexp.MustCompile(`(?i)is already locked|failed to acquire lock|timed out getting [a-z ]*lock`)},
+	{ErrQuotaExceeded, regexp.MustCompile(`(?i)[a-z]+limitexceeded|quota_exceeded|quota '[^']*' exceeded|quota exceeded`)},
+}
+
+// CommandError is returned when a bosh command exits unsuccessfully
+type CommandError struct {
+	// Category is one of ErrAuthFailed, ErrLockHeld, ErrQuotaExceeded or ErrUnknown
+	Category error
+	// Err is the error returned running the command
+	Err error
+	// Output holds the tail of the command output when the CLI is in debug mode
+	Output string
+
+	args string
+}
+
+func (e *CommandError) Error() string {
+	if e.Output != "" {
+		return fmt.Sprintf("bosh %s failed: [%v], last %d lines of output:\n%s", e.args, e.Err, debugTailLines, e.Output)
+	}
+	return fmt.Sprintf("%v: [%v]", e.Category, e.Err)
+}
+
+// Is reports whether target is the category of the failure
+func (e *CommandError) Is(target error) bool {
+	return target == e.Category
+}
+
+// Unwrap returns the error returned running the command
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// categorize returns the category of failure described by the output of a bosh command
+func categorize(output string) error {
+	for _, c := range errorCategories {
+		if c.pattern.MatchString(output) {
+			return c.category
+		}
+	}
+	return ErrUnknown
+}
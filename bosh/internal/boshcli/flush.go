@@ -0,0 +1,81 @@
+package boshcli
+
+import (
+	"bytes"
+	"io"
+)
+
+// FlushLines returns an Option that writes the output of the commands run against the concourse
+// deployment a line at a time, flushing the writer after each line when it has a Flush method,
+// so that output piped to another process or streamed to a UI is not held back by its buffering
+func FlushLines() Option {
+	return func(c *CLI) error {
+		c.flushLines = true
+		return nil
+	}
+}
+
+// lineFlushWriter holds output back until a line is complete, then writes and flushes it
+type lineFlushWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func (l *lineFlushWriter) Write(p []byte) (int, error) {
+	l.buf = append(l.buf, p...)
+	i := bytes.LastIndexByte(l.buf, '\n')
+	if i < 0 {
+		return len(p), nil
+	}
+	if err := l.write(l.buf[:i+1]); err != nil {
+		return 0, err
+	}
+	l.buf = l.buf[i+1:]
+	return len(p), nil
+}
+
+// Close writes and flushes what is left of an unterminated last line
+func (l *lineFlushWriter) Close() error {
+	if len(l.buf) == 0 {
+		return nil
+	}
+	defer func() { l.buf = nil }()
+	return l.write(l.buf)
+}
+
+func (l *lineFlushWriter) write(p []byte) error {
+	for len(p) > 0 {
+		line := p
+		if i := bytes.IndexByte(p, '\n'); i >= 0 {
+			line = p[:i+1]
+		}
+		if _, err := l.w.Write(line); err != nil {
+			return err
+		}
+		if err := flush(l.w); err != nil {
+			return err
+		}
+		p = p[len(line):]
+	}
+	return nil
+}
+
+// flush flushes w when it buffers its output, such as a *bufio.Writer or an http.ResponseWriter
+func flush(w io.Writer) error {
+	switch f := w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case interface{ Flush() }:
+		f.Flush()
+	}
+	return nil
+}
+
+// flushLinesTo wraps stdout in a lineFlushWriter when FlushLines is set, returning a func writing the rest of the output
+func (c *CLI) flushLinesTo(stdout io.Writer) (io.Writer, func() error) {
+	if !c.flushLines || stdout == nil {
+		return stdout, func() error { return nil }
+	}
+	l := &lineFlushWriter{w: stdout}
+	return l, l.Close
+}
@@ -0,0 +1,109 @@
+package boshcli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Outcomes of the deploy actions reported in an Event
+const (
+	OutcomeStarted   = "started"
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+)
+
+// Event describes a deploy action starting or ending. Duration is only set
+// once the action has ended and is measured in seconds
+type Event struct {
+	Action      string  `json:"action"`
+	Environment string  `json:"environment"`
+	Outcome     string  `json:"outcome"`
+	Duration    float64 `json:"duration,omitempty"`
+}
+
+// Hook is notified when create-env, delete-env and the commands run against
+// the concourse deployment start and end
+type Hook interface {
+	Emit(event Event) error
+}
+
+// EventHook returns an Option emitting the deploy events of environment to hook
+func EventHook(environment string, hook Hook) Option {
+	return func(c *CLI) error {
+		if hook == nil {
+			return errors.New("event hook must not be nil")
+		}
+		c.hook = hook
+		c.environment = environment
+		return nil
+	}
+}
+
+// Webhook is a Hook POSTing each Event as JSON to a URL
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook returns a Webhook POSTing events to rawURL
+func NewWebhook(rawURL string) (*Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("webhook URL %q must be an absolute http or https URL", rawURL)
+	}
+	return &Webhook{
+		url:    rawURL,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Emit POSTs event to the webhook URL
+func (w *Webhook) Emit(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// emit reports action as started and returns a func reporting its outcome.
+// A failure to emit an event is printed as a warning rather than failing the action
+func (c *CLI) emit(action string) func(error) {
+	if c.hook == nil {
+		return func(error) {}
+	}
+	start := time.Now()
+	c.emitEvent(Event{Action: action, Environment: c.environment, Outcome: OutcomeStarted})
+	return func(err error) {
+		outcome := OutcomeSucceeded
+		if err != nil {
+			outcome = OutcomeFailed
+		}
+		c.emitEvent(Event{
+			Action:      action,
+			Environment: c.environment,
+			Outcome:     outcome,
+			Duration:    time.Since(start).Seconds(),
+		})
+	}
+}
+
+func (c *CLI) emitEvent(event Event) {
+	if err := c.hook.Emit(event); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: failed to emit %s %s event: [%v]\n", event.Action, event.Outcome, err)
+	}
+}
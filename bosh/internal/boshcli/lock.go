@@ -0,0 +1,78 @@
+package boshcli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// lockFilename is the Store key of the lock held while the director is created or deleted
+const lockFilename = "deploy.lock"
+
+// defaultLockTTL is the age after which a lock is considered abandoned and may be taken over
+const defaultLockTTL = 3 * time.Hour
+
+type deployLock struct {
+	Owner    string    `json:"owner"`
+	Acquired time.Time `json:"acquired"`
+}
+
+// DeployLockTTL returns an Option setting the age after which a deploy lock left
+// behind by another run is considered stale and is taken over
+func DeployLockTTL(ttl time.Duration) Option {
+	return func(c *CLI) error {
+		if ttl <= 0 {
+			return fmt.Errorf("deploy lock TTL must be positive, got %s", ttl)
+		}
+		c.lockTTL = ttl
+		return nil
+	}
+}
+
+// lock acquires the advisory deploy lock in store, failing when another run holds
+// a lock that is not stale. The Store offers no atomic operations so two runs starting
+// at the same moment may both acquire it; the lock guards against the common case of
+// a second operator running control-tower while a deploy is in progress
+func (c *CLI) lock(store Store) (unlock func() error, err error) {
+	data, err := store.Get(lockFilename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: [%v]", lockFilename, err)
+	}
+	if len(data) > 0 {
+		var held deployLock
+		if err = json.Unmarshal(data, &held); err != nil {
+			return nil, fmt.Errorf("failed to parse %s, delete it from the store to release the lock: [%v]", lockFilename, err)
+		}
+		if age := time.Since(held.Acquired); age < c.lockTTL {
+			return nil, fmt.Errorf("environment is locked by %s since %s, wait for it to finish or delete %s from the store if it has died",
+				held.Owner, held.Acquired.Format(time.RFC3339), lockFilename)
+		}
+	}
+	data, err = json.Marshal(deployLock{Owner: lockOwner(), Acquired: time.Now().UTC()})
+	if err != nil {
+		return nil, err
+	}
+	if err = store.Set(lockFilename, data); err != nil {
+		return nil, fmt.Errorf("failed to write %s: [%v]", lockFilename, err)
+	}
+	return func() error {
+		return c.ForceUnlock(store)
+	}, nil
+}
+
+// ForceUnlock releases the deploy lock in store regardless of who holds it
+func (c *CLI) ForceUnlock(store Store) error {
+	if err := store.Set(lockFilename, []byte{}); err != nil {
+		return fmt.Errorf("failed to release %s: [%v]", lockFilename, err)
+	}
+	return nil
+}
+
+func lockOwner() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown host"
+	}
+	return fmt.Sprintf("%s (pid %d)", hostname, os.Getpid())
+}
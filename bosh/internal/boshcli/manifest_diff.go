@@ -0,0 +1,57 @@
+package boshcli
+
+import (
+	"fmt"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// manifestFilename is the Store key of the director manifest last deployed by create-env.
+// The bosh state only records a checksum of the manifest, so it is kept alongside it
+const manifestFilename = "director-manifest.yml"
+
+// recordDirectorManifest stores manifest as the last deployed director manifest,
+// encrypting it when a passphrase is set as it contains the admin password
+func (c *CLI) recordDirectorManifest(store Store, manifest string) error {
+	data := []byte(manifest)
+	if c.passphrase != "" && len(data) > 0 {
+		var err error
+		if data, err = encrypt(c.passphrase, data); err != nil {
+			return fmt.Errorf("failed to encrypt %s: [%v]", manifestFilename, err)
+		}
+	}
+	if err := store.Set(manifestFilename, data); err != nil {
+		return fmt.Errorf("failed to write %s: [%v]", manifestFilename, err)
+	}
+	return nil
+}
+
+// DirectorManifestDiff renders the director manifest for config and returns a unified diff
+// against the manifest last deployed by create-env. The diff is empty when they are the same
+func (c *CLI) DirectorManifestDiff(store Store, config IAASEnvironment, password, cert, key, ca string, tags map[string]string) (string, error) {
+	current, err := store.Get(manifestFilename)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: [%v]", manifestFilename, err)
+	}
+	if len(current) == 0 {
+		return "", fmt.Errorf("%s not found in store, has the director been deployed by this version of control-tower?", manifestFilename)
+	}
+	if c.passphrase != "" {
+		if current, err = decrypt(c.passphrase, current); err != nil {
+			return "", fmt.Errorf("failed to read %s: [%v]", manifestFilename, err)
+		}
+	}
+
+	manifest, err := c.directorManifest(config, password, cert, key, ca, tags)
+	if err != nil {
+		return "", err
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(current)),
+		B:        difflib.SplitLines(manifest),
+		FromFile: "deployed",
+		ToFile:   "rendered",
+		Context:  3,
+	})
+}
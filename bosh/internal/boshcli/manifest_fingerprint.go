@@ -0,0 +1,72 @@
+package boshcli
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+
+	goyaml "gopkg.in/yaml.v2"
+)
+
+// secretDigestKey keys the HMAC each secret is replaced with. It only keeps the digests apart from
+// plain SHA256s of the secrets, the fingerprint is not meant to be kept secret
+var secretDigestKey = []byte("control-tower manifest fingerprint")
+
+// secretKey matches the manifest keys whose values are secrets, such as password,
+// secret_access_key, private_key, gcp_credentials_json and json_key
+var secretKey = regexp.MustCompile(`(?i)(password|secret|private_key|credentials|(^|_)key$)`)
+
+// ManifestFingerprint renders the director manifest for config and returns the hex SHA256 of it with its keys
+// sorted and each secret replaced by its digest, so that a pipeline can skip create-env when the fingerprint is
+// unchanged. Rotating a secret, such as password or key, changes the fingerprint
+func (c *CLI) ManifestFingerprint(config IAASEnvironment, password, cert, key, ca string, tags map[string]string) (string, error) {
+	manifest, err := c.directorManifest(config, password, cert, key, ca, tags)
+	if err != nil {
+		return "", err
+	}
+	return fingerprintManifest(manifest)
+}
+
+// fingerprintManifest re-marshals manifest with its secrets replaced by their digests, which sorts its keys and drops
+// comments and formatting, and returns the hex SHA256 of the result
+func fingerprintManifest(manifest string) (string, error) {
+	var v interface{}
+	if err := goyaml.Unmarshal([]byte(manifest), &v); err != nil {
+		return "", fmt.Errorf("failed to parse the director manifest: [%v]", err)
+	}
+	b, err := goyaml.Marshal(redactSecrets(v))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// redactSecrets replaces the value of every key matching secretKey in v, at any depth, with an HMAC of it
+func redactSecrets(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		for k, value := range v {
+			if name, ok := k.(string); ok && secretKey.MatchString(name) {
+				v[k] = secretDigest(value)
+				continue
+			}
+			v[k] = redactSecrets(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redactSecrets(value)
+		}
+	}
+	return v
+}
+
+// secretDigest returns the hex HMAC-SHA256 of a secret value. fmt prints maps with their keys sorted, so
+// structured secrets have a stable digest too
+func secretDigest(value interface{}) string {
+	mac := hmac.New(sha256.New, secretDigestKey)
+	fmt.Fprint(mac, value)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,37 @@
+package boshcli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MigrateStore copies keys from src to dst, such as the bosh state and vars when moving an
+// environment to another bucket, region or IAAS. Keys absent from src are skipped. Each copy is
+// read back from dst and checked, and an error reports the keys already copied before the failure
+func MigrateStore(src, dst Store, keys []string) error {
+	var copied []string
+	for _, key := range keys {
+		if err := migrateKey(src, dst, key); err != nil {
+			if len(copied) == 0 {
+				return err
+			}
+			return fmt.Errorf("%v, after copying [%s]", err, strings.Join(copied, ", "))
+		}
+		copied = append(copied, key)
+	}
+	return nil
+}
+
+func migrateKey(src, dst Store, key string) error {
+	data, err := src.Get(key)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: [%v]", key, err)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	if err = dst.Set(key, data); err != nil {
+		return fmt.Errorf("failed to write %s: [%v]", key, err)
+	}
+	return verifyUpload(dst, key, data)
+}
@@ -0,0 +1,115 @@
+package boshcli
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+)
+
+// ProgressEvent is a step of a bosh task, parsed from the output of the command running it
+type ProgressEvent struct {
+	// Stage is the kind of step, such as Compiling packages or Updating instance
+	Stage string
+	// Task is what the step acts on, such as a package or an instance
+	Task string
+	// Percent is the share of the steps started in Stage which are done
+	Percent int
+	// Done is set when the step has finished
+	Done bool
+}
+
+// progressLine matches a step in the output of a bosh task. Steps are printed when they
+// start and again with their duration when they finish
+var progressLine = regexp.MustCompile(`^Task \d+ \| \d{2}:\d{2}:\d{2} \| ([^:]+): (.+?)(?: \((\d{2}:\d{2}:\d{2})\))?$`)
+
+// parseProgress sends an event to events for each step in the bosh output read from r
+func parseProgress(r io.Reader, events chan<- ProgressEvent) {
+	type count struct{ started, done int }
+	stages := map[string]*count{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		m := progressLine.FindStringSubmatch(scanner.Text())
+		if m == nil || m[1] == "Error" {
+			continue
+		}
+		c := stages[m[1]]
+		if c == nil {
+			c = &count{}
+			stages[m[1]] = c
+		}
+		done := m[3] != ""
+		if done {
+			c.done++
+			// Steps too quick to be printed when they start are only printed when done
+			if c.done > c.started {
+				c.started = c.done
+			}
+		} else {
+			c.started++
+		}
+		events <- ProgressEvent{Stage: m[1], Task: m[2], Percent: c.done * 100 / c.started, Done: done}
+	}
+	// Drain the rest of the output so that the command is not blocked writing it
+	io.Copy(ioutil.Discard, r)
+}
+
+// DeployWithProgress runs bosh deploy against the concourse deployment on the director at ip, sending
+// its steps to the returned events channel, which must be drained. The channel is closed once the command
+// has exited, after its outcome is sent to the returned error channel
+func (c *CLI) DeployWithProgress(ip, password, ca string, flags ...string) (<-chan ProgressEvent, <-chan error) {
+	return c.progress(func(stdout io.Writer) error {
+		s, err := c.NewSession(nil, ip, password, ca)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		return s.deployWithProgress(stdout, flags...)
+	})
+}
+
+// DeployWithProgress runs bosh deploy against the concourse deployment, sending its steps to the returned
+// events channel, which must be drained. The channel is closed once the command has exited, after its
+// outcome is sent to the returned error channel
+func (s *Session) DeployWithProgress(flags ...string) (<-chan ProgressEvent, <-chan error) {
+	return s.cli.progress(func(stdout io.Writer) error {
+		return s.deployWithProgress(stdout, flags...)
+	})
+}
+
+func (s *Session) deployWithProgress(stdout io.Writer, flags ...string) (err error) {
+	done := s.cli.emit("deploy")
+	defer func() { done(err) }()
+
+	log, stderr, closeTee := s.cli.tee(nil, os.Stderr)
+	defer closeTee()
+	cmd := s.cli.command(s.authenticatedFlags("deploy", flags)...)
+	cmd.Stderr = stderr
+	cmd.Stdout = stdout
+	if log != nil {
+		cmd.Stdout = io.MultiWriter(stdout, log)
+	}
+	return s.cli.run(cmd)
+}
+
+// progress runs a command with run, parsing the output it writes into events
+func (c *CLI) progress(run func(stdout io.Writer) error) (<-chan ProgressEvent, <-chan error) {
+	events := make(chan ProgressEvent)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(errc)
+		defer close(events)
+		r, w := io.Pipe()
+		parsed := make(chan struct{})
+		go func() {
+			parseProgress(r, events)
+			close(parsed)
+		}()
+		err := run(w)
+		w.Close()
+		<-parsed
+		errc <- err
+	}()
+	return events, errc
+}
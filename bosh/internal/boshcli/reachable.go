@@ -0,0 +1,66 @@
+package boshcli
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// Failures of CheckATCReachable, matched with errors.Is
+var (
+	ErrATCConnectionRefused = errors.New("ATC refused the connection")
+	ErrATCTLS               = errors.New("ATC TLS handshake failed")
+)
+
+// reachableTimeout bounds the request made by CheckATCReachable
+var reachableTimeout = 10 * time.Second
+
+// CheckATCReachable makes an HTTPS GET to atcURL, such as the URL of ConcourseCredentials, trusting only
+// ca, the CA of the deployment. It succeeds when the ATC presents a certificate valid for atcURL and
+// responds with a 2xx or 3xx status. Redirects are not followed. A refused connection is reported as
+// ErrATCConnectionRefused and a handshake or certificate failure as ErrATCTLS
+func CheckATCReachable(atcURL, ca string) error {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(ca)) {
+		return errors.New("failed to parse the deployment CA")
+	}
+	client := &http.Client{
+		Timeout:   reachableTimeout,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(atcURL)
+	if err != nil {
+		return reachableError(atcURL, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("ATC at %s responded with %s", atcURL, resp.Status)
+	}
+	return nil
+}
+
+// reachableError categorises the failure of the request made by CheckATCReachable
+func reachableError(atcURL string, err error) error {
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		hostname         x509.HostnameError
+		invalid          x509.CertificateInvalidError
+		recordHeader     tls.RecordHeaderError
+		alert            tls.AlertError
+	)
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return fmt.Errorf("%w at %s: [%v]", ErrATCConnectionRefused, atcURL, err)
+	case errors.As(err, &unknownAuthority), errors.As(err, &hostname), errors.As(err, &invalid),
+		errors.As(err, &recordHeader), errors.As(err, &alert):
+		return fmt.Errorf("%w at %s: [%v]", ErrATCTLS, atcURL, err)
+	}
+	return fmt.Errorf("failed to reach the ATC at %s: [%v]", atcURL, err)
+}
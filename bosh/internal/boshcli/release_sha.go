@@ -0,0 +1,87 @@
+package boshcli
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/EngineerBetter/control-tower/resource"
+)
+
+// Downloader opens the release tarball at url for reading
+type Downloader func(url string) (io.ReadCloser, error)
+
+// VerifyReleases returns an Option that downloads the BOSH and BPM releases before create-env
+// and checks them against their expected SHA1, catching tampered or corrupt mirrors.
+// download defaults to an HTTP GET when nil. Air-gapped setups can leave the Option out
+func VerifyReleases(download Downloader) Option {
+	return func(c *CLI) error {
+		if download == nil {
+			download = httpDownload
+		}
+		c.downloadRelease = download
+		return nil
+	}
+}
+
+func httpDownload(url string) (io.ReadCloser, error) {
+	client := &http.Client{Timeout: 10 * time.Minute}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s responded with %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// releases returns the BOSH and BPM releases deployed to the director, honouring any overrides
+func (c *CLI) releases() (bosh, bpm resource.Resource) {
+	bosh = resource.Get(resource.BOSHRelease)
+	if c.boshRelease != nil {
+		bosh = *c.boshRelease
+	}
+	bpm = resource.Get(resource.BPMRelease)
+	if c.bpmRelease != nil {
+		bpm = *c.bpmRelease
+	}
+	return bosh, bpm
+}
+
+// verifyReleases checks the SHA1 of the releases referenced by the director manifest
+func (c *CLI) verifyReleases() error {
+	bosh, bpm := c.releases()
+	for _, r := range []struct {
+		name    string
+		release resource.Resource
+	}{
+		{"bosh", bosh},
+		{"bpm", bpm},
+	} {
+		if err := verifyReleaseSHA1(c.downloadRelease, r.name, r.release); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyReleaseSHA1(download Downloader, name string, r resource.Resource) error {
+	body, err := download(r.URL)
+	if err != nil {
+		return fmt.Errorf("failed to download %s release from %s: [%v]", name, r.URL, err)
+	}
+	defer body.Close()
+	h := sha1.New()
+	if _, err = io.Copy(h, body); err != nil {
+		return fmt.Errorf("failed to download %s release from %s: [%v]", name, r.URL, err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != r.SHA1 {
+		return fmt.Errorf("%s release %s downloaded from %s has SHA1 %s but %s was expected", name, r.Version, r.URL, got, r.SHA1)
+	}
+	return nil
+}
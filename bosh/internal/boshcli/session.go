@@ -0,0 +1,333 @@
+package boshcli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/EngineerBetter/control-tower/util"
+)
+
+// Session runs authenticated commands against a director, writing its CA
+// certificate to disk once so that several commands can be run in sequence
+type Session struct {
+	cli      *CLI
+	config   IAASEnvironment
+	ip       string
+	password string
+	caPath   string
+}
+
+// NewSession returns a Session authenticating against the director at ip.
+// Close must be called to remove the CA certificate from disk once it is no longer needed
+func (c *CLI) NewSession(config IAASEnvironment, ip, password, ca string) (*Session, error) {
+	caPath, err := c.writeTempFile([]byte(ca))
+	if err != nil {
+		return nil, err
+	}
+	return &Session{
+		cli:      c,
+		config:   config,
+		ip:       ip,
+		password: password,
+		caPath:   caPath,
+	}, nil
+}
+
+// Close removes the CA certificate of the Session from disk
+func (s *Session) Close() error {
+	return util.RemoveTemp(s.caPath)
+}
+
+// queryFlags returns the flags authenticating commands that read from the director
+func (s *Session) queryFlags() []string {
+	return []string{"--environment", s.ip, "--ca-cert", s.caPath, "--client", s.cli.clientName, "--client-secret", s.password}
+}
+
+// updateFlags returns the flags authenticating commands that change the director state
+func (s *Session) updateFlags() []string {
+	return []string{"--non-interactive", "--environment", fmt.Sprintf("https://%s", s.ip), "--ca-cert", s.caPath, "--client", s.cli.clientName, "--client-secret", s.password}
+}
+
+func (s *Session) runUpdate(args ...string) error {
+	cmd := s.cli.command(append(s.updateFlags(), args...)...)
+	cmd.Stderr = os.Stderr
+	cmd.Stdout = os.Stdout
+	return s.cli.run(cmd)
+}
+
+// RunAuthenticatedCommand runs action against the concourse deployment
+func (s *Session) RunAuthenticatedCommand(action string, detach bool, stdout io.Writer, flags ...string) (err error) {
+	done := s.cli.emit(action)
+	defer func() { done(err) }()
+
+	flags = s.authenticatedFlags(action, flags)
+	if detach && action == "deploy" {
+		return s.cli.detachedBoshCommand(stdout, flags...)
+	}
+	return s.cli.boshCommand(stdout, flags...)
+}
+
+// authenticatedFlags returns the flags running action against the concourse deployment,
+// along with the --max-in-flight and --canaries options of the CLI when deploying
+func (s *Session) authenticatedFlags(action string, flags []string) []string {
+	flags = append(append(s.updateFlags(), "--deployment", "concourse", action), flags...)
+	if action == "deploy" {
+		if s.cli.maxInFlight != "" {
+			flags = append(flags, "--max-in-flight="+s.cli.maxInFlight)
+		}
+		if s.cli.canaries != "" {
+			flags = append(flags, "--canaries="+s.cli.canaries)
+		}
+	}
+	return flags
+}
+
+// UpdateCloudConfig generates cloud config from template and use it to update bosh cloud config
+func (s *Session) UpdateCloudConfig() error {
+	cloudConfig, err := s.config.ConfigureDirectorCloudConfig()
+	if err != nil {
+		return err
+	}
+	if s.cli.cloudConfigW != nil {
+		if _, err = io.WriteString(s.cli.cloudConfigW, cloudConfig); err != nil {
+			return err
+		}
+	}
+	cloudConfigPath, err := s.cli.writeTempFile([]byte(cloudConfig))
+	if err != nil {
+		return err
+	}
+	defer util.RemoveTemp(cloudConfigPath)
+	return s.runUpdate("update-cloud-config", cloudConfigPath)
+}
+
+// Locks runs bosh locks
+func (s *Session) Locks() ([]byte, error) {
+	var out bytes.Buffer
+	cmd := s.cli.command(append(s.queryFlags(), "locks", "--json")...)
+	cmd.Stdout = &out
+	if err := s.cli.run(cmd); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Deployments runs bosh deployments, listing every deployment of the director
+func (s *Session) Deployments() ([]byte, error) {
+	var out bytes.Buffer
+	cmd := s.cli.command(append(s.queryFlags(), "deployments", "--json")...)
+	cmd.Stdout = &out
+	if err := s.cli.run(cmd); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Events runs bosh events, keeping at most limit events when limit is positive
+func (s *Session) Events(limit int) ([]byte, error) {
+	var out bytes.Buffer
+	cmd := s.cli.command(append(s.queryFlags(), "events", "--json")...)
+	cmd.Stdout = &out
+	if err := s.cli.run(cmd); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		return out.Bytes(), nil
+	}
+	return limitRows(out.Bytes(), limit)
+}
+
+// LastTaskOutput returns the combined debug output of the most recent task of the concourse deployment
+func (s *Session) LastTaskOutput() ([]byte, error) {
+	var out bytes.Buffer
+	authFlags := append(s.queryFlags(), "--deployment", "concourse")
+	cmd := s.cli.command(append(authFlags, "tasks", "--recent=1", "--json")...)
+	cmd.Stdout = &out
+	if err := s.cli.run(cmd); err != nil {
+		return nil, err
+	}
+	var tasks struct {
+		Tables []struct {
+			Rows []struct {
+				ID string `json:"id"`
+			}
+		}
+	}
+	if err := json.Unmarshal(out.Bytes(), &tasks); err != nil {
+		return nil, fmt.Errorf("failed to parse bosh tasks output: [%v]", err)
+	}
+	if len(tasks.Tables) == 0 || len(tasks.Tables[0].Rows) == 0 {
+		return nil, errors.New("no tasks found for the concourse deployment")
+	}
+
+	out.Reset()
+	cmd = s.cli.command(append(authFlags, "task", tasks.Tables[0].Rows[0].ID, "--debug")...)
+	output := &syncWriter{w: &out}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if err := s.cli.run(cmd); err != nil {
+		return out.Bytes(), err
+	}
+	return out.Bytes(), nil
+}
+
+// UploadConcourseStemcell uploads a stemcell for the chosen IAAS, retrying failed uploads. The worker
+// stemcell is always downloaded by the director, as LocalStemcellPath only replaces the concourse stemcell
+func (s *Session) UploadConcourseStemcell() error {
+	stemcell := s.cli.localStemcell
+	if stemcell == "" {
+		var err error
+		if stemcell, err = s.config.ConfigureConcourseStemcell(); err != nil {
+			return err
+		}
+	}
+	workerStemcell, err := s.config.ConfigureWorkerStemcell()
+	if err != nil {
+		return err
+	}
+	if err = s.uploadStemcell(stemcell); err != nil {
+		return err
+	}
+	if workerStemcell == "" || workerStemcell == stemcell {
+		return nil
+	}
+	return s.uploadStemcell(workerStemcell)
+}
+
+func (s *Session) uploadStemcell(stemcell string) error {
+	for attempt := 0; ; attempt++ {
+		err := s.runUpdate("upload-stemcell", stemcell)
+		if err == nil || attempt == s.cli.stemcellRetries || errors.Is(err, ErrAuthFailed) || s.cli.ctx.Err() != nil {
+			return err
+		}
+		select {
+		case <-time.After(s.cli.stemcellBackoff << uint(attempt)):
+		case <-s.cli.ctx.Done():
+			return err
+		}
+	}
+}
+
+// Recreate runs BOSH recreate
+func (s *Session) Recreate() error {
+	return s.runUpdate("--deployment", "concourse", "recreate")
+}
+
+// RecreateInstance runs BOSH recreate against a single instance group
+func (s *Session) RecreateInstance(instanceGroup string) error {
+	if instanceGroup == "" {
+		return errors.New("instance group must not be empty")
+	}
+	return s.runUpdate("--deployment", "concourse", "recreate", instanceGroup)
+}
+
+// VMs runs bosh vms against the concourse deployment
+func (s *Session) VMs() ([]byte, error) {
+	var out bytes.Buffer
+	cmd := s.cli.command(append(s.queryFlags(), "--deployment", "concourse", "vms", "--json")...)
+	cmd.Stdout = &out
+	if err := s.cli.run(cmd); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// RecreateFailing runs BOSH recreate against every instance of the concourse deployment
+// whose processes are not running. Every failing instance is recreated even when an
+// earlier one fails, and the instances that were recreated are returned
+func (s *Session) RecreateFailing() ([]string, error) {
+	out, err := s.VMs()
+	if err != nil {
+		return nil, err
+	}
+	var vms struct {
+		Tables []struct {
+			Rows []struct {
+				Instance     string `json:"instance"`
+				ProcessState string `json:"process_state"`
+			}
+		}
+	}
+	if err = json.Unmarshal(out, &vms); err != nil {
+		return nil, fmt.Errorf("failed to parse bosh vms output: [%v]", err)
+	}
+	var recreated, failed []string
+	for _, table := range vms.Tables {
+		for _, row := range table.Rows {
+			if row.ProcessState == "running" {
+				continue
+			}
+			if err = s.runUpdate("--deployment", "concourse", "recreate", row.Instance); err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", row.Instance, err))
+				continue
+			}
+			recreated = append(recreated, row.Instance)
+		}
+	}
+	if len(failed) > 0 {
+		return recreated, fmt.Errorf("failed to recreate %d instance(s): [%s]", len(failed), strings.Join(failed, ", "))
+	}
+	return recreated, nil
+}
+
+// pausedInstanceGroup is the instance group stopped while concourse is paused. The workers
+// and the database are left running so that builds in progress are not lost
+const pausedInstanceGroup = "web"
+
+// Pause runs BOSH stop against the web instances of the concourse deployment,
+// returning once they have stopped. Their persistent state is kept
+func (s *Session) Pause() error {
+	return s.runUpdate("--deployment", "concourse", "stop", pausedInstanceGroup)
+}
+
+// Resume runs BOSH start against the web instances of the concourse deployment,
+// returning once they are running
+func (s *Session) Resume() error {
+	return s.runUpdate("--deployment", "concourse", "start", pausedInstanceGroup)
+}
+
+// CleanUp runs BOSH clean-up to remove unused releases and stemcells from the director.
+// all also removes orphaned disks and unused compiled packages
+func (s *Session) CleanUp(all bool) error {
+	args := []string{"clean-up"}
+	if all {
+		args = append(args, "--all")
+	}
+	return s.runUpdate(args...)
+}
+
+// FetchLogs runs BOSH logs against an instance group of the concourse deployment
+// and writes the downloaded tarball to dest
+func (s *Session) FetchLogs(instanceGroup, dest string) error {
+	if instanceGroup == "" {
+		return errors.New("instance group must not be empty")
+	}
+	if dest == "" {
+		return errors.New("destination must not be empty")
+	}
+	dir, err := ioutil.TempDir(s.cli.tempDir, "")
+	if err != nil {
+		return err
+	}
+	util.RemoveOnInterrupt(dir)
+	defer util.RemoveTemp(dir)
+	if err = s.runUpdate("--deployment", "concourse", "logs", instanceGroup, "--dir", dir); err != nil {
+		return err
+	}
+	tarballs, err := filepath.Glob(filepath.Join(dir, "*.tgz"))
+	if err != nil {
+		return err
+	}
+	if len(tarballs) != 1 {
+		return fmt.Errorf("expected bosh logs to download 1 tarball, found %d", len(tarballs))
+	}
+	return copyFile(tarballs[0], dest)
+}
@@ -0,0 +1,108 @@
+package boshcli
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Names of the pipeline and job set by SmokeTest in the main team
+const (
+	SmokeTestPipeline = "control-tower-smoke-test"
+	SmokeTestJob      = "smoke-test"
+)
+
+// smokeTestPipelineConfig runs a task doing nothing, which needs a worker able to fetch an image and run a container
+const smokeTestPipelineConfig = `jobs:
+- name: smoke-test
+  plan:
+  - task: noop
+    config:
+      platform: linux
+      image_resource:
+        type: registry-image
+        source: {repository: busybox}
+      run: {path: "true"}
+`
+
+// Polling of the smoke test build, the timeout allows for the image to be fetched
+var (
+	smokeTestPollInterval = 2 * time.Second
+	smokeTestTimeout      = 5 * time.Minute
+)
+
+// SmokeTestFailure is returned by SmokeTest when the smoke test build ran but did not succeed
+type SmokeTestFailure struct {
+	BuildID int
+	Status  string
+}
+
+func (f *SmokeTestFailure) Error() string {
+	return fmt.Sprintf("smoke test build %d of %s/%s %s", f.BuildID, SmokeTestPipeline, SmokeTestJob, f.Status)
+}
+
+type concourseBuild struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+}
+
+func (b concourseBuild) finished() bool {
+	switch b.Status {
+	case "pending", "started":
+		return false
+	}
+	return true
+}
+
+// SmokeTest logs in to the Concourse API at creds.URL, sets and unpauses a pipeline running a task
+// that does nothing, then triggers it and waits for the build to finish. It returns nil when the build
+// succeeds and a *SmokeTestFailure when it does not. Rejected credentials are reported as ErrConcourseAuth.
+// client defaults to one with a timeout when nil; it must trust the ATC certificate
+func SmokeTest(creds ConcourseCredentials, client *http.Client) error {
+	api, err := newConcourseAPI(creds, client)
+	if err != nil {
+		return err
+	}
+	pipeline := "/api/v1/teams/main/pipelines/" + SmokeTestPipeline
+
+	// The current config version must be sent when updating an existing pipeline
+	header := http.Header{"Content-Type": {"application/x-yaml"}}
+	current, err := api.do(http.MethodGet, pipeline+"/config", nil, nil, nil)
+	var respErr *concourseResponseError
+	switch {
+	case err == nil:
+		header.Set("X-Concourse-Config-Version", current.Get("X-Concourse-Config-Version"))
+	case !errors.As(err, &respErr) || respErr.statusCode != http.StatusNotFound:
+		return fmt.Errorf("failed to get the smoke test pipeline: [%w]", err)
+	}
+	if _, err = api.do(http.MethodPut, pipeline+"/config", header, strings.NewReader(smokeTestPipelineConfig), nil); err != nil {
+		return fmt.Errorf("failed to set the smoke test pipeline: [%w]", err)
+	}
+	if _, err = api.do(http.MethodPut, pipeline+"/unpause", nil, nil, nil); err != nil {
+		return fmt.Errorf("failed to unpause the smoke test pipeline: [%w]", err)
+	}
+
+	var build concourseBuild
+	if _, err = api.do(http.MethodPost, pipeline+"/jobs/"+SmokeTestJob+"/builds", nil, nil, &build); err != nil {
+		return fmt.Errorf("failed to trigger the smoke test: [%w]", err)
+	}
+	deadline := time.Now().Add(smokeTestTimeout)
+	for {
+		if _, err = api.do(http.MethodGet, fmt.Sprintf("/api/v1/builds/%d", build.ID), nil, nil, &build); err != nil {
+			return fmt.Errorf("failed to get the smoke test build: [%w]", err)
+		}
+		if build.finished() {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("smoke test build %d did not finish within %s", build.ID, smokeTestTimeout)
+		}
+		time.Sleep(smokeTestPollInterval)
+	}
+	if build.Status != "succeeded" {
+		return &SmokeTestFailure{BuildID: build.ID, Status: build.Status}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package boshcli
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// TeeOutput returns an Option which appends the output of the commands run against
+// the concourse deployment to the file at path, as well as writing it to the console
+func TeeOutput(path string) Option {
+	return func(c *CLI) error {
+		f, err := openTeeFile(path)
+		if err != nil {
+			return err
+		}
+		c.teeOutputPath = path
+		return f.Close()
+	}
+}
+
+func openTeeFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output log %s: [%v]", path, err)
+	}
+	return f, nil
+}
+
+// tee returns writers copying stdout and stderr to the output log when one is set.
+// Failing to open the log is reported as a warning so that the command still runs
+func (c *CLI) tee(stdout, stderr io.Writer) (io.Writer, io.Writer, func()) {
+	if c.teeOutputPath == "" {
+		return stdout, stderr, func() {}
+	}
+	f, err := openTeeFile(c.teeOutputPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: %v, output will only be written to the console\n", err)
+		return stdout, stderr, func() {}
+	}
+	return teeWriter(stdout, f), teeWriter(stderr, f), func() { f.Close() }
+}
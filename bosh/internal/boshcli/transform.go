@@ -0,0 +1,34 @@
+package boshcli
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ManifestTransform returns a modified copy of the rendered director manifest
+type ManifestTransform func(manifest string) (string, error)
+
+// TransformManifest returns an Option passing the rendered director manifest through transform
+// before it is written to disk for create-env, delete-env and the manifest diff, such as to have
+// a policy engine enforce or inject values. The transformed manifest must still be valid YAML
+func TransformManifest(transform ManifestTransform) Option {
+	return func(c *CLI) error {
+		if transform == nil {
+			return errors.New("manifest transform must not be nil")
+		}
+		c.transform = transform
+		return nil
+	}
+}
+
+// transformManifest applies the ManifestTransform of the CLI to manifest, if any
+func (c *CLI) transformManifest(manifest string) (string, error) {
+	if c.transform == nil {
+		return manifest, nil
+	}
+	transformed, err := c.transform(manifest)
+	if err != nil {
+		return "", fmt.Errorf("failed to transform the director manifest: [%v]", err)
+	}
+	return transformed, nil
+}
@@ -0,0 +1,79 @@
+package boshcli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	goyaml "gopkg.in/yaml.v2"
+)
+
+// MissingReleases returns the releases of manifest which are not uploaded to the director at ip
+func (c *CLI) MissingReleases(config IAASEnvironment, ip, password, ca string, manifest []byte) ([]string, error) {
+	s, err := c.NewSession(config, ip, password, ca)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	return s.MissingReleases(manifest)
+}
+
+// MissingReleases runs bosh releases and returns the releases of manifest which are not uploaded
+// to the director, as name/version. A release at version latest is only looked up by name
+func (s *Session) MissingReleases(manifest []byte) ([]string, error) {
+	var m struct {
+		Releases []struct {
+			Name    string `yaml:"name"`
+			Version string `yaml:"version"`
+		} `yaml:"releases"`
+	}
+	if err := goyaml.Unmarshal(manifest, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: [%v]", err)
+	}
+	uploaded, err := s.uploadedReleases()
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, r := range m.Releases {
+		versions, ok := uploaded[r.Name]
+		if ok && (r.Version == "latest" || versions[r.Version]) {
+			continue
+		}
+		missing = append(missing, r.Name+"/"+r.Version)
+	}
+	return missing, nil
+}
+
+// uploadedReleases runs bosh releases and returns the versions uploaded of each release
+func (s *Session) uploadedReleases() (map[string]map[string]bool, error) {
+	var out bytes.Buffer
+	cmd := s.cli.command(append(s.queryFlags(), "releases", "--json")...)
+	cmd.Stdout = &out
+	if err := s.cli.run(cmd); err != nil {
+		return nil, err
+	}
+	var releases struct {
+		Tables []struct {
+			Rows []struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+			}
+		}
+	}
+	if err := json.Unmarshal(out.Bytes(), &releases); err != nil {
+		return nil, fmt.Errorf("failed to parse bosh releases output: [%v]", err)
+	}
+	uploaded := map[string]map[string]bool{}
+	for _, table := range releases.Tables {
+		for _, row := range table.Rows {
+			if uploaded[row.Name] == nil {
+				uploaded[row.Name] = map[string]bool{}
+			}
+			// Versions used by a deployment are marked with a *
+			uploaded[row.Name][strings.TrimSuffix(row.Version, "*")] = true
+		}
+	}
+	return uploaded, nil
+}
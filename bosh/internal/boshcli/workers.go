@@ -0,0 +1,164 @@
+package boshcli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrConcourseAuth is returned when the Concourse API rejects the admin credentials
+var ErrConcourseAuth = errors.New("concourse rejected the admin credentials")
+
+// Worker states reported by the Concourse API
+const (
+	WorkerRunning = "running"
+	WorkerStalled = "stalled"
+)
+
+// WorkerSummary describes the utilization of the Concourse workers.
+// Active counts running workers and Containers counts the containers on every worker
+type WorkerSummary struct {
+	Active     int
+	Stalled    int
+	Containers int
+	Workers    []WorkerStatus
+}
+
+// WorkerStatus describes a single Concourse worker
+type WorkerStatus struct {
+	Name       string `json:"name"`
+	State      string `json:"state"`
+	Containers int    `json:"active_containers"`
+	Volumes    int    `json:"active_volumes"`
+	Team       string `json:"team"`
+}
+
+// SummariseWorkers logs in to the Concourse API at creds.URL and returns the utilization of its
+// workers. client defaults to one with a timeout when nil; it must trust the ATC certificate
+func SummariseWorkers(creds ConcourseCredentials, client *http.Client) (WorkerSummary, error) {
+	api, err := newConcourseAPI(creds, client)
+	if err != nil {
+		return WorkerSummary{}, err
+	}
+
+	var workers []WorkerStatus
+	if _, err = api.do(http.MethodGet, "/api/v1/workers", nil, nil, &workers); err != nil {
+		return WorkerSummary{}, fmt.Errorf("failed to list concourse workers: [%w]", err)
+	}
+	summary := WorkerSummary{Workers: workers}
+	for _, worker := range workers {
+		switch worker.State {
+		case WorkerRunning:
+			summary.Active++
+		case WorkerStalled:
+			summary.Stalled++
+		}
+		summary.Containers += worker.Containers
+	}
+	return summary, nil
+}
+
+// concourseAPI makes requests to the Concourse API as the admin user
+type concourseAPI struct {
+	creds  ConcourseCredentials
+	client *http.Client
+	token  string
+}
+
+// newConcourseAPI logs in to the Concourse API at creds.URL. client defaults to one with a timeout when nil
+func newConcourseAPI(creds ConcourseCredentials, client *http.Client) (*concourseAPI, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	token, err := concourseToken(creds, client)
+	if err != nil {
+		return nil, err
+	}
+	return &concourseAPI{creds: creds, client: client, token: token}, nil
+}
+
+// do sends a request with body to path, decoding a JSON response into out when it is not nil
+func (a *concourseAPI) do(method, path string, header http.Header, body io.Reader, out interface{}) (http.Header, error) {
+	req, err := http.NewRequest(method, a.creds.URL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err = checkConcourseResponse(resp, a.creds); err != nil {
+		return nil, err
+	}
+	if out != nil {
+		if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return nil, fmt.Errorf("failed to parse the response of %s: [%v]", path, err)
+		}
+	}
+	return resp.Header, nil
+}
+
+// concourseToken exchanges the admin credentials for an access token the way fly does
+func concourseToken(creds ConcourseCredentials, client *http.Client) (string, error) {
+	form := url.Values{
+		"grant_type": {"password"},
+		"username":   {creds.Username},
+		"password":   {creds.Password},
+		"scope":      {"openid profile email federated:id groups"},
+	}
+	req, err := http.NewRequest(http.MethodPost, creds.URL+"/sky/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("fly", "Zmx5")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to log in to concourse: [%v]", err)
+	}
+	defer resp.Body.Close()
+	if err = checkConcourseResponse(resp, creds); err != nil {
+		return "", err
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to parse the concourse token: [%v]", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("concourse returned no access token")
+	}
+	return token.AccessToken, nil
+}
+
+// concourseResponseError is returned when the Concourse API responds with an error other than an auth failure
+type concourseResponseError struct {
+	url        string
+	status     string
+	statusCode int
+}
+
+func (e *concourseResponseError) Error() string {
+	return fmt.Sprintf("%s responded with %s", e.url, e.status)
+}
+
+func checkConcourseResponse(resp *http.Response, creds ConcourseCredentials) error {
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%w: %s as %s responded with %s", ErrConcourseAuth, resp.Request.URL, creds.Username, resp.Status)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return &concourseResponseError{url: resp.Request.URL.String(), status: resp.Status, statusCode: resp.StatusCode}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+// Package bucketcheck checks the S3 compatible bucket of a Store is usable before a deploy relies on it,
+// which is the same for the AWS bucket and the GCS bucket reached through its S3 interoperability API
+package bucketcheck
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// WriteCheckKey is the sentinel key written and deleted by Writable
+const WriteCheckKey = ".control-tower-write-check"
+
+// Exists returns an error when bucket does not exist or is not accessible with the credentials of client
+func Exists(client s3iface.S3API, bucket string) error {
+	_, err := client.HeadBucket(&s3.HeadBucketInput{
+		Bucket: aws.String(bucket),
+	})
+	if err == nil {
+		return nil
+	}
+	if reqErr, ok := err.(awserr.RequestFailure); ok {
+		switch reqErr.StatusCode() {
+		case 404:
+			return fmt.Errorf("bucket %q does not exist", bucket)
+		case 403:
+			return fmt.Errorf("bucket %q is not accessible with the current credentials", bucket)
+		}
+	}
+	return fmt.Errorf("failed to check bucket %q: [%v]", bucket, err)
+}
+
+// Writable writes and deletes key, which should be WriteCheckKey under any prefix of the Store,
+// returning an error when the credentials of client can not write to bucket
+func Writable(client s3iface.S3API, bucket, key string) error {
+	_, err := client.PutObject(&s3.PutObjectInput{
+		Body:   bytes.NewReader(nil),
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if reqErr, ok := err.(awserr.RequestFailure); ok && reqErr.StatusCode() == 403 {
+		return fmt.Errorf("bucket %q is not writable with the current credentials", bucket)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write to bucket %q: [%v]", bucket, err)
+	}
+	_, err = client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete %s from bucket %q: [%v]", WriteCheckKey, bucket, err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package bucketcheck
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+type mockS3API struct {
+	s3iface.S3API
+	headErr   error
+	putErr    error
+	deleteErr error
+	deleted   []string
+}
+
+func (m *mockS3API) HeadBucket(*s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+	return &s3.HeadBucketOutput{}, m.headErr
+}
+
+func (m *mockS3API) PutObject(*s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	return &s3.PutObjectOutput{}, m.putErr
+}
+
+func (m *mockS3API) DeleteObject(input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	m.deleted = append(m.deleted, aws.StringValue(input.Key))
+	return &s3.DeleteObjectOutput{}, m.deleteErr
+}
+
+func requestFailure(status int) error {
+	return awserr.NewRequestFailure(awserr.New("Error", "error", nil), status, "request-id")
+}
+
+func TestExists(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr string
+	}{
+		{name: "existing bucket"},
+		{name: "missing bucket", err: requestFailure(404), wantErr: `bucket "my-bucket" does not exist`},
+		{name: "forbidden bucket", err: requestFailure(403), wantErr: `bucket "my-bucket" is not accessible with the current credentials`},
+		{name: "other error", err: errors.New("timeout"), wantErr: `failed to check bucket "my-bucket": [timeout]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Exists(&mockS3API{headErr: tt.err}, "my-bucket")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Exists() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Exists() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWritable(t *testing.T) {
+	tests := []struct {
+		name      string
+		putErr    error
+		deleteErr error
+		wantErr   string
+	}{
+		{name: "writable bucket"},
+		{name: "read only bucket", putErr: requestFailure(403), wantErr: "is not writable with the current credentials"},
+		{name: "write fails", putErr: errors.New("timeout"), wantErr: "failed to write to bucket"},
+		{name: "delete fails", deleteErr: errors.New("timeout"), wantErr: "failed to delete " + WriteCheckKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &mockS3API{putErr: tt.putErr, deleteErr: tt.deleteErr}
+			err := Writable(m, "my-bucket", "env/"+WriteCheckKey)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Writable() error = %v", err)
+				}
+				if len(m.deleted) != 1 || m.deleted[0] != "env/"+WriteCheckKey {
+					t.Errorf("Writable() deleted %v, want the sentinel key", m.deleted)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Writable() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,404 @@
+// Package concourseops renders the operations that customise the concourse
+// deployment manifest for an IAAS Environment
+package concourseops
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EngineerBetter/control-tower/resource"
+	"github.com/EngineerBetter/control-tower/util/yaml"
+)
+
+// ExternalDB holds the connection details of a database used by concourse
+// in place of the colocated one
+type ExternalDB struct {
+	Host     string
+	Port     string
+	Name     string
+	User     string
+	Password string
+}
+
+// GC holds the garbage collection settings of the ATC, each a duration.
+// Unset settings keep the defaults of the concourse release
+type GC struct {
+	Interval           string
+	OneOffGracePeriod  string
+	MissingGracePeriod string
+	FailedGracePeriod  string
+}
+
+// lookupHost resolves a domain, it is replaced in tests
+var lookupHost = net.LookupHost
+
+// domainPattern matches a fully qualified domain name
+var domainPattern = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?\.)+[a-zA-Z]{2,}$`)
+
+// Update strategies of the concourse deployment. Serial updates one instance group
+// at a time while parallel updates independent instance groups together
+const (
+	UpdateSerial   = "serial"
+	UpdateParallel = "parallel"
+)
+
+// DefaultATCPort is the port the concourse web UI is served on when ATCPort is not set
+const DefaultATCPort = 443
+
+// Params holds the Environment parameters that customise the concourse deployment.
+// When LetsEncrypt is set the ATC obtains a certificate for Domain with ACME rather
+// than using the one generated by control-tower, so Domain must resolve to ATCPublicIP.
+// More than one WebInstances needs a load balancer in front of the web instance group,
+// as ATCPublicIP is only attached to a single VM. WorkerPools need the vm_types rendered
+// into the cloud config for each pool. An ATCPort other than 443 is added to the external
+// URL on Domain, or on ATCPublicIP when there is no Domain. A WorkerStemcell must be
+// uploaded to the director alongside the stemcell of the deployment. WorkerMaxTasks of 0 leaves
+// the number of active tasks on each worker unlimited
+type Params struct {
+	ATCPort                 int
+	ATCPublicIP             string
+	DiskSizes               DiskSizes
+	Domain                  string
+	ExternalDB              ExternalDB
+	ExtraHosts              map[string]string
+	GC                      GC
+	LetsEncrypt             bool
+	TokenSigningKey         string
+	UpdateStrategy          string
+	WebInstances            int
+	WorkerAZs               []string
+	WorkerDrainTimeout      string
+	WorkerMaxTasks          int
+	WorkerPools             []WorkerPool
+	WorkerRebalanceInterval string
+	WorkerRegistryCAs       []string
+	WorkerRuntime           string
+	WorkerStemcell          WorkerStemcell
+	WorkerVMExtensions      []string
+}
+
+// Render returns an ops file applying params to the concourse deployment manifest.
+// It returns an empty string when params require no changes.
+func Render(p Params) (string, error) {
+	var ops string
+	vars := map[string]interface{}{}
+	osConf := false
+
+	if len(p.ExtraHosts) > 0 {
+		script, err := extraHostsScript(p.ExtraHosts)
+		if err != nil {
+			return "", err
+		}
+		vars["extra_hosts_script"] = script
+		ops += resource.ConcourseExtraHostsOps
+		osConf = true
+	}
+
+	if len(p.WorkerRegistryCAs) > 0 {
+		for i, ca := range p.WorkerRegistryCAs {
+			if err := validateCert(ca); err != nil {
+				return "", fmt.Errorf("invalid worker registry CA at index %d: [%v]", i, err)
+			}
+		}
+		vars["worker_registry_cas"] = strings.Join(p.WorkerRegistryCAs, "\n")
+		ops += resource.ConcourseWorkerCACertsOps
+		osConf = true
+	}
+
+	switch p.WorkerRuntime {
+	case "":
+	case "guardian", "containerd":
+		vars["worker_runtime"] = p.WorkerRuntime
+		ops += resource.ConcourseWorkerRuntimeOps
+	default:
+		return "", fmt.Errorf("unknown worker runtime %q, must be guardian or containerd", p.WorkerRuntime)
+	}
+
+	if p.WorkerStemcell != (WorkerStemcell{}) {
+		if err := p.WorkerStemcell.Validate(); err != nil {
+			return "", err
+		}
+		vars["worker_stemcell_os"] = p.WorkerStemcell.OS
+		vars["worker_stemcell_version"] = p.WorkerStemcell.Version
+		ops += resource.ConcourseWorkerStemcellOps
+	}
+
+	switch p.UpdateStrategy {
+	case "":
+	case UpdateSerial, UpdateParallel:
+		vars["update_serial"] = p.UpdateStrategy == UpdateSerial
+		ops += resource.ConcourseUpdateSerialOps
+	default:
+		return "", fmt.Errorf("unknown update strategy %q, must be %s or %s", p.UpdateStrategy, UpdateSerial, UpdateParallel)
+	}
+
+	if p.WebInstances < 0 {
+		return "", fmt.Errorf("web instances must be positive, got %d", p.WebInstances)
+	}
+	if p.WebInstances > 0 {
+		vars["web_instances"] = p.WebInstances
+		ops += resource.ConcourseWebInstancesOps
+	}
+
+	if p.WorkerDrainTimeout != "" {
+		if _, err := time.ParseDuration(p.WorkerDrainTimeout); err != nil {
+			return "", fmt.Errorf("invalid worker drain timeout %q: [%v]", p.WorkerDrainTimeout, err)
+		}
+		vars["worker_drain_timeout"] = p.WorkerDrainTimeout
+		ops += resource.ConcourseWorkerDrainTimeoutOps
+	}
+
+	if p.WorkerRebalanceInterval != "" {
+		if _, err := time.ParseDuration(p.WorkerRebalanceInterval); err != nil {
+			return "", fmt.Errorf("invalid worker rebalance interval %q: [%v]", p.WorkerRebalanceInterval, err)
+		}
+		vars["worker_rebalance_interval"] = p.WorkerRebalanceInterval
+		ops += resource.ConcourseWorkerRebalanceIntervalOps
+	}
+
+	if p.WorkerMaxTasks < 0 {
+		return "", fmt.Errorf("worker max tasks must not be negative, got %d", p.WorkerMaxTasks)
+	}
+	if p.WorkerMaxTasks > 0 {
+		vars["worker_max_tasks"] = p.WorkerMaxTasks
+		ops += resource.ConcourseWorkerMaxTasksOps
+	}
+
+	if p.GC != (GC{}) {
+		gc, err := p.GC.properties()
+		if err != nil {
+			return "", err
+		}
+		vars["gc"] = gc
+		ops += resource.ConcourseGCOps
+	}
+
+	if p.ExternalDB != (ExternalDB{}) {
+		if err := p.ExternalDB.validate(); err != nil {
+			return "", err
+		}
+		vars["external_db_host"] = p.ExternalDB.Host
+		port, _ := strconv.Atoi(p.ExternalDB.Port)
+		vars["external_db_port"] = port
+		vars["external_db_name"] = p.ExternalDB.Name
+		vars["external_db_user"] = p.ExternalDB.User
+		vars["external_db_password"] = p.ExternalDB.Password
+		ops += resource.ConcourseExternalDBOps
+	}
+
+	if p.DiskSizes != (DiskSizes{}) {
+		disks, err := p.DiskSizes.disks()
+		if err != nil {
+			return "", err
+		}
+		if p.DiskSizes.DB > 0 && p.ExternalDB != (ExternalDB{}) {
+			return "", errors.New("db disk size cannot be set when using an external database")
+		}
+		ops += diskOps(disks, vars)
+	}
+
+	if p.Domain != "" {
+		if !domainPattern.MatchString(p.Domain) {
+			return "", fmt.Errorf("invalid domain %q", p.Domain)
+		}
+		vars["atc_domain"] = p.Domain
+		ops += resource.ConcourseExternalURLOps
+	}
+
+	if p.ATCPort != 0 && p.ATCPort != DefaultATCPort {
+		if p.ATCPort < 1 || p.ATCPort > 65535 {
+			return "", fmt.Errorf("ATC port must be between 1 and 65535, got %d", p.ATCPort)
+		}
+		host := p.Domain
+		if host == "" {
+			host = p.ATCPublicIP
+		}
+		if host == "" {
+			return "", fmt.Errorf("a domain or the ATC public IP is required to serve concourse on port %d", p.ATCPort)
+		}
+		vars["atc_port"] = p.ATCPort
+		vars["atc_external_host"] = host
+		ops += resource.ConcourseATCPortOps
+	}
+
+	if p.LetsEncrypt {
+		if err := checkDomain(p.Domain, p.ATCPublicIP); err != nil {
+			return "", err
+		}
+		ops += resource.ConcourseLetsEncryptOps
+	}
+
+	if p.TokenSigningKey != "" {
+		key, err := tokenSigningKey(p.TokenSigningKey)
+		if err != nil {
+			return "", fmt.Errorf("invalid token signing key: [%v]", err)
+		}
+		vars["token_signing_key"] = key
+		ops += resource.ConcourseTokenSigningKeyOps
+	}
+
+	for _, name := range p.WorkerVMExtensions {
+		ops += fmt.Sprintf("- type: replace\n  path: /instance_groups/name=worker/vm_extensions?/-\n  value: %q\n", name)
+	}
+
+	if len(p.WorkerAZs) > 0 {
+		ops += fmt.Sprintf("- type: replace\n  path: /instance_groups/name=worker/azs\n  value: [%s]\n", strings.Join(p.WorkerAZs, ", "))
+	}
+
+	if len(p.WorkerPools) > 0 {
+		if err := validateWorkerPools(p.WorkerPools); err != nil {
+			return "", err
+		}
+		stemcell := "xenial"
+		if p.WorkerStemcell != (WorkerStemcell{}) {
+			stemcell = WorkerStemcellAlias
+		}
+		ops += workerPoolOps(p.WorkerPools, p.WorkerAZs, stemcell, vars)
+	}
+
+	if ops == "" {
+		return "", nil
+	}
+	if osConf {
+		ops = resource.ConcourseOSConfOps + ops
+	}
+	return yaml.Interpolate(ops, "", vars)
+}
+
+func extraHostsScript(hosts map[string]string) (string, error) {
+	var names []string
+	for name := range hosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("#!/bin/bash\n")
+	for _, name := range names {
+		ip := hosts[name]
+		if net.ParseIP(ip) == nil {
+			return "", fmt.Errorf("invalid IP %q for extra host %q", ip, name)
+		}
+		if name == "" || strings.ContainsAny(name, " \t\n'\"#") {
+			return "", fmt.Errorf("invalid extra host name %q", name)
+		}
+		entry := fmt.Sprintf("%s %s", ip, name)
+		fmt.Fprintf(&b, "grep -qxF '%s' /etc/hosts || echo '%s' >> /etc/hosts\n", entry, entry)
+	}
+	return b.String(), nil
+}
+
+func validateCert(cert string) error {
+	block, _ := pem.Decode([]byte(cert))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return errors.New("not a PEM encoded certificate")
+	}
+	_, err := x509.ParseCertificate(block.Bytes)
+	return err
+}
+
+// tokenSigningKey returns the token_signing_key property of the web job for an RSA private key, with
+// the public key derived from it as the ATC expects both
+func tokenSigningKey(privateKey string) (map[string]string, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return nil, errors.New("not a PEM encoded private key")
+	}
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unexpected PEM block %q, want an RSA private key", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA private key")
+	}
+	public, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"private_key": privateKey,
+		"public_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public})),
+	}, nil
+}
+
+// checkDomain returns an error unless domain resolves to ip, as the ACME
+// challenge for domain would otherwise never reach the ATC
+func checkDomain(domain, ip string) error {
+	if domain == "" {
+		return errors.New("a domain is required to use Let's Encrypt")
+	}
+	if ip == "" {
+		return errors.New("the ATC public IP is required to use Let's Encrypt")
+	}
+	addrs, err := lookupHost(domain)
+	if err != nil {
+		return fmt.Errorf("domain %q does not resolve, point it at the ATC IP %s before using Let's Encrypt: [%v]", domain, ip, err)
+	}
+	for _, addr := range addrs {
+		if addr == ip {
+			return nil
+		}
+	}
+	return fmt.Errorf("domain %q resolves to %s rather than the ATC IP %s, update its DNS before using Let's Encrypt", domain, strings.Join(addrs, ", "), ip)
+}
+
+// properties returns the gc properties of the web job, checking each setting is a duration
+func (gc GC) properties() (map[string]string, error) {
+	properties := map[string]string{}
+	for _, f := range []struct{ name, value string }{
+		{"interval", gc.Interval},
+		{"one_off_grace_period", gc.OneOffGracePeriod},
+		{"missing_grace_period", gc.MissingGracePeriod},
+		{"failed_grace_period", gc.FailedGracePeriod},
+	} {
+		if f.value == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(f.value); err != nil {
+			return nil, fmt.Errorf("invalid gc %s %q: [%v]", strings.Replace(f.name, "_", " ", -1), f.value, err)
+		}
+		properties[f.name] = f.value
+	}
+	return properties, nil
+}
+
+func (db ExternalDB) validate() error {
+	var missing []string
+	for _, f := range []struct{ name, value string }{
+		{"host", db.Host},
+		{"port", db.Port},
+		{"name", db.Name},
+		{"user", db.User},
+		{"password", db.Password},
+	} {
+		if f.value == "" {
+			missing = append(missing, f.name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("external database is missing %s", strings.Join(missing, ", "))
+	}
+	if port, err := strconv.Atoi(db.Port); err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("invalid external database port %q", db.Port)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package concourseops
+
+import (
+	"fmt"
+
+	goyaml "gopkg.in/yaml.v2"
+)
+
+// DiskSizes holds the size in GB of the persistent disk of each concourse instance group.
+// Instance groups without a size keep the persistent disk of the deployment
+type DiskSizes struct {
+	DB     int
+	Web    int
+	Worker int
+}
+
+// DiskType returns the name of the cloud config disk_type of the persistent disk of instanceGroup
+func DiskType(instanceGroup string) string {
+	return instanceGroup + "-disk"
+}
+
+type instanceGroupDisk struct {
+	instanceGroup string
+	size          int
+}
+
+// disks returns the instance groups given a size, erroring on sizes which are not positive
+func (d DiskSizes) disks() ([]instanceGroupDisk, error) {
+	var disks []instanceGroupDisk
+	for _, disk := range []instanceGroupDisk{{"db", d.DB}, {"web", d.Web}, {"worker", d.Worker}} {
+		if disk.size < 0 {
+			return nil, fmt.Errorf("%s disk size must be positive, got %d", disk.instanceGroup, disk.size)
+		}
+		if disk.size > 0 {
+			disks = append(disks, disk)
+		}
+	}
+	return disks, nil
+}
+
+type diskType struct {
+	Name            string                 `yaml:"name"`
+	DiskSize        int                    `yaml:"disk_size"`
+	CloudProperties map[string]interface{} `yaml:"cloud_properties"`
+}
+
+// RenderDiskTypes returns the cloud config disk_types list items of sizes, each with the
+// cloud_properties of the IAAS returned by cloudProperties for its instance group
+func RenderDiskTypes(sizes DiskSizes, cloudProperties func(instanceGroup string) map[string]interface{}) (string, error) {
+	disks, err := sizes.disks()
+	if err != nil || len(disks) == 0 {
+		return "", err
+	}
+	var diskTypes []diskType
+	for _, disk := range disks {
+		diskTypes = append(diskTypes, diskType{
+			Name:            DiskType(disk.instanceGroup),
+			DiskSize:        disk.size * 1024,
+			CloudProperties: cloudProperties(disk.instanceGroup),
+		})
+	}
+	b, err := goyaml.Marshal(diskTypes)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// diskOps returns ops giving each instance group of disks its own disk_type. The web and
+// worker instance groups of the concourse manifest have no persistent disk to replace
+func diskOps(disks []instanceGroupDisk, vars map[string]interface{}) string {
+	var ops string
+	for _, disk := range disks {
+		name := disk.instanceGroup + "_disk_type"
+		vars[name] = DiskType(disk.instanceGroup)
+		ops += fmt.Sprintf("- type: replace\n  path: /instance_groups/name=%s/persistent_disk_type?\n  value: ((%s))\n", disk.instanceGroup, name)
+	}
+	return ops
+}
@@ -0,0 +1,32 @@
+package concourseops
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// WorkerStemcellAlias is the alias of the stemcell the workers run on when a WorkerStemcell is set
+const WorkerStemcellAlias = "worker"
+
+// WorkerStemcell is the stemcell line the workers run on, such as for kernel features
+// missing from the stemcell of the rest of the deployment
+type WorkerStemcell struct {
+	OS      string
+	Version string
+}
+
+var (
+	stemcellOSPattern      = regexp.MustCompile(`^ubuntu-[a-z]+$`)
+	stemcellVersionPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)*$`)
+)
+
+// Validate checks the stemcell has an ubuntu OS and a numeric version, the only ones published as light stemcells
+func (s WorkerStemcell) Validate() error {
+	if !stemcellOSPattern.MatchString(s.OS) {
+		return fmt.Errorf("invalid worker stemcell OS %q, must be an ubuntu release such as ubuntu-xenial", s.OS)
+	}
+	if !stemcellVersionPattern.MatchString(s.Version) {
+		return fmt.Errorf("invalid worker stemcell version %q", s.Version)
+	}
+	return nil
+}
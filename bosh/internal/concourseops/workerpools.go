@@ -0,0 +1,120 @@
+package concourseops
+
+import (
+	"fmt"
+	"regexp"
+
+	goyaml "gopkg.in/yaml.v2"
+)
+
+// WorkerPool is a group of concourse workers running on their own instance type.
+// Pipelines run steps on a pool by setting its Tags, steps without tags only run
+// on workers without tags
+type WorkerPool struct {
+	Name         string
+	InstanceType string
+	Count        int
+	Tags         []string
+}
+
+// workerPoolNamePattern matches names usable as both a vm_type and an instance group
+var workerPoolNamePattern = regexp.MustCompile(`^[a-z]([-a-z0-9]*[a-z0-9])?$`)
+
+// reservedInstanceGroups holds the instance groups of the concourse deployment
+var reservedInstanceGroups = map[string]bool{"web": true, "worker": true, "db": true}
+
+// WorkerPoolVMType returns the name of the cloud config vm_type of the pool called name
+func WorkerPoolVMType(name string) string {
+	return "worker-pool-" + name
+}
+
+// validateWorkerPools checks every pool has a unique name, an instance type and at least one worker
+func validateWorkerPools(pools []WorkerPool) error {
+	seen := map[string]bool{}
+	for i, pool := range pools {
+		if !workerPoolNamePattern.MatchString(pool.Name) {
+			return fmt.Errorf("invalid name %q of worker pool at index %d", pool.Name, i)
+		}
+		if seen[pool.Name] || (i > 0 && reservedInstanceGroups[pool.Name]) {
+			return fmt.Errorf("worker pool %q is already defined", pool.Name)
+		}
+		seen[pool.Name] = true
+		if pool.InstanceType == "" {
+			return fmt.Errorf("worker pool %q has no instance type", pool.Name)
+		}
+		if pool.Count < 1 {
+			return fmt.Errorf("worker pool %q must have at least one worker, got %d", pool.Name, pool.Count)
+		}
+	}
+	return nil
+}
+
+type vmType struct {
+	Name            string                 `yaml:"name"`
+	CloudProperties map[string]interface{} `yaml:"cloud_properties"`
+}
+
+// RenderWorkerPoolVMTypes returns the cloud config vm_types list items of pools, with the
+// cloud_properties of each IAAS built by cloudProperties
+func RenderWorkerPoolVMTypes(pools []WorkerPool, cloudProperties func(WorkerPool) map[string]interface{}) (string, error) {
+	if len(pools) == 0 {
+		return "", nil
+	}
+	if err := validateWorkerPools(pools); err != nil {
+		return "", err
+	}
+	var vmTypes []vmType
+	for _, pool := range pools {
+		vmTypes = append(vmTypes, vmType{Name: WorkerPoolVMType(pool.Name), CloudProperties: cloudProperties(pool)})
+	}
+	b, err := goyaml.Marshal(vmTypes)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// workerPoolOps returns ops running the first pool on the worker instance group, so that a single
+// pool keeps the layout of the deployment, and adding an instance group on stemcell for each following pool
+func workerPoolOps(pools []WorkerPool, azs []string, stemcell string, vars map[string]interface{}) string {
+	if len(azs) == 0 {
+		azs = []string{"z1"}
+	}
+	var ops string
+	for i, pool := range pools {
+		prefix := fmt.Sprintf("worker_pool_%d", i)
+		if i == 0 {
+			vars[prefix+"_vm_type"] = WorkerPoolVMType(pool.Name)
+			vars[prefix+"_count"] = pool.Count
+			ops += fmt.Sprintf("- type: replace\n  path: /instance_groups/name=worker/vm_type\n  value: ((%s_vm_type))\n", prefix)
+			ops += fmt.Sprintf("- type: replace\n  path: /instance_groups/name=worker/instances\n  value: ((%s_count))\n", prefix)
+			if len(pool.Tags) > 0 {
+				vars[prefix+"_tags"] = pool.Tags
+				ops += fmt.Sprintf("- type: replace\n  path: /instance_groups/name=worker/jobs/name=worker/properties/tags?\n  value: ((%s_tags))\n", prefix)
+			}
+			continue
+		}
+		properties := map[string]interface{}{
+			"drain_timeout":  "10m",
+			"worker_gateway": map[string]interface{}{"worker_key": "((worker_key))"},
+		}
+		if len(pool.Tags) > 0 {
+			properties["tags"] = pool.Tags
+		}
+		vars[prefix] = map[string]interface{}{
+			"name":      pool.Name,
+			"instances": pool.Count,
+			"vm_type":   WorkerPoolVMType(pool.Name),
+			"stemcell":  stemcell,
+			"azs":       azs,
+			"networks":  []map[string]interface{}{{"name": "((worker_network_name))"}},
+			"jobs": []map[string]interface{}{{
+				"name":       "worker",
+				"release":    "concourse",
+				"properties": properties,
+			}},
+		}
+		ops += fmt.Sprintf("- type: replace\n  path: /instance_groups/name=%s?\n  value: ((%s))\n", pool.Name, prefix)
+	}
+	return ops
+}
@@ -0,0 +1,82 @@
+// Package cost estimates the monthly cost of the VMs of a deployment
+package cost
+
+import (
+	"fmt"
+	"math"
+)
+
+// HoursPerMonth is the average number of hours in a month used by the IAAS for monthly prices
+const HoursPerMonth = 730
+
+// PriceSource returns the on-demand hourly price in USD of an instance type, such as from a pricing API
+type PriceSource func(instanceType string) (float64, error)
+
+// VMGroup is a group of VMs of the same instance type, such as an instance group of a deployment
+type VMGroup struct {
+	Name         string
+	InstanceType string
+	Count        int
+}
+
+// GroupCost is the estimated cost of a VMGroup. Bundled is set when the price comes from the
+// bundled price table rather than the PriceSource
+type GroupCost struct {
+	VMGroup
+	HourlyPrice float64
+	MonthlyCost float64
+	Bundled     bool
+}
+
+// Estimate is the monthly cost of a deployment broken down by VM group
+type Estimate struct {
+	Groups       []GroupCost
+	MonthlyTotal float64
+}
+
+// Monthly estimates the monthly cost of groups with the prices from source. When source is nil
+// or fails to price an instance type the price is looked up in the bundled table instead
+func Monthly(groups []VMGroup, source PriceSource, table map[string]float64) (Estimate, error) {
+	var estimate Estimate
+	for _, group := range groups {
+		if group.Count < 0 {
+			return Estimate{}, fmt.Errorf("VM group %s must have a positive count, got %d", group.Name, group.Count)
+		}
+		price, bundled, err := hourlyPrice(group.InstanceType, source, table)
+		if err != nil {
+			return Estimate{}, fmt.Errorf("failed to price VM group %s: [%v]", group.Name, err)
+		}
+		monthly := round(price * HoursPerMonth * float64(group.Count))
+		estimate.Groups = append(estimate.Groups, GroupCost{
+			VMGroup:     group,
+			HourlyPrice: price,
+			MonthlyCost: monthly,
+			Bundled:     bundled,
+		})
+		estimate.MonthlyTotal = round(estimate.MonthlyTotal + monthly)
+	}
+	return estimate, nil
+}
+
+func hourlyPrice(instanceType string, source PriceSource, table map[string]float64) (float64, bool, error) {
+	var sourceErr error
+	if source != nil {
+		price, err := source(instanceType)
+		if err == nil {
+			return price, false, nil
+		}
+		sourceErr = err
+	}
+	if price, ok := table[instanceType]; ok {
+		return price, true, nil
+	}
+	if sourceErr != nil {
+		return 0, false, fmt.Errorf("no bundled price for instance type %s after the price source failed: [%v]", instanceType, sourceErr)
+	}
+	return 0, false, fmt.Errorf("no bundled price for instance type %s", instanceType)
+}
+
+// round rounds to the cent
+func round(usd float64) float64 {
+	return math.Round(usd*100) / 100
+}
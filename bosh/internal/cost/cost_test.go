@@ -0,0 +1,85 @@
+package cost
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMonthly(t *testing.T) {
+	table := map[string]float64{"m5.large": 0.107, "t2.small": 0.023}
+	groups := []VMGroup{
+		{Name: "web", InstanceType: "t2.small", Count: 1},
+		{Name: "worker", InstanceType: "m5.large", Count: 2},
+	}
+	tests := []struct {
+		name    string
+		groups  []VMGroup
+		source  PriceSource
+		want    Estimate
+		wantErr bool
+	}{
+		{
+			name:   "bundled table",
+			groups: groups,
+			want: Estimate{
+				Groups: []GroupCost{
+					{VMGroup: groups[0], HourlyPrice: 0.023, MonthlyCost: 16.79, Bundled: true},
+					{VMGroup: groups[1], HourlyPrice: 0.107, MonthlyCost: 156.22, Bundled: true},
+				},
+				MonthlyTotal: 173.01,
+			},
+		},
+		{
+			name:   "price source",
+			groups: groups,
+			source: func(instanceType string) (float64, error) { return 0.1, nil },
+			want: Estimate{
+				Groups: []GroupCost{
+					{VMGroup: groups[0], HourlyPrice: 0.1, MonthlyCost: 73},
+					{VMGroup: groups[1], HourlyPrice: 0.1, MonthlyCost: 146},
+				},
+				MonthlyTotal: 219,
+			},
+		},
+		{
+			name:   "price source failing for an instance type",
+			groups: groups,
+			source: func(instanceType string) (float64, error) {
+				if instanceType == "m5.large" {
+					return 0, errors.New("throttled")
+				}
+				return 0.1, nil
+			},
+			want: Estimate{
+				Groups: []GroupCost{
+					{VMGroup: groups[0], HourlyPrice: 0.1, MonthlyCost: 73},
+					{VMGroup: groups[1], HourlyPrice: 0.107, MonthlyCost: 156.22, Bundled: true},
+				},
+				MonthlyTotal: 229.22,
+			},
+		},
+		{
+			name:    "instance type without a price",
+			groups:  []VMGroup{{Name: "worker", InstanceType: "p3.2xlarge", Count: 1}},
+			source:  func(instanceType string) (float64, error) { return 0, errors.New("unavailable") },
+			wantErr: true,
+		},
+		{
+			name:    "negative count",
+			groups:  []VMGroup{{Name: "worker", InstanceType: "m5.large", Count: -1}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Monthly(tt.groups, tt.source, table)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Monthly() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Monthly() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
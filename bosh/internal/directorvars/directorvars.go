@@ -0,0 +1,89 @@
+// Package directorvars validates the settings of the director which do not depend on the IAAS
+// and renders them as the values of its manifest vars
+package directorvars
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Default ports of the bosh agent mbus, reached by create-env, and of the director's NATS, reached by the agents
+const (
+	DefaultMbusPort = 6868
+	DefaultNATSPort = 4222
+)
+
+// AgentPorts returns the mbus and NATS ports, defaulting to those of the director release when zero
+func AgentPorts(mbus, nats int) (int, int, error) {
+	mbusPort, natsPort := DefaultMbusPort, DefaultNATSPort
+	if mbus != 0 {
+		mbusPort = mbus
+	}
+	if nats != 0 {
+		natsPort = nats
+	}
+	if mbusPort < 1 || mbusPort > 65535 {
+		return 0, 0, fmt.Errorf("mbus port must be between 1 and 65535, got %d", mbus)
+	}
+	if natsPort < 1 || natsPort > 65535 {
+		return 0, 0, fmt.Errorf("NATS port must be between 1 and 65535, got %d", nats)
+	}
+	if mbusPort == natsPort {
+		return 0, 0, fmt.Errorf("mbus and NATS must use different ports, both are %d", mbusPort)
+	}
+	return mbusPort, natsPort, nil
+}
+
+// TrustedCerts validates proxyCACert and returns the CAs installed on the VMs the director creates,
+// which are the non-empty trusted certs followed by proxyCACert. It is empty when proxyCACert is
+func TrustedCerts(proxyCACert string, trusted ...string) (string, error) {
+	if proxyCACert == "" {
+		return "", nil
+	}
+	block, rest := pem.Decode([]byte(proxyCACert))
+	if block == nil {
+		return "", errors.New("invalid proxy CA cert, must be PEM encoded")
+	}
+	for block != nil {
+		if block.Type != "CERTIFICATE" {
+			return "", fmt.Errorf("invalid proxy CA cert, found a PEM block of type %s", block.Type)
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return "", fmt.Errorf("invalid proxy CA cert: [%v]", err)
+		}
+		block, rest = pem.Decode(rest)
+	}
+	if len(bytes.TrimSpace(rest)) > 0 {
+		return "", errors.New("invalid proxy CA cert, found trailing data after the PEM blocks")
+	}
+	var certs string
+	for _, cert := range append(trusted, proxyCACert) {
+		if cert != "" {
+			certs += strings.TrimSpace(cert) + "\n"
+		}
+	}
+	return certs, nil
+}
+
+// NoProxy validates proxyURL and returns the hosts the director at internalIP reaches without the proxy,
+// which always include the director itself followed by noProxy. The proxy also carries the other HTTP(S)
+// calls of the director
+func NoProxy(proxyURL, internalIP string, noProxy []string) (string, error) {
+	if proxyURL == "" {
+		if len(noProxy) > 0 {
+			return "", errors.New("blobstore no proxy hosts are set without a blobstore proxy URL")
+		}
+		return "", nil
+	}
+	u, err := url.Parse(proxyURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", errors.New("invalid blobstore proxy URL, must be an http or https URL")
+	}
+	hosts := append([]string{"localhost", "127.0.0.1", internalIP}, noProxy...)
+	return strings.Join(hosts, ","), nil
+}
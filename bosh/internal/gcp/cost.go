@@ -0,0 +1,21 @@
+package gcp
+
+import "github.com/EngineerBetter/control-tower/bosh/internal/cost"
+
+// onDemandPrices are the hourly on-demand prices in USD of the machine types in the cloud config,
+// used when the pricing source is unavailable
+var onDemandPrices = map[string]float64{
+	"n1-standard-1":  0.0475,
+	"n1-standard-2":  0.095,
+	"n1-standard-4":  0.19,
+	"n1-standard-8":  0.38,
+	"n1-standard-16": 0.76,
+	"n1-standard-32": 1.52,
+	"n1-standard-64": 3.04,
+}
+
+// EstimateMonthlyCost returns the estimated monthly on-demand cost of groups, priced by source
+// and falling back to a bundled price table for machine types source fails to price or when it is nil
+func (e Environment) EstimateMonthlyCost(groups []cost.VMGroup, source cost.PriceSource) (cost.Estimate, error) {
+	return cost.Monthly(groups, source, onDemandPrices)
+}
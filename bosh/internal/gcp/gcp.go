@@ -2,11 +2,22 @@ package gcp
 
 import (
 	"bytes"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 
+	"github.com/EngineerBetter/control-tower/bosh/internal/batch"
+	"github.com/EngineerBetter/control-tower/bosh/internal/bucketcheck"
+	"github.com/EngineerBetter/control-tower/bosh/internal/concourseops"
+	"github.com/EngineerBetter/control-tower/bosh/internal/directorvars"
+	"github.com/EngineerBetter/control-tower/bosh/internal/meta"
+	"github.com/EngineerBetter/control-tower/bosh/internal/stemcells"
+	"github.com/EngineerBetter/control-tower/bosh/internal/vmextensions"
 	"github.com/EngineerBetter/control-tower/iaas"
 	"github.com/EngineerBetter/control-tower/resource"
 	"github.com/EngineerBetter/control-tower/util"
@@ -15,45 +26,162 @@ import (
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	goyaml "gopkg.in/yaml.v2"
 )
 
+// defaultJumpboxUser is the user given the PublicKey when JumpboxUser is unset
+const defaultJumpboxUser = "jumpbox"
+
 // Environment holds all the parameters GCP IAAS needs
 type Environment struct {
-	CustomOperations    string
-	DirectorName        string
-	ExternalIP          string
-	GcpCredentialsJSON  string
-	InternalCIDR        string
-	InternalGW          string
-	InternalIP          string
-	Network             string
-	PrivateCIDR         string
-	PrivateCIDRGateway  string
-	PrivateCIDRReserved string
-	PrivateSubnetwork   string
-	ProjectID           string
-	PublicCIDR          string
-	PublicCIDRGateway   string
-	PublicCIDRReserved  string
-	PublicCIDRStatic    string
-	PublicKey           string
-	PublicSubnetwork    string
-	Spot                bool
-	Tags                string
-	Zone                string
+	ATCPort                 int
+	ATCPublicIP             string
+	BlobstoreBucket         string
+	BlobstoreCredsJSON      string
+	BlobstoreNoProxy        []string
+	BlobstoreProxyURL       string
+	CustomOperations        string
+	DBDiskSizeGB            int
+	DirectorMaxTasks        int
+	DirectorName            string
+	Domain                  string
+	EnableLocalDNS          bool
+	ExternalBlobstore       bool
+	ExternalDBHost          string
+	ExternalDBName          string
+	ExternalDBPassword      string
+	ExternalDBPort          string
+	ExternalDBUser          string
+	ExternalIP              string
+	ExtraHosts              map[string]string
+	GCFailedGracePeriod     string
+	GCInterval              string
+	GCMissingGracePeriod    string
+	GCOneOffGracePeriod     string
+	GcpCredentialsJSON      string
+	InternalCIDR            string
+	InternalGW              string
+	InternalIP              string
+	JumpboxUser             string
+	Labels                  map[string]string
+	LetsEncrypt             bool
+	MbusPort                int
+	NATSPort                int
+	Network                 string
+	NetworkTags             []string
+	PrivateCIDR             string
+	PrivateCIDRGateway      string
+	PrivateCIDRReserved     string
+	PrivateDirector         bool
+	PrivateSubnetwork       string
+	ProjectID               string
+	ProxyCACert             string
+	PublicCIDR              string
+	PublicCIDRGateway       string
+	PublicCIDRReserved      string
+	PublicCIDRStatic        string
+	PublicKey               string
+	PublicSubnetwork        string
+	Spot                    bool
+	Tags                    string
+	TaskRetentionDays       int
+	TokenSigningKey         string
+	UpdateStrategy          string
+	VMExtensions            []string
+	VMLabels                map[string]string
+	WebDiskSizeGB           int
+	WebInstanceCount        int
+	WorkerDiskSizeGB        int
+	WorkerDrainTimeout      string
+	WorkerMaxTasks          int
+	WorkerNodeGroup         string
+	WorkerPools             []concourseops.WorkerPool
+	WorkerRebalanceInterval string
+	WorkerRegistryCAs       []string
+	WorkerRuntime           string
+	WorkerStemcellOS        string
+	WorkerStemcellVersion   string
+	WorkerZones             []string
+	Zone                    string
 }
 
-var allOperations = resource.GCPCPIOps + resource.GCPExternalIPOps + resource.GCPDirectorCustomOps + resource.GCPJumpboxUserOps
+func (e Environment) operations(labels map[string]string) string {
+	ops := resource.GCPCPIOps
+	if !e.PrivateDirector {
+		ops += resource.GCPExternalIPOps
+	}
+	ops += resource.GCPDirectorCustomOps + resource.GCPJumpboxUserOps
+	if len(labels) > 0 {
+		ops += resource.GCPLabelsOps
+	}
+	if e.ExternalBlobstore {
+		ops += resource.GCPGCSBlobstoreOps
+	}
+	if e.EnableLocalDNS {
+		ops += resource.LocalDNSOps
+	}
+	if e.BlobstoreProxyURL != "" {
+		ops += resource.DirectorBlobstoreProxyOps
+	}
+	if e.ProxyCACert != "" {
+		ops += resource.DirectorTrustedCertsOps
+	}
+	if e.DirectorMaxTasks > 0 {
+		ops += resource.DirectorMaxTasksOps
+	}
+	if e.TaskRetentionDays > 0 {
+		ops += resource.DirectorTaskRetentionOps
+	}
+	return ops + e.CustomOperations
+}
+
+// checkTaskRetention validates the limits on the tasks kept by the director,
+// which default to those of the director release when unset
+func (e Environment) checkTaskRetention() error {
+	if e.DirectorMaxTasks < 0 {
+		return fmt.Errorf("director max tasks must be positive, got %d", e.DirectorMaxTasks)
+	}
+	if e.TaskRetentionDays < 0 {
+		return fmt.Errorf("director task retention must be a positive number of days, got %d", e.TaskRetentionDays)
+	}
+	return nil
+}
 
 // ConfigureDirectorManifestCPI interpolates all the Environment parameters and
-// required release versions into ready to use Director manifest
+// required release versions into ready to use Director manifest.
+// When PrivateDirector is set the director is given no external IP and is
+// reached on its InternalIP. When ExternalBlobstore is set the director stores
+// its blobs in the GCS bucket BlobstoreBucket rather than on its own disk.
 func (e Environment) ConfigureDirectorManifestCPI() (string, error) {
 	gcpCreds, err := ioutil.ReadFile(e.GcpCredentialsJSON)
 	if err != nil {
 		return "", err
 	}
+	blobstoreCreds, err := e.blobstoreCreds()
+	if err != nil {
+		return "", err
+	}
+	networkTags, labels, err := e.tags()
+	if err != nil {
+		return "", err
+	}
+	if err = e.checkTaskRetention(); err != nil {
+		return "", err
+	}
+	mbusPort, natsPort, err := directorvars.AgentPorts(e.MbusPort, e.NATSPort)
+	if err != nil {
+		return "", err
+	}
+	noProxy, err := directorvars.NoProxy(e.BlobstoreProxyURL, e.InternalIP, e.BlobstoreNoProxy)
+	if err != nil {
+		return "", err
+	}
+	trustedCerts, err := directorvars.TrustedCerts(e.ProxyCACert)
+	if err != nil {
+		return "", err
+	}
 
-	return yaml.Interpolate(resource.DirectorManifest, allOperations+e.CustomOperations, map[string]interface{}{
+	return yaml.Interpolate(resource.DirectorManifest, e.operations(labels), map[string]interface{}{
 		"internal_cidr":        e.InternalCIDR,
 		"internal_gw":          e.InternalGW,
 		"internal_ip":          e.InternalIP,
@@ -66,11 +194,135 @@ func (e Environment) ConfigureDirectorManifestCPI() (string, error) {
 		"gcp_credentials_json": string(gcpCreds),
 		"external_ip":          e.ExternalIP,
 		"public_key":           e.PublicKey,
+		"jumpbox_user":         e.jumpboxUser(),
+		"blobstore_bucket":     e.BlobstoreBucket,
+		"blobstore_json_key":   blobstoreCreds,
+		"network_tags":         append([]string{"external"}, networkTags...),
+		"labels":               labels,
+		"max_tasks":            e.DirectorMaxTasks,
+		"task_retention_days":  e.TaskRetentionDays,
+		"mbus_port":            mbusPort,
+		"nats_port":            natsPort,
+		"blobstore_proxy_url":  e.BlobstoreProxyURL,
+		"blobstore_no_proxy":   noProxy,
+		"trusted_certs":        trustedCerts,
 	})
 }
 
+var (
+	networkTagPattern = regexp.MustCompile(`^[a-z]([-a-z0-9]{0,61}[a-z0-9])?$`)
+	labelKeyPattern   = regexp.MustCompile(`^[a-z][-_a-z0-9]{0,62}$`)
+	labelValuePattern = regexp.MustCompile(`^[-_a-z0-9]{0,63}$`)
+	nodeGroupPattern  = regexp.MustCompile(`^[a-z]([-a-z0-9]{0,61}[a-z0-9])?$`)
+)
+
+// tags returns the network tags and labels of the Environment. When neither NetworkTags
+// nor Labels are set they are read from Tags, which holds either a YAML list of network
+// tags or a YAML map of labels
+func (e Environment) tags() ([]string, map[string]string, error) {
+	networkTags, labels := e.NetworkTags, e.Labels
+	if len(networkTags) == 0 && len(labels) == 0 && e.Tags != "" {
+		var tags interface{}
+		if err := goyaml.Unmarshal([]byte(e.Tags), &tags); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse tags %q: [%v]", e.Tags, err)
+		}
+		switch t := tags.(type) {
+		case string:
+			networkTags = []string{t}
+		case []interface{}:
+			for _, tag := range t {
+				networkTags = append(networkTags, fmt.Sprint(tag))
+			}
+		case map[interface{}]interface{}:
+			labels = map[string]string{}
+			for key, value := range t {
+				labels[fmt.Sprint(key)] = fmt.Sprint(value)
+			}
+		default:
+			return nil, nil, fmt.Errorf("tags %q must be a list of network tags or a map of labels", e.Tags)
+		}
+	}
+	for _, tag := range networkTags {
+		if !networkTagPattern.MatchString(tag) {
+			return nil, nil, fmt.Errorf("invalid network tag %q, must be lowercase letters, digits and dashes", tag)
+		}
+	}
+	if err := checkLabels(labels); err != nil {
+		return nil, nil, err
+	}
+	return networkTags, labels, nil
+}
+
+// maxLabels is the number of labels GCP allows on a resource
+const maxLabels = 64
+
+// checkLabels validates labels against the GCP constraints on label keys and values
+func checkLabels(labels map[string]string) error {
+	if len(labels) > maxLabels {
+		return fmt.Errorf("%d labels given, GCP allows at most %d", len(labels), maxLabels)
+	}
+	for key, value := range labels {
+		if !labelKeyPattern.MatchString(key) || !labelValuePattern.MatchString(value) {
+			return fmt.Errorf("invalid label %s=%s, keys and values must be lowercase letters, digits, dashes and underscores", key, value)
+		}
+	}
+	return nil
+}
+
+// vmLabels returns VMLabels as the labels property of the cloud_properties of a vm_type,
+// or an empty string when there are none. Keys and values are quoted so that YAML
+// keeps them as strings
+func (e Environment) vmLabels() (string, error) {
+	if len(e.VMLabels) == 0 {
+		return "", nil
+	}
+	if err := checkLabels(e.VMLabels); err != nil {
+		return "", err
+	}
+	var keys []string
+	for key := range e.VMLabels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%q: %q", key, e.VMLabels[key]))
+	}
+	return fmt.Sprintf("\n    labels: {%s}", strings.Join(pairs, ", ")), nil
+}
+
+// blobstoreCreds returns the service account key the director uses to
+// access the external blobstore, or an empty string when it is not enabled
+func (e Environment) blobstoreCreds() (string, error) {
+	if !e.ExternalBlobstore {
+		return "", nil
+	}
+	if e.BlobstoreBucket == "" {
+		return "", errors.New("a blobstore bucket is required to use an external blobstore")
+	}
+	if e.BlobstoreCredsJSON == "" {
+		return "", errors.New("blobstore credentials are required to use an external blobstore")
+	}
+	creds, err := ioutil.ReadFile(e.BlobstoreCredsJSON)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the blobstore credentials: [%v]", err)
+	}
+	return string(creds), nil
+}
+
+// jumpboxUser returns the user the director's break-glass access is granted to
+func (e Environment) jumpboxUser() string {
+	if e.JumpboxUser == "" {
+		return defaultJumpboxUser
+	}
+	return e.JumpboxUser
+}
+
 type gcpCloudConfigParams struct {
 	Zone                string
+	ExtraAZs            string
+	PrivateNetworkTags  string
+	PrivateSubnetAZs    string
 	Spot                bool
 	PublicSubnetwork    string
 	PrivateSubnetwork   string
@@ -82,6 +334,10 @@ type gcpCloudConfigParams struct {
 	PrivateCIDR         string
 	PrivateCIDRGateway  string
 	PrivateCIDRReserved string
+	VMExtensions        string
+	WorkerPoolVMTypes   string
+	DiskTypes           string
+	VMLabels            string
 }
 
 // IAASCheck returns the IAAS provider
@@ -91,8 +347,49 @@ func (e Environment) IAASCheck() iaas.Name {
 
 // ConfigureDirectorCloudConfig inserts values from the environment into the config template passed as argument
 func (e Environment) ConfigureDirectorCloudConfig() (string, error) {
+	definitions, err := e.vmExtensions()
+	if err != nil {
+		return "", err
+	}
+	vmExtensions, _, err := vmextensions.Render(definitions)
+	if err != nil {
+		return "", err
+	}
+	zones, err := e.zones()
+	if err != nil {
+		return "", err
+	}
+	var extraAZs string
+	for i, zone := range zones[1:] {
+		extraAZs += fmt.Sprintf("\n- name: %s\n  cloud_properties:\n    zone: %s", azName(i+1), zone)
+	}
+	privateSubnetAZs := "az: " + azName(0)
+	if len(zones) > 1 {
+		privateSubnetAZs = fmt.Sprintf("azs: [%s]", strings.Join(azNames(len(zones)), ", "))
+	}
+	networkTags, _, err := e.tags()
+	if err != nil {
+		return "", err
+	}
+	vmLabels, err := e.vmLabels()
+	if err != nil {
+		return "", err
+	}
+	workerPoolVMTypes, err := concourseops.RenderWorkerPoolVMTypes(e.WorkerPools, e.workerPoolCloudProperties)
+	if err != nil {
+		return "", err
+	}
+	diskTypes, err := concourseops.RenderDiskTypes(e.diskSizes(), func(string) map[string]interface{} {
+		return map[string]interface{}{"type": "pd-ssd"}
+	})
+	if err != nil {
+		return "", err
+	}
 	templateParams := gcpCloudConfigParams{
-		Zone:                e.Zone,
+		Zone:                zones[0],
+		ExtraAZs:            extraAZs,
+		PrivateNetworkTags:  fmt.Sprintf("[%s]", strings.Join(append([]string{"no-ip"}, networkTags...), ", ")),
+		PrivateSubnetAZs:    privateSubnetAZs,
 		PublicSubnetwork:    e.PublicSubnetwork,
 		PrivateSubnetwork:   e.PrivateSubnetwork,
 		Spot:                e.Spot,
@@ -104,6 +401,10 @@ func (e Environment) ConfigureDirectorCloudConfig() (string, error) {
 		PrivateCIDR:         e.PrivateCIDR,
 		PrivateCIDRGateway:  e.PrivateCIDRGateway,
 		PrivateCIDRReserved: e.PrivateCIDRReserved,
+		VMExtensions:        vmExtensions,
+		WorkerPoolVMTypes:   workerPoolVMTypes,
+		DiskTypes:           strings.TrimSuffix(diskTypes, "\n"),
+		VMLabels:            vmLabels,
 	}
 
 	cc, err := util.RenderTemplate("cloud-config", resource.GCPDirectorCloudConfig, templateParams)
@@ -113,30 +414,203 @@ func (e Environment) ConfigureDirectorCloudConfig() (string, error) {
 	return string(cc), err
 }
 
+// zones returns the zones of the cloud config AZs. The first is the zone of the
+// director, which the other instance groups are deployed to, followed by the other
+// zones in WorkerZones. They must all be in the same region to share its subnetworks
+func (e Environment) zones() ([]string, error) {
+	zones := []string{e.Zone}
+	region := zoneRegion(e.Zone)
+	for i, zone := range e.WorkerZones {
+		if zone == "" {
+			return nil, fmt.Errorf("worker zone at index %d is empty", i)
+		}
+		if zoneRegion(zone) != region {
+			return nil, fmt.Errorf("worker zone %q is not in the region %q of zone %q", zone, region, e.Zone)
+		}
+		if !contains(zones, zone) {
+			zones = append(zones, zone)
+		}
+	}
+	return zones, nil
+}
+
+// zoneRegion returns the region of a zone such as europe-west1-b
+func zoneRegion(zone string) string {
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		return zone[:i]
+	}
+	return zone
+}
+
+func azName(i int) string {
+	return fmt.Sprintf("z%d", i+1)
+}
+
+func azNames(n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = azName(i)
+	}
+	return names
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+// workerPoolCloudProperties returns the cloud_properties of the vm_type of a worker pool,
+// with the disk of the default workers
+func (e Environment) workerPoolCloudProperties(pool concourseops.WorkerPool) map[string]interface{} {
+	properties := map[string]interface{}{
+		"machine_type":      pool.InstanceType,
+		"root_disk_size_gb": 200,
+		"root_disk_type":    "pd-ssd",
+	}
+	if e.Spot {
+		properties["preemptible"] = true
+	}
+	if len(e.VMLabels) > 0 {
+		properties["labels"] = e.VMLabels
+	}
+	return properties
+}
+
+// vmExtensions returns the user defined vm_extension definitions along with the one
+// scheduling the workers onto the sole-tenant node group WorkerNodeGroup. The node
+// group must already exist in the zones of the workers, the GCP CPI does not create it
+func (e Environment) vmExtensions() ([]string, error) {
+	if e.WorkerNodeGroup == "" {
+		return e.VMExtensions, nil
+	}
+	if !nodeGroupPattern.MatchString(e.WorkerNodeGroup) {
+		return nil, fmt.Errorf("worker node group %q is not a valid node group name", e.WorkerNodeGroup)
+	}
+	placement, err := vmextensions.Placement(map[string]interface{}{"node_group": e.WorkerNodeGroup})
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]string{}, e.VMExtensions...), placement), nil
+}
+
+// diskSizes returns the sizes of the persistent disks of the concourse instance groups
+func (e Environment) diskSizes() concourseops.DiskSizes {
+	return concourseops.DiskSizes{DB: e.DBDiskSizeGB, Web: e.WebDiskSizeGB, Worker: e.WorkerDiskSizeGB}
+}
+
+// ConfigureConcourseOps returns the operations that customise the concourse deployment for the Environment
+func (e Environment) ConfigureConcourseOps() (string, error) {
+	definitions, err := e.vmExtensions()
+	if err != nil {
+		return "", err
+	}
+	_, vmExtensions, err := vmextensions.Render(definitions)
+	if err != nil {
+		return "", err
+	}
+	zones, err := e.zones()
+	if err != nil {
+		return "", err
+	}
+	var workerAZs []string
+	if len(zones) > 1 {
+		workerAZs = azNames(len(zones))
+	}
+	gc := concourseops.GC{
+		Interval:           e.GCInterval,
+		OneOffGracePeriod:  e.GCOneOffGracePeriod,
+		MissingGracePeriod: e.GCMissingGracePeriod,
+		FailedGracePeriod:  e.GCFailedGracePeriod,
+	}
+	return concourseops.Render(concourseops.Params{
+		ATCPort:     e.ATCPort,
+		ATCPublicIP: e.ATCPublicIP,
+		DiskSizes:   e.diskSizes(),
+		Domain:      e.Domain,
+		ExternalDB: concourseops.ExternalDB{
+			Host:     e.ExternalDBHost,
+			Port:     e.ExternalDBPort,
+			Name:     e.ExternalDBName,
+			User:     e.ExternalDBUser,
+			Password: e.ExternalDBPassword,
+		},
+		ExtraHosts:              e.ExtraHosts,
+		GC:                      gc,
+		LetsEncrypt:             e.LetsEncrypt,
+		TokenSigningKey:         e.TokenSigningKey,
+		UpdateStrategy:          e.UpdateStrategy,
+		WebInstances:            e.WebInstanceCount,
+		WorkerAZs:               workerAZs,
+		WorkerDrainTimeout:      e.WorkerDrainTimeout,
+		WorkerMaxTasks:          e.WorkerMaxTasks,
+		WorkerPools:             e.WorkerPools,
+		WorkerRebalanceInterval: e.WorkerRebalanceInterval,
+		WorkerRegistryCAs:       e.WorkerRegistryCAs,
+		WorkerRuntime:           e.WorkerRuntime,
+		WorkerStemcell:          e.workerStemcell(),
+		WorkerVMExtensions:      vmExtensions,
+	})
+}
+
 // ConfigureConcourseStemcell returns the stemcell location string for an AWS specific stemcell for the required concourse version
 func (e Environment) ConfigureConcourseStemcell() (string, error) {
-	var ops []struct {
-		Path  string
-		Value json.RawMessage
-	}
-	err := json.Unmarshal([]byte(resource.GCPReleaseVersions), &ops)
+	version, err := stemcells.Version(iaas.GCP, resource.GCPReleaseVersions)
 	if err != nil {
 		return "", err
 	}
-	var version string
-	for _, op := range ops {
-		if op.Path != "/stemcells/alias=xenial/version" {
-			continue
-		}
-		err := json.Unmarshal(op.Value, &version)
-		if err != nil {
-			return "", err
-		}
+	return stemcellURL("ubuntu-xenial", version), nil
+}
+
+// RenderAll writes the director manifest, cloud config, concourse ops and stemcell URL
+// of the Environment into dir, without contacting the IAAS or the director, so that
+// they can be deployed by other tooling. The concourse ops are only written when
+// the Environment customises the concourse deployment, and the worker stemcell URL
+// when the workers run on their own stemcell
+func (e Environment) RenderAll(dir string) error {
+	director, err := e.ConfigureDirectorManifestCPI()
+	if err != nil {
+		return fmt.Errorf("failed to render the director manifest: [%v]", err)
+	}
+	cloudConfig, err := e.ConfigureDirectorCloudConfig()
+	if err != nil {
+		return fmt.Errorf("failed to render the cloud config: [%v]", err)
+	}
+	concourseOps, err := e.ConfigureConcourseOps()
+	if err != nil {
+		return fmt.Errorf("failed to render the concourse ops: [%v]", err)
+	}
+	stemcell, err := e.ConfigureConcourseStemcell()
+	if err != nil {
+		return fmt.Errorf("failed to resolve the stemcell: [%v]", err)
+	}
+	files := map[string]string{
+		"director.yml":     director,
+		"cloud-config.yml": cloudConfig,
+		"stemcell-url.txt": stemcell + "\n",
+	}
+	if concourseOps != "" {
+		files["concourse-ops.yml"] = concourseOps
+	}
+	workerStemcell, err := e.ConfigureWorkerStemcell()
+	if err != nil {
+		return fmt.Errorf("failed to resolve the worker stemcell: [%v]", err)
 	}
-	if version == "" {
-		return "", errors.New("did not find stemcell version in versions.json")
+	if workerStemcell != "" {
+		files["worker-stemcell-url.txt"] = workerStemcell + "\n"
 	}
-	return fmt.Sprintf("https://s3.amazonaws.com/bosh-gce-light-stemcells/%s/light-bosh-stemcell-%s-google-kvm-ubuntu-xenial-go_agent.tgz", version, version), nil
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0600); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Store holds the abstraction of a aws storage artifact
@@ -145,12 +619,50 @@ type Store struct {
 	bucket string
 }
 
+// StoreOption configures a Store at construction
+type StoreOption func(*Store) error
+
+// CheckBucket returns a StoreOption which fails Store construction when the
+// bucket does not exist or is not accessible, rather than letting Get treat
+// every key as missing
+func CheckBucket() StoreOption {
+	return func(s *Store) error {
+		return bucketcheck.Exists(s.s3, s.bucket)
+	}
+}
+
+// CheckWrite returns a StoreOption which fails Store construction when the
+// credentials can not write to the bucket, see CanWrite
+func CheckWrite() StoreOption {
+	return func(s *Store) error {
+		return s.CanWrite()
+	}
+}
+
+// RecordMeta returns a StoreOption which records the bucket, region and IAAS
+// of the Store the first time it is used, so that later runs can read them with Meta
+func RecordMeta(region string) StoreOption {
+	return func(s *Store) error {
+		return meta.Record(meta.Meta{
+			Bucket: s.bucket,
+			Region: region,
+			IAAS:   iaas.Name(iaas.GCP).String(),
+		}, s.Get, s.Set)
+	}
+}
+
 // NewStore returns a reference to a new Store
-func NewStore(s3 s3iface.S3API, bucket string) *Store {
-	return &Store{
+func NewStore(s3 s3iface.S3API, bucket string, opts ...StoreOption) (*Store, error) {
+	s := &Store{
 		s3:     s3,
 		bucket: bucket,
 	}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
 }
 
 // Get returns the contents of a Store element identified with a key
@@ -178,3 +690,26 @@ func (s *Store) Set(key string, value []byte) error {
 	})
 	return err
 }
+
+// Meta returns the metadata recorded by RecordMeta. found is false
+// when the Store has not been used before
+func (s *Store) Meta() (m meta.Meta, found bool, err error) {
+	return meta.Read(s.Get)
+}
+
+// GetMany returns the contents of the Store elements identified with keys
+func (s *Store) GetMany(keys []string) (map[string][]byte, error) {
+	return batch.Get(keys, s.Get)
+}
+
+// SetMany stores the contents of several Store elements identified with their keys
+func (s *Store) SetMany(values map[string][]byte) error {
+	return batch.Set(values, s.Set)
+}
+
+// CanWrite writes and deletes a sentinel key, returning an error when the credentials
+// can not write to the bucket. Checking before a deploy avoids it failing to upload
+// its state once the director has already been created
+func (s *Store) CanWrite() error {
+	return bucketcheck.Writable(s.s3, s.bucket, bucketcheck.WriteCheckKey)
+}
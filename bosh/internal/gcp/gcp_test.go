@@ -1,16 +1,23 @@
 package gcp
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"regexp"
 	"strings"
+	"sync"
 	"testing"
 	"text/template"
 	"text/template/parse"
+	"time"
 
+	"github.com/EngineerBetter/control-tower/bosh/internal/concourseops"
+	"github.com/EngineerBetter/control-tower/bosh/internal/cost"
 	"github.com/EngineerBetter/control-tower/resource"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
@@ -20,6 +27,9 @@ import (
 type mockS3API struct {
 	s3iface.S3API
 	getObjectOutput *s3.GetObjectOutput
+	headBucketErr   error
+	putObjectErr    error
+	deleteInput     *s3.DeleteObjectInput
 	err             error
 }
 
@@ -28,9 +38,22 @@ func (m *mockS3API) GetObject(in *s3.GetObjectInput) (*s3.GetObjectOutput, error
 }
 
 func (m *mockS3API) PutObject(in *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	if m.putObjectErr != nil {
+		return nil, m.putObjectErr
+	}
 	return nil, m.err
 }
 
+func (m *mockS3API) DeleteObject(in *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	m.deleteInput = in
+	return &s3.DeleteObjectOutput{}, nil
+
+}
+
+func (m *mockS3API) HeadBucket(in *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+	return &s3.HeadBucketOutput{}, m.headBucketErr
+}
+
 func TestStore_Get(t *testing.T) {
 	type fields struct {
 		s3     s3iface.S3API
@@ -159,6 +182,93 @@ func TestStore_Set(t *testing.T) {
 	}
 }
 
+func TestNewStore_CheckWrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr string
+	}{
+		{
+			name: "bucket writable",
+		},
+		{
+			name:    "bucket read only",
+			err:     awserr.NewRequestFailure(awserr.New("AccessDenied", "Access Denied", nil), 403, "request-id"),
+			wantErr: `bucket "my bucket" is not writable with the current credentials`,
+		},
+		{
+			name:    "other failure",
+			err:     errors.New("an error"),
+			wantErr: `failed to write to bucket "my bucket": [an error]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &mockS3API{putObjectErr: tt.err}
+			_, err := NewStore(m, "my bucket", CheckWrite())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("NewStore() error = %v", err)
+				}
+				if m.deleteInput == nil || *m.deleteInput.Key != ".control-tower-write-check" {
+					t.Errorf("NewStore() expected to delete the write check key, deleted %v", m.deleteInput)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("NewStore() error = %v, want %v", err, tt.wantErr)
+			}
+			if m.deleteInput != nil {
+				t.Errorf("NewStore() deleted %v after failing to write it", *m.deleteInput.Key)
+			}
+		})
+	}
+}
+
+func TestNewStore_CheckBucket(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr string
+	}{
+		{
+			name: "bucket present",
+		},
+		{
+			name:    "bucket absent",
+			err:     awserr.NewRequestFailure(awserr.New("NotFound", "Not Found", nil), 404, "request-id"),
+			wantErr: `bucket "my bucket" does not exist`,
+		},
+		{
+			name:    "bucket not accessible",
+			err:     awserr.NewRequestFailure(awserr.New("Forbidden", "Forbidden", nil), 403, "request-id"),
+			wantErr: `bucket "my bucket" is not accessible with the current credentials`,
+		},
+		{
+			name:    "other failure",
+			err:     errors.New("an error"),
+			wantErr: `failed to check bucket "my bucket": [an error]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewStore(&mockS3API{headBucketErr: tt.err}, "my bucket", CheckBucket())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("NewStore() error = %v", err)
+				}
+				if s == nil {
+					t.Errorf("NewStore() returned a nil Store")
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("NewStore() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestEnvironment_ConfigureDirectorCloudConfig(t *testing.T) {
 
 	fullTemplateParams := Environment{
@@ -230,6 +340,214 @@ func TestEnvironment_ConfigureDirectorCloudConfig(t *testing.T) {
 				return a == b, fmt.Sprintf("templating failed while rendering without spots")
 			},
 		},
+		{
+			name:    "Success- vm extensions rendered",
+			fields:  fullTemplateParams,
+			want:    getFixture("../fixtures/gcp_cloud_config_vm_extensions.yml"),
+			wantErr: false,
+			init: func(e Environment) Environment {
+				n := e
+				n.VMExtensions = []string{"name: worker-tags\ncloud_properties:\n  tags:\n  - concourse-worker\n", "name: worker-scopes\ncloud_properties:\n  service_scopes:\n  - devstorage.read_only\n"}
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return a == b, fmt.Sprintf("templating failed while rendering vm extensions")
+			},
+		},
+		{
+			name:    "Success- worker pools rendered",
+			fields:  fullTemplateParams,
+			want:    getFixture("../fixtures/gcp_cloud_config_worker_pools.yml"),
+			wantErr: false,
+			init: func(e Environment) Environment {
+				n := e
+				n.WorkerPools = []concourseops.WorkerPool{
+					{Name: "general", InstanceType: "n1-standard-4", Count: 3},
+					{Name: "big", InstanceType: "n1-highmem-16", Count: 1, Tags: []string{"big"}},
+				}
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return a == b, fmt.Sprintf("templating failed while rendering worker pools")
+			},
+		},
+		{
+			name:    "Success- per component disk sizes rendered",
+			fields:  fullTemplateParams,
+			want:    getFixture("../fixtures/gcp_cloud_config_disk_sizes.yml"),
+			wantErr: false,
+			init: func(e Environment) Environment {
+				n := e
+				n.DBDiskSizeGB = 20
+				n.WebDiskSizeGB = 10
+				n.WorkerDiskSizeGB = 100
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return a == b, fmt.Sprintf("templating failed while rendering per component disk sizes")
+			},
+		},
+		{
+			name:    "Failure- negative disk size",
+			fields:  fullTemplateParams,
+			wantErr: true,
+			init: func(e Environment) Environment {
+				n := e
+				n.WebDiskSizeGB = -10
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return true, ""
+			},
+		},
+		{
+			name:    "Failure- worker pool without instance type",
+			fields:  fullTemplateParams,
+			wantErr: true,
+			init: func(e Environment) Environment {
+				n := e
+				n.WorkerPools = []concourseops.WorkerPool{{Name: "general", Count: 1}}
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return true, ""
+			},
+		},
+		{
+			name:    "Success- vm labels rendered",
+			fields:  fullTemplateParams,
+			want:    getFixture("../fixtures/gcp_cloud_config_vm_labels.yml"),
+			wantErr: false,
+			init: func(e Environment) Environment {
+				n := e
+				n.VMLabels = map[string]string{"team": "platform", "cost-centre": "123"}
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return a == b, fmt.Sprintf("templating failed while rendering vm labels")
+			},
+		},
+		{
+			name:    "Failure- invalid vm label",
+			fields:  fullTemplateParams,
+			wantErr: true,
+			init: func(e Environment) Environment {
+				n := e
+				n.VMLabels = map[string]string{"Team": "platform"}
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return true, ""
+			},
+		},
+		{
+			name:    "Success- worker node group rendered",
+			fields:  fullTemplateParams,
+			want:    getFixture("../fixtures/gcp_cloud_config_node_group.yml"),
+			wantErr: false,
+			init: func(e Environment) Environment {
+				n := e
+				n.WorkerNodeGroup = "build-farm"
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return a == b, fmt.Sprintf("templating failed while rendering worker node group")
+			},
+		},
+		{
+			name:    "Success- worker zones rendered",
+			fields:  fullTemplateParams,
+			want:    getFixture("../fixtures/gcp_cloud_config_worker_zones.yml"),
+			wantErr: false,
+			init: func(e Environment) Environment {
+				n := e
+				n.Zone = "europe-west1-b"
+				n.WorkerZones = []string{"europe-west1-b", "europe-west1-c", "europe-west1-d"}
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return a == b, fmt.Sprintf("templating failed while rendering worker zones")
+			},
+		},
+		{
+			name:    "Success- network tags rendered",
+			fields:  fullTemplateParams,
+			wantErr: false,
+			init: func(e Environment) Environment {
+				n := e
+				n.NetworkTags = []string{"ci", "build-cache"}
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return strings.Contains(a, "      tags: [no-ip, ci, build-cache]\n"), fmt.Sprintf("templating failed while rendering network tags")
+			},
+		},
+		{
+			name:    "Failure- invalid network tag",
+			fields:  fullTemplateParams,
+			wantErr: true,
+			init: func(e Environment) Environment {
+				n := e
+				n.NetworkTags = []string{"Build Cache"}
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return true, ""
+			},
+		},
+		{
+			name:    "Failure- invalid label",
+			fields:  fullTemplateParams,
+			wantErr: true,
+			init: func(e Environment) Environment {
+				n := e
+				n.Labels = map[string]string{"Team": "platform"}
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return true, ""
+			},
+		},
+		{
+			name:    "Failure- worker zone in another region",
+			fields:  fullTemplateParams,
+			wantErr: true,
+			init: func(e Environment) Environment {
+				n := e
+				n.Zone = "europe-west1-b"
+				n.WorkerZones = []string{"us-central1-a"}
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return true, ""
+			},
+		},
+		{
+			name:    "Failure- invalid worker node group",
+			fields:  fullTemplateParams,
+			wantErr: true,
+			init: func(e Environment) Environment {
+				n := e
+				n.WorkerNodeGroup = "Build_Farm"
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return true, ""
+			},
+		},
+		{
+			name:    "Failure- invalid vm extension",
+			fields:  fullTemplateParams,
+			wantErr: true,
+			init: func(e Environment) Environment {
+				n := e
+				n.VMExtensions = []string{"cloud_properties: {}"}
+				return n
+			},
+			validate: func(a, b string) (bool, string) {
+				return true, ""
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -291,6 +609,113 @@ func TestEnvironment_ConfigureConcourseStemcell(t *testing.T) {
 	}
 }
 
+func TestEnvironment_ConfigureWorkerStemcell(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     Environment
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "workers run on the concourse stemcell",
+			env:  Environment{},
+		},
+		{
+			name: "resolve the worker stemcell",
+			env:  Environment{WorkerStemcellOS: "ubuntu-bionic", WorkerStemcellVersion: "1.10"},
+			want: "https://s3.amazonaws.com/bosh-gce-light-stemcells/1.10/light-bosh-stemcell-1.10-google-kvm-ubuntu-bionic-go_agent.tgz",
+		},
+		{
+			name:    "worker stemcell without OS",
+			env:     Environment{WorkerStemcellVersion: "1.10"},
+			wantErr: true,
+		},
+		{
+			name:    "worker stemcell with invalid version",
+			env:     Environment{WorkerStemcellOS: "ubuntu-bionic", WorkerStemcellVersion: "latest"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.env.ConfigureWorkerStemcell()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Environment.ConfigureWorkerStemcell() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Environment.ConfigureWorkerStemcell() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvironment_CheckStemcellURLs(t *testing.T) {
+	defer func(v string) { resource.GCPReleaseVersions = v }(resource.GCPReleaseVersions)
+	resource.GCPReleaseVersions = getStemcellFixture("stemcell_version")
+	defer func(f func(string) error) { headStemcell = f }(headStemcell)
+	const (
+		concourseURL = "https://s3.amazonaws.com/bosh-gce-light-stemcells/5/light-bosh-stemcell-5-google-kvm-ubuntu-xenial-go_agent.tgz"
+		workerURL    = "https://s3.amazonaws.com/bosh-gce-light-stemcells/1.10/light-bosh-stemcell-1.10-google-kvm-ubuntu-bionic-go_agent.tgz"
+	)
+	tests := []struct {
+		name     string
+		env      Environment
+		missing  map[string]bool
+		wantHead []string
+		wantErr  string
+	}{
+		{
+			name:     "concourse stemcell only",
+			env:      Environment{},
+			wantHead: []string{concourseURL},
+		},
+		{
+			name:     "distinct concourse and worker stemcells",
+			env:      Environment{WorkerStemcellOS: "ubuntu-bionic", WorkerStemcellVersion: "1.10"},
+			wantHead: []string{concourseURL, workerURL},
+		},
+		{
+			name:     "missing worker stemcell",
+			env:      Environment{WorkerStemcellOS: "ubuntu-bionic", WorkerStemcellVersion: "1.10"},
+			missing:  map[string]bool{workerURL: true},
+			wantHead: []string{concourseURL, workerURL},
+			wantErr:  "failed to resolve 1 stemcell(s): [worker " + workerURL + ": got status 404 Not Found]",
+		},
+		{
+			name:     "invalid worker stemcell",
+			env:      Environment{WorkerStemcellOS: "ubuntu-bionic"},
+			wantHead: []string{concourseURL},
+			wantErr:  "failed to resolve 1 stemcell(s): [worker: invalid worker stemcell version",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var heads []string
+			headStemcell = func(url string) error {
+				heads = append(heads, url)
+				if tt.missing[url] {
+					return errors.New("got status 404 Not Found")
+				}
+				return nil
+			}
+			err := tt.env.CheckStemcellURLs()
+			if !reflect.DeepEqual(heads, tt.wantHead) {
+				t.Errorf("Environment.CheckStemcellURLs() checked %v, want %v", heads, tt.wantHead)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Environment.CheckStemcellURLs() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("Environment.CheckStemcellURLs() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func listTemplFields(t *template.Template) map[string]int {
 	m := make(map[string]int)
 	return listNodeFields(t.Tree.Root, m)
@@ -342,3 +767,698 @@ func Test_CloudConfigStructureTest(t *testing.T) {
 		}
 	})
 }
+
+func TestEnvironment_ConfigureDirectorManifestCPI(t *testing.T) {
+	credentials, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(credentials.Name())
+	credentials.Close()
+	contents, err := ioutil.ReadFile("../fixtures/proxy_ca.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyCA := string(contents)
+
+	tests := []struct {
+		name            string
+		privateDirector bool
+		enableLocalDNS  bool
+		jumpboxUser     string
+		publicKey       string
+		networkTags     []string
+		labels          map[string]string
+		tags            string
+		maxTasks        int
+		retentionDays   int
+		mbusPort        int
+		natsPort        int
+		blobstoreProxy  string
+		noProxy         []string
+		proxyCA         string
+		wantContains    []string
+		wantNotContains []string
+	}{
+		{
+			name:         "public director has an external IP",
+			wantContains: []string{"name: public", "static_ips:\n    - 1.2.3.4", "mbus:((mbus_bootstrap_password))@1.2.3.4:6868"},
+		},
+		{
+			name:            "private director has no external IP",
+			privateDirector: true,
+			wantContains:    []string{"mbus:((mbus_bootstrap_password))@10.0.0.6:6868"},
+			wantNotContains: []string{"1.2.3.4", "name: public"},
+		},
+		{
+			name:            "local DNS is disabled by default",
+			wantNotContains: []string{"use_dns_addresses"},
+		},
+		{
+			name:           "local DNS enabled",
+			enableLocalDNS: true,
+			wantContains:   []string{"use_dns_addresses: true"},
+		},
+		{
+			name:         "default jumpbox user",
+			publicKey:    "ssh-rsa AAAAdefault",
+			wantContains: []string{"gateway_user: jumpbox", "- name: jumpbox\n        public_key: ssh-rsa AAAAdefault"},
+		},
+		{
+			name:            "custom jumpbox user and key",
+			jumpboxUser:     "breakglass",
+			publicKey:       "ssh-ed25519 AAAAcustom",
+			wantContains:    []string{"gateway_user: breakglass", "- name: breakglass\n        public_key: ssh-ed25519 AAAAcustom"},
+			wantNotContains: []string{"name: jumpbox"},
+		},
+		{
+			name:            "no network tags or labels",
+			wantContains:    []string{"tags:\n      - external\n"},
+			wantNotContains: []string{"labels:"},
+		},
+		{
+			name:         "network tags and labels",
+			networkTags:  []string{"ci", "build-cache"},
+			labels:       map[string]string{"team": "platform", "cost-centre": "cc_123"},
+			wantContains: []string{"tags:\n      - external\n      - ci\n      - build-cache\n", "labels:\n      cost-centre: cc_123\n      team: platform\n"},
+		},
+		{
+			name:            "network tags from tags",
+			tags:            "[ci, build-cache]",
+			wantContains:    []string{"tags:\n      - external\n      - ci\n      - build-cache\n"},
+			wantNotContains: []string{"labels:"},
+		},
+		{
+			name:         "labels from tags",
+			tags:         "{team: platform}",
+			wantContains: []string{"tags:\n      - external\n", "labels:\n      team: platform\n"},
+		},
+		{
+			name:            "tags are ignored when network tags are set",
+			networkTags:     []string{"ci"},
+			tags:            "{team: platform}",
+			wantContains:    []string{"- ci\n"},
+			wantNotContains: []string{"labels:"},
+		},
+		{
+			name:            "task retention defaults to the director release",
+			wantNotContains: []string{"max_tasks", "tasks_retention_period"},
+		},
+		{
+			name:          "task retention",
+			maxTasks:      500,
+			retentionDays: 30,
+			wantContains:  []string{"max_tasks: 500", "tasks_retention_period: 30"},
+		},
+		{
+			name:         "default agent ports",
+			wantContains: []string{"@10.0.0.6:4222\n", "port: 4222\n", "@1.2.3.4:6868\n", "@0.0.0.0:6868\n"},
+		},
+		{
+			name:            "custom agent ports",
+			mbusPort:        8443,
+			natsPort:        4443,
+			wantContains:    []string{"@10.0.0.6:4443\n", "port: 4443\n", "@1.2.3.4:8443\n", "@0.0.0.0:8443\n"},
+			wantNotContains: []string{"4222", "6868"},
+		},
+		{
+			name:            "no blobstore proxy by default",
+			wantNotContains: []string{"http_proxy", "no_proxy"},
+		},
+		{
+			name:           "blobstore proxy",
+			blobstoreProxy: "http://proxy.internal:3128",
+			noProxy:        []string{"10.0.1.0/24"},
+			wantContains: []string{
+				"env:\n      http_proxy: http://proxy.internal:3128\n      https_proxy: http://proxy.internal:3128\n",
+				"no_proxy: localhost,127.0.0.1,10.0.0.6,10.0.1.0/24\n",
+			},
+		},
+		{
+			name:            "no trusted certs by default",
+			wantNotContains: []string{"trusted_certs"},
+		},
+		{
+			name:    "proxy CA trusted",
+			proxyCA: proxyCA,
+			wantContains: []string{
+				"trusted_certs: |\n        -----BEGIN CERTIFICATE-----\n",
+				strings.Replace(strings.TrimSpace(proxyCA), "\n", "\n        ", -1),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Environment{
+				ExternalIP:         "1.2.3.4",
+				GcpCredentialsJSON: credentials.Name(),
+				InternalCIDR:       "10.0.0.0/24",
+				InternalGW:         "10.0.0.1",
+				InternalIP:         "10.0.0.6",
+				PrivateDirector:    tt.privateDirector,
+				EnableLocalDNS:     tt.enableLocalDNS,
+				JumpboxUser:        tt.jumpboxUser,
+				PublicKey:          tt.publicKey,
+				NetworkTags:        tt.networkTags,
+				Labels:             tt.labels,
+				Tags:               tt.tags,
+				BlobstoreProxyURL:  tt.blobstoreProxy,
+				BlobstoreNoProxy:   tt.noProxy,
+				DirectorMaxTasks:   tt.maxTasks,
+				TaskRetentionDays:  tt.retentionDays,
+				MbusPort:           tt.mbusPort,
+				NATSPort:           tt.natsPort,
+				ProxyCACert:        tt.proxyCA,
+			}
+			got, err := e.ConfigureDirectorManifestCPI()
+			if err != nil {
+				t.Fatalf("Environment.ConfigureDirectorManifestCPI() error = %v", err)
+			}
+			for _, s := range tt.wantContains {
+				if !strings.Contains(got, s) {
+					t.Errorf("Environment.ConfigureDirectorManifestCPI() expected to contain %q", s)
+				}
+			}
+			for _, s := range tt.wantNotContains {
+				if strings.Contains(got, s) {
+					t.Errorf("Environment.ConfigureDirectorManifestCPI() expected not to contain %q", s)
+				}
+			}
+		})
+	}
+}
+
+func TestEnvironment_ConfigureDirectorManifestCPIAgentPorts(t *testing.T) {
+	credentials, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(credentials.Name())
+	credentials.Close()
+
+	for _, e := range []Environment{
+		{MbusPort: 70000}, {NATSPort: -1}, {MbusPort: 4222},
+		{BlobstoreProxyURL: "proxy.internal:3128"}, {BlobstoreProxyURL: "ftp://proxy.internal"}, {BlobstoreNoProxy: []string{"10.0.1.0/24"}},
+		{ProxyCACert: "not a cert"}, {ProxyCACert: "-----BEGIN CERTIFICATE-----\nbm90IGEgY2VydA==\n-----END CERTIFICATE-----\n"},
+	} {
+		e.GcpCredentialsJSON = credentials.Name()
+		if _, err := e.ConfigureDirectorManifestCPI(); err == nil {
+			t.Errorf("Environment.ConfigureDirectorManifestCPI() expected an error for %+v", e)
+		}
+	}
+}
+
+func TestEnvironment_ConfigureDirectorManifestCPIExternalBlobstore(t *testing.T) {
+	credentials, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(credentials.Name())
+	credentials.Close()
+	blobstoreCreds, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(blobstoreCreds.Name())
+	if _, err = blobstoreCreds.WriteString(`{"type":"service_account","client_email":"blobstore@project.iam.gserviceaccount.com"}`); err != nil {
+		t.Fatal(err)
+	}
+	blobstoreCreds.Close()
+
+	tests := []struct {
+		name            string
+		bucket          string
+		credsJSON       string
+		wantErr         string
+		wantContains    []string
+		wantNotContains []string
+	}{
+		{
+			name:      "external blobstore",
+			bucket:    "director-blobs",
+			credsJSON: blobstoreCreds.Name(),
+			wantContains: []string{
+				"bucket_name: director-blobs",
+				"credentials_source: static",
+				"provider: gcs",
+				`blobstore@project.iam.gserviceaccount.com`,
+			},
+			wantNotContains: []string{"provider: dav", "name: blobstore_server_tls"},
+		},
+		{
+			name:      "missing bucket",
+			credsJSON: blobstoreCreds.Name(),
+			wantErr:   "a blobstore bucket is required to use an external blobstore",
+		},
+		{
+			name:    "missing credentials",
+			bucket:  "director-blobs",
+			wantErr: "blobstore credentials are required to use an external blobstore",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Environment{
+				ExternalIP:         "1.2.3.4",
+				GcpCredentialsJSON: credentials.Name(),
+				InternalCIDR:       "10.0.0.0/24",
+				InternalGW:         "10.0.0.1",
+				InternalIP:         "10.0.0.6",
+				ExternalBlobstore:  true,
+				BlobstoreBucket:    tt.bucket,
+				BlobstoreCredsJSON: tt.credsJSON,
+			}
+			got, err := e.ConfigureDirectorManifestCPI()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Environment.ConfigureDirectorManifestCPI() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Environment.ConfigureDirectorManifestCPI() error = %v", err)
+			}
+			for _, s := range tt.wantContains {
+				if !strings.Contains(got, s) {
+					t.Errorf("Environment.ConfigureDirectorManifestCPI() expected to contain %q", s)
+				}
+			}
+			for _, s := range tt.wantNotContains {
+				if strings.Contains(got, s) {
+					t.Errorf("Environment.ConfigureDirectorManifestCPI() expected not to contain %q", s)
+				}
+			}
+		})
+	}
+}
+
+func TestEnvironment_ConfigureConcourseOps(t *testing.T) {
+	e := Environment{
+		ExtraHosts:    map[string]string{"artifacts.internal": "10.0.1.5"},
+		WorkerRuntime: "containerd",
+	}
+	got, err := e.ConfigureConcourseOps()
+	if err != nil {
+		t.Fatalf("Environment.ConfigureConcourseOps() error = %v", err)
+	}
+	if !strings.Contains(got, "10.0.1.5 artifacts.internal") {
+		t.Errorf("Environment.ConfigureConcourseOps() = %s\nexpected to contain the extra host", got)
+	}
+	if !strings.Contains(got, "value: containerd") {
+		t.Errorf("Environment.ConfigureConcourseOps() = %s\nexpected to contain the worker runtime", got)
+	}
+
+	e.WorkerRuntime = "houdini"
+	if _, err := e.ConfigureConcourseOps(); err == nil {
+		t.Errorf("Environment.ConfigureConcourseOps() expected an error for an unknown worker runtime")
+	}
+
+	e.WorkerRuntime = ""
+	e.ExternalDBHost = "10.0.2.10"
+	e.ExternalDBPort = "5432"
+	e.ExternalDBName = "atc"
+	e.ExternalDBUser = "concourse"
+	e.ExternalDBPassword = "s3cret"
+	got, err = e.ConfigureConcourseOps()
+	if err != nil {
+		t.Fatalf("Environment.ConfigureConcourseOps() error = %v", err)
+	}
+	if !strings.Contains(got, "value: 10.0.2.10") {
+		t.Errorf("Environment.ConfigureConcourseOps() = %s\nexpected to contain the external database host", got)
+	}
+
+	e.ExternalDBPassword = ""
+	if _, err := e.ConfigureConcourseOps(); err == nil {
+		t.Errorf("Environment.ConfigureConcourseOps() expected an error for an incomplete external database")
+	}
+
+	e.ExternalDBHost = ""
+	e.ExternalDBPort = ""
+	e.ExternalDBName = ""
+	e.ExternalDBUser = ""
+	e.WorkerNodeGroup = "build-farm"
+	e.WorkerDrainTimeout = "45m"
+	e.WorkerRebalanceInterval = "1h30m"
+	got, err = e.ConfigureConcourseOps()
+	if err != nil {
+		t.Fatalf("Environment.ConfigureConcourseOps() error = %v", err)
+	}
+	if !strings.Contains(got, "value: worker-placement") {
+		t.Errorf("Environment.ConfigureConcourseOps() = %s\nexpected to attach the worker placement vm extension", got)
+	}
+	if !strings.Contains(got, "value: 45m") || !strings.Contains(got, "value: 1h30m") {
+		t.Errorf("Environment.ConfigureConcourseOps() = %s\nexpected to contain the worker drain timeout and rebalance interval", got)
+	}
+
+	e.WorkerDrainTimeout = "forever"
+	if _, err := e.ConfigureConcourseOps(); err == nil {
+		t.Errorf("Environment.ConfigureConcourseOps() expected an error for an invalid worker drain timeout")
+	}
+
+	e.WorkerDrainTimeout = ""
+	e.WorkerMaxTasks = 5
+	got, err = e.ConfigureConcourseOps()
+	if err != nil {
+		t.Fatalf("Environment.ConfigureConcourseOps() error = %v", err)
+	}
+	if !strings.Contains(got, "path: /instance_groups/name=web/jobs/name=web/properties/max_active_tasks_per_worker?\n  type: replace\n  value: 5") {
+		t.Errorf("Environment.ConfigureConcourseOps() = %s\nexpected to contain the worker max tasks", got)
+	}
+
+	e.WorkerMaxTasks = -1
+	if _, err := e.ConfigureConcourseOps(); err == nil {
+		t.Errorf("Environment.ConfigureConcourseOps() expected an error for negative worker max tasks")
+	}
+
+	e.WorkerMaxTasks = 0
+	e.WebDiskSizeGB = 10
+	got, err = e.ConfigureConcourseOps()
+	if err != nil {
+		t.Fatalf("Environment.ConfigureConcourseOps() error = %v", err)
+	}
+	if !strings.Contains(got, "path: /instance_groups/name=web/persistent_disk_type?\n  type: replace\n  value: web-disk") {
+		t.Errorf("Environment.ConfigureConcourseOps() = %s\nexpected to give the web instance group its own disk type", got)
+	}
+
+	e.WebDiskSizeGB = 0
+	e.Zone = "europe-west1-b"
+	e.WorkerZones = []string{"europe-west1-c"}
+	got, err = e.ConfigureConcourseOps()
+	if err != nil {
+		t.Fatalf("Environment.ConfigureConcourseOps() error = %v", err)
+	}
+	if !strings.Contains(got, "path: /instance_groups/name=worker/azs\n  type: replace\n  value:\n  - z1\n  - z2\n") {
+		t.Errorf("Environment.ConfigureConcourseOps() = %s\nexpected to spread the workers across the zones", got)
+	}
+
+	e.GCInterval = "1m"
+	e.GCMissingGracePeriod = "10m"
+	got, err = e.ConfigureConcourseOps()
+	if err != nil {
+		t.Fatalf("Environment.ConfigureConcourseOps() error = %v", err)
+	}
+	if !strings.Contains(got, "interval: 1m") || !strings.Contains(got, "missing_grace_period: 10m") {
+		t.Errorf("Environment.ConfigureConcourseOps() = %s\nexpected to contain the garbage collection settings", got)
+	}
+
+	e.GCInterval = "every minute"
+	if _, err := e.ConfigureConcourseOps(); err == nil {
+		t.Errorf("Environment.ConfigureConcourseOps() expected an error for an invalid garbage collection interval")
+	}
+}
+
+type mapS3API struct {
+	s3iface.S3API
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (m *mapS3API) GetObject(in *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	object, ok := m.objects[*in.Key]
+	if !ok {
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "no such key", nil)
+	}
+	return &s3.GetObjectOutput{Body: ioutil.NopCloser(bytes.NewReader(object))}, nil
+}
+
+func (m *mapS3API) PutObject(in *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	object, err := ioutil.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[*in.Key] = object
+	return nil, nil
+}
+
+func TestStore_SetManyGetMany(t *testing.T) {
+	m := &mapS3API{objects: map[string][]byte{}}
+	s, err := NewStore(m, "my bucket")
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	values := map[string][]byte{
+		"state.json": []byte("{}"),
+		"vars.yaml":  []byte("admin_password: secret"),
+	}
+	if err := s.SetMany(values); err != nil {
+		t.Fatalf("Store.SetMany() error = %v", err)
+	}
+	got, err := s.GetMany([]string{"state.json", "vars.yaml", "missing"})
+	if err != nil {
+		t.Fatalf("Store.GetMany() error = %v", err)
+	}
+	want := map[string][]byte{
+		"state.json": []byte("{}"),
+		"vars.yaml":  []byte("admin_password: secret"),
+		"missing":    {},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Store.GetMany() = %v, want %v", got, want)
+	}
+}
+
+func TestEnvironment_RenderAll(t *testing.T) {
+	defer func(v string) { resource.GCPReleaseVersions = v }(resource.GCPReleaseVersions)
+	resource.GCPReleaseVersions = getStemcellFixture("stemcell_version")
+	credentials, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(credentials.Name())
+	credentials.Close()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	e := Environment{
+		ExternalIP:         "1.2.3.4",
+		GcpCredentialsJSON: credentials.Name(),
+		InternalCIDR:       "10.0.0.0/24",
+		InternalIP:         "10.0.0.6",
+		WorkerRuntime:      "containerd",
+		Zone:               "europe-west1-b",
+	}
+	if err := e.RenderAll(filepath.Join(dir, "rendered")); err != nil {
+		t.Fatalf("Environment.RenderAll() error = %v", err)
+	}
+
+	director, err := e.ConfigureDirectorManifestCPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cloudConfig, err := e.ConfigureDirectorCloudConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	concourseOps, err := e.ConfigureConcourseOps()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"director.yml":      director,
+		"cloud-config.yml":  cloudConfig,
+		"concourse-ops.yml": concourseOps,
+		"stemcell-url.txt":  "https://s3.amazonaws.com/bosh-gce-light-stemcells/5/light-bosh-stemcell-5-google-kvm-ubuntu-xenial-go_agent.tgz\n",
+	} {
+		got, err := ioutil.ReadFile(filepath.Join(dir, "rendered", name))
+		if err != nil {
+			t.Errorf("Environment.RenderAll() did not write %s: %v", name, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("Environment.RenderAll() wrote %s = %s\nwant %s", name, got, want)
+		}
+	}
+}
+
+func TestStore_RecordMeta(t *testing.T) {
+	m := &mapS3API{objects: map[string][]byte{}}
+	fresh, err := NewStore(m, "my bucket")
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	if _, found, err := fresh.Meta(); err != nil || found {
+		t.Fatalf("Store.Meta() found = %v, error = %v, want a fresh environment", found, err)
+	}
+
+	s, err := NewStore(m, "my bucket", RecordMeta("europe-west1"))
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	if _, ok := m.objects["meta.json"]; !ok {
+		t.Fatalf("RecordMeta() did not write %s", "meta.json")
+	}
+	got, found, err := s.Meta()
+	if err != nil || !found {
+		t.Fatalf("Store.Meta() found = %v, error = %v", found, err)
+	}
+	if got.Bucket != "my bucket" || got.Region != "europe-west1" || got.IAAS != "GCP" || got.CreatedAt.IsZero() {
+		t.Errorf("Store.Meta() = %+v", got)
+	}
+}
+
+type fakeStorageAPI struct {
+	err error
+}
+
+func (f fakeStorageAPI) ListBuckets(string) error {
+	return f.err
+}
+
+type fakeComputeAPI struct {
+	project string
+	err     error
+}
+
+func (f *fakeComputeAPI) GetProject(project string) error {
+	f.project = project
+	return f.err
+}
+
+func TestEnvironment_Preflight(t *testing.T) {
+	tests := []struct {
+		name       string
+		storageErr error
+		computeErr error
+		wantErr    string
+	}{
+		{
+			name: "every API reachable",
+		},
+		{
+			name:       "Compute unreachable",
+			computeErr: errors.New("googleapi: Error 403: Access Not Configured"),
+			wantErr:    "failed to reach 1 GCP API(s): [Compute: googleapi: Error 403: Access Not Configured]",
+		},
+		{
+			name:       "Storage and Compute unreachable",
+			storageErr: errors.New("invalid_grant"),
+			computeErr: errors.New("invalid_grant"),
+			wantErr:    "failed to reach 2 GCP API(s): [Storage: invalid_grant, Compute: invalid_grant]",
+		},
+	}
+	defer func(f func(Environment) (storageAPI, computeAPI, error)) { newPreflightClients = f }(newPreflightClients)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compute := &fakeComputeAPI{err: tt.computeErr}
+			newPreflightClients = func(Environment) (storageAPI, computeAPI, error) {
+				return fakeStorageAPI{err: tt.storageErr}, compute, nil
+			}
+			err := Environment{ProjectID: "my-project"}.Preflight()
+			if compute.project != "my-project" {
+				t.Errorf("Environment.Preflight() checked project %q, want %q", compute.project, "my-project")
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Environment.Preflight() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Environment.Preflight() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnvironment_PreflightMissingCredentials(t *testing.T) {
+	err := Environment{GcpCredentialsJSON: filepath.Join(t.TempDir(), "missing.json")}.Preflight()
+	if err == nil || !strings.HasPrefix(err.Error(), "failed to create GCP clients") {
+		t.Errorf("Environment.Preflight() error = %v, want a client creation error", err)
+	}
+}
+
+func TestEnvironment_EstimateMonthlyCost(t *testing.T) {
+	groups := []cost.VMGroup{
+		{Name: "web", InstanceType: "n1-standard-1", Count: 1},
+		{Name: "worker", InstanceType: "n1-standard-4", Count: 2},
+	}
+	pricingAPI := func(machineType string) (float64, error) {
+		return 0, errors.New("billing API unavailable")
+	}
+	got, err := Environment{}.EstimateMonthlyCost(groups, pricingAPI)
+	if err != nil {
+		t.Fatalf("Environment.EstimateMonthlyCost() error = %v", err)
+	}
+	want := cost.Estimate{
+		Groups: []cost.GroupCost{
+			{VMGroup: groups[0], HourlyPrice: 0.0475, MonthlyCost: 34.67, Bundled: true},
+			{VMGroup: groups[1], HourlyPrice: 0.19, MonthlyCost: 277.4, Bundled: true},
+		},
+		MonthlyTotal: 312.07,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Environment.EstimateMonthlyCost() = %+v, want %+v", got, want)
+	}
+	if _, err = (Environment{}).EstimateMonthlyCost([]cost.VMGroup{{Name: "worker", InstanceType: "a2-highgpu-1g", Count: 1}}, pricingAPI); err == nil {
+		t.Error("Environment.EstimateMonthlyCost() expected an error for a machine type without a bundled price")
+	}
+}
+
+type fakeSnapshotAPI struct {
+	failDisk  string
+	snapshots map[string]string
+}
+
+func (f fakeSnapshotAPI) SnapshotDisk(project, disk, snapshot string) error {
+	if disk == f.failDisk {
+		return errors.New("resourceNotReady")
+	}
+	f.snapshots[disk] = project + "/" + snapshot
+	return nil
+}
+
+func TestEnvironment_SnapshotDisks(t *testing.T) {
+	tests := []struct {
+		name     string
+		failDisk string
+		want     map[string]string
+		wantErr  string
+	}{
+		{
+			name: "every disk snapshotted",
+			want: map[string]string{"disk-1a": "disk-1a-20200102030405", "disk-2b": "disk-2b-20200102030405"},
+		},
+		{
+			name:     "a disk failing to snapshot",
+			failDisk: "disk-1a",
+			want:     map[string]string{"disk-2b": "disk-2b-20200102030405"},
+			wantErr:  "failed to snapshot 1 disk(s): [disk-1a: resourceNotReady], snapshotted [disk-2b:disk-2b-20200102030405]",
+		},
+	}
+	defer func(f func(Environment) (snapshotAPI, error)) { newSnapshotClient = f }(newSnapshotClient)
+	defer func(f func() time.Time) { snapshotTime = f }(snapshotTime)
+	snapshotTime = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := fakeSnapshotAPI{failDisk: tt.failDisk, snapshots: map[string]string{}}
+			newSnapshotClient = func(Environment) (snapshotAPI, error) {
+				return fake, nil
+			}
+			got, err := Environment{ProjectID: "my-project"}.SnapshotDisks([]string{"disk-1a", "disk-2b"})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Environment.SnapshotDisks() = %v, want %v", got, tt.want)
+			}
+			for disk, snapshot := range got {
+				if fake.snapshots[disk] != "my-project/"+snapshot {
+					t.Errorf("Environment.SnapshotDisks() created %q for %s, want my-project/%s", fake.snapshots[disk], disk, snapshot)
+				}
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Environment.SnapshotDisks() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Environment.SnapshotDisks() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"golang.org/x/oauth2/google"
+	compute "google.golang.org/api/compute/v1"
+	storage "google.golang.org/api/storage/v1"
+)
+
+// storageAPI is the part of the Cloud Storage API used by Preflight
+type storageAPI interface {
+	ListBuckets(project string) error
+}
+
+// computeAPI is the part of the Compute Engine API used by Preflight
+type computeAPI interface {
+	GetProject(project string) error
+}
+
+type storageService struct {
+	s *storage.Service
+}
+
+func (s storageService) ListBuckets(project string) error {
+	_, err := s.s.Buckets.List(project).MaxResults(1).Do()
+	return err
+}
+
+type computeService struct {
+	s *compute.Service
+}
+
+func (s computeService) GetProject(project string) error {
+	_, err := s.s.Projects.Get(project).Do()
+	return err
+}
+
+// newPreflightClients builds the clients Preflight calls with the credentials
+// of the Environment, it is replaced in tests
+var newPreflightClients = func(e Environment) (storageAPI, computeAPI, error) {
+	creds, err := ioutil.ReadFile(e.GcpCredentialsJSON)
+	if err != nil {
+		return nil, nil, err
+	}
+	conf, err := google.JWTConfigFromJSON(creds, compute.CloudPlatformScope)
+	if err != nil {
+		return nil, nil, err
+	}
+	client := conf.Client(context.Background())
+	s, err := storage.New(client)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := compute.New(client)
+	if err != nil {
+		return nil, nil, err
+	}
+	return storageService{s}, computeService{c}, nil
+}
+
+// Preflight makes a lightweight authenticated call to Cloud Storage and Compute Engine, the GCP
+// APIs the director needs, returning a single error naming every API that could not be reached
+func (e Environment) Preflight() error {
+	storageClient, computeClient, err := newPreflightClients(e)
+	if err != nil {
+		return fmt.Errorf("failed to create GCP clients: [%v]", err)
+	}
+	checks := []struct {
+		api  string
+		call func() error
+	}{
+		{"Storage", func() error { return storageClient.ListBuckets(e.ProjectID) }},
+		{"Compute", func() error { return computeClient.GetProject(e.ProjectID) }},
+	}
+	var failed []string
+	for _, check := range checks {
+		if err := check.call(); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", check.api, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to reach %d GCP API(s): [%s]", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
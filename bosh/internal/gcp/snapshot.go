@@ -0,0 +1,107 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2/google"
+	compute "google.golang.org/api/compute/v1"
+)
+
+// snapshotAPI is the part of the Compute Engine API used by SnapshotDisks
+type snapshotAPI interface {
+	SnapshotDisk(project, disk, snapshot string) error
+}
+
+// SnapshotDisk creates a snapshot of disk in whichever zone of project it is in
+func (s computeService) SnapshotDisk(project, disk, snapshot string) error {
+	var zone string
+	err := s.s.Disks.AggregatedList(project).Filter(fmt.Sprintf("name = %s", disk)).Pages(context.Background(), func(list *compute.DiskAggregatedList) error {
+		for _, scoped := range list.Items {
+			for _, d := range scoped.Disks {
+				if d.Name == disk {
+					zone = path.Base(d.Zone)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if zone == "" {
+		return fmt.Errorf("disk %s not found", disk)
+	}
+	_, err = s.s.Disks.CreateSnapshot(project, zone, disk, &compute.Snapshot{
+		Name:        snapshot,
+		Description: fmt.Sprintf("control-tower snapshot of %s", disk),
+	}).Do()
+	return err
+}
+
+// newSnapshotClient builds the client SnapshotDisks calls with the credentials
+// of the Environment, it is replaced in tests
+var newSnapshotClient = func(e Environment) (snapshotAPI, error) {
+	creds, err := ioutil.ReadFile(e.GcpCredentialsJSON)
+	if err != nil {
+		return nil, err
+	}
+	conf, err := google.JWTConfigFromJSON(creds, compute.CloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	c, err := compute.New(conf.Client(context.Background()))
+	if err != nil {
+		return nil, err
+	}
+	return computeService{c}, nil
+}
+
+// snapshotTime is replaced in tests
+var snapshotTime = time.Now
+
+// SnapshotDisks creates a snapshot of each disk in diskCIDs, the persistent disk CIDs reported
+// by bosh, and returns the snapshot names by disk CID. Snapshots are named after their disk and
+// the time of the call. Every disk is snapshotted even when an earlier one fails, and the snapshots
+// that were created are returned along with the error
+func (e Environment) SnapshotDisks(diskCIDs []string) (map[string]string, error) {
+	client, err := newSnapshotClient(e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCP clients: [%v]", err)
+	}
+	suffix := snapshotTime().UTC().Format("-20060102150405")
+	snapshots := map[string]string{}
+	var failed []string
+	for _, cid := range diskCIDs {
+		// Snapshot names are limited to 63 characters
+		name := cid
+		if len(name) > 63-len(suffix) {
+			name = name[:63-len(suffix)]
+		}
+		name += suffix
+		if err := client.SnapshotDisk(e.ProjectID, cid, name); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", cid, err))
+			continue
+		}
+		snapshots[cid] = name
+	}
+	if len(failed) > 0 {
+		return snapshots, fmt.Errorf("failed to snapshot %d disk(s): [%s], snapshotted [%s]", len(failed), strings.Join(failed, ", "), describeSnapshots(snapshots))
+	}
+	return snapshots, nil
+}
+
+// describeSnapshots lists snapshots as disk:snapshot pairs ordered by disk
+func describeSnapshots(snapshots map[string]string) string {
+	var pairs []string
+	for cid, snapshot := range snapshots {
+		pairs = append(pairs, cid+":"+snapshot)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
@@ -0,0 +1,79 @@
+package gcp
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/EngineerBetter/control-tower/bosh/internal/concourseops"
+	"github.com/EngineerBetter/control-tower/bosh/internal/stemcells"
+	"github.com/EngineerBetter/control-tower/iaas"
+)
+
+// stemcellURL returns the URL of the GCP light stemcell of os at version
+func stemcellURL(os, version string) string {
+	return stemcells.URL(stemcells.Key{IAAS: iaas.GCP, OS: os, Version: version}, func() string {
+		return fmt.Sprintf("https://s3.amazonaws.com/bosh-gce-light-stemcells/%s/light-bosh-stemcell-%s-google-kvm-%s-go_agent.tgz", version, version, os)
+	})
+}
+
+// workerStemcell returns the stemcell the workers run on, which is zero when they use the stemcell of the deployment
+func (e Environment) workerStemcell() concourseops.WorkerStemcell {
+	return concourseops.WorkerStemcell{OS: e.WorkerStemcellOS, Version: e.WorkerStemcellVersion}
+}
+
+// ConfigureWorkerStemcell returns the URL of the stemcell the workers run on,
+// or an empty string when they run on the one returned by ConfigureConcourseStemcell
+func (e Environment) ConfigureWorkerStemcell() (string, error) {
+	stemcell := e.workerStemcell()
+	if stemcell == (concourseops.WorkerStemcell{}) {
+		return "", nil
+	}
+	if err := stemcell.Validate(); err != nil {
+		return "", err
+	}
+	return stemcellURL(stemcell.OS, stemcell.Version), nil
+}
+
+// headStemcell checks a stemcell can be downloaded from url, it is replaced in tests
+var headStemcell = func(url string) error {
+	resp, err := http.Head(url)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("got status %s", resp.Status)
+	}
+	return nil
+}
+
+// CheckStemcellURLs checks the stemcells of the deployment and of the workers both
+// resolve to stemcells that can be downloaded, naming every one that cannot
+func (e Environment) CheckStemcellURLs() error {
+	checks := []struct {
+		name string
+		url  func() (string, error)
+	}{
+		{"concourse", e.ConfigureConcourseStemcell},
+		{"worker", e.ConfigureWorkerStemcell},
+	}
+	var failed []string
+	for _, check := range checks {
+		url, err := check.url()
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", check.name, err))
+			continue
+		}
+		if url == "" {
+			continue
+		}
+		if err = headStemcell(url); err != nil {
+			failed = append(failed, fmt.Sprintf("%s %s: %v", check.name, url, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to resolve %d stemcell(s): [%s]", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
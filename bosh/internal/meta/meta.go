@@ -0,0 +1,56 @@
+// Package meta records where the state of an environment is kept, so that
+// later runs can find their bucket and region without them being supplied again
+package meta
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Key is the Store key of the environment metadata
+const Key = "meta.json"
+
+// Meta describes the Store of an environment
+type Meta struct {
+	Bucket    string    `json:"bucket"`
+	Region    string    `json:"region"`
+	IAAS      string    `json:"iaas"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Read returns the metadata stored under Key. found is false when
+// none has been recorded yet, meaning the environment is new
+func Read(get func(key string) ([]byte, error)) (m Meta, found bool, err error) {
+	data, err := get(Key)
+	if err != nil {
+		return Meta{}, false, fmt.Errorf("failed to read %s: [%v]", Key, err)
+	}
+	if len(data) == 0 {
+		return Meta{}, false, nil
+	}
+	if err = json.Unmarshal(data, &m); err != nil {
+		return Meta{}, false, fmt.Errorf("failed to parse %s: [%v]", Key, err)
+	}
+	return m, true, nil
+}
+
+// Record stores m under Key unless metadata has already been recorded,
+// keeping the time the environment was first used
+func Record(m Meta, get func(key string) ([]byte, error), set func(key string, value []byte) error) error {
+	_, found, err := Read(get)
+	if err != nil || found {
+		return err
+	}
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now().UTC()
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err = set(Key, data); err != nil {
+		return fmt.Errorf("failed to write %s: [%v]", Key, err)
+	}
+	return nil
+}
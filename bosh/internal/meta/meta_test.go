@@ -0,0 +1,103 @@
+package meta
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mapStore map[string][]byte
+
+func (s mapStore) get(key string) ([]byte, error) {
+	return s[key], nil
+}
+
+func (s mapStore) set(key string, value []byte) error {
+	s[key] = value
+	return nil
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name      string
+		store     mapStore
+		want      Meta
+		wantFound bool
+		wantErr   string
+	}{
+		{
+			name:  "fresh environment",
+			store: mapStore{},
+		},
+		{
+			name:      "recorded metadata",
+			store:     mapStore{Key: []byte(`{"bucket":"my-bucket","region":"eu-west-1","iaas":"AWS","created_at":"2019-05-01T10:00:00Z"}`)},
+			want:      Meta{Bucket: "my-bucket", Region: "eu-west-1", IAAS: "AWS", CreatedAt: time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC)},
+			wantFound: true,
+		},
+		{
+			name:    "corrupt metadata",
+			store:   mapStore{Key: []byte(`{`)},
+			wantErr: "failed to parse meta.json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found, err := Read(tt.store.get)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Read() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if found != tt.wantFound {
+				t.Errorf("Read() found = %v, want %v", found, tt.wantFound)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Read() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecord(t *testing.T) {
+	store := mapStore{}
+	first := Meta{Bucket: "my-bucket", Region: "eu-west-1", IAAS: "AWS"}
+	if err := Record(first, store.get, store.set); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	got, found, err := Read(store.get)
+	if err != nil || !found {
+		t.Fatalf("Read() = %v, %v, want recorded metadata", found, err)
+	}
+	if got.CreatedAt.IsZero() {
+		t.Errorf("Record() did not set CreatedAt")
+	}
+	first.CreatedAt = got.CreatedAt
+	if !reflect.DeepEqual(got, first) {
+		t.Errorf("Read() = %+v, want %+v", got, first)
+	}
+
+	if err = Record(Meta{Bucket: "other-bucket", Region: "us-east-1", IAAS: "AWS"}, store.get, store.set); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	got, _, _ = Read(store.get)
+	if !reflect.DeepEqual(got, first) {
+		t.Errorf("Record() overwrote existing metadata, got %+v, want %+v", got, first)
+	}
+}
+
+func TestRecordWriteError(t *testing.T) {
+	store := mapStore{}
+	err := Record(Meta{Bucket: "my-bucket"}, store.get, func(string, []byte) error {
+		return errors.New("access denied")
+	})
+	if err == nil || err.Error() != "failed to write meta.json: [access denied]" {
+		t.Errorf("Record() error = %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+// Package stemcells resolves the stemcells concourse is deployed with, caching the versions
+// parsed from the release versions of each IAAS and the URLs built from them
+package stemcells
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/EngineerBetter/control-tower/iaas"
+)
+
+// versionPath is the op of the release versions setting the stemcell version of the concourse deployment
+const versionPath = "/stemcells/alias=xenial/version"
+
+// Key identifies the URL of a stemcell, OS includes any architecture suffix
+type Key struct {
+	IAAS    iaas.Name
+	OS      string
+	Version string
+}
+
+type parsedVersions struct {
+	sum     [sha256.Size]byte
+	version string
+}
+
+// Cache holds the stemcell version parsed from the release versions of each IAAS and the URLs
+// resolved for each Key. It is safe for concurrent use
+type Cache struct {
+	mu       sync.Mutex
+	versions map[iaas.Name]parsedVersions
+	urls     map[Key]string
+}
+
+// NewCache returns an empty Cache
+func NewCache() *Cache {
+	return &Cache{
+		versions: map[iaas.Name]parsedVersions{},
+		urls:     map[Key]string{},
+	}
+}
+
+// parseVersion returns the stemcell version set by the release versions ops, it is replaced in tests
+var parseVersion = func(versions string) (string, error) {
+	var ops []struct {
+		Path  string
+		Value json.RawMessage
+	}
+	if err := json.Unmarshal([]byte(versions), &ops); err != nil {
+		return "", err
+	}
+	var version string
+	for _, op := range ops {
+		if op.Path != versionPath {
+			continue
+		}
+		if err := json.Unmarshal(op.Value, &version); err != nil {
+			return "", err
+		}
+	}
+	if version == "" {
+		return "", errors.New("did not find stemcell version in versions.json")
+	}
+	return version, nil
+}
+
+// Version returns the stemcell version set by versions, the release versions of name. They are only
+// parsed again when they differ from the last ones seen for name, which also drops the URLs cached for name
+func (c *Cache) Version(name iaas.Name, versions string) (string, error) {
+	sum := sha256.Sum256([]byte(versions))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if parsed, ok := c.versions[name]; ok && parsed.sum == sum {
+		return parsed.version, nil
+	}
+	version, err := parseVersion(versions)
+	if err != nil {
+		return "", err
+	}
+	for key := range c.urls {
+		if key.IAAS == name {
+			delete(c.urls, key)
+		}
+	}
+	c.versions[name] = parsedVersions{sum: sum, version: version}
+	return version, nil
+}
+
+// URL returns the URL of the stemcell identified by key, calling build the first time it is resolved
+func (c *Cache) URL(key Key, build func() string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if url, ok := c.urls[key]; ok {
+		return url
+	}
+	url := build()
+	c.urls[key] = url
+	return url
+}
+
+// defaultCache is shared by every Environment of the process
+var defaultCache = NewCache()
+
+// Version returns the stemcell version set by versions, the release versions of name, from a cache shared by the process
+func Version(name iaas.Name, versions string) (string, error) {
+	return defaultCache.Version(name, versions)
+}
+
+// URL returns the URL of the stemcell identified by key from a cache shared by the process, calling build on a miss
+func URL(key Key, build func() string) string {
+	return defaultCache.URL(key, build)
+}
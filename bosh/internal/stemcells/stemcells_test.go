@@ -0,0 +1,108 @@
+package stemcells
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/EngineerBetter/control-tower/iaas"
+)
+
+const (
+	versionsV1 = `[{"path": "/stemcells/alias=xenial/version", "value": "1"}]`
+	versionsV2 = `[{"path": "/stemcells/alias=xenial/version", "value": "2"}]`
+)
+
+// countParses counts the calls to parseVersion until the returned func restores it
+func countParses(t *testing.T) (*int, func()) {
+	t.Helper()
+	var mu sync.Mutex
+	parses := 0
+	parse := parseVersion
+	parseVersion = func(versions string) (string, error) {
+		mu.Lock()
+		parses++
+		mu.Unlock()
+		return parse(versions)
+	}
+	return &parses, func() { parseVersion = parse }
+}
+
+func TestCache_VersionParsesOnce(t *testing.T) {
+	parses, restore := countParses(t)
+	defer restore()
+	c := NewCache()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			version, err := c.Version(iaas.AWS, versionsV1)
+			if err != nil || version != "1" {
+				t.Errorf("Cache.Version() = %q, %v, want 1", version, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if *parses != 1 {
+		t.Errorf("versions parsed %d times, want 1", *parses)
+	}
+
+	if _, err := c.Version(iaas.GCP, versionsV1); err != nil {
+		t.Fatal(err)
+	}
+	if *parses != 2 {
+		t.Errorf("versions parsed %d times after a second IAAS, want 2", *parses)
+	}
+}
+
+func TestCache_VersionInvalidation(t *testing.T) {
+	parses, restore := countParses(t)
+	defer restore()
+	c := NewCache()
+	key := Key{IAAS: iaas.AWS, OS: "ubuntu-xenial", Version: "1"}
+	builds := 0
+	build := func() string {
+		builds++
+		return "https://example.com/stemcell-1.tgz"
+	}
+
+	if _, err := c.Version(iaas.AWS, versionsV1); err != nil {
+		t.Fatal(err)
+	}
+	c.URL(key, build)
+	c.URL(key, build)
+	if builds != 1 {
+		t.Errorf("URL built %d times, want 1", builds)
+	}
+
+	version, err := c.Version(iaas.AWS, versionsV2)
+	if err != nil || version != "2" {
+		t.Errorf("Cache.Version() = %q, %v after the versions changed, want 2", version, err)
+	}
+	if *parses != 2 {
+		t.Errorf("versions parsed %d times, want 2", *parses)
+	}
+	c.URL(key, build)
+	if builds != 2 {
+		t.Errorf("URL built %d times after the versions changed, want 2", builds)
+	}
+}
+
+func TestCache_VersionErrors(t *testing.T) {
+	c := NewCache()
+	for name, versions := range map[string]string{
+		"invalid JSON":    "{",
+		"no stemcell":     `[{"path": "/releases/name=concourse/version", "value": "1"}]`,
+		"invalid version": `[{"path": "/stemcells/alias=xenial/version", "value": 1}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := c.Version(iaas.AWS, versions); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+	if version, err := c.Version(iaas.AWS, versionsV1); err != nil || version != "1" {
+		t.Errorf("Cache.Version() = %q, %v after errors, want 1", version, err)
+	}
+}
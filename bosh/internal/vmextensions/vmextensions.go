@@ -0,0 +1,57 @@
+// Package vmextensions renders user defined vm_extensions for the cloud config
+package vmextensions
+
+import (
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+type vmExtension struct {
+	Name            string                 `yaml:"name"`
+	CloudProperties map[string]interface{} `yaml:"cloud_properties,omitempty"`
+}
+
+// PlacementName is the name of the vm_extension constraining where workers are placed
+const PlacementName = "worker-placement"
+
+// reserved holds the names of vm_extensions already defined by control-tower
+var reserved = map[string]bool{"atc": true}
+
+// Render parses definitions, each a YAML vm_extension with a name and cloud_properties,
+// and returns them as cloud config list items along with their names
+func Render(definitions []string) (string, []string, error) {
+	if len(definitions) == 0 {
+		return "", nil, nil
+	}
+	var extensions []vmExtension
+	var names []string
+	seen := map[string]bool{}
+	for i, definition := range definitions {
+		var extension vmExtension
+		if err := yaml.UnmarshalStrict([]byte(definition), &extension); err != nil {
+			return "", nil, fmt.Errorf("invalid vm extension at index %d: [%v]", i, err)
+		}
+		if extension.Name == "" {
+			return "", nil, fmt.Errorf("vm extension at index %d has no name", i)
+		}
+		if reserved[extension.Name] || seen[extension.Name] {
+			return "", nil, fmt.Errorf("vm extension %q is already defined", extension.Name)
+		}
+		seen[extension.Name] = true
+		extensions = append(extensions, extension)
+		names = append(names, extension.Name)
+	}
+	b, err := yaml.Marshal(extensions)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(b), names, nil
+}
+
+// Placement returns the definition of the vm_extension applying cloudProperties
+// to constrain where workers are placed
+func Placement(cloudProperties map[string]interface{}) (string, error) {
+	b, err := yaml.Marshal(vmExtension{Name: PlacementName, CloudProperties: cloudProperties})
+	return string(b), err
+}
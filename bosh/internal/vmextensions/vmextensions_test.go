@@ -0,0 +1,70 @@
+package vmextensions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name        string
+		definitions []string
+		want        string
+		wantNames   []string
+		wantErr     bool
+	}{
+		{
+			name: "no extensions",
+		},
+		{
+			name: "extensions",
+			definitions: []string{
+				"name: placement\ncloud_properties:\n  placement_group: workers\n",
+				"name: scratch\ncloud_properties:\n  ephemeral_disk:\n    size: 500000\n",
+			},
+			want: `- name: placement
+  cloud_properties:
+    placement_group: workers
+- name: scratch
+  cloud_properties:
+    ephemeral_disk:
+      size: 500000
+`,
+			wantNames: []string{"placement", "scratch"},
+		},
+		{
+			name:        "extension without a name",
+			definitions: []string{"cloud_properties:\n  placement_group: workers\n"},
+			wantErr:     true,
+		},
+		{
+			name:        "extension with an unknown key",
+			definitions: []string{"name: placement\nproperties: {}\n"},
+			wantErr:     true,
+		},
+		{
+			name:        "extension redefining atc",
+			definitions: []string{"name: atc\n"},
+			wantErr:     true,
+		},
+		{
+			name:        "duplicate extensions",
+			definitions: []string{"name: placement\n", "name: placement\n"},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, names, err := Render(tt.definitions)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("Render() names = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
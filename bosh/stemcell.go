@@ -0,0 +1,20 @@
+package bosh
+
+import (
+	"fmt"
+
+	"github.com/EngineerBetter/control-tower/bosh/internal/aws"
+	"github.com/EngineerBetter/control-tower/bosh/internal/gcp"
+	"github.com/EngineerBetter/control-tower/iaas"
+)
+
+// StemcellURL returns the URL of the stemcell Concourse is deployed with on the given IAAS
+func StemcellURL(name iaas.Name) (string, error) {
+	switch name {
+	case iaas.AWS:
+		return aws.Environment{}.ConfigureConcourseStemcell()
+	case iaas.GCP:
+		return gcp.Environment{}.ConfigureConcourseStemcell()
+	}
+	return "", fmt.Errorf("IAAS not supported: %s", name)
+}
@@ -10,6 +10,7 @@ var Commands = []cli.Command{
 	destroyCmd,
 	infoCmd,
 	maintainCmd,
+	stemcellURLCmd,
 }
 
 var nonInteractive bool
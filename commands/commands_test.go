@@ -235,4 +235,36 @@ var _ = Describe("commands", func() {
 			})
 		})
 	})
+
+	Describe("stemcell-url", func() {
+		Context("When the IAAS is AWS", func() {
+			It("should print the AWS stemcell URL", func() {
+				command := exec.Command(cliPath, "stemcell-url", "--iaas", "AWS")
+				session, err := Start(command, GinkgoWriter, GinkgoWriter)
+				Expect(err).ToNot(HaveOccurred())
+				Eventually(session).Should(Exit(0))
+				Expect(session.Out).To(Say(`https://s3.amazonaws.com/bosh-aws-light-stemcells/\S+/light-bosh-stemcell-\S+-aws-xen-hvm-ubuntu-xenial-go_agent.tgz`))
+			})
+		})
+
+		Context("When the IAAS is GCP", func() {
+			It("should print the GCP stemcell URL", func() {
+				command := exec.Command(cliPath, "stemcell-url", "--iaas", "GCP")
+				session, err := Start(command, GinkgoWriter, GinkgoWriter)
+				Expect(err).ToNot(HaveOccurred())
+				Eventually(session).Should(Exit(0))
+				Expect(session.Out).To(Say(`https://s3.amazonaws.com/bosh-gce-light-stemcells/\S+/light-bosh-stemcell-\S+-google-kvm-ubuntu-xenial-go_agent.tgz`))
+			})
+		})
+
+		Context("When the IAAS is not specified", func() {
+			It("Should show a meaningful error", func() {
+				command := exec.Command(cliPath, "stemcell-url")
+				session, err := Start(command, GinkgoWriter, GinkgoWriter)
+				Expect(err).ToNot(HaveOccurred())
+				Eventually(session).Should(Exit(1))
+				Expect(session.Err).To(Say("--iaas flag not set"))
+			})
+		})
+	})
 })
@@ -68,7 +68,7 @@ var deployFlags = []cli.Flag{
 	},
 	cli.StringFlag{
 		Name:        "worker-type",
-		Usage:       "(optional) Specify a worker type for aws (m5 or m4)",
+		Usage:       "(optional) Specify a worker type for aws (m5, m4 or a Graviton family such as m6g)",
 		EnvVar:      "WORKER_TYPE",
 		Value:       "m4",
 		Destination: &initialDeployArgs.WorkerType,
@@ -178,6 +178,142 @@ var deployFlags = []cli.Flag{
 		EnvVar:      "RDS_SUBNET_RANGE2",
 		Destination: &initialDeployArgs.RDS2CIDR,
 	},
+	cli.IntFlag{
+		Name:        "mbus-port",
+		Usage:       "(optional) Port the BOSH agent on the director listens on for mbus (default: 6868)",
+		EnvVar:      "MBUS_PORT",
+		Destination: &initialDeployArgs.MbusPort,
+	},
+	cli.IntFlag{
+		Name:        "nats-port",
+		Usage:       "(optional) Port the director's NATS listens on for the BOSH agents (default: 4222)",
+		EnvVar:      "NATS_PORT",
+		Destination: &initialDeployArgs.NATSPort,
+	},
+	cli.IntFlag{
+		Name:        "atc-port",
+		Usage:       "(optional) Port to serve the Concourse web interface on (default: 443)",
+		EnvVar:      "ATC_PORT",
+		Destination: &initialDeployArgs.ATCPort,
+	},
+	cli.IntFlag{
+		Name:        "db-disk-size",
+		Usage:       "(optional) Size in GB of the persistent disk of the colocated Concourse database",
+		EnvVar:      "DB_DISK_SIZE",
+		Destination: &initialDeployArgs.DBDiskSizeGB,
+	},
+	cli.IntFlag{
+		Name:        "web-disk-size",
+		Usage:       "(optional) Size in GB of the persistent disk of each Concourse web node",
+		EnvVar:      "WEB_DISK_SIZE",
+		Destination: &initialDeployArgs.WebDiskSizeGB,
+	},
+	cli.IntFlag{
+		Name:        "worker-disk-size",
+		Usage:       "(optional) Size in GB of the persistent disk of each Concourse worker",
+		EnvVar:      "WORKER_DISK_SIZE",
+		Destination: &initialDeployArgs.WorkerDiskSizeGB,
+	},
+	cli.StringFlag{
+		Name:        "worker-disk-type",
+		Usage:       "(optional) Disk type of the Concourse workers for aws. Can be ebs or instance-store",
+		EnvVar:      "WORKER_DISK_TYPE",
+		Destination: &initialDeployArgs.WorkerDiskType,
+	},
+	cli.StringFlag{
+		Name:        "external-db-host",
+		Usage:       "(optional) Host of a postgres database for Concourse to use in place of the colocated one",
+		EnvVar:      "EXTERNAL_DB_HOST",
+		Destination: &initialDeployArgs.ExternalDBHost,
+	},
+	cli.StringFlag{
+		Name:        "external-db-port",
+		Usage:       "(optional) Port of the external postgres database",
+		EnvVar:      "EXTERNAL_DB_PORT",
+		Destination: &initialDeployArgs.ExternalDBPort,
+	},
+	cli.StringFlag{
+		Name:        "external-db-name",
+		Usage:       "(optional) Name of the external postgres database",
+		EnvVar:      "EXTERNAL_DB_NAME",
+		Destination: &initialDeployArgs.ExternalDBName,
+	},
+	cli.StringFlag{
+		Name:        "external-db-user",
+		Usage:       "(optional) User of the external postgres database",
+		EnvVar:      "EXTERNAL_DB_USER",
+		Destination: &initialDeployArgs.ExternalDBUser,
+	},
+	cli.StringFlag{
+		Name:        "external-db-password",
+		Usage:       "(optional) Password of the external postgres database",
+		EnvVar:      "EXTERNAL_DB_PASSWORD",
+		Destination: &initialDeployArgs.ExternalDBPassword,
+	},
+	cli.StringSliceFlag{
+		Name:  "extra-host",
+		Usage: "(optional) hostname=IP entry to add to /etc/hosts on the Concourse workers - Multiple entries can be added with multiple uses of this flag",
+		Value: &initialDeployArgs.ExtraHosts,
+	},
+	cli.BoolFlag{
+		Name:        "lets-encrypt",
+		Usage:       "(optional) Have the Concourse web node obtain its certificate for --domain from Let's Encrypt",
+		EnvVar:      "LETS_ENCRYPT",
+		Destination: &initialDeployArgs.LetsEncrypt,
+	},
+	cli.BoolFlag{
+		Name:        "require-imdsv2",
+		Usage:       "(optional) Require IMDSv2 for the instance metadata of the director and workers on aws",
+		EnvVar:      "REQUIRE_IMDSV2",
+		Destination: &initialDeployArgs.RequireIMDSv2,
+	},
+	cli.StringFlag{
+		Name:        "token-signing-key",
+		Usage:       "(optional) RSA private key the Concourse web node signs its auth tokens with",
+		EnvVar:      "TOKEN_SIGNING_KEY",
+		Destination: &initialDeployArgs.TokenSigningKey,
+	},
+	cli.IntFlag{
+		Name:        "web-instances",
+		Usage:       "(optional) Number of Concourse web nodes, more than one needs a load balancer in front of them",
+		EnvVar:      "WEB_INSTANCES",
+		Destination: &initialDeployArgs.WebInstanceCount,
+	},
+	cli.IntFlag{
+		Name:        "worker-max-tasks",
+		Usage:       "(optional) Maximum number of active tasks on each Concourse worker (default: unlimited)",
+		EnvVar:      "WORKER_MAX_TASKS",
+		Destination: &initialDeployArgs.WorkerMaxTasks,
+	},
+	cli.StringFlag{
+		Name:        "worker-placement-group",
+		Usage:       "(optional) Existing aws placement group to launch the Concourse workers into",
+		EnvVar:      "WORKER_PLACEMENT_GROUP",
+		Destination: &initialDeployArgs.WorkerPlacementGroup,
+	},
+	cli.StringFlag{
+		Name:        "worker-node-group",
+		Usage:       "(optional) Existing gcp sole-tenant node group to run the Concourse workers on",
+		EnvVar:      "WORKER_NODE_GROUP",
+		Destination: &initialDeployArgs.WorkerNodeGroup,
+	},
+	cli.StringFlag{
+		Name:        "worker-stemcell-os",
+		Usage:       "(optional) Stemcell OS of the Concourse workers, requires --worker-stemcell-version",
+		EnvVar:      "WORKER_STEMCELL_OS",
+		Destination: &initialDeployArgs.WorkerStemcellOS,
+	},
+	cli.StringFlag{
+		Name:        "worker-stemcell-version",
+		Usage:       "(optional) Stemcell version of the Concourse workers, requires --worker-stemcell-os",
+		EnvVar:      "WORKER_STEMCELL_VERSION",
+		Destination: &initialDeployArgs.WorkerStemcellVersion,
+	},
+	cli.StringSliceFlag{
+		Name:  "worker-zone",
+		Usage: "(optional) Additional gcp zone to spread the Concourse workers across - Multiple zones can be added with multiple uses of this flag",
+		Value: &initialDeployArgs.WorkerZones,
+	},
 }
 
 func deployAction(c *cli.Context, deployArgs deploy.Args, provider iaas.Provider) error {
@@ -3,7 +3,9 @@ package deploy
 import (
 	"errors"
 	"fmt"
+	"net"
 	"regexp"
+	"strings"
 
 	"gopkg.in/urfave/cli.v1"
 )
@@ -60,6 +62,54 @@ type Args struct {
 	RDS1CIDRIsSet    bool
 	RDS2CIDR         string
 	RDS2CIDRIsSet    bool
+	MbusPort         int
+	MbusPortIsSet    bool
+	NATSPort         int
+	NATSPortIsSet    bool
+
+	// The following customise the concourse deployment
+	ATCPort                    int
+	ATCPortIsSet               bool
+	DBDiskSizeGB               int
+	DBDiskSizeGBIsSet          bool
+	WebDiskSizeGB              int
+	WebDiskSizeGBIsSet         bool
+	WorkerDiskSizeGB           int
+	WorkerDiskSizeGBIsSet      bool
+	WorkerDiskType             string
+	WorkerDiskTypeIsSet        bool
+	ExternalDBHost             string
+	ExternalDBHostIsSet        bool
+	ExternalDBPort             string
+	ExternalDBPortIsSet        bool
+	ExternalDBName             string
+	ExternalDBNameIsSet        bool
+	ExternalDBUser             string
+	ExternalDBUserIsSet        bool
+	ExternalDBPassword         string
+	ExternalDBPasswordIsSet    bool
+	ExtraHosts                 cli.StringSlice
+	ExtraHostsIsSet            bool
+	LetsEncrypt                bool
+	LetsEncryptIsSet           bool
+	RequireIMDSv2              bool
+	RequireIMDSv2IsSet         bool
+	TokenSigningKey            string
+	TokenSigningKeyIsSet       bool
+	WebInstanceCount           int
+	WebInstanceCountIsSet      bool
+	WorkerMaxTasks             int
+	WorkerMaxTasksIsSet        bool
+	WorkerPlacementGroup       string
+	WorkerPlacementGroupIsSet  bool
+	WorkerNodeGroup            string
+	WorkerNodeGroupIsSet       bool
+	WorkerStemcellOS           string
+	WorkerStemcellOSIsSet      bool
+	WorkerStemcellVersion      string
+	WorkerStemcellVersionIsSet bool
+	WorkerZones                cli.StringSlice
+	WorkerZonesIsSet           bool
 }
 
 // MarkSetFlags is marking the IsSet DeployArgs
@@ -113,6 +163,52 @@ func (a *Args) MarkSetFlags(c FlagSetChecker) error {
 				a.RDS1CIDRIsSet = true
 			case "rds-subnet-range2":
 				a.RDS2CIDRIsSet = true
+			case "mbus-port":
+				a.MbusPortIsSet = true
+			case "nats-port":
+				a.NATSPortIsSet = true
+			case "atc-port":
+				a.ATCPortIsSet = true
+			case "db-disk-size":
+				a.DBDiskSizeGBIsSet = true
+			case "web-disk-size":
+				a.WebDiskSizeGBIsSet = true
+			case "worker-disk-size":
+				a.WorkerDiskSizeGBIsSet = true
+			case "worker-disk-type":
+				a.WorkerDiskTypeIsSet = true
+			case "external-db-host":
+				a.ExternalDBHostIsSet = true
+			case "external-db-port":
+				a.ExternalDBPortIsSet = true
+			case "external-db-name":
+				a.ExternalDBNameIsSet = true
+			case "external-db-user":
+				a.ExternalDBUserIsSet = true
+			case "external-db-password":
+				a.ExternalDBPasswordIsSet = true
+			case "extra-host":
+				a.ExtraHostsIsSet = true
+			case "lets-encrypt":
+				a.LetsEncryptIsSet = true
+			case "require-imdsv2":
+				a.RequireIMDSv2IsSet = true
+			case "token-signing-key":
+				a.TokenSigningKeyIsSet = true
+			case "web-instances":
+				a.WebInstanceCountIsSet = true
+			case "worker-max-tasks":
+				a.WorkerMaxTasksIsSet = true
+			case "worker-placement-group":
+				a.WorkerPlacementGroupIsSet = true
+			case "worker-node-group":
+				a.WorkerNodeGroupIsSet = true
+			case "worker-stemcell-os":
+				a.WorkerStemcellOSIsSet = true
+			case "worker-stemcell-version":
+				a.WorkerStemcellVersionIsSet = true
+			case "worker-zone":
+				a.WorkerZonesIsSet = true
 			default:
 				return fmt.Errorf("flag %q is not supported by deployment flags", f)
 			}
@@ -166,6 +262,18 @@ func (a Args) Validate() error {
 		return err
 	}
 
+	if err := a.validateATCPort(); err != nil {
+		return err
+	}
+
+	if err := a.validateAgentPorts(); err != nil {
+		return err
+	}
+
+	if err := a.validateExtraHosts(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -248,6 +356,38 @@ func (a Args) validateTags() error {
 	return nil
 }
 
+func (a Args) validateATCPort() error {
+	if a.ATCPortIsSet && (a.ATCPort < 1 || a.ATCPort > 65535) {
+		return fmt.Errorf("--atc-port must be between 1 and 65535, got %d", a.ATCPort)
+	}
+
+	return nil
+}
+
+func (a Args) validateAgentPorts() error {
+	if a.MbusPortIsSet && (a.MbusPort < 1 || a.MbusPort > 65535) {
+		return fmt.Errorf("--mbus-port must be between 1 and 65535, got %d", a.MbusPort)
+	}
+	if a.NATSPortIsSet && (a.NATSPort < 1 || a.NATSPort > 65535) {
+		return fmt.Errorf("--nats-port must be between 1 and 65535, got %d", a.NATSPort)
+	}
+	if a.MbusPortIsSet && a.NATSPortIsSet && a.MbusPort == a.NATSPort {
+		return errors.New("--mbus-port and --nats-port must be different ports")
+	}
+
+	return nil
+}
+
+func (a Args) validateExtraHosts() error {
+	for _, entry := range a.ExtraHosts {
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || net.ParseIP(kv[1]) == nil {
+			return fmt.Errorf("`%v` is not in the format `hostname=IP`", entry)
+		}
+	}
+	return nil
+}
+
 // FlagSetChecker allows us to find out if flags were set, adn what the names of all flags are
 type FlagSetChecker interface {
 	IsSet(name string) bool
@@ -182,6 +182,60 @@ func TestDeployArgs_Validate(t *testing.T) {
 			},
 			wantErr:     true,
 			expectedErr: "both --public-subnet-range and --private-subnet-range are required when either is provided",
+		},
+		{
+			name: "ATC port must be in range",
+			modification: func() Args {
+				args := defaultFields
+				args.ATCPort = 0
+				args.ATCPortIsSet = true
+				return args
+			},
+			wantErr:     true,
+			expectedErr: "--atc-port must be between 1 and 65535, got 0",
+		},
+		{
+			name: "Mbus port must be in range",
+			modification: func() Args {
+				args := defaultFields
+				args.MbusPort = 70000
+				args.MbusPortIsSet = true
+				return args
+			},
+			wantErr:     true,
+			expectedErr: "--mbus-port must be between 1 and 65535, got 70000",
+		},
+		{
+			name: "Mbus and NATS ports must differ",
+			modification: func() Args {
+				args := defaultFields
+				args.MbusPort = 7868
+				args.MbusPortIsSet = true
+				args.NATSPort = 7868
+				args.NATSPortIsSet = true
+				return args
+			},
+			wantErr:     true,
+			expectedErr: "--mbus-port and --nats-port must be different ports",
+		},
+		{
+			name: "Extra hosts should be in the format 'hostname=IP'",
+			modification: func() Args {
+				args := defaultFields
+				args.ExtraHosts = []string{"artifacts.internal=10.0.0.20"}
+				return args
+			},
+			wantErr: false,
+		},
+		{
+			name: "Invalid extra hosts should throw a helpful error",
+			modification: func() Args {
+				args := defaultFields
+				args.ExtraHosts = []string{"artifacts.internal=not-an-ip"}
+				return args
+			},
+			wantErr:     true,
+			expectedErr: "`artifacts.internal=not-an-ip` is not in the format `hostname=IP`",
 		}}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/EngineerBetter/control-tower/bosh"
+	"github.com/EngineerBetter/control-tower/iaas"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var stemcellURLIAAS string
+
+var stemcellURLFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:        "iaas",
+		Usage:       "(required) IAAS, can be AWS or GCP",
+		EnvVar:      "IAAS",
+		Destination: &stemcellURLIAAS,
+	},
+}
+
+var stemcellURLCmd = cli.Command{
+	Name:  "stemcell-url",
+	Usage: "Prints the URL of the stemcell Concourse is deployed with",
+	Flags: stemcellURLFlags,
+	Action: func(c *cli.Context) error {
+		if stemcellURLIAAS == "" {
+			return errors.New("Error validating args on stemcell-url: [--iaas flag not set]")
+		}
+		iaasName, err := iaas.Validate(stemcellURLIAAS)
+		if err != nil {
+			return fmt.Errorf("Error mapping to supported IAASes on stemcell-url: [%v]", err)
+		}
+		url, err := bosh.StemcellURL(iaasName)
+		if err != nil {
+			return fmt.Errorf("Error resolving stemcell URL: [%v]", err)
+		}
+		_, err = fmt.Fprintln(os.Stdout, url)
+		return err
+	},
+}
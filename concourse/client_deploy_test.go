@@ -500,6 +500,12 @@ wEW5QkylaPEkbVDhJWeR1I8=
 					args.WorkerSizeIsSet = true
 					args.WorkerType = "m5"
 					args.WorkerTypeIsSet = true
+					args.ExtraHosts = []string{"artifacts.internal=10.0.0.20"}
+					args.ExtraHostsIsSet = true
+					args.WorkerMaxTasks = 8
+					args.WorkerMaxTasksIsSet = true
+					args.ATCPort = 8443
+					args.ATCPortIsSet = true
 
 					configAfterLoad = configInBucket
 					configAfterLoad.AllowIPs = "\"88.98.225.40/32\""
@@ -521,9 +527,13 @@ wEW5QkylaPEkbVDhJWeR1I8=
 					configAfterLoad.Tags = args.Tags
 					configAfterLoad.WorkerType = args.WorkerType
 					configAfterLoad.VMProvisioningType = config.ON_DEMAND
+					configAfterLoad.ExtraHosts = map[string]string{"artifacts.internal": "10.0.0.20"}
+					configAfterLoad.WorkerMaxTasks = args.WorkerMaxTasks
+					configAfterLoad.ATCPort = args.ATCPort
 
 					terraformInputVars = &terraform.AWSInputVars{
 						AllowIPs:               configAfterLoad.AllowIPs,
+						ATCPort:                configAfterLoad.ATCPort,
 						AvailabilityZone:       configAfterLoad.AvailabilityZone,
 						ConfigBucket:           configAfterLoad.ConfigBucket,
 						Deployment:             configAfterLoad.Deployment,
@@ -810,6 +820,21 @@ wEW5QkylaPEkbVDhJWeR1I8=
 					Eventually(stdout).Should(gbytes.Say("fly --target happymeal login --concourse-url https://ci.google.com --username admin --password s3cret"))
 				})
 			})
+
+			Context("and a custom ATC port is provided", func() {
+				BeforeEach(func() {
+					args.ATCPort = 8443
+					args.ATCPortIsSet = true
+				})
+
+				It("Prints the concourse URL with the port", func() {
+					client := buildClient()
+					err := client.Deploy()
+					Expect(err).ToNot(HaveOccurred())
+					Eventually(stdout).Should(gbytes.Say("DEPLOY SUCCESSFUL"))
+					Eventually(stdout).Should(gbytes.Say("--concourse-url https://ci.google.com:8443 --username"))
+				})
+			})
 		})
 
 		Context("When the user tries to change the region of an existing deployment", func() {
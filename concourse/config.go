@@ -157,6 +157,75 @@ func applyArgumentsToConfig(conf config.Config, deployArgs *deploy.Args, provide
 	if deployArgs.WorkerTypeIsSet {
 		conf.WorkerType = deployArgs.WorkerType
 	}
+	if deployArgs.MbusPortIsSet {
+		conf.MbusPort = deployArgs.MbusPort
+	}
+	if deployArgs.NATSPortIsSet {
+		conf.NATSPort = deployArgs.NATSPort
+	}
+	if deployArgs.ATCPortIsSet {
+		conf.ATCPort = deployArgs.ATCPort
+	}
+	if deployArgs.DBDiskSizeGBIsSet {
+		conf.DBDiskSizeGB = deployArgs.DBDiskSizeGB
+	}
+	if deployArgs.WebDiskSizeGBIsSet {
+		conf.WebDiskSizeGB = deployArgs.WebDiskSizeGB
+	}
+	if deployArgs.WorkerDiskSizeGBIsSet {
+		conf.WorkerDiskSizeGB = deployArgs.WorkerDiskSizeGB
+	}
+	if deployArgs.WorkerDiskTypeIsSet {
+		conf.WorkerDiskType = deployArgs.WorkerDiskType
+	}
+	if deployArgs.ExternalDBHostIsSet {
+		conf.ExternalDBHost = deployArgs.ExternalDBHost
+	}
+	if deployArgs.ExternalDBPortIsSet {
+		conf.ExternalDBPort = deployArgs.ExternalDBPort
+	}
+	if deployArgs.ExternalDBNameIsSet {
+		conf.ExternalDBName = deployArgs.ExternalDBName
+	}
+	if deployArgs.ExternalDBUserIsSet {
+		conf.ExternalDBUser = deployArgs.ExternalDBUser
+	}
+	if deployArgs.ExternalDBPasswordIsSet {
+		conf.ExternalDBPassword = deployArgs.ExternalDBPassword
+	}
+	if deployArgs.ExtraHostsIsSet {
+		conf.ExtraHosts = parseExtraHosts(deployArgs.ExtraHosts)
+	}
+	if deployArgs.LetsEncryptIsSet {
+		conf.LetsEncrypt = deployArgs.LetsEncrypt
+	}
+	if deployArgs.RequireIMDSv2IsSet {
+		conf.RequireIMDSv2 = deployArgs.RequireIMDSv2
+	}
+	if deployArgs.TokenSigningKeyIsSet {
+		conf.TokenSigningKey = deployArgs.TokenSigningKey
+	}
+	if deployArgs.WebInstanceCountIsSet {
+		conf.WebInstanceCount = deployArgs.WebInstanceCount
+	}
+	if deployArgs.WorkerMaxTasksIsSet {
+		conf.WorkerMaxTasks = deployArgs.WorkerMaxTasks
+	}
+	if deployArgs.WorkerPlacementGroupIsSet {
+		conf.WorkerPlacementGroup = deployArgs.WorkerPlacementGroup
+	}
+	if deployArgs.WorkerNodeGroupIsSet {
+		conf.WorkerNodeGroup = deployArgs.WorkerNodeGroup
+	}
+	if deployArgs.WorkerStemcellOSIsSet {
+		conf.WorkerStemcellOS = deployArgs.WorkerStemcellOS
+	}
+	if deployArgs.WorkerStemcellVersionIsSet {
+		conf.WorkerStemcellVersion = deployArgs.WorkerStemcellVersion
+	}
+	if deployArgs.WorkerZonesIsSet {
+		conf.WorkerZones = deployArgs.WorkerZones
+	}
 
 	var isDomainUpdated bool
 	if deployArgs.DomainIsSet {
@@ -235,6 +304,17 @@ func populateConfigWithDefaultCIDRs(conf config.Config, provider iaas.Provider)
 	return conf
 }
 
+// parseExtraHosts returns the hostname=IP entries validated by the deploy args as a map of hostname to IP
+func parseExtraHosts(entries []string) map[string]string {
+	hosts := map[string]string{}
+	for _, entry := range entries {
+		if kv := strings.SplitN(entry, "=", 2); len(kv) == 2 {
+			hosts[kv[0]] = kv[1]
+		}
+	}
+	return hosts
+}
+
 func updateAllowedIPs(c config.Config, ingressAddresses cidrBlocks) (config.Config, error) {
 	addr, err := ingressAddresses.String()
 	if err != nil {
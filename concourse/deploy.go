@@ -155,7 +155,7 @@ func (client *Client) deployBoshAndPipeline(c config.ConfigView, tfOutputs terra
 
 	flyClient, err := client.flyClientFactory(client.provider, fly.Credentials{
 		Target:   c.GetDeployment(),
-		API:      fmt.Sprintf("https://%s", c.GetDomain()),
+		API:      atcURL(c),
 		Username: bp.ConcourseUsername,
 		Password: bp.ConcoursePassword,
 	},
@@ -176,6 +176,7 @@ func (client *Client) deployBoshAndPipeline(c config.ConfigView, tfOutputs terra
 		ConcoursePassword:         bp.ConcoursePassword,
 		ConcourseUsername:         bp.ConcourseUsername,
 		ConcourseUserProvidedCert: client.deployArgs.TLSCertIsSet && client.deployArgs.TLSKeyIsSet,
+		ATCURL:                    atcURL(c),
 		Domain:                    c.GetDomain(),
 		IAAS:                      c.GetIAAS(),
 		Namespace:                 c.GetNamespace(),
@@ -207,7 +208,7 @@ func (client *Client) updateBoshAndPipeline(c config.ConfigView, tfOutputs terra
 
 	flyClient, err := client.flyClientFactory(client.provider, fly.Credentials{
 		Target:   c.GetDeployment(),
-		API:      fmt.Sprintf("https://%s", c.GetDomain()),
+		API:      atcURL(c),
 		Username: c.GetConcourseUsername(),
 		Password: c.GetConcoursePassword(),
 	},
@@ -565,7 +566,7 @@ func (client *Client) setHostedZone(c config.ConfigView, domain string) (HostedZ
 }
 
 const deployMsg = `DEPLOY SUCCESSFUL. Log in with:
-fly --target {{.Project}} login{{if not .ConcourseUserProvidedCert}} --insecure{{end}} --concourse-url https://{{.Domain}} --username {{.ConcourseUsername}} --password {{.ConcoursePassword}}
+fly --target {{.Project}} login{{if not .ConcourseUserProvidedCert}} --insecure{{end}} --concourse-url {{.ATCURL}} --username {{.ConcourseUsername}} --password {{.ConcoursePassword}}
 
 Metrics available at https://{{.Domain}}:3000 using the same username and password
 
@@ -574,6 +575,7 @@ eval "$(control-tower info --region {{.Region}} {{ if ne .Namespace .Region }} -
 `
 
 type deployMessageParams struct {
+	ATCURL                    string
 	ConcoursePassword         string
 	ConcourseUsername         string
 	ConcourseUserProvidedCert bool
@@ -584,6 +586,14 @@ type deployMessageParams struct {
 	Region                    string
 }
 
+// atcURL returns the URL of the Concourse web interface, with the ATC port when it is not the default
+func atcURL(c config.ConfigView) string {
+	if port := c.GetATCPort(); port != 0 && port != 443 {
+		return fmt.Sprintf("https://%s:%d", c.GetDomain(), port)
+	}
+	return fmt.Sprintf("https://%s", c.GetDomain())
+}
+
 func writeDeploySuccessMessage(params deployMessageParams, stdout io.Writer) error {
 	t := template.Must(template.New("deploy").Parse(deployMsg))
 	return t.Execute(stdout, params)
@@ -156,7 +156,7 @@ Instances:
 Concourse credentials:
 	username: {{.Config.ConcourseUsername}}
 	password: {{.Config.ConcoursePassword}}
-	URL:      https://{{.Config.Domain}}
+	URL:      {{atcURL .Config}}
 
 Credhub credentials:
 	username: {{.Config.CredhubUsername}}
@@ -189,7 +189,8 @@ func (info *Info) String() string {
 		"replace": func(old, new, s string) string {
 			return strings.Replace(s, old, new, -1)
 		},
-		"blue": color.New(color.FgCyan, color.Bold).Sprint,
+		"blue":   color.New(color.FgCyan, color.Bold).Sprint,
+		"atcURL": atcURL,
 	}).Parse(infoTemplate))
 	var buf bytes.Buffer
 	err := t.Execute(&buf, info)
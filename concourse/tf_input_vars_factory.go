@@ -42,6 +42,7 @@ type AWSInputVarsFactory struct{}
 
 func (f *AWSInputVarsFactory) NewInputVars(c config.ConfigView) terraform.InputVars {
 	return &terraform.AWSInputVars{
+		ATCPort:                c.GetATCPort(),
 		NetworkCIDR:            c.GetNetworkCIDR(),
 		PublicCIDR:             c.GetPublicCIDR(),
 		PrivateCIDR:            c.GetPrivateCIDR(),
@@ -51,7 +52,9 @@ func (f *AWSInputVarsFactory) NewInputVars(c config.ConfigView) terraform.InputV
 		Deployment:             c.GetDeployment(),
 		HostedZoneID:           c.GetHostedZoneID(),
 		HostedZoneRecordPrefix: c.GetHostedZoneRecordPrefix(),
+		MbusPort:               c.GetMbusPort(),
 		Namespace:              c.GetNamespace(),
+		NATSPort:               c.GetNATSPort(),
 		Project:                c.GetProject(),
 		PublicKey:              c.GetPublicKey(),
 		RDSDefaultDatabaseName: c.GetRDSDefaultDatabaseName(),
@@ -76,6 +79,7 @@ type GCPInputVarsFactory struct {
 func (f *GCPInputVarsFactory) NewInputVars(c config.ConfigView) terraform.InputVars {
 	return &terraform.GCPInputVars{
 		AllowIPs:           c.GetAllowIPs(),
+		ATCPort:            c.GetATCPort(),
 		ConfigBucket:       c.GetConfigBucket(),
 		DBName:             c.GetRDSDefaultDatabaseName(),
 		DBPassword:         c.GetRDSPassword(),
@@ -86,7 +90,9 @@ func (f *GCPInputVarsFactory) NewInputVars(c config.ConfigView) terraform.InputV
 		DNSRecordSetPrefix: c.GetHostedZoneRecordPrefix(),
 		ExternalIP:         c.GetSourceAccessIP(),
 		GCPCredentialsJSON: f.credentialsPath,
+		MbusPort:           c.GetMbusPort(),
 		Namespace:          c.GetNamespace(),
+		NATSPort:           c.GetNATSPort(),
 		Project:            f.project,
 		Region:             f.region,
 		Tags:               "",
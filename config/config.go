@@ -13,67 +13,91 @@ func ConvertSpotBoolToVMProvisioningType(spot bool) string {
 
 // Config represents a control-tower configuration file
 type Config struct {
-	AllowIPs                 string `json:"allow_ips"`
-	AvailabilityZone         string `json:"availability_zone"`
-	ConcourseCACert          string `json:"concourse_ca_cert"`
-	ConcourseCert            string `json:"concourse_cert"`
-	ConcourseKey             string `json:"concourse_key"`
-	ConcoursePassword        string `json:"concourse_password"`
-	ConcourseUsername        string `json:"concourse_username"`
-	ConcourseWebSize         string `json:"concourse_web_size"`
-	ConcourseWorkerCount     int    `json:"concourse_worker_count"`
-	ConcourseWorkerSize      string `json:"concourse_worker_size"`
-	ConfigBucket             string `json:"config_bucket"`
-	CredhubAdminClientSecret string `json:"credhub_admin_client_secret"`
-	CredhubCACert            string `json:"credhub_ca_cert"`
-	CredhubPassword          string `json:"credhub_password"`
-	CredhubURL               string `json:"credhub_url"`
-	CredhubUsername          string `json:"credhub_username"`
-	Deployment               string `json:"deployment"`
-	DirectorCACert           string `json:"director_ca_cert"`
-	DirectorCert             string `json:"director_cert"`
-	DirectorHMUserPassword   string `json:"director_hm_user_password"`
-	DirectorKey              string `json:"director_key"`
-	DirectorMbusPassword     string `json:"director_mbus_password"`
-	DirectorNATSPassword     string `json:"director_nats_password"`
-	DirectorPassword         string `json:"director_password"`
-	DirectorPublicIP         string `json:"director_public_ip"`
-	DirectorRegistryPassword string `json:"director_registry_password"`
-	DirectorUsername         string `json:"director_username"`
-	Domain                   string `json:"domain"`
-	EncryptionKey            string `json:"encryption_key"`
-	GithubClientID           string `json:"github_client_id"`
-	GithubClientSecret       string `json:"github_client_secret"`
-	GrafanaPassword          string `json:"grafana_password"`
-	HostedZoneID             string `json:"hosted_zone_id"`
-	HostedZoneRecordPrefix   string `json:"hosted_zone_record_prefix"`
-	IAAS                     string `json:"iaas"`
-	Namespace                string `json:"namespace"`
-	NetworkCIDR              string `json:"network_cidr"`
-	PrivateCIDR              string `json:"private_cidr"`
-	PrivateKey               string `json:"private_key"`
-	Project                  string `json:"project"`
-	PublicCIDR               string `json:"public_cidr"`
-	PublicKey                string `json:"public_key"`
-	RDS1CIDR                 string `json:"rds1_cidr"`
-	RDS2CIDR                 string `json:"rds2_cidr"`
-	RDSDefaultDatabaseName   string `json:"rds_default_database_name"`
-	RDSInstanceClass         string `json:"rds_instance_class"`
-	RDSPassword              string `json:"rds_password"`
-	RDSUsername              string `json:"rds_username"`
-	Region                   string `json:"region"`
-	SourceAccessIP           string `json:"source_access_ip"`
+	AllowIPs                 string            `json:"allow_ips"`
+	ATCPort                  int               `json:"atc_port"`
+	AvailabilityZone         string            `json:"availability_zone"`
+	ConcourseCACert          string            `json:"concourse_ca_cert"`
+	ConcourseCert            string            `json:"concourse_cert"`
+	ConcourseKey             string            `json:"concourse_key"`
+	ConcoursePassword        string            `json:"concourse_password"`
+	ConcourseUsername        string            `json:"concourse_username"`
+	ConcourseWebSize         string            `json:"concourse_web_size"`
+	ConcourseWorkerCount     int               `json:"concourse_worker_count"`
+	ConcourseWorkerSize      string            `json:"concourse_worker_size"`
+	ConfigBucket             string            `json:"config_bucket"`
+	CredhubAdminClientSecret string            `json:"credhub_admin_client_secret"`
+	CredhubCACert            string            `json:"credhub_ca_cert"`
+	CredhubPassword          string            `json:"credhub_password"`
+	CredhubURL               string            `json:"credhub_url"`
+	CredhubUsername          string            `json:"credhub_username"`
+	DBDiskSizeGB             int               `json:"db_disk_size_gb"`
+	Deployment               string            `json:"deployment"`
+	DirectorCACert           string            `json:"director_ca_cert"`
+	DirectorCert             string            `json:"director_cert"`
+	DirectorHMUserPassword   string            `json:"director_hm_user_password"`
+	DirectorKey              string            `json:"director_key"`
+	DirectorMbusPassword     string            `json:"director_mbus_password"`
+	DirectorNATSPassword     string            `json:"director_nats_password"`
+	DirectorPassword         string            `json:"director_password"`
+	DirectorPublicIP         string            `json:"director_public_ip"`
+	DirectorRegistryPassword string            `json:"director_registry_password"`
+	DirectorUsername         string            `json:"director_username"`
+	Domain                   string            `json:"domain"`
+	EncryptionKey            string            `json:"encryption_key"`
+	ExternalDBHost           string            `json:"external_db_host"`
+	ExternalDBName           string            `json:"external_db_name"`
+	ExternalDBPassword       string            `json:"external_db_password"`
+	ExternalDBPort           string            `json:"external_db_port"`
+	ExternalDBUser           string            `json:"external_db_user"`
+	ExtraHosts               map[string]string `json:"extra_hosts"`
+	GithubClientID           string            `json:"github_client_id"`
+	GithubClientSecret       string            `json:"github_client_secret"`
+	GrafanaPassword          string            `json:"grafana_password"`
+	HostedZoneID             string            `json:"hosted_zone_id"`
+	HostedZoneRecordPrefix   string            `json:"hosted_zone_record_prefix"`
+	IAAS                     string            `json:"iaas"`
+	LetsEncrypt              bool              `json:"lets_encrypt"`
+	MbusPort                 int               `json:"mbus_port"`
+	Namespace                string            `json:"namespace"`
+	NATSPort                 int               `json:"nats_port"`
+	NetworkCIDR              string            `json:"network_cidr"`
+	PrivateCIDR              string            `json:"private_cidr"`
+	PrivateKey               string            `json:"private_key"`
+	Project                  string            `json:"project"`
+	PublicCIDR               string            `json:"public_cidr"`
+	PublicKey                string            `json:"public_key"`
+	RDS1CIDR                 string            `json:"rds1_cidr"`
+	RDS2CIDR                 string            `json:"rds2_cidr"`
+	RDSDefaultDatabaseName   string            `json:"rds_default_database_name"`
+	RDSInstanceClass         string            `json:"rds_instance_class"`
+	RDSPassword              string            `json:"rds_password"`
+	RDSUsername              string            `json:"rds_username"`
+	Region                   string            `json:"region"`
+	RequireIMDSv2            bool              `json:"require_imdsv2"`
+	SourceAccessIP           string            `json:"source_access_ip"`
 	//Spot is deprecated, exists only as we need to migrate old configs to VMProvisioningType
-	Spot               bool     `json:"spot"`
-	Tags               []string `json:"tags"`
-	TFStatePath        string   `json:"tf_state_path"`
-	Version            string   `json:"version"`
-	VMProvisioningType string   `json:vm_provisioning_type`
-	WorkerType         string   `json:"worker_type"`
+	Spot                  bool     `json:"spot"`
+	Tags                  []string `json:"tags"`
+	TFStatePath           string   `json:"tf_state_path"`
+	TokenSigningKey       string   `json:"token_signing_key"`
+	Version               string   `json:"version"`
+	VMProvisioningType    string   `json:vm_provisioning_type`
+	WebDiskSizeGB         int      `json:"web_disk_size_gb"`
+	WebInstanceCount      int      `json:"web_instance_count"`
+	WorkerDiskSizeGB      int      `json:"worker_disk_size_gb"`
+	WorkerDiskType        string   `json:"worker_disk_type"`
+	WorkerMaxTasks        int      `json:"worker_max_tasks"`
+	WorkerNodeGroup       string   `json:"worker_node_group"`
+	WorkerPlacementGroup  string   `json:"worker_placement_group"`
+	WorkerStemcellOS      string   `json:"worker_stemcell_os"`
+	WorkerStemcellVersion string   `json:"worker_stemcell_version"`
+	WorkerType            string   `json:"worker_type"`
+	WorkerZones           []string `json:"worker_zones"`
 }
 
 type ConfigView interface {
 	GetAllowIPs() string
+	GetATCPort() int
 	GetAvailabilityZone() string
 	GetConcourseCACert() string
 	GetConcourseCert() string
@@ -89,6 +113,7 @@ type ConfigView interface {
 	GetCredhubPassword() string
 	GetCredhubURL() string
 	GetCredhubUsername() string
+	GetDBDiskSizeGB() int
 	GetDeployment() string
 	GetDirectorCACert() string
 	GetDirectorCert() string
@@ -102,13 +127,22 @@ type ConfigView interface {
 	GetDirectorUsername() string
 	GetDomain() string
 	GetEncryptionKey() string
+	GetExternalDBHost() string
+	GetExternalDBName() string
+	GetExternalDBPassword() string
+	GetExternalDBPort() string
+	GetExternalDBUser() string
+	GetExtraHosts() map[string]string
 	GetGithubClientID() string
 	GetGithubClientSecret() string
 	GetGrafanaPassword() string
 	GetHostedZoneID() string
 	GetHostedZoneRecordPrefix() string
 	GetIAAS() string
+	GetLetsEncrypt() bool
+	GetMbusPort() int
 	GetNamespace() string
+	GetNATSPort() int
 	GetNetworkCIDR() string
 	GetPrivateCIDR() string
 	GetPrivateKey() string
@@ -122,11 +156,23 @@ type ConfigView interface {
 	GetRDSPassword() string
 	GetRDSUsername() string
 	GetRegion() string
+	GetRequireIMDSv2() bool
 	GetSourceAccessIP() string
 	GetTags() []string
 	GetTFStatePath() string
+	GetTokenSigningKey() string
 	GetVersion() string
+	GetWebDiskSizeGB() int
+	GetWebInstanceCount() int
+	GetWorkerDiskSizeGB() int
+	GetWorkerDiskType() string
+	GetWorkerMaxTasks() int
+	GetWorkerNodeGroup() string
+	GetWorkerPlacementGroup() string
+	GetWorkerStemcellOS() string
+	GetWorkerStemcellVersion() string
 	GetWorkerType() string
+	GetWorkerZones() []string
 	IsGithubAuthSet() bool
 	IsSpot() bool
 }
@@ -135,6 +181,10 @@ func (c Config) GetAllowIPs() string {
 	return c.AllowIPs
 }
 
+func (c Config) GetATCPort() int {
+	return c.ATCPort
+}
+
 func (c Config) GetAvailabilityZone() string {
 	return c.AvailabilityZone
 }
@@ -195,6 +245,10 @@ func (c Config) GetCredhubUsername() string {
 	return c.CredhubUsername
 }
 
+func (c Config) GetDBDiskSizeGB() int {
+	return c.DBDiskSizeGB
+}
+
 func (c Config) GetDeployment() string {
 	return c.Deployment
 }
@@ -247,6 +301,30 @@ func (c Config) GetEncryptionKey() string {
 	return c.EncryptionKey
 }
 
+func (c Config) GetExternalDBHost() string {
+	return c.ExternalDBHost
+}
+
+func (c Config) GetExternalDBName() string {
+	return c.ExternalDBName
+}
+
+func (c Config) GetExternalDBPassword() string {
+	return c.ExternalDBPassword
+}
+
+func (c Config) GetExternalDBPort() string {
+	return c.ExternalDBPort
+}
+
+func (c Config) GetExternalDBUser() string {
+	return c.ExternalDBUser
+}
+
+func (c Config) GetExtraHosts() map[string]string {
+	return c.ExtraHosts
+}
+
 func (c Config) GetGithubClientID() string {
 	return c.GithubClientID
 }
@@ -271,10 +349,22 @@ func (c Config) GetIAAS() string {
 	return c.IAAS
 }
 
+func (c Config) GetLetsEncrypt() bool {
+	return c.LetsEncrypt
+}
+
+func (c Config) GetMbusPort() int {
+	return c.MbusPort
+}
+
 func (c Config) GetNamespace() string {
 	return c.Namespace
 }
 
+func (c Config) GetNATSPort() int {
+	return c.NATSPort
+}
+
 func (c Config) GetNetworkCIDR() string {
 	return c.NetworkCIDR
 }
@@ -327,6 +417,10 @@ func (c Config) GetRegion() string {
 	return c.Region
 }
 
+func (c Config) GetRequireIMDSv2() bool {
+	return c.RequireIMDSv2
+}
+
 func (c Config) GetSourceAccessIP() string {
 	return c.SourceAccessIP
 }
@@ -339,14 +433,58 @@ func (c Config) GetTFStatePath() string {
 	return c.TFStatePath
 }
 
+func (c Config) GetTokenSigningKey() string {
+	return c.TokenSigningKey
+}
+
 func (c Config) GetVersion() string {
 	return c.Version
 }
 
+func (c Config) GetWebDiskSizeGB() int {
+	return c.WebDiskSizeGB
+}
+
+func (c Config) GetWebInstanceCount() int {
+	return c.WebInstanceCount
+}
+
+func (c Config) GetWorkerDiskSizeGB() int {
+	return c.WorkerDiskSizeGB
+}
+
+func (c Config) GetWorkerDiskType() string {
+	return c.WorkerDiskType
+}
+
+func (c Config) GetWorkerMaxTasks() int {
+	return c.WorkerMaxTasks
+}
+
+func (c Config) GetWorkerNodeGroup() string {
+	return c.WorkerNodeGroup
+}
+
+func (c Config) GetWorkerPlacementGroup() string {
+	return c.WorkerPlacementGroup
+}
+
+func (c Config) GetWorkerStemcellOS() string {
+	return c.WorkerStemcellOS
+}
+
+func (c Config) GetWorkerStemcellVersion() string {
+	return c.WorkerStemcellVersion
+}
+
 func (c Config) GetWorkerType() string {
 	return c.WorkerType
 }
 
+func (c Config) GetWorkerZones() []string {
+	return c.WorkerZones
+}
+
 func (c Config) IsGithubAuthSet() bool {
 	return c.GithubClientID != "" && c.GithubClientSecret != ""
 }
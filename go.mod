@@ -21,6 +21,7 @@ require (
 	github.com/nu7hatch/gouuid v0.0.0-20131221200532-179d4d0c4d8d // indirect
 	github.com/onsi/ginkgo v1.7.0
 	github.com/onsi/gomega v1.4.3
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/square/certstrap v1.1.1
 	github.com/stretchr/testify v1.3.0
 	github.com/tjarratt/gcounterfeiter v0.0.0-20160901063240-8a4c307ac402
@@ -3,8 +3,11 @@ package main
 import (
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/EngineerBetter/control-tower/commands"
+	"github.com/EngineerBetter/control-tower/util"
 	"github.com/fatih/color"
 
 	"gopkg.in/urfave/cli.v1"
@@ -29,8 +32,17 @@ Built by %s %s
 
 `, cli.AppHelpTemplate, blue("EngineerBetter"), blue("http://engineerbetter.com"))
 
-	if err := app.Run(os.Args); err != nil {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go util.HandleInterrupts(signals, os.Exit)
+
+	err := app.Run(os.Args)
+	util.CleanupTempFiles()
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
+		if util.Interrupted().Err() != nil {
+			os.Exit(130)
+		}
 		os.Exit(1)
 	}
 }
@@ -31,9 +31,10 @@ var _ = Describe("control-tower", func() {
 		Expect(err).ToNot(HaveOccurred(), "Error running CLI: "+cliPath)
 		Eventually(session).Should(Exit(0))
 		Expect(session.Out).To(Say("Control-Tower - A CLI tool to deploy Concourse CI"))
-		Expect(session.Out).To(Say("deploy, d    Deploys or updates a Concourse"))
-		Expect(session.Out).To(Say("destroy, x   Destroys a Concourse"))
-		Expect(session.Out).To(Say("info, i      Fetches information on a deployed environment"))
-		Expect(session.Out).To(Say("maintain, m  Handles maintenance operations in control-tower"))
+		Expect(session.Out).To(Say("deploy, d     Deploys or updates a Concourse"))
+		Expect(session.Out).To(Say("destroy, x    Destroys a Concourse"))
+		Expect(session.Out).To(Say("info, i       Fetches information on a deployed environment"))
+		Expect(session.Out).To(Say("maintain, m   Handles maintenance operations in control-tower"))
+		Expect(session.Out).To(Say("stemcell-url  Prints the URL of the stemcell Concourse is deployed with"))
 	})
 })
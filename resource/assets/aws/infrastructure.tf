@@ -316,8 +316,8 @@ resource "aws_security_group" "director" {
   }
 
   ingress {
-    from_port   = 6868
-    to_port     = 6868
+    from_port   = {{ .MbusIngressPort }}
+    to_port     = {{ .MbusIngressPort }}
     protocol    = "tcp"
     cidr_blocks = ["${var.source_access_ip}/32", "${aws_nat_gateway.default.public_ip}/32"]
   }
@@ -356,15 +356,15 @@ resource "aws_security_group" "vms" {
   }
 
   ingress {
-    from_port   = 6868
-    to_port     = 6868
+    from_port   = {{ .MbusIngressPort }}
+    to_port     = {{ .MbusIngressPort }}
     protocol    = "tcp"
     cidr_blocks = ["${var.network_cidr}"]
   }
 
   ingress {
-    from_port   = 4222
-    to_port     = 4222
+    from_port   = {{ .NATSIngressPort }}
+    to_port     = {{ .NATSIngressPort }}
     protocol    = "tcp"
     cidr_blocks = ["${var.network_cidr}"]
   }
@@ -501,8 +501,8 @@ resource "aws_security_group" "atc" {
   }
 
   ingress {
-    from_port   = 443
-    to_port     = 443
+    from_port   = {{ .ATCIngressPort }}
+    to_port     = {{ .ATCIngressPort }}
     protocol    = "tcp"
     cidr_blocks = ["${aws_eip.nat.public_ip}/32", "${aws_eip.atc.public_ip}/32", {{ .AllowIPs }}]
   }
@@ -185,7 +185,7 @@ resource "google_compute_firewall" "director" {
   source_ranges = ["${var.source_access_ip}/32", "${google_compute_instance.nat-instance.network_interface.0.access_config.0.nat_ip}/32"]
   allow {
     protocol = "tcp"
-    ports = ["6868", "25555", "22"]
+    ports = ["{{ .MbusIngressPort }}", "25555", "22"]
   }
 }
 
@@ -222,7 +222,7 @@ resource "google_compute_firewall" "atc-https" {
   source_ranges = ["${google_compute_instance.nat-instance.network_interface.0.access_config.0.nat_ip}/32", "${google_compute_address.atc_ip.address}/32", {{ .AllowIPs }}]
   allow {
     protocol = "tcp"
-    ports = ["443", "8443"]
+    ports = ["{{ .ATCIngressPort }}", "8443"]
   }
 }
 
@@ -234,7 +234,7 @@ resource "google_compute_firewall" "from-public" {
   source_ranges = ["${var.public_cidr}"]
   allow {
     protocol = "tcp"
-    ports = ["6868","4222", "25250", "25555", "25777", "5555", "2222", "7777", "7788", "7799", "22"]
+    ports = ["{{ .MbusIngressPort }}","{{ .NATSIngressPort }}", "25250", "25555", "25777", "5555", "2222", "7777", "7788", "7799", "22"]
   }
   allow {
     protocol = "udp"
@@ -253,7 +253,7 @@ resource "google_compute_firewall" "from-private" {
   source_ranges = ["${var.private_cidr}"]
   allow {
     protocol = "tcp"
-    ports = ["6868","4222", "25250", "25555", "25777", "5555", "2222", "7777", "7788", "7799", "22", "3307", "8844", "8443"]
+    ports = ["{{ .MbusIngressPort }}","{{ .NATSIngressPort }}", "25250", "25555", "25777", "5555", "2222", "7777", "7788", "7799", "22", "3307", "8844", "8443"]
   }
   allow {
     protocol = "udp"
@@ -43,6 +43,8 @@ var (
 	AWSDirectorCloudConfig = mustAssetString("assets/aws/cloud-config.yml")
 	// AWSCPIOps statically defines aws-cpi.yml contents
 	AWSCPIOps = mustAssetString("assets/aws/cpi.yml")
+	// AWSDirectorIMDSv2Ops requires the director VM to use IMDSv2 to reach the instance metadata
+	AWSDirectorIMDSv2Ops = mustAssetString("assets/aws/director-imdsv2.yml")
 	//GCPJumpboxUserOps statically defines gcp jumpbox-user.yml
 	GCPJumpboxUserOps = mustAssetString("assets/gcp/jumpbox-user.yml")
 	// GCPDirectorCloudConfig statically defines gcp cloud-config.yml
@@ -53,6 +55,10 @@ var (
 	GCPExternalIPOps = mustAssetString("assets/gcp/external-ip.yml")
 	// GCPDirectorCustomOps statically defines custom-ops.yml contents
 	GCPDirectorCustomOps = mustAssetString("assets/gcp/custom-ops.yml")
+	// GCPLabelsOps statically defines gcp labels.yml contents
+	GCPLabelsOps = mustAssetString("assets/gcp/labels.yml")
+	// GCPGCSBlobstoreOps statically defines gcs-blobstore.yml contents
+	GCPGCSBlobstoreOps = mustAssetString("assets/gcp/gcs-blobstore.yml")
 
 	// AWSTerraformConfig holds the terraform conf for AWS
 	AWSTerraformConfig = mustAssetString("assets/aws/infrastructure.tf")
@@ -62,6 +68,17 @@ var (
 
 	// ExternalIPOps statically defines external-ip.yml contents
 	ExternalIPOps = mustAssetString("assets/external-ip.yml")
+	// LocalDNSOps makes the director give out BOSH DNS addresses, on top of the
+	// local_dns records already enabled in the director manifest
+	LocalDNSOps = mustAssetString("assets/local-dns.yml")
+	// DirectorMaxTasksOps limits the number of tasks, and their logs, the director keeps
+	DirectorMaxTasksOps = mustAssetString("assets/director-max-tasks.yml")
+	// DirectorTaskRetentionOps makes the director delete tasks, and their logs, older than a number of days
+	DirectorTaskRetentionOps = mustAssetString("assets/director-task-retention.yml")
+	// DirectorBlobstoreProxyOps makes the director reach the blobstore, and other HTTP(S) endpoints, through a proxy
+	DirectorBlobstoreProxyOps = mustAssetString("assets/director-blobstore-proxy.yml")
+	// DirectorTrustedCertsOps sets the CAs the director installs on the VMs it creates, such as the CA of an internal proxy
+	DirectorTrustedCertsOps = mustAssetString("assets/director-trusted-certs.yml")
 	// AWSDirectorCustomOps statically defines custom-ops.yml contents
 	AWSDirectorCustomOps = mustAssetString("assets/aws/custom-ops.yml")
 
@@ -79,6 +96,39 @@ var (
 
 	// CleanupCerts moves renewed values of certs to old keys in director vars store
 	CleanupCerts = mustAssetString("assets/maintenance/cleanup-certs.yml")
+
+	// ConcourseOSConfOps adds the os-conf release to the concourse deployment
+	ConcourseOSConfOps = mustAssetString("assets/concourse/os-conf.yml")
+	// ConcourseExtraHostsOps adds extra /etc/hosts entries to the concourse workers
+	ConcourseExtraHostsOps = mustAssetString("assets/concourse/extra-hosts.yml")
+	// ConcourseWorkerCACertsOps adds trusted CA certificates to the concourse workers
+	ConcourseWorkerCACertsOps = mustAssetString("assets/concourse/worker-ca-certs.yml")
+	// ConcourseWorkerRuntimeOps sets the container runtime of the concourse workers
+	ConcourseWorkerRuntimeOps = mustAssetString("assets/concourse/worker-runtime.yml")
+	// ConcourseWorkerDrainTimeoutOps sets how long the concourse workers wait for their containers to finish when draining
+	ConcourseWorkerDrainTimeoutOps = mustAssetString("assets/concourse/worker-drain-timeout.yml")
+	// ConcourseWorkerRebalanceIntervalOps sets how often the concourse workers rebalance across the web nodes
+	ConcourseWorkerRebalanceIntervalOps = mustAssetString("assets/concourse/worker-rebalance-interval.yml")
+	// ConcourseWorkerMaxTasksOps caps the number of tasks the ATC schedules at once on each concourse worker
+	ConcourseWorkerMaxTasksOps = mustAssetString("assets/concourse/worker-max-tasks.yml")
+	// ConcourseExternalDBOps points the concourse web job at an external database
+	ConcourseExternalDBOps = mustAssetString("assets/concourse/external-db.yml")
+	// ConcourseExternalURLOps serves the concourse web UI on a domain
+	ConcourseExternalURLOps = mustAssetString("assets/concourse/external-url.yml")
+	// ConcourseLetsEncryptOps has the concourse web job obtain its certificate with ACME
+	ConcourseLetsEncryptOps = mustAssetString("assets/concourse/lets-encrypt.yml")
+	// ConcourseTokenSigningKeyOps sets the key the ATC signs its auth tokens with
+	ConcourseTokenSigningKeyOps = mustAssetString("assets/concourse/token-signing-key.yml")
+	// ConcourseUpdateSerialOps sets whether the instance groups of the concourse deployment are updated one at a time
+	ConcourseUpdateSerialOps = mustAssetString("assets/concourse/update-serial.yml")
+	// ConcourseWorkerStemcellOps runs the concourse workers on a stemcell other than the one of the rest of the deployment
+	ConcourseWorkerStemcellOps = mustAssetString("assets/concourse/worker-stemcell.yml")
+	// ConcourseWebInstancesOps sets the number of concourse web instances
+	ConcourseWebInstancesOps = mustAssetString("assets/concourse/web-instances.yml")
+	// ConcourseGCOps sets how often the ATC garbage collects containers and volumes, and how long it keeps them
+	ConcourseGCOps = mustAssetString("assets/concourse/gc.yml")
+	// ConcourseATCPortOps serves the concourse web UI on a port other than 443
+	ConcourseATCPortOps = mustAssetString("assets/concourse/atc-port.yml")
 )
 
 // NOTE(px) remove this in a later version of github.com/mattn/go-bindata
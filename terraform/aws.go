@@ -13,12 +13,15 @@ import (
 // InputVars holds all the parameters AWS IAAS needs
 type AWSInputVars struct {
 	AllowIPs               string
+	ATCPort                int
 	AvailabilityZone       string
 	ConfigBucket           string
 	Deployment             string
 	HostedZoneID           string
 	HostedZoneRecordPrefix string
+	MbusPort               int
 	Namespace              string
+	NATSPort               int
 	NetworkCIDR            string
 	PrivateCIDR            string
 	Project                string
@@ -44,6 +47,30 @@ func (v *AWSInputVars) ConfigureTerraform(terraformContents string) (string, err
 	return string(terraformConfig), err
 }
 
+// ATCIngressPort returns the port the ATC security group lets the concourse web UI be reached on
+func (v *AWSInputVars) ATCIngressPort() int {
+	if v.ATCPort == 0 {
+		return 443
+	}
+	return v.ATCPort
+}
+
+// MbusIngressPort returns the port the director and VMs security groups let the bosh agent mbus be reached on
+func (v *AWSInputVars) MbusIngressPort() int {
+	if v.MbusPort == 0 {
+		return 6868
+	}
+	return v.MbusPort
+}
+
+// NATSIngressPort returns the port the VMs security group lets the director NATS be reached on
+func (v *AWSInputVars) NATSIngressPort() int {
+	if v.NATSPort == 0 {
+		return 4222
+	}
+	return v.NATSPort
+}
+
 // MetadataStringValue is a terraform output string variable
 type MetadataStringValue struct {
 	Value string `json:"value"`
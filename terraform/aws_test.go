@@ -3,8 +3,10 @@ package terraform_test
 import (
 	"bytes"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/EngineerBetter/control-tower/resource"
 	. "github.com/EngineerBetter/control-tower/terraform"
 )
 
@@ -149,3 +151,55 @@ func TestAWSMetadata_Init(t *testing.T) {
 		})
 	}
 }
+
+func TestAWSInputVars_ConfigureTerraformATCPort(t *testing.T) {
+	tests := []struct {
+		name    string
+		atcPort int
+		want    string
+	}{
+		{name: "default port", want: "from_port   = 443\n    to_port     = 443\n"},
+		{name: "custom port", atcPort: 4443, want: "from_port   = 4443\n    to_port     = 4443\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := &AWSInputVars{ATCPort: test.atcPort}
+			got, err := v.ConfigureTerraform(resource.AWSTerraformConfig)
+			if err != nil {
+				t.Fatalf("InputVars.ConfigureTerraform() error = %v", err)
+			}
+			if !strings.Contains(got, test.want) {
+				t.Errorf("InputVars.ConfigureTerraform() expected the ATC ingress to contain %q", test.want)
+			}
+		})
+	}
+}
+
+func TestAWSInputVars_ConfigureTerraformAgentPorts(t *testing.T) {
+	tests := []struct {
+		name     string
+		mbusPort int
+		natsPort int
+		want     []string
+	}{
+		{name: "default ports", want: []string{"from_port   = 6868\n    to_port     = 6868\n", "from_port   = 4222\n    to_port     = 4222\n"}},
+		{name: "custom ports", mbusPort: 7868, natsPort: 5222, want: []string{"from_port   = 7868\n    to_port     = 7868\n", "from_port   = 5222\n    to_port     = 5222\n"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := &AWSInputVars{MbusPort: test.mbusPort, NATSPort: test.natsPort}
+			got, err := v.ConfigureTerraform(resource.AWSTerraformConfig)
+			if err != nil {
+				t.Fatalf("InputVars.ConfigureTerraform() error = %v", err)
+			}
+			for _, want := range test.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("InputVars.ConfigureTerraform() expected the agent ingress to contain %q", want)
+				}
+			}
+			if test.mbusPort != 0 && strings.Contains(got, "6868") {
+				t.Errorf("InputVars.ConfigureTerraform() expected no ingress on the default mbus port")
+			}
+		})
+	}
+}
@@ -13,6 +13,7 @@ import (
 // InputVars holds all the parameters GCP IAAS needs
 type GCPInputVars struct {
 	AllowIPs           string
+	ATCPort            int
 	ConfigBucket       string
 	DBName             string
 	DBPassword         string
@@ -23,7 +24,9 @@ type GCPInputVars struct {
 	DNSRecordSetPrefix string
 	ExternalIP         string
 	GCPCredentialsJSON string
+	MbusPort           int
 	Namespace          string
+	NATSPort           int
 	PrivateCIDR        string
 	Project            string
 	PublicCIDR         string
@@ -41,6 +44,30 @@ func (v *GCPInputVars) ConfigureTerraform(terraformContents string) (string, err
 	return string(terraformConfig), err
 }
 
+// ATCIngressPort returns the port the ATC firewall lets the concourse web UI be reached on
+func (v *GCPInputVars) ATCIngressPort() int {
+	if v.ATCPort == 0 {
+		return 443
+	}
+	return v.ATCPort
+}
+
+// MbusIngressPort returns the port the director and VM firewalls let the bosh agent mbus be reached on
+func (v *GCPInputVars) MbusIngressPort() int {
+	if v.MbusPort == 0 {
+		return 6868
+	}
+	return v.MbusPort
+}
+
+// NATSIngressPort returns the port the VM firewalls let the director NATS be reached on
+func (v *GCPInputVars) NATSIngressPort() int {
+	if v.NATSPort == 0 {
+		return 4222
+	}
+	return v.NATSPort
+}
+
 // Metadata represents output from terraform on GCP or GCP
 type GCPOutputs struct {
 	Network                    MetadataStringValue `json:"network" valid:"required"`
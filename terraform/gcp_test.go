@@ -3,8 +3,10 @@ package terraform_test
 import (
 	"bytes"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/EngineerBetter/control-tower/resource"
 	. "github.com/EngineerBetter/control-tower/terraform"
 )
 
@@ -155,3 +157,52 @@ func TestGCPMetadata_Init(t *testing.T) {
 		})
 	}
 }
+
+func TestGCPInputVars_ConfigureTerraformATCPort(t *testing.T) {
+	tests := []struct {
+		name    string
+		atcPort int
+		want    string
+	}{
+		{name: "default port", want: `ports = ["443", "8443"]`},
+		{name: "custom port", atcPort: 4443, want: `ports = ["4443", "8443"]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := &GCPInputVars{ATCPort: test.atcPort}
+			got, err := v.ConfigureTerraform(resource.GCPTerraformConfig)
+			if err != nil {
+				t.Fatalf("InputVars.ConfigureTerraform() error = %v", err)
+			}
+			if !strings.Contains(got, test.want) {
+				t.Errorf("InputVars.ConfigureTerraform() expected the ATC ingress to contain %q", test.want)
+			}
+		})
+	}
+}
+
+func TestGCPInputVars_ConfigureTerraformAgentPorts(t *testing.T) {
+	tests := []struct {
+		name     string
+		mbusPort int
+		natsPort int
+		want     []string
+	}{
+		{name: "default ports", want: []string{`ports = ["6868", "25555", "22"]`, `ports = ["6868","4222", "25250"`}},
+		{name: "custom ports", mbusPort: 7868, natsPort: 5222, want: []string{`ports = ["7868", "25555", "22"]`, `ports = ["7868","5222", "25250"`}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := &GCPInputVars{MbusPort: test.mbusPort, NATSPort: test.natsPort}
+			got, err := v.ConfigureTerraform(resource.GCPTerraformConfig)
+			if err != nil {
+				t.Fatalf("InputVars.ConfigureTerraform() error = %v", err)
+			}
+			for _, want := range test.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("InputVars.ConfigureTerraform() expected the agent firewalls to contain %q", want)
+				}
+			}
+		})
+	}
+}
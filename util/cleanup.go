@@ -0,0 +1,58 @@
+package util
+
+import (
+	"context"
+	"os"
+	"sync"
+)
+
+var cleanup = struct {
+	sync.Mutex
+	paths map[string]bool
+}{paths: map[string]bool{}}
+
+var interrupted, interrupt = context.WithCancel(context.Background())
+
+// RemoveOnInterrupt registers a temporary file or directory to be removed by
+// CleanupTempFiles, so it is not left behind if the process is interrupted
+// before its deferred removal runs. Only register files that can be recreated,
+// never the only copy of state
+func RemoveOnInterrupt(path string) {
+	cleanup.Lock()
+	defer cleanup.Unlock()
+	cleanup.paths[path] = true
+}
+
+// RemoveTemp removes a path registered with RemoveOnInterrupt and deregisters it
+func RemoveTemp(path string) error {
+	cleanup.Lock()
+	defer cleanup.Unlock()
+	delete(cleanup.paths, path)
+	return os.RemoveAll(path)
+}
+
+// CleanupTempFiles removes every path registered with RemoveOnInterrupt
+func CleanupTempFiles() {
+	cleanup.Lock()
+	defer cleanup.Unlock()
+	for path := range cleanup.paths {
+		os.RemoveAll(path)
+		delete(cleanup.paths, path)
+	}
+}
+
+// Interrupted returns a context which is cancelled when HandleInterrupts receives a signal
+func Interrupted() context.Context {
+	return interrupted
+}
+
+// HandleInterrupts cancels the Interrupted context on the first signal, so that running commands
+// are killed and return through their deferred uploads of state. A second signal removes the
+// registered temporary files and exits non-zero straight away
+func HandleInterrupts(signals <-chan os.Signal, exit func(code int)) {
+	<-signals
+	interrupt()
+	<-signals
+	CleanupTempFiles()
+	exit(130)
+}
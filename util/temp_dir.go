@@ -17,6 +17,7 @@ func NewTempDir() (*TempDir, error) {
 	if err != nil {
 		return nil, err
 	}
+	RemoveOnInterrupt(path)
 
 	return &TempDir{
 		path: path,
@@ -40,7 +41,7 @@ func (tempDir *TempDir) Path(filename string) string {
 
 // Cleanup deletes the tempDir
 func (tempDir *TempDir) Cleanup() error {
-	return os.RemoveAll(tempDir.path)
+	return RemoveTemp(tempDir.path)
 }
 
 // PushDir runs the function in the tempDir
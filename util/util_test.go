@@ -2,6 +2,8 @@ package util_test
 
 import (
 	"io"
+	"io/ioutil"
+	"os"
 
 	"github.com/EngineerBetter/control-tower/util"
 	. "github.com/onsi/ginkgo"
@@ -49,4 +51,48 @@ var _ = Describe("util functions", func() {
 			})
 		})
 	})
+
+	Describe("interrupt cleanup", func() {
+		It("cancels on the first signal, then removes registered temporary files and exits non-zero", func() {
+			f, err := ioutil.TempFile("", "")
+			Expect(err).ToNot(HaveOccurred())
+			f.Close()
+			dir, err := util.NewTempDir()
+			Expect(err).ToNot(HaveOccurred())
+			path, err := dir.Save("creds.yml", []byte("secret"))
+			Expect(err).ToNot(HaveOccurred())
+			util.RemoveOnInterrupt(f.Name())
+
+			signals := make(chan os.Signal, 1)
+			exitCode := make(chan int, 1)
+			go util.HandleInterrupts(signals, func(code int) { exitCode <- code })
+			signals <- os.Interrupt
+
+			Eventually(util.Interrupted().Done()).Should(BeClosed())
+			Consistently(exitCode).ShouldNot(Receive())
+			_, err = os.Stat(f.Name())
+			Expect(err).ToNot(HaveOccurred())
+
+			signals <- os.Interrupt
+			Eventually(exitCode).Should(Receive(Equal(130)))
+			_, err = os.Stat(f.Name())
+			Expect(os.IsNotExist(err)).To(BeTrue())
+			_, err = os.Stat(path)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("does not remove a path again once it has been removed", func() {
+			f, err := ioutil.TempFile("", "")
+			Expect(err).ToNot(HaveOccurred())
+			f.Close()
+			util.RemoveOnInterrupt(f.Name())
+			Expect(util.RemoveTemp(f.Name())).To(Succeed())
+
+			Expect(ioutil.WriteFile(f.Name(), []byte("state"), 0600)).To(Succeed())
+			defer os.Remove(f.Name())
+			util.CleanupTempFiles()
+			_, err = os.Stat(f.Name())
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
 })